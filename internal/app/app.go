@@ -22,6 +22,7 @@ import (
 
 	"github.com/charmbracelet/crush/internal/lsp"
 	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/notifications"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/session"
 )
@@ -32,6 +33,10 @@ type App struct {
 	History     history.Service
 	Permissions permission.Service
 
+	// Notifications is shared by the notify tool and completion notifications
+	// so they draw on the same rate limits
+	Notifications *notifications.Registry
+
 	CoderAgent agent.Service
 
 	LSPClients map[string]*lsp.Client
@@ -65,13 +70,25 @@ func New(ctx context.Context, conn *sql.DB, cfg *config.Config) (*App, error) {
 		allowedTools = cfg.Permissions.AllowedTools
 	}
 
+	var permissions permission.Service = permission.NewPermissionService(cfg.WorkingDir(), skipPermissionsRequests, allowedTools)
+	if cfg.Permissions != nil && len(cfg.Permissions.DenyRules) > 0 {
+		// Learning stays off; the smart service is only used to enforce the rules
+		smart := permission.NewSmartPermissionService(permissions, cfg.WorkingDir(), false)
+		if err := smart.SetDenyRules(cfg.Permissions.DenyRules); err != nil {
+			return nil, fmt.Errorf("failed to load permission deny rules: %w", err)
+		}
+		permissions = smart
+	}
+
 	app := &App{
 		Sessions:    sessions,
 		Messages:    messages,
 		History:     files,
-		Permissions: permission.NewPermissionService(cfg.WorkingDir(), skipPermissionsRequests, allowedTools),
+		Permissions: permissions,
 		LSPClients:  make(map[string]*lsp.Client),
 
+		Notifications: notifications.NewRegistryFromConfig(cfg.Notifications),
+
 		globalCtx: ctx,
 
 		config: cfg,
@@ -275,6 +292,7 @@ func (app *App) InitCoderAgent() error {
 		app.Messages,
 		app.History,
 		app.LSPClients,
+		app.Notifications,
 	)
 	if err != nil {
 		slog.Error("Failed to create coder agent", "err", err)
@@ -322,7 +340,7 @@ func (app *App) Subscribe(program *tea.Program) {
 // Shutdown performs a graceful shutdown of the application.
 func (app *App) Shutdown() {
 	if app.CoderAgent != nil {
-		app.CoderAgent.CancelAll()
+		app.CoderAgent.Shutdown()
 	}
 
 	for cancel := range app.watcherCancelFuncs.Seq() {
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
 	"time"
 
@@ -27,6 +28,13 @@ type Checkpoint struct {
 	Branch      string    `json:"branch"`
 	Files       []string  `json:"files"`
 	IsStashed   bool      `json:"is_stashed"`
+	SessionID   string    `json:"session_id,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+}
+
+// HasTag reports whether the checkpoint carries tag
+func (c Checkpoint) HasTag(tag string) bool {
+	return slices.Contains(c.Tags, strings.TrimSpace(tag))
 }
 
 // CheckpointList holds multiple checkpoints
@@ -34,6 +42,17 @@ type CheckpointList struct {
 	Checkpoints []Checkpoint `json:"checkpoints"`
 }
 
+// WithTag returns the checkpoints carrying tag
+func (l *CheckpointList) WithTag(tag string) *CheckpointList {
+	filtered := &CheckpointList{}
+	for _, checkpoint := range l.Checkpoints {
+		if checkpoint.HasTag(tag) {
+			filtered.Checkpoints = append(filtered.Checkpoints, checkpoint)
+		}
+	}
+	return filtered
+}
+
 // NewCheckpointService creates a new checkpoint service
 func NewCheckpointService(workingDir string, permissions permission.Service) *CheckpointService {
 	return &CheckpointService{
@@ -42,8 +61,10 @@ func NewCheckpointService(workingDir string, permissions permission.Service) *Ch
 	}
 }
 
-// CreateCheckpoint creates a new checkpoint by committing current changes
-func (cs *CheckpointService) CreateCheckpoint(ctx context.Context, message string) (*Checkpoint, error) {
+// CreateCheckpoint creates a new checkpoint by stashing current changes and
+// records its metadata in the checkpoint index on behalf of the given session.
+// Tags group related checkpoints so listings can be filtered by them.
+func (cs *CheckpointService) CreateCheckpoint(ctx context.Context, sessionID, message string, tags []string) (*Checkpoint, error) {
 	// Check if we're in a git repository
 	if !cs.isGitRepo() {
 		return nil, fmt.Errorf("not in a git repository")
@@ -55,18 +76,19 @@ func (cs *CheckpointService) CreateCheckpoint(ctx context.Context, message strin
 		return nil, fmt.Errorf("failed to get current branch: %w", err)
 	}
 
-	// Check for uncommitted changes
-	hasChanges, err := cs.hasUncommittedChanges()
+	// Record what is about to be stashed, which also tells whether there is anything to stash
+	files, err := cs.changedFiles()
 	if err != nil {
 		return nil, fmt.Errorf("failed to check for changes: %w", err)
 	}
 
 	var checkpoint *Checkpoint
 
-	if hasChanges {
+	if len(files) > 0 {
 		// Create checkpoint by stashing changes with a message
 		stashMessage := fmt.Sprintf("crush-checkpoint: %s", message)
-		if err := cs.runGitCommand("stash", "push", "-m", stashMessage, "--include-untracked"); err != nil {
+		args := append([]string{"stash", "push", "-m", stashMessage, "--include-untracked", "--"}, checkpointPathspec...)
+		if err := cs.runGitCommand(args...); err != nil {
 			return nil, fmt.Errorf("failed to create stash: %w", err)
 		}
 
@@ -76,16 +98,26 @@ func (cs *CheckpointService) CreateCheckpoint(ctx context.Context, message strin
 			return nil, fmt.Errorf("failed to get stash hash: %w", err)
 		}
 
+		now := time.Now()
 		checkpoint = &Checkpoint{
-			ID:        fmt.Sprintf("stash-%d", time.Now().Unix()),
+			ID:        fmt.Sprintf("stash-%d", now.UnixNano()),
 			Message:   message,
-			Timestamp: time.Now(),
+			Timestamp: now,
 			Hash:      stashHash,
 			Branch:    branch,
+			Files:     files,
 			IsStashed: true,
+			SessionID: sessionID,
+			Tags:      normalizeTags(tags),
+		}
+
+		// The stash already holds the changes, so a failure to index it only
+		// costs the richer metadata in listings
+		if err := cs.indexCheckpoint(*checkpoint); err != nil {
+			slog.Warn("Failed to record checkpoint metadata", "id", checkpoint.ID, "error", err)
 		}
 
-		slog.Info("Created checkpoint via stash", "message", message, "hash", stashHash)
+		slog.Info("Created checkpoint via stash", "message", message, "hash", stashHash, "files", len(files))
 	} else {
 		// No changes to checkpoint
 		return nil, fmt.Errorf("no uncommitted changes to checkpoint")
@@ -121,26 +153,25 @@ func (cs *CheckpointService) ListCheckpoints(ctx context.Context) (*CheckpointLi
 	return &CheckpointList{Checkpoints: checkpoints}, nil
 }
 
-// RestoreCheckpoint restores a checkpoint by applying a stash or resetting to a commit
-func (cs *CheckpointService) RestoreCheckpoint(ctx context.Context, checkpointID string) error {
+// RestoreCheckpoint restores a checkpoint by applying a stash or resetting to a commit.
+// Restoring can overwrite current changes, so permission is always requested
+// on behalf of the given session and tool call.
+func (cs *CheckpointService) RestoreCheckpoint(ctx context.Context, sessionID, toolCallID, checkpointID string) error {
 	if !cs.isGitRepo() {
 		return fmt.Errorf("not in a git repository")
 	}
 
 	// Request permission for potentially destructive operation
-	sessionID, messageID := getContextValues(ctx)
-	if sessionID != "" && messageID != "" {
-		granted := cs.permissions.Request(permission.CreatePermissionRequest{
-			SessionID:   sessionID,
-			ToolCallID:  messageID,
-			ToolName:    "checkpoint_restore",
-			Action:      "restore",
-			Path:        cs.workingDir,
-			Description: fmt.Sprintf("Restore checkpoint %s (this will overwrite current changes)", checkpointID),
-		})
-		if !granted {
-			return fmt.Errorf("permission denied to restore checkpoint")
-		}
+	granted := cs.permissions.Request(permission.CreatePermissionRequest{
+		SessionID:   sessionID,
+		ToolCallID:  toolCallID,
+		ToolName:    "checkpoint_restore",
+		Action:      "restore",
+		Path:        cs.workingDir,
+		Description: fmt.Sprintf("Restore checkpoint %s (this will overwrite current changes)", checkpointID),
+	})
+	if !granted {
+		return fmt.Errorf("permission denied to restore checkpoint")
 	}
 
 	if strings.HasPrefix(checkpointID, "stash-") {
@@ -170,6 +201,9 @@ func (cs *CheckpointService) DeleteCheckpoint(ctx context.Context, checkpointID
 				if err := cs.runGitCommand("stash", "drop", fmt.Sprintf("stash@{%d}", i)); err != nil {
 					return fmt.Errorf("failed to drop stash: %w", err)
 				}
+				if err := cs.unindexCheckpoint(checkpointID); err != nil {
+					slog.Warn("Failed to remove checkpoint metadata", "id", checkpointID, "error", err)
+				}
 				slog.Info("Deleted checkpoint", "id", checkpointID)
 				return nil
 			}
@@ -198,15 +232,70 @@ func (cs *CheckpointService) getCurrentBranch() (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
-// hasUncommittedChanges checks if there are uncommitted changes
-func (cs *CheckpointService) hasUncommittedChanges() (bool, error) {
-	cmd := exec.Command("git", "status", "--porcelain")
+// changedFiles returns the paths of all modified, staged and untracked files,
+// i.e. everything a stash with --include-untracked would capture
+func (cs *CheckpointService) changedFiles() ([]string, error) {
+	args := append([]string{"status", "--porcelain", "-z", "--untracked-files=all", "--"}, checkpointPathspec...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = cs.workingDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return parsePorcelainZ(string(output)), nil
+}
+
+// normalizeTags trims tags and drops empty and repeated ones
+func normalizeTags(tags []string) []string {
+	var normalized []string
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// checkpointPathspec selects what a checkpoint captures: the whole work tree
+// except the Crush data directory
+var checkpointPathspec = []string{":/", ":(exclude)" + dataDir}
+
+// parsePorcelainZ extracts the file paths from `git status --porcelain -z`
+// output. Each entry is "XY path"; renames and copies are followed by an
+// extra entry holding the original path, which is skipped.
+func parsePorcelainZ(output string) []string {
+	var files []string
+	entries := strings.Split(output, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		files = append(files, entry[3:])
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++
+		}
+	}
+	return files
+}
+
+// filesIn returns the files touched by a commit or stash using a git command
+// that lists NUL separated names, such as `git show --name-only -z`
+func (cs *CheckpointService) filesIn(args ...string) ([]string, error) {
+	cmd := exec.Command("git", args...)
 	cmd.Dir = cs.workingDir
 	output, err := cmd.Output()
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+
+	var files []string
+	for name := range strings.SplitSeq(string(output), "\x00") {
+		if name = strings.TrimPrefix(name, "\n"); name != "" {
+			files = append(files, name)
+		}
 	}
-	return len(strings.TrimSpace(string(output))) > 0, nil
+	return files, nil
 }
 
 // runGitCommand runs a git command in the working directory
@@ -238,6 +327,12 @@ func (cs *CheckpointService) getStashes() ([]Checkpoint, error) {
 		return nil, err
 	}
 
+	idx, err := cs.loadIndex()
+	if err != nil {
+		slog.Warn("Ignoring checkpoint index", "error", err)
+		idx = &checkpointIndex{}
+	}
+
 	var checkpoints []Checkpoint
 	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
 	
@@ -251,6 +346,14 @@ func (cs *CheckpointService) getStashes() ([]Checkpoint, error) {
 		}
 
 		hash := parts[0]
+		if checkpoint, ok := idx.byHash(hash); ok {
+			checkpoint.IsStashed = true
+			checkpoints = append(checkpoints, checkpoint)
+			continue
+		}
+
+		// Stashes missing from the index, such as ones made before it
+		// existed, only have what the stash message records
 		message := strings.TrimPrefix(parts[2], "On ")
 		
 		// Extract crush checkpoint message
@@ -263,11 +366,17 @@ func (cs *CheckpointService) getStashes() ([]Checkpoint, error) {
 
 		timestamp := time.Unix(parseUnixTimestamp(parts[3]), 0)
 
+		files, err := cs.filesIn("stash", "show", "--name-only", "-z", "--include-untracked", hash)
+		if err != nil {
+			slog.Warn("Failed to list checkpoint files", "stash", hash, "error", err)
+		}
+
 		checkpoints = append(checkpoints, Checkpoint{
 			ID:        fmt.Sprintf("stash-%d", i),
 			Message:   message,
 			Timestamp: timestamp,
 			Hash:      hash,
+			Files:     files,
 			IsStashed: true,
 		})
 	}
@@ -300,11 +409,17 @@ func (cs *CheckpointService) getRecentCommits(limit int) ([]Checkpoint, error) {
 		message := parts[1]
 		timestamp := time.Unix(parseUnixTimestamp(parts[2]), 0)
 
+		files, err := cs.filesIn("show", "--name-only", "-z", "--format=", hash)
+		if err != nil {
+			slog.Warn("Failed to list checkpoint files", "commit", hash, "error", err)
+		}
+
 		checkpoints = append(checkpoints, Checkpoint{
 			ID:        hash[:8], // Short hash
 			Message:   message,
 			Timestamp: timestamp,
 			Hash:      hash,
+			Files:     files,
 			IsStashed: false,
 		})
 	}
@@ -351,9 +466,46 @@ func parseUnixTimestamp(s string) int64 {
 	return time.Now().Unix()
 }
 
-// getContextValues extracts session and message IDs from context
-func getContextValues(ctx context.Context) (string, string) {
-	sessionID, _ := ctx.Value("sessionID").(string)
-	messageID, _ := ctx.Value("messageID").(string)
-	return sessionID, messageID
-}
\ No newline at end of file
+// indexCheckpoint adds a checkpoint to the index, dropping entries for
+// stashes that no longer exist
+func (cs *CheckpointService) indexCheckpoint(checkpoint Checkpoint) error {
+	idx, err := cs.loadIndex()
+	if err != nil {
+		return err
+	}
+	hashes, err := cs.stashHashes()
+	if err != nil {
+		return err
+	}
+	idx.prune(hashes)
+	idx.Checkpoints[checkpoint.ID] = checkpoint
+	return cs.saveIndex(idx)
+}
+
+// unindexCheckpoint removes a checkpoint from the index
+func (cs *CheckpointService) unindexCheckpoint(checkpointID string) error {
+	idx, err := cs.loadIndex()
+	if err != nil {
+		return err
+	}
+	if _, ok := idx.Checkpoints[checkpointID]; !ok {
+		return nil
+	}
+	delete(idx.Checkpoints, checkpointID)
+	return cs.saveIndex(idx)
+}
+
+// stashHashes returns the hashes of all current stashes
+func (cs *CheckpointService) stashHashes() (map[string]bool, error) {
+	cmd := exec.Command("git", "stash", "list", "--format=%H")
+	cmd.Dir = cs.workingDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[string]bool)
+	for hash := range strings.FieldsSeq(string(output)) {
+		hashes[hash] = true
+	}
+	return hashes, nil
+}
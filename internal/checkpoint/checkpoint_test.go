@@ -0,0 +1,205 @@
+package checkpoint
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/stretchr/testify/require"
+)
+
+// gitRepo creates a repository in a temporary directory with one commit
+// containing the given files
+func gitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=crush", "GIT_AUTHOR_EMAIL=crush@example.com", "GIT_COMMITTER_NAME=crush", "GIT_COMMITTER_EMAIL=crush@example.com")
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+
+	git("init", "-q")
+	for name, content := range files {
+		writeFile(t, dir, name, content)
+	}
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial commit")
+	return dir
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestCreateCheckpointRecordsChangedFiles(t *testing.T) {
+	dir := gitRepo(t, map[string]string{"main.go": "package main\n", "README.md": "# demo\n", "docs/guide.md": "guide\n"})
+	writeFile(t, dir, "main.go", "package main\n\nfunc main() {}\n")
+	require.NoError(t, os.Remove(filepath.Join(dir, "README.md")))
+	writeFile(t, dir, "internal/new file.go", "package internal\n")
+
+	cs := NewCheckpointService(dir, permission.NewPermissionService(dir, true, nil))
+	checkpoint, err := cs.CreateCheckpoint(t.Context(), "session-1", "before refactor", nil)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"main.go", "README.md", "internal/new file.go"}, checkpoint.Files)
+
+	list, err := cs.ListCheckpoints(t.Context())
+	require.NoError(t, err)
+	require.Len(t, list.Checkpoints, 2)
+
+	stash := list.Checkpoints[0]
+	require.True(t, stash.IsStashed)
+	require.ElementsMatch(t, checkpoint.Files, stash.Files)
+
+	commit := list.Checkpoints[1]
+	require.False(t, commit.IsStashed)
+	require.ElementsMatch(t, []string{"README.md", "docs/guide.md", "main.go"}, commit.Files)
+}
+
+func TestCreateCheckpointWithoutChanges(t *testing.T) {
+	dir := gitRepo(t, map[string]string{"main.go": "package main\n"})
+
+	cs := NewCheckpointService(dir, permission.NewPermissionService(dir, true, nil))
+	_, err := cs.CreateCheckpoint(t.Context(), "session-1", "nothing", nil)
+	require.ErrorContains(t, err, "no uncommitted changes")
+}
+
+func TestParsePorcelainZ(t *testing.T) {
+	output := " M main.go\x00R  new.go\x00old.go\x00?? dir/untracked file.txt\x00D  gone.go\x00"
+	require.Equal(t, []string{"main.go", "new.go", "dir/untracked file.txt", "gone.go"}, parsePorcelainZ(output))
+	require.Empty(t, parsePorcelainZ(""))
+}
+
+func TestCheckpointIndexRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	cs := NewCheckpointService(dir, permission.NewPermissionService(dir, true, nil))
+
+	idx, err := cs.loadIndex()
+	require.NoError(t, err)
+	require.Empty(t, idx.Checkpoints)
+
+	checkpoint := Checkpoint{
+		ID:        "stash-1",
+		Message:   "before refactor: rename | helpers",
+		Timestamp: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+		Hash:      "abc123",
+		Branch:    "main",
+		Files:     []string{"main.go", "dir/new file.go"},
+		IsStashed: true,
+		SessionID: "session-1",
+	}
+	idx.Checkpoints[checkpoint.ID] = checkpoint
+	require.NoError(t, cs.saveIndex(idx))
+	require.FileExists(t, filepath.Join(dir, IndexFile))
+
+	loaded, err := cs.loadIndex()
+	require.NoError(t, err)
+	require.Equal(t, map[string]Checkpoint{"stash-1": checkpoint}, loaded.Checkpoints)
+
+	found, ok := loaded.byHash("abc123")
+	require.True(t, ok)
+	require.Equal(t, checkpoint, found)
+
+	loaded.prune(map[string]bool{"other": true})
+	require.Empty(t, loaded.Checkpoints)
+}
+
+func TestCheckpointIndexInvalid(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, IndexFile, "{not json")
+
+	cs := NewCheckpointService(dir, permission.NewPermissionService(dir, true, nil))
+	_, err := cs.loadIndex()
+	require.ErrorContains(t, err, "failed to parse checkpoint index")
+}
+
+func TestListCheckpointsUsesIndex(t *testing.T) {
+	dir := gitRepo(t, map[string]string{"main.go": "package main\n"})
+	cs := NewCheckpointService(dir, permission.NewPermissionService(dir, true, nil))
+
+	// A stash made outside Crush is listed from its stash message alone
+	writeFile(t, dir, "main.go", "package main\n\n// manual\n")
+	cmd := exec.Command("git", "stash", "push", "-m", "manual stash")
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	writeFile(t, dir, "main.go", "package main\n\nfunc main() {}\n")
+	writeFile(t, dir, ".crush/crush.db", "data")
+	created, err := cs.CreateCheckpoint(t.Context(), "session-1", "before: the | refactor", nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"main.go"}, created.Files)
+	require.FileExists(t, filepath.Join(dir, ".crush", "crush.db"), "the data directory must not be stashed")
+
+	list, err := cs.ListCheckpoints(t.Context())
+	require.NoError(t, err)
+	require.Len(t, list.Checkpoints, 3)
+
+	indexed := list.Checkpoints[0]
+	require.Equal(t, created.ID, indexed.ID)
+	require.Equal(t, "before: the | refactor", indexed.Message)
+	require.Equal(t, "session-1", indexed.SessionID)
+	require.Equal(t, created.Branch, indexed.Branch)
+	require.True(t, indexed.IsStashed)
+
+	manual := list.Checkpoints[1]
+	require.True(t, manual.IsStashed)
+	require.Contains(t, manual.Message, "manual stash")
+	require.Empty(t, manual.SessionID)
+
+	// Checkpoints are found by the ID returned on creation
+	require.NoError(t, cs.RestoreCheckpoint(t.Context(), "session-1", "call-1", created.ID))
+	content, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	require.NoError(t, err)
+	require.Equal(t, "package main\n\nfunc main() {}\n", string(content))
+
+	require.NoError(t, cs.DeleteCheckpoint(t.Context(), created.ID))
+	idx, err := cs.loadIndex()
+	require.NoError(t, err)
+	require.NotContains(t, idx.Checkpoints, created.ID)
+
+	list, err = cs.ListCheckpoints(t.Context())
+	require.NoError(t, err)
+	require.Len(t, list.Checkpoints, 2)
+}
+
+func TestCheckpointTags(t *testing.T) {
+	dir := gitRepo(t, map[string]string{"main.go": "package main\n"})
+	cs := NewCheckpointService(dir, permission.NewPermissionService(dir, true, nil))
+
+	writeFile(t, dir, "main.go", "package main\n\n// one\n")
+	first, err := cs.CreateCheckpoint(t.Context(), "session-1", "first", []string{" before-refactor ", "", "experiment-A", "before-refactor"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"before-refactor", "experiment-A"}, first.Tags)
+
+	writeFile(t, dir, "main.go", "package main\n\n// two\n")
+	second, err := cs.CreateCheckpoint(t.Context(), "session-1", "second", []string{"experiment-B"})
+	require.NoError(t, err)
+
+	list, err := cs.ListCheckpoints(t.Context())
+	require.NoError(t, err)
+
+	tagged := list.WithTag("before-refactor")
+	require.Len(t, tagged.Checkpoints, 1)
+	require.Equal(t, first.ID, tagged.Checkpoints[0].ID)
+	require.Equal(t, first.Tags, tagged.Checkpoints[0].Tags)
+
+	tagged = list.WithTag("experiment-B")
+	require.Len(t, tagged.Checkpoints, 1)
+	require.Equal(t, second.ID, tagged.Checkpoints[0].ID)
+
+	require.Empty(t, list.WithTag("missing").Checkpoints)
+}
@@ -0,0 +1,89 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// IndexFile is where checkpoint metadata is kept, relative to the working
+// directory. Git stays the source of truth for checkpoint content; the index
+// only describes the stashes it created.
+const IndexFile = ".crush/checkpoints.json"
+
+// dataDir is excluded from checkpoints so stashing never takes the index, or
+// anything else Crush keeps there, away from the running application
+const dataDir = ".crush"
+
+// checkpointIndex maps checkpoint IDs to their metadata
+type checkpointIndex struct {
+	Checkpoints map[string]Checkpoint `json:"checkpoints"`
+}
+
+// byHash returns the indexed checkpoint for a stash hash
+func (idx *checkpointIndex) byHash(hash string) (Checkpoint, bool) {
+	for _, checkpoint := range idx.Checkpoints {
+		if checkpoint.Hash == hash {
+			return checkpoint, true
+		}
+	}
+	return Checkpoint{}, false
+}
+
+// prune drops entries whose stash no longer exists
+func (idx *checkpointIndex) prune(hashes map[string]bool) {
+	for id, checkpoint := range idx.Checkpoints {
+		if !hashes[checkpoint.Hash] {
+			delete(idx.Checkpoints, id)
+		}
+	}
+}
+
+func (cs *CheckpointService) indexPath() string {
+	return filepath.Join(cs.workingDir, IndexFile)
+}
+
+// loadIndex reads the checkpoint index, returning an empty one if it doesn't
+// exist yet
+func (cs *CheckpointService) loadIndex() (*checkpointIndex, error) {
+	idx := &checkpointIndex{Checkpoints: make(map[string]Checkpoint)}
+
+	data, err := os.ReadFile(cs.indexPath())
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint index: %w", err)
+	}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint index %s: %w", cs.indexPath(), err)
+	}
+	if idx.Checkpoints == nil {
+		idx.Checkpoints = make(map[string]Checkpoint)
+	}
+	return idx, nil
+}
+
+// saveIndex writes the checkpoint index, replacing the previous file
+// atomically so an interrupted write can't corrupt it
+func (cs *CheckpointService) saveIndex(idx *checkpointIndex) error {
+	path := cs.indexPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create checkpoint index directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint index: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write checkpoint index: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write checkpoint index: %w", err)
+	}
+	return nil
+}
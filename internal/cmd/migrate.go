@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Inspect and manage database migrations",
+	Long: `Inspect the database schema version and apply or roll back migrations.
+
+Crush applies pending migrations automatically on startup. Use this command to
+roll back a broken migration after an upgrade.`,
+	Example: `
+# Show the current schema version
+crush migrate status
+
+# Roll back the most recent migration
+crush migrate down
+
+# Roll back to a specific version
+crush migrate down --to 20250624000000`,
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the current schema version",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMigrationDB(cmd, func(conn *sql.DB, dialect string) error {
+			version, err := db.CurrentVersion(conn, dialect)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Current schema version: %d\n", version)
+			return nil
+		})
+	},
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply all pending migrations",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMigrationDB(cmd, db.MigrateUp)
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Roll back migrations",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		steps, _ := cmd.Flags().GetInt("steps")
+		to, _ := cmd.Flags().GetInt64("to")
+
+		return withMigrationDB(cmd, func(conn *sql.DB, dialect string) error {
+			if cmd.Flags().Changed("to") {
+				return db.MigrateDownTo(conn, dialect, to)
+			}
+			return db.MigrateDown(conn, dialect, steps)
+		})
+	},
+}
+
+// withMigrationDB opens the configured database without applying migrations
+// and runs fn against it
+func withMigrationDB(cmd *cobra.Command, fn func(conn *sql.DB, dialect string) error) error {
+	cwd, err := ResolveCwd(cmd)
+	if err != nil {
+		return err
+	}
+	dataDir, _ := cmd.Flags().GetString("data-dir")
+
+	cfg, err := config.Load(cwd, dataDir, false)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %v", err)
+	}
+
+	dbConfig := cfg.Database
+	if dbConfig == nil {
+		// Default to SQLite
+		dbConfig = &db.DatabaseConfig{
+			Type:     "sqlite",
+			Database: "crush.db",
+			DataDir:  cfg.Options.DataDirectory,
+		}
+	}
+
+	dialect, err := db.Dialect(dbConfig)
+	if err != nil {
+		return err
+	}
+	conn, err := db.Open(cmd.Context(), dbConfig)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer conn.Close()
+
+	return fn(conn, dialect)
+}
+
+func init() {
+	migrateDownCmd.Flags().Int("steps", 1, "Number of migrations to roll back")
+	migrateDownCmd.Flags().Int64("to", 0, "Roll back to this schema version instead of a number of steps")
+	migrateCmd.AddCommand(migrateStatusCmd, migrateUpCmd, migrateDownCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"os"
 
 	"github.com/charmbracelet/crush/internal/app"
 	"github.com/charmbracelet/crush/internal/config"
@@ -27,11 +28,39 @@ crush web
 # Start on custom port  
 crush web --port 3000
 
+# Listen on all interfaces to allow access from other devices on the network
+crush web --host 0.0.0.0
+
 # Start with debug logging
-crush web --debug`,
+crush web --debug
+
+# Serve over HTTPS with your own certificate
+crush web --tls-cert cert.pem --tls-key key.pem
+
+# Serve over HTTPS with a generated self-signed certificate for local development
+crush web --tls-self-signed
+
+# Enable the authenticated management endpoints
+crush web --auth-token "$(openssl rand -hex 32)"`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		host, _ := cmd.Flags().GetString("host")
 		port, _ := cmd.Flags().GetInt("port")
 		debug, _ := cmd.Flags().GetBool("debug")
+		tlsCert, _ := cmd.Flags().GetString("tls-cert")
+		tlsKey, _ := cmd.Flags().GetString("tls-key")
+		tlsSelfSigned, _ := cmd.Flags().GetBool("tls-self-signed")
+		authToken, _ := cmd.Flags().GetString("auth-token")
+		maxChatBody, _ := cmd.Flags().GetInt64("max-chat-body")
+		maxDockerBody, _ := cmd.Flags().GetInt64("max-docker-body")
+		if authToken == "" {
+			authToken = os.Getenv("CRUSH_WEB_AUTH_TOKEN")
+		}
+
+		// Fail fast on TLS misconfiguration before initializing the backend
+		tlsOpts := server.TLSOptions{CertFile: tlsCert, KeyFile: tlsKey, SelfSigned: tlsSelfSigned}
+		if err := tlsOpts.Validate(); err != nil {
+			return err
+		}
 		
 		slog.Info("Initializing Crush web interface with backend integration", "port", port, "debug", debug)
 		
@@ -69,9 +98,15 @@ crush web --debug`,
 		sessions := crushApp.Sessions
 		permissions := crushApp.Permissions
 
-		slog.Info("Starting Crush web interface with full backend", "port", port)
+		slog.Info("Starting Crush web interface with full backend", "host", host, "port", port)
 		
-		webServer := server.NewWebServer(port, agent, sessions, permissions)
+		webServer := server.NewWebServer(host, port, agent, sessions, permissions)
+		if err := webServer.SetTLS(tlsOpts); err != nil {
+			return err
+		}
+		webServer.SetAuthToken(authToken)
+		webServer.SetBodyLimits(maxChatBody, maxDockerBody)
+		webServer.SetMessages(crushApp.Messages)
 		if err := webServer.Start(); err != nil {
 			return fmt.Errorf("failed to start web server: %w", err)
 		}
@@ -81,7 +116,14 @@ crush web --debug`,
 }
 
 func init() {
+	webCmd.Flags().String("host", server.DefaultHost, "Address to bind the web server to; use 0.0.0.0 to allow access from other machines")
 	webCmd.Flags().IntP("port", "p", 8080, "Port to run the web server on")
 	webCmd.Flags().Bool("debug", false, "Enable debug logging")
+	webCmd.Flags().String("tls-cert", "", "TLS certificate file; serves HTTPS together with --tls-key")
+	webCmd.Flags().String("tls-key", "", "TLS private key file; serves HTTPS together with --tls-cert")
+	webCmd.Flags().Bool("tls-self-signed", false, "Serve HTTPS with a generated self-signed certificate (local development only)")
+	webCmd.Flags().String("auth-token", "", "Bearer token required by management endpoints such as /api/permissions (default $CRUSH_WEB_AUTH_TOKEN)")
+	webCmd.Flags().Int64("max-chat-body", 1<<20, "Maximum size in bytes of chat, session and permission request bodies")
+	webCmd.Flags().Int64("max-docker-body", 32<<20, "Maximum size in bytes of docker request bodies")
 	rootCmd.AddCommand(webCmd)
 }
\ No newline at end of file
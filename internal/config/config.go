@@ -136,6 +136,7 @@ type TUIOptions struct {
 type Permissions struct {
 	AllowedTools []string `json:"allowed_tools,omitempty" jsonschema:"description=List of tools that don't require permission prompts,example=bash,example=view"` // Tools that don't require permission prompts
 	SkipRequests bool     `json:"-"`                                                                                                                              // Automatically accept all permissions (YOLO mode)
+	DenyRules    []string `json:"deny_rules,omitempty" jsonschema:"description=Rules that deny matching tool:action:path requests,example=*:write:/etc/**"`       // Requests that are always denied
 }
 
 type Options struct {
@@ -152,19 +153,27 @@ type Options struct {
 
 type EnhanceOptions struct {
 	// Response caching options
-	EnableCache     bool `json:"enable_cache,omitempty" jsonschema:"description=Enable response caching to reduce API calls,default=true"`
-	CacheTTLMinutes int  `json:"cache_ttl_minutes,omitempty" jsonschema:"description=Cache time-to-live in minutes,default=30,minimum=1,maximum=1440"`
-	CacheMaxEntries int  `json:"cache_max_entries,omitempty" jsonschema:"description=Maximum number of cache entries,default=100,minimum=10,maximum=1000"`
+	EnableCache          bool           `json:"enable_cache,omitempty" jsonschema:"description=Enable response caching to reduce API calls,default=true"`
+	CacheTTLMinutes      int            `json:"cache_ttl_minutes,omitempty" jsonschema:"description=Cache time-to-live in minutes,default=30,minimum=1,maximum=1440"`
+	CacheModelTTLMinutes map[string]int `json:"cache_model_ttl_minutes,omitempty" jsonschema:"description=Cache time-to-live in minutes for specific models keyed by model ID; other models use cache_ttl_minutes"`
+	CacheMaxEntries      int            `json:"cache_max_entries,omitempty" jsonschema:"description=Maximum number of cache entries,default=100,minimum=10,maximum=1000"`
+	PersistCache         bool           `json:"persist_cache,omitempty" jsonschema:"description=Persist cached responses to the data directory across runs,default=false"`
+	CacheSweepMinutes    int            `json:"cache_sweep_minutes,omitempty" jsonschema:"description=Interval in minutes at which expired cache entries are removed in the background,default=5,minimum=1,maximum=1440"`
 
 	// Cost estimation options
 	EnableCostEstimation bool    `json:"enable_cost_estimation,omitempty" jsonschema:"description=Enable cost estimation before API calls,default=true"`
 	MaxCostThreshold     float64 `json:"max_cost_threshold,omitempty" jsonschema:"description=Maximum cost per request before warning (in USD),default=0.50,minimum=0.01,maximum=10.0"`
 	AutoOptimizeContext  bool    `json:"auto_optimize_context,omitempty" jsonschema:"description=Automatically optimize context for cost reduction,default=true"`
+	SessionBudget        float64 `json:"session_budget,omitempty" jsonschema:"description=Maximum cumulative cost of a chat session in USD before requests are blocked (0 for no budget),minimum=0"`
 
 	// Feedback mechanism options
-	EnableFeedback   bool    `json:"enable_feedback,omitempty" jsonschema:"description=Enable response quality feedback mechanism,default=true"`
-	QualityThreshold float64 `json:"quality_threshold,omitempty" jsonschema:"description=Minimum quality score for responses (0.0-1.0),default=0.7,minimum=0.0,maximum=1.0"`
-	MaxRetryAttempts int     `json:"max_retry_attempts,omitempty" jsonschema:"description=Maximum retry attempts for improving responses,default=2,minimum=0,maximum=5"`
+	EnableFeedback   bool               `json:"enable_feedback,omitempty" jsonschema:"description=Enable response quality feedback mechanism,default=true"`
+	QualityThreshold float64            `json:"quality_threshold,omitempty" jsonschema:"description=Minimum quality score for responses (0.0-1.0),default=0.7,minimum=0.0,maximum=1.0"`
+	MaxRetryAttempts int                `json:"max_retry_attempts,omitempty" jsonschema:"description=Maximum retry attempts for improving responses,default=2,minimum=0,maximum=5"`
+	FeedbackWeights  map[string]float64 `json:"feedback_weights,omitempty" jsonschema:"description=Weights for quality metrics (completeness, clarity, relevance, specificity, error_indicators) in the overall score"`
+	RecordFeedback   bool               `json:"record_feedback,omitempty" jsonschema:"description=Append response quality evaluations to the data directory for later analysis,default=false"`
+	ValidatePaths    bool               `json:"validate_paths,omitempty" jsonschema:"description=Flag responses that cite file paths which don't exist in the working directory,default=false"`
+	Evaluator        string             `json:"evaluator,omitempty" jsonschema:"description=How response quality is scored: heuristic text metrics or an LLM judge using the small model,enum=heuristic,enum=llm,default=heuristic"`
 }
 
 type MCPs map[string]MCPConfig
@@ -275,7 +284,7 @@ type Config struct {
 
 	Permissions *Permissions `json:"permissions,omitempty" jsonschema:"description=Permission settings for tool usage"`
 
-	Notifications *notifications.NotificationConfig `json:"notifications,omitempty" jsonschema:"description=Notification service configurations (Discord, Telegram)"`
+	Notifications *notifications.NotificationConfig `json:"notifications,omitempty" jsonschema:"description=Notification service configurations (Discord, Telegram, email)"`
 
 	Database *db.DatabaseConfig `json:"database,omitempty" jsonschema:"description=Database configuration (SQLite, PostgreSQL, MySQL)"`
 
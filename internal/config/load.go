@@ -288,7 +288,8 @@ func (c *Config) configureProviders(env env.Env, resolver VariableResolver, know
 		if apiKey == "" || err != nil {
 			slog.Warn("Provider is missing API key, this might be OK for local providers", "provider", id)
 		}
-		baseURL, err := resolver.ResolveValue(providerConfig.BaseURL)
+		// Report every unresolved reference in the endpoint at once
+		baseURL, err := resolver.ResolveValueCollecting(providerConfig.BaseURL)
 		if baseURL == "" || err != nil {
 			slog.Warn("Skipping custom provider due to missing API endpoint", "provider", id, "error", err)
 			c.Providers.Del(id)
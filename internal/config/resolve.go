@@ -2,9 +2,12 @@ package config
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"os/user"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -13,7 +16,55 @@ import (
 )
 
 type VariableResolver interface {
+	// ResolveValue resolves value, failing on the first reference that can't
+	// be resolved
 	ResolveValue(value string) (string, error)
+	// ResolveValueCollecting resolves value like ResolveValue but keeps going
+	// past unresolved references, returning an *UnresolvedError that lists
+	// every one of them
+	ResolveValueCollecting(value string) (string, error)
+}
+
+// UnresolvedError lists every reference in a config value that could not be
+// resolved
+type UnresolvedError struct {
+	Value string
+	// MissingVariables are the referenced environment variables that are not set
+	MissingVariables []string
+	// BlockedCommands are the $(command) substitutions that were not allowed to run
+	BlockedCommands []string
+	// Errors holds the remaining problems, such as malformed references and
+	// failed commands, along with the reason each command was blocked
+	Errors []error
+}
+
+func (e *UnresolvedError) Error() string {
+	var problems []string
+	if len(e.MissingVariables) > 0 {
+		problems = append(problems, "environment variables not set: "+strings.Join(e.MissingVariables, ", "))
+	}
+	if len(e.BlockedCommands) > 0 {
+		problems = append(problems, "commands blocked: "+strings.Join(e.BlockedCommands, ", "))
+	}
+	for _, err := range e.Errors {
+		problems = append(problems, err.Error())
+	}
+	return fmt.Sprintf("unresolved references in value %s: %s", e.Value, strings.Join(problems, "; "))
+}
+
+func (e *UnresolvedError) Unwrap() []error {
+	return e.Errors
+}
+
+// empty reports whether no problems were recorded
+func (e *UnresolvedError) empty() bool {
+	return len(e.MissingVariables) == 0 && len(e.BlockedCommands) == 0 && len(e.Errors) == 0
+}
+
+func (e *UnresolvedError) missing(varName string) {
+	if !slices.Contains(e.MissingVariables, varName) {
+		e.MissingVariables = append(e.MissingVariables, varName)
+	}
 }
 
 type Shell interface {
@@ -25,6 +76,8 @@ type shellVariableResolver struct {
 	env   env.Env
 	allowCommandSubstitution bool
 	allowedCommands []string
+	// dryRun validates and logs $(command) substitutions without running them
+	dryRun bool
 }
 
 // List of commands that are considered safe for command substitution
@@ -77,6 +130,44 @@ func NewShellVariableResolverWithCommands(env env.Env, allowedCommands []string)
 	}
 }
 
+// NewShellVariableResolverAppending creates a resolver with command substitution enabled
+// that allows the default safe commands plus the given extra commands
+func NewShellVariableResolverAppending(env env.Env, extraCommands []string) VariableResolver {
+	return NewShellVariableResolverWithCommands(env, appendAllowedCommands(defaultAllowedCommands, extraCommands))
+}
+
+// NewDryRunShellVariableResolver creates a resolver for previewing a config's
+// command substitutions without side effects. Each $(command) is validated
+// against the dangerous patterns and allowedCommands (the defaults when nil)
+// like it would be for NewShellVariableResolverWithCommands, but instead of
+// running it the resolver logs it and substitutes "<dry-run:command>".
+// Environment variables resolve normally.
+func NewDryRunShellVariableResolver(env env.Env, allowedCommands []string) VariableResolver {
+	if allowedCommands == nil {
+		allowedCommands = defaultAllowedCommands
+	}
+	return &shellVariableResolver{
+		env:                      env,
+		allowCommandSubstitution: true,
+		allowedCommands:          allowedCommands,
+		dryRun:                   true,
+	}
+}
+
+// appendAllowedCommands returns a new allowlist containing base followed by
+// any extra commands not already present.
+func appendAllowedCommands(base, extra []string) []string {
+	commands := make([]string, 0, len(base)+len(extra))
+	for _, command := range append(slices.Clone(base), extra...) {
+		command = strings.TrimSpace(command)
+		if command == "" || slices.Contains(commands, command) {
+			continue
+		}
+		commands = append(commands, command)
+	}
+	return commands
+}
+
 // validateCommand checks if a command is safe to execute
 func (r *shellVariableResolver) validateCommand(command string) error {
 	if !r.allowCommandSubstitution {
@@ -112,75 +203,105 @@ func (r *shellVariableResolver) validateCommand(command string) error {
 // it will resolve shell-like variable substitution anywhere in the string, including:
 // - $(command) for command substitution (if enabled and command is safe)
 // - $VAR or ${VAR} for environment variables
+// - a leading ~ or ~user for home directories
+//
+// Variables whose values reference other set variables are expanded
+// recursively up to maxVariableExpansionDepth levels, and reference cycles are
+// reported as errors. Command output is inserted literally and never
+// re-interpreted.
 func (r *shellVariableResolver) ResolveValue(value string) (string, error) {
 	// Special case: lone $ is an error (backward compatibility)
 	if value == "$" {
 		return "", fmt.Errorf("invalid value format: %s", value)
 	}
 
-	// If no $ found, return as-is
-	if !strings.Contains(value, "$") {
-		return value, nil
+	home, rest := expandTilde(value, r.env)
+	resolved, err := r.resolve(rest, nil, nil)
+	if err != nil {
+		return "", err
 	}
+	return home + resolved, nil
+}
 
-	result := value
+// ResolveValueCollecting resolves value like ResolveValue, but references
+// that can't be resolved are left in place while scanning continues. If any
+// were found, the partially resolved value is returned with an
+// *UnresolvedError listing all of them.
+func (r *shellVariableResolver) ResolveValueCollecting(value string) (string, error) {
+	unresolved := &UnresolvedError{Value: value}
+	if value == "$" {
+		unresolved.Errors = append(unresolved.Errors, fmt.Errorf("invalid value format: %s", value))
+		return value, unresolved
+	}
 
-	// Handle command substitution: $(command)
-	for {
-		start := strings.Index(result, "$(")
-		if start == -1 {
-			break
-		}
+	home, rest := expandTilde(value, r.env)
+	result, err := r.resolve(rest, nil, unresolved)
+	if err != nil {
+		return "", err
+	}
+	result = home + result
+	if !unresolved.empty() {
+		return result, unresolved
+	}
+	return result, nil
+}
 
-		// Find matching closing parenthesis
-		depth := 0
-		end := -1
-		for i := start + 2; i < len(result); i++ {
-			if result[i] == '(' {
-				depth++
-			} else if result[i] == ')' {
-				if depth == 0 {
-					end = i
-					break
-				}
-				depth--
-			}
-		}
+// expandTilde splits a leading ~ or ~user prefix, which runs up to the first
+// slash, off value and returns the home directory it names along with the
+// rest of the value. As in a shell, a tilde anywhere else is literal. When the
+// home directory can't be determined, home is empty and rest is value.
+//
+// A bare ~ names the HOME of env rather than of the process, so a resolver
+// given its own environment never sees the process's home directory.
+func expandTilde(value string, env env.Env) (home, rest string) {
+	if !strings.HasPrefix(value, "~") {
+		return "", value
+	}
 
-		if end == -1 {
-			return "", fmt.Errorf("unmatched $( in value: %s", value)
-		}
+	prefix, rest := value, ""
+	if i := strings.IndexByte(value, '/'); i >= 0 {
+		prefix, rest = value[:i], value[i:]
+	}
 
-		command := result[start+2 : end]
-		
-		// Validate command before execution
-		if err := r.validateCommand(command); err != nil {
-			slog.Warn("🚨 SECURITY: Blocked unsafe command substitution",
-				"command", command,
-				"error", err.Error(),
-				"config_value", value,
-			)
-			return "", fmt.Errorf("command substitution blocked: %w", err)
+	if name := prefix[1:]; name != "" {
+		u, err := user.Lookup(name)
+		if err != nil || u.HomeDir == "" {
+			return "", value
 		}
-
-		slog.Info("Executing safe command substitution",
-			"command", command,
-		)
-
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-
-		stdout, _, err := r.shell.Exec(ctx, command)
-		cancel()
-		if err != nil {
-			return "", fmt.Errorf("command execution failed for '%s': %w", command, err)
+		home = u.HomeDir
+	} else {
+		home = env.Get("HOME")
+		if home == "" {
+			return "", value
 		}
+	}
+
+	// Avoid a double slash for a home directory of /
+	if rest != "" {
+		home = strings.TrimSuffix(home, "/")
+	}
+	return home, rest
+}
 
-		// Replace the $(command) with the output
-		replacement := strings.TrimSpace(stdout)
-		result = result[:start] + replacement + result[end+1:]
+// maxVariableExpansionDepth bounds how many levels of variable indirection are followed
+const maxVariableExpansionDepth = 10
+
+// resolve substitutes the references in value. expanding holds the chain of
+// variables whose values are currently being expanded; when it is non-empty
+// value came from a variable, so command substitution is not performed and
+// anything that is not a reference to a set variable is kept literally.
+//
+// With a nil unresolved, resolve fails on the first reference it can't
+// resolve. Otherwise each such reference is recorded in unresolved and kept
+// literally, and resolve carries on.
+func (r *shellVariableResolver) resolve(value string, expanding []string, unresolved *UnresolvedError) (string, error) {
+	// If no $ found, return as-is
+	if !strings.Contains(value, "$") {
+		return value, nil
 	}
 
-	// Handle environment variables: $VAR and ${VAR}
+	nested := len(expanding) > 0
+	result := value
 	searchStart := 0
 	for {
 		start := strings.Index(result[searchStart:], "$")
@@ -189,12 +310,37 @@ func (r *shellVariableResolver) ResolveValue(value string) (string, error) {
 		}
 		start += searchStart // Adjust for the offset
 
-		// Skip if this is part of $( which we already handled
+		// Handle command substitution: $(command)
 		if start+1 < len(result) && result[start+1] == '(' {
-			// Skip past this $(...)
-			searchStart = start + 1
+			if nested {
+				// Values of variables are data, never commands to run
+				searchStart = start + 1
+				continue
+			}
+
+			replacement, end, err := r.substituteCommand(result, start, value)
+			if err != nil {
+				if unresolved == nil || end == 0 {
+					// Without a closing parenthesis there is nothing to skip past
+					if unresolved == nil {
+						return "", err
+					}
+					unresolved.Errors = append(unresolved.Errors, err)
+					break
+				}
+				var blocked *blockedCommandError
+				if errors.As(err, &blocked) {
+					unresolved.BlockedCommands = append(unresolved.BlockedCommands, blocked.command)
+				}
+				unresolved.Errors = append(unresolved.Errors, err)
+				searchStart = end + 1
+				continue
+			}
+			result = result[:start] + replacement + result[end+1:]
+			searchStart = start + len(replacement) // Command output is not re-interpreted
 			continue
 		}
+
 		var varName string
 		var end int
 
@@ -202,20 +348,47 @@ func (r *shellVariableResolver) ResolveValue(value string) (string, error) {
 			// Handle ${VAR} format
 			closeIdx := strings.Index(result[start+2:], "}")
 			if closeIdx == -1 {
-				return "", fmt.Errorf("unmatched ${ in value: %s", value)
+				if nested {
+					searchStart = start + 1
+					continue
+				}
+				err := fmt.Errorf("unmatched ${ in value: %s", value)
+				if unresolved == nil {
+					return "", err
+				}
+				unresolved.Errors = append(unresolved.Errors, err)
+				break
 			}
 			varName = result[start+2 : start+2+closeIdx]
 			end = start + 2 + closeIdx + 1
 		} else {
 			// Handle $VAR format - variable names must start with letter or underscore
 			if start+1 >= len(result) {
-				return "", fmt.Errorf("incomplete variable reference at end of string: %s", value)
+				if nested {
+					break
+				}
+				err := fmt.Errorf("incomplete variable reference at end of string: %s", value)
+				if unresolved == nil {
+					return "", err
+				}
+				unresolved.Errors = append(unresolved.Errors, err)
+				break
 			}
 
 			if result[start+1] != '_' &&
 				(result[start+1] < 'a' || result[start+1] > 'z') &&
 				(result[start+1] < 'A' || result[start+1] > 'Z') {
-				return "", fmt.Errorf("invalid variable name starting with '%c' in: %s", result[start+1], value)
+				if nested {
+					searchStart = start + 1
+					continue
+				}
+				err := fmt.Errorf("invalid variable name starting with '%c' in: %s", result[start+1], value)
+				if unresolved == nil {
+					return "", err
+				}
+				unresolved.Errors = append(unresolved.Errors, err)
+				searchStart = start + 1
+				continue
 			}
 
 			end = start + 1
@@ -230,7 +403,38 @@ func (r *shellVariableResolver) ResolveValue(value string) (string, error) {
 
 		envValue := r.env.Get(varName)
 		if envValue == "" {
-			return "", fmt.Errorf("environment variable %q not set", varName)
+			if nested {
+				// Most likely a literal $ in a secret such as a password
+				searchStart = start + 1
+				continue
+			}
+			if unresolved == nil {
+				return "", fmt.Errorf("environment variable %q not set", varName)
+			}
+			unresolved.missing(varName)
+			searchStart = end
+			continue
+		}
+
+		if strings.Contains(envValue, "$") {
+			chain := append(slices.Clone(expanding), varName)
+			var err error
+			switch {
+			case slices.Contains(expanding, varName):
+				err = fmt.Errorf("variable reference cycle detected: %s", strings.Join(chain, " -> "))
+			case len(chain) > maxVariableExpansionDepth:
+				err = fmt.Errorf("variable expansion exceeds maximum depth of %d: %s", maxVariableExpansionDepth, strings.Join(chain, " -> "))
+			default:
+				envValue, err = r.resolve(envValue, chain, nil)
+			}
+			if err != nil {
+				if unresolved == nil || nested {
+					return "", err
+				}
+				unresolved.Errors = append(unresolved.Errors, err)
+				searchStart = end
+				continue
+			}
 		}
 
 		result = result[:start] + envValue + result[end:]
@@ -240,6 +444,78 @@ func (r *shellVariableResolver) ResolveValue(value string) (string, error) {
 	return result, nil
 }
 
+// substituteCommand runs the $(command) starting at start in result and
+// returns its trimmed output along with the index of the closing parenthesis.
+// value is the original config value, used in error messages.
+func (r *shellVariableResolver) substituteCommand(result string, start int, value string) (string, int, error) {
+	// Find matching closing parenthesis
+	depth := 0
+	end := -1
+	for i := start + 2; i < len(result); i++ {
+		if result[i] == '(' {
+			depth++
+		} else if result[i] == ')' {
+			if depth == 0 {
+				end = i
+				break
+			}
+			depth--
+		}
+	}
+
+	if end == -1 {
+		return "", 0, fmt.Errorf("unmatched $( in value: %s", value)
+	}
+
+	command := result[start+2 : end]
+
+	// Validate command before execution
+	if err := r.validateCommand(command); err != nil {
+		slog.Warn("🚨 SECURITY: Blocked unsafe command substitution",
+			"command", command,
+			"error", err.Error(),
+			"config_value", value,
+		)
+		return "", end, &blockedCommandError{command: command, err: err}
+	}
+
+	if r.dryRun {
+		slog.Info("Dry run: command substitution would execute",
+			"command", command,
+			"config_value", value,
+		)
+		return fmt.Sprintf("<dry-run:%s>", command), end, nil
+	}
+
+	slog.Info("Executing safe command substitution",
+		"command", command,
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	stdout, _, err := r.shell.Exec(ctx, command)
+	if err != nil {
+		return "", end, fmt.Errorf("command execution failed for '%s': %w", command, err)
+	}
+
+	return strings.TrimSpace(stdout), end, nil
+}
+
+// blockedCommandError reports a $(command) that validation did not allow to run
+type blockedCommandError struct {
+	command string
+	err     error
+}
+
+func (e *blockedCommandError) Error() string {
+	return fmt.Sprintf("command substitution blocked: %v", e.err)
+}
+
+func (e *blockedCommandError) Unwrap() error {
+	return e.err
+}
+
 type environmentVariableResolver struct {
 	env env.Env
 }
@@ -252,6 +528,9 @@ func NewEnvironmentVariableResolver(env env.Env) VariableResolver {
 
 // ResolveValue resolves environment variables from the provided env.Env.
 func (r *environmentVariableResolver) ResolveValue(value string) (string, error) {
+	if home, rest := expandTilde(value, r.env); home != "" {
+		return home + rest, nil
+	}
 	if !strings.HasPrefix(value, "$") {
 		return value, nil
 	}
@@ -263,3 +542,14 @@ func (r *environmentVariableResolver) ResolveValue(value string) (string, error)
 	}
 	return resolvedValue, nil
 }
+
+// ResolveValueCollecting resolves value like ResolveValue. A value holds at
+// most one reference, so the *UnresolvedError returned lists at most one
+// missing variable.
+func (r *environmentVariableResolver) ResolveValueCollecting(value string) (string, error) {
+	resolvedValue, err := r.ResolveValue(value)
+	if err != nil {
+		return value, &UnresolvedError{Value: value, MissingVariables: []string{strings.TrimPrefix(value, "$")}}
+	}
+	return resolvedValue, nil
+}
@@ -3,22 +3,29 @@ package db
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
+	"net/url"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
-	_ "github.com/ncruces/go-sqlite3/driver"
+	"github.com/go-sql-driver/mysql" // MySQL driver
+	"github.com/lib/pq"              // PostgreSQL driver
+	"github.com/ncruces/go-sqlite3"
+	sqlitedriver "github.com/ncruces/go-sqlite3/driver"
 	_ "github.com/ncruces/go-sqlite3/embed"
-	_ "github.com/lib/pq"           // PostgreSQL driver
-	_ "github.com/go-sql-driver/mysql" // MySQL driver
-
-	"github.com/pressly/goose/v3"
+	_ "github.com/ncruces/go-sqlite3/vfs/xts" // Encryption at rest for SQLite
 )
 
 // DatabaseConfig holds database connection configuration
 type DatabaseConfig struct {
-	Type     string `json:"type"`     // sqlite, postgres, mysql
+	Type     string `json:"type"` // sqlite, postgres, mysql
 	Host     string `json:"host,omitempty"`
 	Port     int    `json:"port,omitempty"`
 	Database string `json:"database"`
@@ -26,10 +33,164 @@ type DatabaseConfig struct {
 	Password string `json:"password,omitempty"`
 	SSLMode  string `json:"ssl_mode,omitempty"`
 	DataDir  string `json:"data_dir,omitempty"` // For SQLite
+
+	// EncryptionKey encrypts the SQLite database at rest with AES-XTS when set.
+	// The same key is required to open the database afterwards.
+	EncryptionKey string `json:"encryption_key,omitempty"`
+
+	// Connection pool tuning. Zero values use the defaults below.
+	MaxOpenConns    int `json:"max_open_conns,omitempty"`
+	MaxIdleConns    int `json:"max_idle_conns,omitempty"`
+	ConnMaxLifetime int `json:"conn_max_lifetime,omitempty"` // Seconds
+
+	// ConnectMaxWait bounds how long remote databases are retried while they start up
+	ConnectMaxWait int `json:"connect_max_wait,omitempty"` // Seconds
+}
+
+const (
+	defaultMaxOpenConns    = 10
+	defaultMaxIdleConns    = 5
+	defaultConnMaxLifetime = 30 * time.Minute
+
+	defaultConnectMaxWait = 30 * time.Second
+	connectInitialBackoff = 250 * time.Millisecond
+	connectMaxBackoff     = 5 * time.Second
+)
+
+// connectMaxWait returns how long to keep retrying the initial connection
+func connectMaxWait(config *DatabaseConfig) time.Duration {
+	if config.ConnectMaxWait > 0 {
+		return time.Duration(config.ConnectMaxWait) * time.Second
+	}
+	return defaultConnectMaxWait
+}
+
+// pingWithRetry pings db until it answers, backing off exponentially between
+// attempts. It gives up once maxWait has elapsed or ctx is done and returns
+// the last ping error. Errors that retrying can't fix, such as rejected
+// credentials or a missing database, are returned right away.
+func pingWithRetry(ctx context.Context, db *sql.DB, maxWait time.Duration) error {
+	deadline := time.Now().Add(maxWait)
+	backoff := connectInitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		if !isTransientPingError(err) {
+			return err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return err
+		}
+		wait := min(backoff, remaining)
+
+		slog.Debug("Database not ready, retrying", "attempt", attempt, "wait", wait, "error", err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (retry aborted: %v)", err, ctx.Err())
+		case <-timer.C:
+		}
+
+		backoff = min(backoff*2, connectMaxBackoff)
+	}
 }
 
-// Connect connects to the database based on the configuration
+// isTransientPingError reports whether a ping failed in a way that may go away
+// on its own, such as a server that is still starting up or not accepting
+// connections yet. Errors the server returns for the request itself, like
+// authentication failures, are not transient.
+func isTransientPingError(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// Class 08 is connection exceptions, 53 insufficient resources (such
+		// as too many connections), and 57P03 a server that is starting up
+		return pqErr.Code.Class() == "08" || pqErr.Code.Class() == "53" || pqErr.Code == "57P03"
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		// ER_CON_COUNT_ERROR (too many connections) and ER_SERVER_SHUTDOWN
+		return mysqlErr.Number == 1040 || mysqlErr.Number == 1053
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
+
+// poolSettings returns the connection pool limits for the given dialect.
+// SQLite allows a single writer, so it always uses one long-lived connection
+// to avoid "database is locked" errors under concurrent use and to keep the
+// per-connection pragmas set in connectSQLite.
+func poolSettings(config *DatabaseConfig, dialect string) (maxOpen, maxIdle int, maxLifetime time.Duration) {
+	if dialect == "sqlite3" {
+		if config.MaxOpenConns > 1 {
+			slog.Warn("Ignoring max_open_conns for SQLite, which only supports a single writer", "max_open_conns", config.MaxOpenConns)
+		}
+		return 1, 1, 0
+	}
+
+	maxOpen = config.MaxOpenConns
+	if maxOpen <= 0 {
+		maxOpen = defaultMaxOpenConns
+	}
+	maxIdle = config.MaxIdleConns
+	if maxIdle <= 0 {
+		maxIdle = defaultMaxIdleConns
+	}
+	if maxIdle > maxOpen {
+		maxIdle = maxOpen
+	}
+	maxLifetime = time.Duration(config.ConnMaxLifetime) * time.Second
+	if maxLifetime <= 0 {
+		maxLifetime = defaultConnMaxLifetime
+	}
+	return maxOpen, maxIdle, maxLifetime
+}
+
+// applyPoolSettings configures the connection pool of db for the given dialect
+func applyPoolSettings(db *sql.DB, config *DatabaseConfig, dialect string) {
+	maxOpen, maxIdle, maxLifetime := poolSettings(config, dialect)
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxLifetime(maxLifetime)
+	slog.Debug("Configured database connection pool",
+		"dialect", dialect,
+		"max_open_conns", maxOpen,
+		"max_idle_conns", maxIdle,
+		"conn_max_lifetime", maxLifetime)
+}
+
+// Connect connects to the database based on the configuration and applies
+// any pending migrations
 func Connect(ctx context.Context, config *DatabaseConfig) (*sql.DB, error) {
+	db, err := Open(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	dialect, err := Dialect(config)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return applyMigrations(db, dialect)
+}
+
+// Open connects to the database based on the configuration without applying
+// migrations, e.g. to inspect or roll back the schema
+func Open(ctx context.Context, config *DatabaseConfig) (*sql.DB, error) {
 	switch strings.ToLower(config.Type) {
 	case "sqlite", "":
 		return connectSQLite(ctx, config)
@@ -42,28 +203,49 @@ func Connect(ctx context.Context, config *DatabaseConfig) (*sql.DB, error) {
 	}
 }
 
+// Dialect returns the goose dialect for the configured database type
+func Dialect(config *DatabaseConfig) (string, error) {
+	switch strings.ToLower(config.Type) {
+	case "sqlite", "":
+		return "sqlite3", nil
+	case "postgres", "postgresql":
+		return "postgres", nil
+	case "mysql":
+		return "mysql", nil
+	default:
+		return "", fmt.Errorf("unsupported database type: %s", config.Type)
+	}
+}
+
 // connectSQLite connects to SQLite database
 func connectSQLite(ctx context.Context, config *DatabaseConfig) (*sql.DB, error) {
 	dataDir := config.DataDir
 	if dataDir == "" {
 		return nil, fmt.Errorf("data.dir is not set for SQLite")
 	}
-	
+
 	dbPath := config.Database
 	if dbPath == "" {
 		dbPath = "crush.db"
 	}
-	
+
 	// If not absolute path, make it relative to dataDir
 	if !filepath.IsAbs(dbPath) {
 		dbPath = filepath.Join(dataDir, dbPath)
 	}
 
 	// Open the SQLite database
-	db, err := sql.Open("sqlite3", dbPath)
+	var db *sql.DB
+	var err error
+	if config.EncryptionKey != "" {
+		db, err = openEncryptedSQLite(dbPath, config.EncryptionKey)
+	} else {
+		db, err = sql.Open("sqlite3", dbPath)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	applyPoolSettings(db, config, "sqlite3")
 
 	// Verify connection
 	if err = db.PingContext(ctx); err != nil {
@@ -71,6 +253,12 @@ func connectSQLite(ctx context.Context, config *DatabaseConfig) (*sql.DB, error)
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	// Opening is lazy, so read the schema to find out whether the key fits
+	if err = verifySQLiteReadable(ctx, db, config.EncryptionKey != ""); err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	// Set pragmas for better performance
 	pragmas := []string{
 		"PRAGMA foreign_keys = ON;",
@@ -88,7 +276,34 @@ func connectSQLite(ctx context.Context, config *DatabaseConfig) (*sql.DB, error)
 		}
 	}
 
-	return applyMigrations(db, "sqlite3")
+	return db, nil
+}
+
+// openEncryptedSQLite opens the SQLite database at dbPath through the xts VFS.
+// The key is set with a PRAGMA on every new connection rather than in the URI,
+// so it doesn't leak through the database filename.
+func openEncryptedSQLite(dbPath, key string) (*sql.DB, error) {
+	dsn := (&url.URL{Scheme: "file", OmitHost: true, Path: filepath.ToSlash(dbPath), RawQuery: "vfs=xts"}).String()
+	return sqlitedriver.Open(dsn, func(conn *sqlite3.Conn) error {
+		return conn.Exec("PRAGMA textkey = " + sqlite3.Quote(key))
+	})
+}
+
+// verifySQLiteReadable reads the schema of db, turning the error SQLite
+// reports for an undecryptable file into one that names the likely cause
+func verifySQLiteReadable(ctx context.Context, db *sql.DB, encrypted bool) error {
+	var tables int
+	err := db.QueryRowContext(ctx, "SELECT count(*) FROM sqlite_master").Scan(&tables)
+	switch {
+	case err == nil:
+		return nil
+	case !errors.Is(err, sqlite3.NOTADB):
+		return fmt.Errorf("failed to read database: %w", err)
+	case encrypted:
+		return fmt.Errorf("failed to decrypt database: the encryption key is wrong or the database is not encrypted: %w", err)
+	default:
+		return fmt.Errorf("failed to read database: it may be encrypted, set database.encryption_key: %w", err)
+	}
 }
 
 // connectPostgres connects to PostgreSQL database
@@ -97,32 +312,33 @@ func connectPostgres(ctx context.Context, config *DatabaseConfig) (*sql.DB, erro
 	if host == "" {
 		host = "localhost"
 	}
-	
+
 	port := config.Port
 	if port == 0 {
 		port = 5432
 	}
-	
+
 	sslMode := config.SSLMode
 	if sslMode == "" {
 		sslMode = "prefer"
 	}
-	
+
 	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		host, port, config.Username, config.Password, config.Database, sslMode)
-	
+
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open PostgreSQL database: %w", err)
 	}
-	
-	// Verify connection
-	if err = db.PingContext(ctx); err != nil {
+	applyPoolSettings(db, config, "postgres")
+
+	// Verify connection, waiting for the server if it is still starting
+	if err = pingWithRetry(ctx, db, connectMaxWait(config)); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to connect to PostgreSQL database: %w", err)
 	}
-	
-	return applyMigrations(db, "postgres")
+
+	return db, nil
 }
 
 // connectMySQL connects to MySQL database
@@ -131,43 +347,36 @@ func connectMySQL(ctx context.Context, config *DatabaseConfig) (*sql.DB, error)
 	if host == "" {
 		host = "localhost"
 	}
-	
+
 	port := config.Port
 	if port == 0 {
 		port = 3306
 	}
-	
+
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true",
 		config.Username, config.Password, host, port, config.Database)
-	
+
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open MySQL database: %w", err)
 	}
-	
-	// Verify connection
-	if err = db.PingContext(ctx); err != nil {
+	applyPoolSettings(db, config, "mysql")
+
+	// Verify connection, waiting for the server if it is still starting
+	if err = pingWithRetry(ctx, db, connectMaxWait(config)); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to connect to MySQL database: %w", err)
 	}
-	
-	return applyMigrations(db, "mysql")
+
+	return db, nil
 }
 
 // applyMigrations applies database migrations
 func applyMigrations(db *sql.DB, dialect string) (*sql.DB, error) {
-	goose.SetBaseFS(FS)
-
-	if err := goose.SetDialect(dialect); err != nil {
-		slog.Error("Failed to set dialect", "error", err)
-		return nil, fmt.Errorf("failed to set dialect: %w", err)
-	}
-
-	if err := goose.Up(db, "migrations"); err != nil {
-		slog.Error("Failed to apply migrations", "error", err)
-		return nil, fmt.Errorf("failed to apply migrations: %w", err)
+	if err := MigrateUp(db, dialect); err != nil {
+		db.Close()
+		return nil, err
 	}
-	
 	return db, nil
 }
 
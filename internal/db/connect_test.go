@@ -0,0 +1,296 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolSettings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		config      DatabaseConfig
+		dialect     string
+		maxOpen     int
+		maxIdle     int
+		maxLifetime time.Duration
+	}{
+		{
+			name:        "postgres defaults",
+			dialect:     "postgres",
+			maxOpen:     defaultMaxOpenConns,
+			maxIdle:     defaultMaxIdleConns,
+			maxLifetime: defaultConnMaxLifetime,
+		},
+		{
+			name:        "mysql configured",
+			config:      DatabaseConfig{MaxOpenConns: 20, MaxIdleConns: 8, ConnMaxLifetime: 60},
+			dialect:     "mysql",
+			maxOpen:     20,
+			maxIdle:     8,
+			maxLifetime: time.Minute,
+		},
+		{
+			name:        "idle capped at open",
+			config:      DatabaseConfig{MaxOpenConns: 2, MaxIdleConns: 8},
+			dialect:     "postgres",
+			maxOpen:     2,
+			maxIdle:     2,
+			maxLifetime: defaultConnMaxLifetime,
+		},
+		{
+			name:    "sqlite forced to a single connection",
+			config:  DatabaseConfig{MaxOpenConns: 20, MaxIdleConns: 8, ConnMaxLifetime: 60},
+			dialect: "sqlite3",
+			maxOpen: 1,
+			maxIdle: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			maxOpen, maxIdle, maxLifetime := poolSettings(&tt.config, tt.dialect)
+			require.Equal(t, tt.maxOpen, maxOpen)
+			require.Equal(t, tt.maxIdle, maxIdle)
+			require.Equal(t, tt.maxLifetime, maxLifetime)
+		})
+	}
+}
+
+func TestApplyPoolSettings(t *testing.T) {
+	t.Parallel()
+
+	// sql.Open does not connect, so no server is needed to inspect the pool.
+	db, err := sql.Open("postgres", "host=localhost dbname=crush")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	applyPoolSettings(db, &DatabaseConfig{MaxOpenConns: 7}, "postgres")
+	require.Equal(t, 7, db.Stats().MaxOpenConnections)
+}
+
+func TestConnectSQLiteUsesSingleConnection(t *testing.T) {
+	t.Parallel()
+
+	db, err := Connect(t.Context(), &DatabaseConfig{
+		Type:         "sqlite",
+		DataDir:      t.TempDir(),
+		MaxOpenConns: 10,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	require.Equal(t, 1, db.Stats().MaxOpenConnections)
+}
+
+func TestConnectEncryptedSQLite(t *testing.T) {
+	t.Parallel()
+
+	dataDir := t.TempDir()
+	config := &DatabaseConfig{Type: "sqlite", DataDir: dataDir, EncryptionKey: "correct horse battery staple"}
+
+	db, err := Connect(t.Context(), config)
+	require.NoError(t, err)
+	_, err = db.ExecContext(t.Context(), "INSERT INTO sessions (id, title, created_at, updated_at) VALUES ('s1', 'secret prompt', 0, 0)")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	raw, err := os.ReadFile(filepath.Join(dataDir, "crush.db"))
+	require.NoError(t, err)
+	require.NotContains(t, string(raw), "SQLite format 3")
+	require.NotContains(t, string(raw), "secret prompt")
+
+	// The plaintext driver can't read it
+	_, err = Connect(t.Context(), &DatabaseConfig{Type: "sqlite", DataDir: dataDir})
+	require.ErrorContains(t, err, "set database.encryption_key")
+
+	_, err = Connect(t.Context(), &DatabaseConfig{Type: "sqlite", DataDir: dataDir, EncryptionKey: "wrong"})
+	require.ErrorContains(t, err, "failed to decrypt database")
+
+	// Reopening with the key keeps the data
+	db, err = Connect(t.Context(), config)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	var title string
+	require.NoError(t, db.QueryRowContext(t.Context(), "SELECT title FROM sessions WHERE id = 's1'").Scan(&title))
+	require.Equal(t, "secret prompt", title)
+}
+
+// dialDriver is a minimal database/sql driver whose connections succeed as
+// soon as a TCP listener accepts them at the DSN address.
+type dialDriver struct{}
+
+type dialConn struct{ net.Conn }
+
+func (dialDriver) Open(name string) (driver.Conn, error) {
+	conn, err := net.DialTimeout("tcp", name, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return dialConn{conn}, nil
+}
+
+func (dialConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (dialConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+// failingDriver is a database/sql driver whose connections always fail with
+// err, counting the attempts
+type failingDriver struct {
+	err      error
+	attempts *atomic.Int32
+}
+
+func (d failingDriver) Open(string) (driver.Conn, error) {
+	d.attempts.Add(1)
+	return nil, d.err
+}
+
+func init() {
+	sql.Register("crush-dial-test", dialDriver{})
+}
+
+// reserveAddr returns a local TCP address that currently has no listener
+func reserveAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+	return addr
+}
+
+func TestPingWithRetryWaitsForServer(t *testing.T) {
+	t.Parallel()
+
+	addr := reserveAddr(t)
+	db, err := sql.Open("crush-dial-test", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	// Bring the server up after the first attempts have failed.
+	ready := make(chan net.Listener, 1)
+	go func() {
+		time.Sleep(400 * time.Millisecond)
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			ready <- nil
+			return
+		}
+		ready <- ln
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	start := time.Now()
+	err = pingWithRetry(t.Context(), db, 5*time.Second)
+	ln := <-ready
+	if ln == nil {
+		t.Skip("could not rebind the reserved address")
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+}
+
+func TestPingWithRetryGivesUp(t *testing.T) {
+	t.Parallel()
+
+	db, err := sql.Open("crush-dial-test", reserveAddr(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	start := time.Now()
+	err = pingWithRetry(t.Context(), db, 300*time.Millisecond)
+	require.Error(t, err)
+	require.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestPingWithRetryHonorsContext(t *testing.T) {
+	t.Parallel()
+
+	db, err := sql.Open("crush-dial-test", reserveAddr(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+
+	err = pingWithRetry(ctx, db, time.Minute)
+	require.Error(t, err)
+	require.ErrorContains(t, err, "retry aborted")
+}
+
+func TestPingWithRetryFailsFastOnAuthErrors(t *testing.T) {
+	t.Parallel()
+
+	for name, authErr := range map[string]error{
+		"postgres": &pq.Error{Code: "28P01", Message: "password authentication failed"},
+		"mysql":    &mysql.MySQLError{Number: 1045, Message: "Access denied"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			attempts := &atomic.Int32{}
+			db := sql.OpenDB(driverConnector{failingDriver{err: authErr, attempts: attempts}})
+			t.Cleanup(func() { db.Close() })
+
+			start := time.Now()
+			err := pingWithRetry(t.Context(), db, time.Minute)
+			require.ErrorIs(t, err, authErr)
+			require.Less(t, time.Since(start), connectInitialBackoff)
+			require.NotContains(t, err.Error(), "retry aborted")
+			// database/sql itself may retry a bad connection, but never backs off
+			require.LessOrEqual(t, attempts.Load(), int32(3))
+		})
+	}
+}
+
+func TestIsTransientPingError(t *testing.T) {
+	t.Parallel()
+
+	tests := map[error]bool{
+		&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}: true,
+		fmt.Errorf("dial: %w", syscall.ECONNREFUSED):        true,
+		driver.ErrBadConn: true,
+		&pq.Error{Code: "57P03", Message: "the database system is starting up"}: true,
+		&pq.Error{Code: "53300", Message: "too many connections"}:               true,
+		&pq.Error{Code: "28P01", Message: "password authentication failed"}:     false,
+		&pq.Error{Code: "3D000", Message: "database does not exist"}:            false,
+		&mysql.MySQLError{Number: 1040, Message: "Too many connections"}:        true,
+		&mysql.MySQLError{Number: 1045, Message: "Access denied"}:               false,
+		&mysql.MySQLError{Number: 1049, Message: "Unknown database"}:            false,
+		errors.New("unexpected failure"):                                        false,
+	}
+	for err, transient := range tests {
+		require.Equal(t, transient, isTransientPingError(err), err.Error())
+	}
+}
+
+// driverConnector opens connections with a driver without registering it
+type driverConnector struct{ driver driver.Driver }
+
+func (c driverConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open("") }
+func (c driverConnector) Driver() driver.Driver                        { return c.driver }
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/pressly/goose/v3"
+)
+
+const migrationsDir = "migrations"
+
+// setupGoose points goose at the embedded migrations for the given dialect
+func setupGoose(dialect string) error {
+	goose.SetBaseFS(FS)
+
+	if err := goose.SetDialect(dialect); err != nil {
+		slog.Error("Failed to set dialect", "error", err)
+		return fmt.Errorf("failed to set dialect: %w", err)
+	}
+	return nil
+}
+
+// MigrateUp applies all pending migrations
+func MigrateUp(db *sql.DB, dialect string) error {
+	if err := setupGoose(dialect); err != nil {
+		return err
+	}
+
+	if err := goose.Up(db, migrationsDir); err != nil {
+		slog.Error("Failed to apply migrations", "error", err)
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+	return nil
+}
+
+// MigrateDown rolls back the most recent steps migrations. Rolling back more
+// migrations than are applied stops at an empty schema.
+func MigrateDown(db *sql.DB, dialect string, steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("steps must be positive, got %d", steps)
+	}
+	if err := setupGoose(dialect); err != nil {
+		return err
+	}
+
+	for range steps {
+		version, err := goose.GetDBVersion(db)
+		if err != nil {
+			return fmt.Errorf("failed to get schema version: %w", err)
+		}
+		if version == 0 {
+			break
+		}
+		if err := goose.Down(db, migrationsDir); err != nil {
+			if errors.Is(err, goose.ErrNoNextVersion) {
+				break
+			}
+			slog.Error("Failed to roll back migration", "version", version, "error", err)
+			return fmt.Errorf("failed to roll back migration %d: %w", version, err)
+		}
+	}
+	return nil
+}
+
+// MigrateDownTo rolls back migrations until the schema is at version
+func MigrateDownTo(db *sql.DB, dialect string, version int64) error {
+	if version < 0 {
+		return fmt.Errorf("version must not be negative, got %d", version)
+	}
+	if err := setupGoose(dialect); err != nil {
+		return err
+	}
+
+	if err := goose.DownTo(db, migrationsDir, version); err != nil {
+		slog.Error("Failed to roll back migrations", "target_version", version, "error", err)
+		return fmt.Errorf("failed to roll back to version %d: %w", version, err)
+	}
+	return nil
+}
+
+// CurrentVersion returns the version of the most recently applied migration,
+// or 0 when no migrations have been applied
+func CurrentVersion(db *sql.DB, dialect string) (int64, error) {
+	if err := setupGoose(dialect); err != nil {
+		return 0, err
+	}
+
+	version, err := goose.GetDBVersion(db)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get schema version: %w", err)
+	}
+	return version, nil
+}
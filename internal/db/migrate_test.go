@@ -0,0 +1,87 @@
+package db
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	latestMigration   = int64(20250627000000)
+	previousMigration = int64(20250624000000)
+)
+
+func openMemoryDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	// Every connection to :memory: is a separate database.
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestMigrations(t *testing.T) {
+	db := openMemoryDB(t)
+
+	version, err := CurrentVersion(db, "sqlite3")
+	require.NoError(t, err)
+	require.Zero(t, version)
+
+	require.NoError(t, MigrateUp(db, "sqlite3"))
+	version, err = CurrentVersion(db, "sqlite3")
+	require.NoError(t, err)
+	require.Equal(t, latestMigration, version)
+
+	require.NoError(t, MigrateDown(db, "sqlite3", 1))
+	version, err = CurrentVersion(db, "sqlite3")
+	require.NoError(t, err)
+	require.Equal(t, previousMigration, version)
+
+	// Reapplying restores the latest schema.
+	require.NoError(t, MigrateUp(db, "sqlite3"))
+	version, err = CurrentVersion(db, "sqlite3")
+	require.NoError(t, err)
+	require.Equal(t, latestMigration, version)
+
+	require.NoError(t, MigrateDownTo(db, "sqlite3", previousMigration))
+	version, err = CurrentVersion(db, "sqlite3")
+	require.NoError(t, err)
+	require.Equal(t, previousMigration, version)
+
+	// Rolling back more steps than applied stops at an empty schema.
+	require.NoError(t, MigrateDown(db, "sqlite3", 100))
+	version, err = CurrentVersion(db, "sqlite3")
+	require.NoError(t, err)
+	require.Zero(t, version)
+
+	var tables int
+	require.NoError(t, db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'sessions'`).Scan(&tables))
+	require.Zero(t, tables)
+}
+
+func TestMigrateDownRejectsInvalidSteps(t *testing.T) {
+	db := openMemoryDB(t)
+	require.Error(t, MigrateDown(db, "sqlite3", 0))
+	require.Error(t, MigrateDownTo(db, "sqlite3", -1))
+}
+
+func TestDialect(t *testing.T) {
+	t.Parallel()
+
+	for typ, want := range map[string]string{
+		"":           "sqlite3",
+		"sqlite":     "sqlite3",
+		"postgresql": "postgres",
+		"MySQL":      "mysql",
+	} {
+		got, err := Dialect(&DatabaseConfig{Type: typ})
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+
+	_, err := Dialect(&DatabaseConfig{Type: "oracle"})
+	require.Error(t, err)
+}
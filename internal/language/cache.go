@@ -0,0 +1,125 @@
+package language
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxDetectionCacheEntries bounds the number of project directories whose
+// detection results are cached at once
+const maxDetectionCacheEntries = 64
+
+// detectionCacheEntry holds detection results for a project directory along
+// with the modification times of the directory and its ProjectConfigFile when
+// they were computed
+type detectionCacheEntry struct {
+	modTime       time.Time
+	configModTime time.Time
+	lastAccessed  time.Time
+	results       []DetectionResult
+}
+
+// detectionResultCache caches language detection results per absolute
+// project path, evicting the least recently used entry once it holds
+// maxEntries directories
+type detectionResultCache struct {
+	mu         sync.Mutex
+	entries    map[string]detectionCacheEntry
+	maxEntries int
+}
+
+var detectionCache = newDetectionResultCache(maxDetectionCacheEntries)
+
+func newDetectionResultCache(maxEntries int) *detectionResultCache {
+	return &detectionResultCache{
+		entries:    make(map[string]detectionCacheEntry),
+		maxEntries: maxEntries,
+	}
+}
+
+// ClearDetectionCache discards all cached language detection results
+func ClearDetectionCache() {
+	detectionCache.clear()
+}
+
+// key returns the cache key of a project directory and an entry stamped with
+// the current modification times. A missing ProjectConfigFile has a zero
+// modification time.
+func (c *detectionResultCache) key(projectPath string) (string, detectionCacheEntry, bool) {
+	absPath, err := filepath.Abs(projectPath)
+	if err != nil {
+		return "", detectionCacheEntry{}, false
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return "", detectionCacheEntry{}, false
+	}
+	stamp := detectionCacheEntry{modTime: info.ModTime()}
+	if info, err := os.Stat(filepath.Join(absPath, ProjectConfigFile)); err == nil {
+		stamp.configModTime = info.ModTime()
+	}
+	return absPath, stamp, true
+}
+
+func (c *detectionResultCache) get(projectPath string) ([]DetectionResult, bool) {
+	key, stamp, ok := c.key(projectPath)
+	if !ok {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !entry.modTime.Equal(stamp.modTime) || !entry.configModTime.Equal(stamp.configModTime) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	entry.lastAccessed = time.Now()
+	c.entries[key] = entry
+	return slices.Clone(entry.results), true
+}
+
+func (c *detectionResultCache) set(projectPath string, results []DetectionResult) {
+	key, stamp, ok := c.key(projectPath)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.maxEntries {
+		c.evictLeastRecentlyUsed()
+	}
+	stamp.lastAccessed = time.Now()
+	stamp.results = slices.Clone(results)
+	c.entries[key] = stamp
+}
+
+// evictLeastRecentlyUsed removes the entry that was accessed least recently.
+// The caller must hold c.mu.
+func (c *detectionResultCache) evictLeastRecentlyUsed() {
+	var lruKey string
+	var lruTime time.Time
+	for key, entry := range c.entries {
+		if lruKey == "" || entry.lastAccessed.Before(lruTime) {
+			lruKey = key
+			lruTime = entry.lastAccessed
+		}
+	}
+	if lruKey != "" {
+		delete(c.entries, lruKey)
+	}
+}
+
+func (c *detectionResultCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
@@ -0,0 +1,109 @@
+package language
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectLanguagesCache(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "main.py", "lib/util.py")
+
+	results, err := DetectLanguages(dir)
+	require.NoError(t, err)
+	require.Equal(t, "python", results[0].Name)
+
+	// Nested changes don't touch the top-level mtime, so the cached result is kept.
+	writeFiles(t, dir, "lib/a.go", "lib/b.go", "lib/c.go")
+	results, err = DetectLanguages(dir)
+	require.NoError(t, err)
+	require.Equal(t, "python", results[0].Name)
+
+	// Mutating returned results must not affect the cache.
+	results[0].Name = "mutated"
+	results, err = DetectLanguages(dir)
+	require.NoError(t, err)
+	require.Equal(t, "python", results[0].Name)
+
+	// Clearing the cache forces a fresh walk.
+	ClearDetectionCache()
+	results, err = DetectLanguages(dir)
+	require.NoError(t, err)
+	require.Equal(t, "go", results[0].Name)
+
+	// Changing the top-level directory invalidates the entry.
+	writeFiles(t, dir, "x.py", "y.py", "z.py")
+	future := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(dir, future, future))
+	results, err = DetectLanguages(dir)
+	require.NoError(t, err)
+	require.Equal(t, "python", results[0].Name)
+}
+
+func TestDetectionCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	t.Parallel()
+
+	cache := newDetectionResultCache(2)
+	dirs := []string{t.TempDir(), t.TempDir(), t.TempDir()}
+	results := []DetectionResult{{Name: "go"}}
+
+	cache.set(dirs[0], results)
+	cache.set(dirs[1], results)
+	// Reading the first entry makes the second the least recently used.
+	_, ok := cache.get(dirs[0])
+	require.True(t, ok)
+
+	cache.set(dirs[2], results)
+	require.Len(t, cache.entries, 2)
+	_, ok = cache.get(dirs[0])
+	require.True(t, ok)
+	_, ok = cache.get(dirs[1])
+	require.False(t, ok)
+	_, ok = cache.get(dirs[2])
+	require.True(t, ok)
+}
+
+func TestDefaultLanguageConfigIsMemoized(t *testing.T) {
+	t.Parallel()
+
+	require.Same(t, DefaultLanguageConfig(), DefaultLanguageConfig())
+}
+
+func BenchmarkDetectLanguages(b *testing.B) {
+	dir := b.TempDir()
+	for i := range 50 {
+		pkg := filepath.Join(dir, fmt.Sprintf("pkg%d", i))
+		if err := os.MkdirAll(pkg, 0o755); err != nil {
+			b.Fatal(err)
+		}
+		for j := range 40 {
+			name := filepath.Join(pkg, fmt.Sprintf("file%d.go", j))
+			if err := os.WriteFile(name, nil, 0o644); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	b.Run("uncached", func(b *testing.B) {
+		for b.Loop() {
+			ClearDetectionCache()
+			if _, err := DetectLanguages(dir); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		ClearDetectionCache()
+		for b.Loop() {
+			if _, err := DetectLanguages(dir); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -1,23 +1,30 @@
 package language
 
 import (
+	"cmp"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+
+	ignore "github.com/sabhiram/go-gitignore"
 )
 
 // SupportedLanguage represents a programming language with its configuration
 type SupportedLanguage struct {
-	Name        string   `json:"name"`
-	Extensions  []string `json:"extensions"`
-	LSPCommand  string   `json:"lsp_command,omitempty"`
-	LintCommand string   `json:"lint_command,omitempty"`
-	FormatCommand string `json:"format_command,omitempty"`
-	BuildCommand string  `json:"build_command,omitempty"`
-	TestCommand string   `json:"test_command,omitempty"`
-	ProjectFiles []string `json:"project_files,omitempty"`
+	Name          string   `json:"name"`
+	Extensions    []string `json:"extensions"`
+	LSPCommand    string   `json:"lsp_command,omitempty"`
+	LintCommand   string   `json:"lint_command,omitempty"`
+	FormatCommand string   `json:"format_command,omitempty"`
+	BuildCommand  string   `json:"build_command,omitempty"`
+	TestCommand   string   `json:"test_command,omitempty"`
+	ProjectFiles  []string `json:"project_files,omitempty"`
 }
 
 // LanguageConfig holds the configuration for all supported languages
@@ -25,166 +32,367 @@ type LanguageConfig struct {
 	Languages map[string]SupportedLanguage `json:"languages"`
 }
 
-// DefaultLanguageConfig returns the default configuration for supported languages
+// DefaultLanguageConfig returns the default configuration for supported languages.
+// The configuration is built once and shared, so callers must not modify it.
 func DefaultLanguageConfig() *LanguageConfig {
+	return defaultLanguageConfig()
+}
+
+var defaultLanguageConfig = sync.OnceValue(func() *LanguageConfig {
 	return &LanguageConfig{
 		Languages: map[string]SupportedLanguage{
 			"go": {
-				Name:         "Go",
-				Extensions:   []string{".go"},
-				LSPCommand:   "gopls",
-				LintCommand:  "golangci-lint run",
+				Name:          "Go",
+				Extensions:    []string{".go"},
+				LSPCommand:    "gopls",
+				LintCommand:   "golangci-lint run",
 				FormatCommand: "gofmt -w",
-				BuildCommand: "go build",
-				TestCommand:  "go test",
-				ProjectFiles: []string{"go.mod", "go.sum"},
+				BuildCommand:  "go build",
+				TestCommand:   "go test",
+				ProjectFiles:  []string{"go.mod", "go.sum"},
 			},
 			"python": {
-				Name:         "Python",
-				Extensions:   []string{".py", ".pyx"},
-				LSPCommand:   "pylsp",
-				LintCommand:  "pylint",
+				Name:          "Python",
+				Extensions:    []string{".py", ".pyx"},
+				LSPCommand:    "pylsp",
+				LintCommand:   "pylint",
 				FormatCommand: "black",
-				BuildCommand: "python -m py_compile",
-				TestCommand:  "python -m pytest",
-				ProjectFiles: []string{"setup.py", "pyproject.toml", "requirements.txt", "Pipfile", "poetry.lock"},
+				BuildCommand:  "python -m py_compile",
+				TestCommand:   "python -m pytest",
+				ProjectFiles:  []string{"setup.py", "pyproject.toml", "requirements.txt", "Pipfile", "poetry.lock"},
 			},
 			"javascript": {
-				Name:         "JavaScript",
-				Extensions:   []string{".js", ".jsx", ".mjs"},
-				LSPCommand:   "typescript-language-server --stdio",
-				LintCommand:  "eslint",
+				Name:          "JavaScript",
+				Extensions:    []string{".js", ".jsx", ".mjs"},
+				LSPCommand:    "typescript-language-server --stdio",
+				LintCommand:   "eslint",
 				FormatCommand: "prettier --write",
-				BuildCommand: "npm run build",
-				TestCommand:  "npm test",
-				ProjectFiles: []string{"package.json", "package-lock.json", "yarn.lock", "pnpm-lock.yaml"},
+				BuildCommand:  "npm run build",
+				TestCommand:   "npm test",
+				ProjectFiles:  []string{"package.json", "package-lock.json", "yarn.lock", "pnpm-lock.yaml"},
 			},
 			"typescript": {
-				Name:         "TypeScript",
-				Extensions:   []string{".ts", ".tsx"},
-				LSPCommand:   "typescript-language-server --stdio",
-				LintCommand:  "eslint",
+				Name:          "TypeScript",
+				Extensions:    []string{".ts", ".tsx"},
+				LSPCommand:    "typescript-language-server --stdio",
+				LintCommand:   "eslint",
 				FormatCommand: "prettier --write",
-				BuildCommand: "tsc",
-				TestCommand:  "npm test",
-				ProjectFiles: []string{"tsconfig.json", "package.json", "package-lock.json"},
+				BuildCommand:  "tsc",
+				TestCommand:   "npm test",
+				ProjectFiles:  []string{"tsconfig.json", "package.json", "package-lock.json"},
 			},
 			"php": {
-				Name:         "PHP",
-				Extensions:   []string{".php", ".phtml"},
-				LSPCommand:   "intelephense --stdio",
-				LintCommand:  "phpcs",
+				Name:          "PHP",
+				Extensions:    []string{".php", ".phtml"},
+				LSPCommand:    "intelephense --stdio",
+				LintCommand:   "phpcs",
 				FormatCommand: "phpcbf",
-				BuildCommand: "php -l",
-				TestCommand:  "phpunit",
-				ProjectFiles: []string{"composer.json", "composer.lock", "phpunit.xml"},
+				BuildCommand:  "php -l",
+				TestCommand:   "phpunit",
+				ProjectFiles:  []string{"composer.json", "composer.lock", "phpunit.xml"},
 			},
 			"rust": {
-				Name:         "Rust",
-				Extensions:   []string{".rs"},
-				LSPCommand:   "rust-analyzer",
-				LintCommand:  "cargo clippy",
+				Name:          "Rust",
+				Extensions:    []string{".rs"},
+				LSPCommand:    "rust-analyzer",
+				LintCommand:   "cargo clippy",
 				FormatCommand: "cargo fmt",
-				BuildCommand: "cargo build",
-				TestCommand:  "cargo test",
-				ProjectFiles: []string{"Cargo.toml", "Cargo.lock"},
+				BuildCommand:  "cargo build",
+				TestCommand:   "cargo test",
+				ProjectFiles:  []string{"Cargo.toml", "Cargo.lock"},
 			},
 			"java": {
-				Name:         "Java",
-				Extensions:   []string{".java"},
-				LSPCommand:   "jdtls",
-				LintCommand:  "checkstyle",
+				Name:          "Java",
+				Extensions:    []string{".java"},
+				LSPCommand:    "jdtls",
+				LintCommand:   "checkstyle",
 				FormatCommand: "google-java-format",
-				BuildCommand: "javac",
-				TestCommand:  "mvn test",
-				ProjectFiles: []string{"pom.xml", "build.gradle", "build.gradle.kts"},
+				BuildCommand:  "javac",
+				TestCommand:   "mvn test",
+				ProjectFiles:  []string{"pom.xml", "build.gradle", "build.gradle.kts"},
+			},
+			"csharp": {
+				Name:          "C#",
+				Extensions:    []string{".cs", ".csx"},
+				LSPCommand:    "omnisharp --languageserver",
+				LintCommand:   "dotnet format --verify-no-changes",
+				FormatCommand: "dotnet format",
+				BuildCommand:  "dotnet build",
+				TestCommand:   "dotnet test",
+				ProjectFiles:  []string{"*.csproj", "*.sln"},
+			},
+			"ruby": {
+				Name:          "Ruby",
+				Extensions:    []string{".rb", ".rake", ".gemspec"},
+				LSPCommand:    "solargraph stdio",
+				LintCommand:   "rubocop",
+				FormatCommand: "rubocop -a",
+				BuildCommand:  "ruby -c",
+				TestCommand:   "bundle exec rspec",
+				ProjectFiles:  []string{"Gemfile", "Gemfile.lock"},
+			},
+			// Shell has no project-wide lint, format or build command: shellcheck,
+			// shfmt and bash -n only check the files they are given
+			"shell": {
+				Name:       "Shell",
+				Extensions: []string{".sh", ".bash", ".zsh"},
+				LSPCommand: "bash-language-server start",
+			},
+			"kotlin": {
+				Name:          "Kotlin",
+				Extensions:    []string{".kt", ".kts"},
+				LSPCommand:    "kotlin-language-server",
+				LintCommand:   "ktlint",
+				FormatCommand: "ktlint -F",
+				BuildCommand:  "gradle build",
+				TestCommand:   "gradle test",
+				ProjectFiles:  []string{"build.gradle.kts", "settings.gradle.kts"},
 			},
 		},
 	}
+})
+
+// DetectionResult describes a language detected in a project
+type DetectionResult struct {
+	Name         string             `json:"name"`
+	Language     *SupportedLanguage `json:"language"`
+	FileCount    int                `json:"file_count"`
+	Confidence   float64            `json:"confidence"`
+	ProjectFiles []string           `json:"project_files,omitempty"`
 }
 
-// DetectLanguage detects the primary language of a project based on files in the directory
+// DetectLanguage detects the primary language of a project based on files in the directory.
+// Languages with a project file (go.mod, package.json, ...) take precedence over
+// languages that are only detected by file extension.
 func DetectLanguage(projectPath string) (string, *SupportedLanguage, error) {
-	config := DefaultLanguageConfig()
-	
-	// Check for project-specific files first
-	for langName, lang := range config.Languages {
+	results, err := DetectLanguages(projectPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	best := PrimaryLanguage(results)
+	return best.Name, best.Language, nil
+}
+
+// DetectLanguage is like the package-level [DetectLanguage] but recognizes
+// the languages of lc, without caching
+func (lc *LanguageConfig) DetectLanguage(projectPath string) (string, *SupportedLanguage, error) {
+	results, err := lc.DetectLanguages(projectPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	best := PrimaryLanguage(results)
+	return best.Name, best.Language, nil
+}
+
+// auxiliaryLanguages are languages that support a project rather than make
+// it up, such as its build and deploy scripts. They are only picked as the
+// primary language when nothing else is detected.
+var auxiliaryLanguages = map[string]bool{"shell": true}
+
+// PrimaryLanguage picks the primary language from non-empty detection results,
+// preferring the most confident language that has a project file, then the
+// most confident language that isn't auxiliary (such as shell)
+func PrimaryLanguage(results []DetectionResult) DetectionResult {
+	for _, result := range results {
+		if len(result.ProjectFiles) > 0 {
+			return result
+		}
+	}
+	for _, result := range results {
+		if !auxiliaryLanguages[result.Name] {
+			return result
+		}
+	}
+	return results[0]
+}
+
+// DetectLanguages detects all languages used in a project. Each result carries
+// the number of source files found and a confidence score (its share of all
+// recognized source files), sorted from most to least confident.
+//
+// The languages recognized are the defaults with the project's
+// ProjectConfigFile merged on top, so a project can add languages and
+// extensions of its own (see [LoadProjectLanguageConfig]).
+//
+// Results are cached per project directory until the modification time of the
+// directory or of its ProjectConfigFile changes, or [ClearDetectionCache] is
+// called. Only the most recently used directories are kept.
+func DetectLanguages(projectPath string) ([]DetectionResult, error) {
+	if results, ok := detectionCache.get(projectPath); ok {
+		return results, nil
+	}
+
+	config, err := LoadProjectLanguageConfig(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	results, err := config.DetectLanguages(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	detectionCache.set(projectPath, results)
+	return slices.Clone(results), nil
+}
+
+// DetectLanguages is like the package-level [DetectLanguages] but recognizes
+// the languages of lc, ignoring the project's ProjectConfigFile, and doesn't
+// cache its results
+func (lc *LanguageConfig) DetectLanguages(projectPath string) ([]DetectionResult, error) {
+	extensionCounts, shebangCounts, err := countSourceFiles(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []DetectionResult
+	total := 0
+	for langName, lang := range lc.Languages {
+		count := shebangCounts[langName]
+		for _, ext := range lang.Extensions {
+			count += extensionCounts[ext]
+		}
+
+		var projectFiles []string
 		for _, projectFile := range lang.ProjectFiles {
-			if _, err := os.Stat(filepath.Join(projectPath, projectFile)); err == nil {
-				return langName, &lang, nil
+			if hasProjectFile(projectPath, projectFile) {
+				projectFiles = append(projectFiles, projectFile)
 			}
 		}
+
+		if count == 0 && len(projectFiles) == 0 {
+			continue
+		}
+
+		langCopy := lang
+		results = append(results, DetectionResult{
+			Name:         langName,
+			Language:     &langCopy,
+			FileCount:    count,
+			ProjectFiles: projectFiles,
+		})
+		total += count
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("could not detect language for project")
+	}
+
+	for i := range results {
+		if total > 0 {
+			results[i].Confidence = float64(results[i].FileCount) / float64(total)
+		}
 	}
-	
-	// Count files by extension
-	extensionCounts := make(map[string]int)
-	err := filepath.Walk(projectPath, func(path string, info os.FileInfo, err error) error {
+
+	slices.SortFunc(results, func(a, b DetectionResult) int {
+		if c := cmp.Compare(b.Confidence, a.Confidence); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(len(b.ProjectFiles), len(a.ProjectFiles)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return results, nil
+}
+
+// countSourceFiles walks the project and counts files by lowercase extension,
+// and files without an extension by the language their shebang names.
+// Common dependency, build and hidden directories are always skipped, as are
+// paths matched by the project's .gitignore.
+func countSourceFiles(projectPath string) (extensionCounts, shebangCounts map[string]int, err error) {
+	gitignore, _ := ignore.CompileIgnoreFile(filepath.Join(projectPath, ".gitignore"))
+
+	extensionCounts = make(map[string]int)
+	shebangCounts = make(map[string]int)
+	err = filepath.Walk(projectPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil // Continue on errors
 		}
-		if info.IsDir() {
-			// Skip common directories
-			dirName := info.Name()
-			if strings.HasPrefix(dirName, ".") || 
-			   dirName == "node_modules" ||
-			   dirName == "vendor" ||
-			   dirName == "target" ||
-			   dirName == "__pycache__" {
-				return filepath.SkipDir
+		if path == projectPath {
+			return nil
+		}
+
+		if info.IsDir() && isCommonSkipDir(info.Name()) {
+			return filepath.SkipDir
+		}
+		if gitignore != nil {
+			if relPath, err := filepath.Rel(projectPath, path); err == nil {
+				relPath = filepath.ToSlash(relPath)
+				if info.IsDir() {
+					relPath += "/"
+				}
+				if gitignore.MatchesPath(relPath) {
+					if info.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
 			}
+		}
+
+		if info.IsDir() {
 			return nil
 		}
-		
+
 		ext := strings.ToLower(filepath.Ext(path))
 		if ext != "" {
 			extensionCounts[ext]++
+		} else if !info.Mode().IsRegular() {
+			return nil
+		} else if lang, ok := DetectLanguageFromShebang(path); ok {
+			// Extensionless scripts, such as CLI entry points
+			shebangCounts[lang]++
 		}
 		return nil
 	})
-	
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to walk directory: %w", err)
-	}
-	
-	// Find the language with the most files
-	var bestLang string
-	var bestCount int
-	var bestConfig *SupportedLanguage
-	
-	for langName, lang := range config.Languages {
-		count := 0
-		for _, ext := range lang.Extensions {
-			count += extensionCounts[ext]
-		}
-		if count > bestCount {
-			bestCount = count
-			bestLang = langName
-			langCopy := lang
-			bestConfig = &langCopy
-		}
+		return nil, nil, fmt.Errorf("failed to walk directory: %w", err)
 	}
-	
-	if bestLang == "" {
-		return "", nil, fmt.Errorf("could not detect language for project")
+	return extensionCounts, shebangCounts, nil
+}
+
+// isCommonSkipDir reports whether a directory is commonly excluded from detection
+func isCommonSkipDir(dirName string) bool {
+	return strings.HasPrefix(dirName, ".") ||
+		dirName == "node_modules" ||
+		dirName == "vendor" ||
+		dirName == "target" ||
+		dirName == "__pycache__"
+}
+
+// hasProjectFile reports whether projectPath contains the given project file,
+// which may be a glob pattern such as "*.csproj"
+func hasProjectFile(projectPath, projectFile string) bool {
+	if strings.ContainsAny(projectFile, "*?[") {
+		matches, err := filepath.Glob(filepath.Join(projectPath, projectFile))
+		return err == nil && len(matches) > 0
 	}
-	
-	return bestLang, bestConfig, nil
+	_, err := os.Stat(filepath.Join(projectPath, projectFile))
+	return err == nil
 }
 
-// GetLanguageByExtension returns the language configuration for a given file extension
+// GetLanguageByExtension returns the default language configuration for a
+// given file extension. Use [LanguageConfig.LanguageByExtension] to include a
+// project's overrides.
 func GetLanguageByExtension(ext string) (string, *SupportedLanguage) {
-	config := DefaultLanguageConfig()
+	return DefaultLanguageConfig().LanguageByExtension(ext)
+}
+
+// LanguageByExtension returns the language of lc for a given file extension.
+// When several languages claim the extension, the first by name wins.
+func (lc *LanguageConfig) LanguageByExtension(ext string) (string, *SupportedLanguage) {
 	ext = strings.ToLower(ext)
-	
-	for langName, lang := range config.Languages {
-		for _, langExt := range lang.Extensions {
-			if langExt == ext {
-				langCopy := lang
-				return langName, &langCopy
-			}
+
+	for _, langName := range slices.Sorted(maps.Keys(lc.Languages)) {
+		lang := lc.Languages[langName]
+		if slices.Contains(lang.Extensions, ext) {
+			return langName, &lang
 		}
 	}
-	
+
 	return "", nil
 }
 
@@ -194,7 +402,7 @@ func (lc *LanguageConfig) SaveToFile(filename string) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
-	
+
 	return os.WriteFile(filename, data, 0644)
 }
 
@@ -204,11 +412,67 @@ func LoadLanguageConfig(filename string) (*LanguageConfig, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
-	
+
 	var config LanguageConfig
 	if err := json.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
-	
+
 	return &config, nil
-}
\ No newline at end of file
+}
+
+// ProjectConfigFile is where a project keeps its language overrides, relative
+// to the project root
+const ProjectConfigFile = ".crush/language.json"
+
+// LoadProjectLanguageConfig returns the default language configuration with
+// the overrides from the project's ProjectConfigFile merged on top. A project
+// without the file gets the shared defaults, so the result must not be
+// modified.
+func LoadProjectLanguageConfig(projectPath string) (*LanguageConfig, error) {
+	overrides, err := LoadLanguageConfig(filepath.Join(projectPath, ProjectConfigFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return DefaultLanguageConfig(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", ProjectConfigFile, err)
+	}
+	return DefaultLanguageConfig().Merge(overrides), nil
+}
+
+// Merge returns a new configuration with overrides applied on top of lc.
+// Languages are matched by key; any field set in an override replaces the
+// same field of the base language, and fields left empty keep their base
+// value. Languages that only exist in overrides are added as they are.
+// Neither configuration is modified.
+func (lc *LanguageConfig) Merge(overrides *LanguageConfig) *LanguageConfig {
+	merged := &LanguageConfig{Languages: maps.Clone(lc.Languages)}
+	if merged.Languages == nil {
+		merged.Languages = make(map[string]SupportedLanguage)
+	}
+	if overrides == nil {
+		return merged
+	}
+
+	for name, override := range overrides.Languages {
+		base, exists := merged.Languages[name]
+		if !exists {
+			merged.Languages[name] = override
+			continue
+		}
+		base.Name = cmp.Or(override.Name, base.Name)
+		base.LSPCommand = cmp.Or(override.LSPCommand, base.LSPCommand)
+		base.LintCommand = cmp.Or(override.LintCommand, base.LintCommand)
+		base.FormatCommand = cmp.Or(override.FormatCommand, base.FormatCommand)
+		base.BuildCommand = cmp.Or(override.BuildCommand, base.BuildCommand)
+		base.TestCommand = cmp.Or(override.TestCommand, base.TestCommand)
+		if len(override.Extensions) > 0 {
+			base.Extensions = override.Extensions
+		}
+		if len(override.ProjectFiles) > 0 {
+			base.ProjectFiles = override.ProjectFiles
+		}
+		merged.Languages[name] = base
+	}
+	return merged
+}
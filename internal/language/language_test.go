@@ -0,0 +1,331 @@
+package language
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, file := range files {
+		path := filepath.Join(dir, file)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(""), 0o644))
+	}
+}
+
+func TestGetLanguageByExtension(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		".cs":  "csharp",
+		".rb":  "ruby",
+		".kt":  "kotlin",
+		".kts": "kotlin",
+		".KT":  "kotlin",
+		".go":  "go",
+	}
+	for ext, expected := range tests {
+		name, lang := GetLanguageByExtension(ext)
+		require.Equal(t, expected, name, ext)
+		require.NotNil(t, lang, ext)
+	}
+
+	name, lang := GetLanguageByExtension(".unknown")
+	require.Empty(t, name)
+	require.Nil(t, lang)
+}
+
+func TestDetectLanguageByProjectFile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		files    []string
+		expected string
+	}{
+		{name: "csproj glob", files: []string{"App.csproj"}, expected: "csharp"},
+		{name: "solution glob", files: []string{"App.sln"}, expected: "csharp"},
+		{name: "Gemfile", files: []string{"Gemfile"}, expected: "ruby"},
+		{name: "gradle kotlin dsl", files: []string{"build.gradle.kts"}, expected: "kotlin"},
+		{name: "java with gradle kotlin dsl", files: []string{"build.gradle.kts", "src/Main.java", "src/Util.java"}, expected: "java"},
+		{name: "kotlin with gradle kotlin dsl", files: []string{"build.gradle.kts", "src/Main.kt", "src/Util.java"}, expected: "kotlin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files...)
+
+			name, lang, err := DetectLanguage(dir)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, name)
+			require.NotNil(t, lang)
+		})
+	}
+}
+
+func TestDetectLanguageByExtension(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		files    []string
+		expected string
+	}{
+		{name: "csharp", files: []string{"src/Program.cs", "src/Startup.cs"}, expected: "csharp"},
+		{name: "ruby", files: []string{"lib/app.rb", "lib/util.rb", "Rakefile.rake"}, expected: "ruby"},
+		{name: "kotlin", files: []string{"src/Main.kt", "src/Util.kt"}, expected: "kotlin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files...)
+
+			name, _, err := DetectLanguage(dir)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, name)
+		})
+	}
+}
+
+func TestDetectLanguages(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir,
+		"go.mod",
+		"main.go",
+		"internal/server/server.go",
+		"web/src/app.ts",
+		"web/src/index.ts",
+		"web/src/api.ts",
+		"web/src/util.tsx",
+		"web/src/main.go",
+		"README.md",
+	)
+
+	results, err := DetectLanguages(dir)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	require.Equal(t, "typescript", results[0].Name)
+	require.Equal(t, 4, results[0].FileCount)
+	require.InDelta(t, 4.0/7.0, results[0].Confidence, 1e-9)
+
+	require.Equal(t, "go", results[1].Name)
+	require.Equal(t, 3, results[1].FileCount)
+	require.InDelta(t, 3.0/7.0, results[1].Confidence, 1e-9)
+	require.Equal(t, []string{"go.mod"}, results[1].ProjectFiles)
+
+	// The single-result detection still prefers languages with a project file.
+	name, lang, err := DetectLanguage(dir)
+	require.NoError(t, err)
+	require.Equal(t, "go", name)
+	require.Equal(t, "Go", lang.Name)
+}
+
+func TestPrimaryLanguageSkipsShell(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir,
+		"main.py",
+		"scripts/build.sh",
+		"scripts/deploy.sh",
+		"scripts/release.sh",
+	)
+
+	results, err := DetectLanguages(dir)
+	require.NoError(t, err)
+	require.Equal(t, "shell", results[0].Name)
+
+	name, _, err := DetectLanguage(dir)
+	require.NoError(t, err)
+	require.Equal(t, "python", name)
+
+	// Shell is still picked when it's the only language.
+	scriptsOnly := t.TempDir()
+	writeFiles(t, scriptsOnly, "install.sh")
+	name, _, err = DetectLanguage(scriptsOnly)
+	require.NoError(t, err)
+	require.Equal(t, "shell", name)
+}
+
+func TestDetectLanguagesEmptyProject(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, "notes.txt")
+
+	_, err := DetectLanguages(dir)
+	require.Error(t, err)
+
+	_, _, err = DetectLanguage(dir)
+	require.Error(t, err)
+}
+
+func TestDetectLanguagesRespectsGitignore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir,
+		"main.py",
+		"app/models.py",
+		"dist/bundle.js",
+		"dist/vendor.js",
+		"dist/chunks/a.js",
+		"dist/chunks/b.js",
+		"generated.min.js",
+	)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("# build output\ndist/\n*.min.js\n"), 0o644))
+
+	results, err := DetectLanguages(dir)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "python", results[0].Name)
+	require.Equal(t, 2, results[0].FileCount)
+}
+
+func TestDetectLanguagesSkipsCommonDirsWithGitignore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir,
+		"main.py",
+		"node_modules/lib/index.js",
+		"vendor/lib/util.js",
+		".cache/lib/site.js",
+		"generated.min.js",
+	)
+	// The .gitignore doesn't mention the dependency directories
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("*.min.js\n"), 0o644))
+
+	results, err := DetectLanguages(dir)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "python", results[0].Name)
+}
+
+func TestDetectLanguagesSkipsCommonDirsWithoutGitignore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir,
+		"main.py",
+		"node_modules/lib/index.js",
+		"node_modules/lib/util.js",
+		".venv/lib/site.js",
+	)
+
+	results, err := DetectLanguages(dir)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "python", results[0].Name)
+}
+
+func TestLanguageConfigMerge(t *testing.T) {
+	t.Parallel()
+
+	base := DefaultLanguageConfig()
+	merged := base.Merge(&LanguageConfig{Languages: map[string]SupportedLanguage{
+		"go":  {FormatCommand: "gofumpt -w", TestCommand: "make test"},
+		"zig": {Name: "Zig", Extensions: []string{".zig"}, BuildCommand: "zig build"},
+	}})
+
+	goLang := merged.Languages["go"]
+	require.Equal(t, "gofumpt -w", goLang.FormatCommand)
+	require.Equal(t, "make test", goLang.TestCommand)
+	require.Equal(t, "golangci-lint run", goLang.LintCommand, "unset fields keep the default")
+	require.Equal(t, []string{".go"}, goLang.Extensions)
+	require.Equal(t, "zig build", merged.Languages["zig"].BuildCommand)
+
+	require.Equal(t, "gofmt -w", base.Languages["go"].FormatCommand, "defaults are not modified")
+	require.NotContains(t, base.Languages, "zig")
+}
+
+func TestLoadProjectLanguageConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	config, err := LoadProjectLanguageConfig(dir)
+	require.NoError(t, err)
+	require.Equal(t, "gofmt -w", config.Languages["go"].FormatCommand)
+
+	overrides := &LanguageConfig{Languages: map[string]SupportedLanguage{"python": {LintCommand: "ruff check"}}}
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".crush"), 0o755))
+	require.NoError(t, overrides.SaveToFile(filepath.Join(dir, ProjectConfigFile)))
+
+	config, err = LoadProjectLanguageConfig(dir)
+	require.NoError(t, err)
+	require.Equal(t, "ruff check", config.Languages["python"].LintCommand)
+	require.Equal(t, "black", config.Languages["python"].FormatCommand)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ProjectConfigFile), []byte("not json"), 0o644))
+	_, err = LoadProjectLanguageConfig(dir)
+	require.ErrorContains(t, err, ProjectConfigFile)
+}
+
+func TestLanguageByExtensionWithLoadedConfig(t *testing.T) {
+	t.Parallel()
+
+	config := DefaultLanguageConfig().Merge(&LanguageConfig{Languages: map[string]SupportedLanguage{
+		"zig": {Name: "Zig", Extensions: []string{".zig"}, FormatCommand: "zig fmt"},
+	}})
+
+	name, lang := config.LanguageByExtension(".ZIG")
+	require.Equal(t, "zig", name)
+	require.Equal(t, "zig fmt", lang.FormatCommand)
+
+	name, _ = config.LanguageByExtension(".go")
+	require.Equal(t, "go", name, "defaults are kept")
+
+	name, lang = GetLanguageByExtension(".zig")
+	require.Empty(t, name, "the defaults are not extended")
+	require.Nil(t, lang)
+}
+
+func TestDetectLanguagesUsesProjectConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "main.zig", "build.zig", "util.py")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".crush"), 0o755))
+
+	results, err := DetectLanguages(dir)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "python", results[0].Name)
+
+	// Adding the config file leaves the project directory's modification
+	// time alone, but still invalidates the cached results
+	configPath := filepath.Join(dir, ProjectConfigFile)
+	overrides := &LanguageConfig{Languages: map[string]SupportedLanguage{
+		"zig": {Name: "Zig", Extensions: []string{".zig"}, ProjectFiles: []string{"build.zig"}},
+	}}
+	require.NoError(t, overrides.SaveToFile(configPath))
+
+	results, err = DetectLanguages(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"zig", "python"}, []string{results[0].Name, results[1].Name})
+	require.Equal(t, []string{"build.zig"}, results[0].ProjectFiles)
+	require.Equal(t, "black", results[1].Language.FormatCommand, "unspecified languages keep the defaults")
+
+	// So does changing it
+	overrides.Languages["zig"] = SupportedLanguage{Extensions: []string{".zig"}, BuildCommand: "zig build"}
+	require.NoError(t, overrides.SaveToFile(configPath))
+	future := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(configPath, future, future))
+
+	name, lang, err := DetectLanguage(dir)
+	require.NoError(t, err)
+	require.Equal(t, "zig", name)
+	require.Equal(t, "zig build", lang.BuildCommand)
+}
@@ -0,0 +1,82 @@
+package language
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxShebangLength bounds how much of a file is read to find its shebang line
+const maxShebangLength = 256
+
+// shebangInterpreters maps script interpreters to the language they run
+var shebangInterpreters = map[string]string{
+	"python":  "python",
+	"pypy":    "python",
+	"node":    "javascript",
+	"nodejs":  "javascript",
+	"bun":     "javascript",
+	"deno":    "typescript",
+	"ts-node": "typescript",
+	"tsx":     "typescript",
+	"sh":      "shell",
+	"bash":    "shell",
+	"zsh":     "shell",
+	"dash":    "shell",
+	"ksh":     "shell",
+	"ruby":    "ruby",
+	"php":     "php",
+	"kotlin":  "kotlin",
+}
+
+// DetectLanguageFromShebang reads the first line of a script and returns the
+// language of the interpreter its shebang names, such as "python" for
+// "#!/usr/bin/env python3". It reports false for files without a shebang or
+// with an unknown interpreter.
+func DetectLanguageFromShebang(path string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	head := make([]byte, maxShebangLength)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", false
+	}
+	line, _, _ := bytes.Cut(head[:n], []byte("\n"))
+	return shebangLanguage(string(line))
+}
+
+// shebangLanguage returns the language of the interpreter named by a shebang
+// line. Interpreters run through env are found after its options and variable
+// assignments, and version suffixes such as python3.12 are ignored.
+func shebangLanguage(line string) (string, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), "#!")
+	if !ok {
+		return "", false
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", false
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if strings.HasPrefix(field, "-") || strings.Contains(field, "=") {
+				continue
+			}
+			interpreter = filepath.Base(field)
+			break
+		}
+	}
+
+	interpreter = strings.TrimRight(interpreter, "0123456789.")
+	lang, ok := shebangInterpreters[interpreter]
+	return lang, ok
+}
@@ -0,0 +1,83 @@
+package language
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectLanguageFromShebang(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		content  string
+		expected string
+		ok       bool
+	}{
+		"env python":          {content: "#!/usr/bin/env python3\nprint('hi')\n", expected: "python", ok: true},
+		"versioned python":    {content: "#!/usr/local/bin/python3.12\n", expected: "python", ok: true},
+		"bash":                {content: "#!/bin/bash\nset -euo pipefail\n", expected: "shell", ok: true},
+		"sh with options":     {content: "#! /bin/sh -e\n", expected: "shell", ok: true},
+		"env node":            {content: "#!/usr/bin/env node\nconsole.log('hi')\n", expected: "javascript", ok: true},
+		"env with split args": {content: "#!/usr/bin/env -S NODE_OPTIONS=--no-warnings node --inspect\n", expected: "javascript", ok: true},
+		"windows line ending": {content: "#!/usr/bin/env ruby\r\nputs 'hi'\r\n", expected: "ruby", ok: true},
+		"unknown interpreter": {content: "#!/usr/bin/env tclsh\n"},
+		"no shebang":          {content: "print('hi')\n"},
+		"empty":               {content: ""},
+		"shebang on line two": {content: "\n#!/bin/bash\n"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "script")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o755))
+
+			lang, ok := DetectLanguageFromShebang(path)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.expected, lang)
+		})
+	}
+
+	_, ok := DetectLanguageFromShebang(filepath.Join(t.TempDir(), "missing"))
+	require.False(t, ok)
+}
+
+func TestDetectLanguageFromShebangReadsOnlyFirstLine(t *testing.T) {
+	t.Parallel()
+
+	// A first line longer than the read limit is not a usable shebang
+	path := filepath.Join(t.TempDir(), "script")
+	require.NoError(t, os.WriteFile(path, []byte("#!/usr/bin/env "+strings.Repeat("x", maxShebangLength)+" python\n"), 0o755))
+	_, ok := DetectLanguageFromShebang(path)
+	require.False(t, ok)
+}
+
+func TestDetectLanguagesCountsExtensionlessScripts(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	scripts := map[string]string{
+		"bin/deploy":  "#!/usr/bin/env python3\n",
+		"bin/migrate": "#!/usr/bin/env python\n",
+		"bin/setup":   "#!/bin/bash\n",
+		"LICENSE":     "MIT License\n",
+	}
+	for name, content := range scripts {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o755))
+	}
+	writeFiles(t, dir, "lib/helpers.sh")
+
+	results, err := DetectLanguages(dir)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, "python", results[0].Name)
+	require.Equal(t, 2, results[0].FileCount)
+	require.Equal(t, "shell", results[1].Name)
+	require.Equal(t, 2, results[1].FileCount)
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"path/filepath"
 	"slices"
 	"strings"
 	"time"
@@ -19,6 +20,7 @@ import (
 	"github.com/charmbracelet/crush/internal/log"
 	"github.com/charmbracelet/crush/internal/lsp"
 	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/notifications"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/pubsub"
 	"github.com/charmbracelet/crush/internal/session"
@@ -56,6 +58,7 @@ type Service interface {
 	Run(ctx context.Context, sessionID string, content string, attachments ...message.Attachment) (<-chan AgentEvent, error)
 	Cancel(sessionID string)
 	CancelAll()
+	Shutdown()
 	IsSessionBusy(sessionID string) bool
 	IsBusy() bool
 	Summarize(ctx context.Context, sessionID string) error
@@ -88,6 +91,13 @@ type agent struct {
 	responseCache *ResponseCache
 	costEstimator *CostEstimator
 	feedbackMech  *FeedbackMechanism
+
+	// Sends a notification when a run finishes, nil when disabled
+	completionNotifier *completionNotifier
+
+	// The agent the agent tool delegates tasks to, shut down along with this
+	// one; nil for agents without the tool
+	taskAgent Service
 }
 
 var agentPromptMap = map[string]prompt.PromptID{
@@ -104,16 +114,19 @@ func NewAgent(
 	messages message.Service,
 	history history.Service,
 	lspClients map[string]*lsp.Client,
+	notificationRegistry *notifications.Registry,
 ) (Service, error) {
 	cfg := config.Get()
 
 	var agentTool tools.BaseTool
+	var taskAgent Service
 	if agentCfg.ID == "coder" {
 		taskAgentCfg := config.Get().Agents["task"]
 		if taskAgentCfg.ID == "" {
 			return nil, fmt.Errorf("task agent not found in config")
 		}
-		taskAgent, err := NewAgent(ctx, taskAgentCfg, permissions, sessions, messages, history, lspClients)
+		var err error
+		taskAgent, err = NewAgent(ctx, taskAgentCfg, permissions, sessions, messages, history, lspClients, notificationRegistry)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create task agent: %w", err)
 		}
@@ -178,6 +191,30 @@ func NewAgent(
 		return nil, err
 	}
 
+	feedbackMech, err := createFeedbackMechanism(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var judge *LLMEvaluator
+	if enhance := cfg.Options.EnhanceFeatures; enhance != nil {
+		switch enhance.Evaluator {
+		case "", EvaluatorHeuristic:
+		case EvaluatorLLM:
+			judgeOpts := []provider.ProviderClientOption{
+				provider.WithModel(config.SelectedModelTypeSmall),
+				provider.WithSystemMessage(prompt.GetPrompt(prompt.PromptJudge, smallModelProviderCfg.ID)),
+			}
+			judgeProvider, err := provider.NewProvider(*smallModelProviderCfg, judgeOpts...)
+			if err != nil {
+				return nil, err
+			}
+			judge = NewLLMEvaluator(judgeProvider)
+			feedbackMech.SetEvaluator(judge)
+		default:
+			slog.Warn("Unknown response evaluator, using heuristic metrics", "evaluator", enhance.Evaluator)
+		}
+	}
+
 	toolFn := func() []tools.BaseTool {
 		slog.Info("Initializing agent tools", "agent", agentCfg.ID)
 		defer func() {
@@ -204,7 +241,8 @@ func NewAgent(
 			// Security and workflow tools
 			tools.NewCheckpointTool(permissions, cwd),
 			tools.NewLintFormatTool(permissions, cwd),
-			tools.NewNotificationTool(permissions, cfg.Notifications),
+			tools.NewBuildTestTool(permissions, cwd),
+			tools.NewNotificationToolWithRegistry(permissions, notificationRegistry),
 		}
 
 		mcpToolsOnce.Do(func() {
@@ -233,7 +271,7 @@ func NewAgent(
 		return filteredTools
 	}
 
-	return &agent{
+	a := &agent{
 		Broker:              pubsub.NewBroker[AgentEvent](),
 		agentCfg:            agentCfg,
 		provider:            agentProvider,
@@ -247,17 +285,24 @@ func NewAgent(
 		tools:               csync.NewLazySlice(toolFn),
 		promptQueue:         csync.NewMap[string, []string](),
 		// Initialize enhancement features with configuration
-		responseCache: createResponseCache(cfg),
-		costEstimator: createCostEstimator(cfg),
-		feedbackMech:  createFeedbackMechanism(cfg),
-	}, nil
+		responseCache: createResponseCache(cfg, agentCfg.ID),
+		costEstimator: createCostEstimator(cfg, providerCfg.Type),
+		feedbackMech:  feedbackMech,
+		// Only the top-level agent notifies, so sub-agent tasks don't alert separately
+		completionNotifier: createCompletionNotifier(cfg, agentCfg.ID, notificationRegistry),
+		taskAgent:          taskAgent,
+	}
+	if judge != nil {
+		judge.SetUsageHandler(a.trackEvaluatorUsage)
+	}
+	return a, nil
 }
 
 // createResponseCache creates a response cache based on configuration
-func createResponseCache(cfg *config.Config) *ResponseCache {
+func createResponseCache(cfg *config.Config, agentID string) *ResponseCache {
 	enhance := cfg.Options.EnhanceFeatures
 	if enhance == nil {
-		return NewResponseCache(true, 30*time.Minute, 100) // Defaults
+		return NewResponseCache(true, 30*time.Minute, 100, WithSweepInterval(defaultCacheSweepInterval)) // Defaults
 	}
 
 	enabled := enhance.EnableCache
@@ -269,30 +314,72 @@ func createResponseCache(cfg *config.Config) *ResponseCache {
 	if maxEntries <= 0 {
 		maxEntries = 100 // Default
 	}
+	sweepInterval := time.Duration(enhance.CacheSweepMinutes) * time.Minute
+	if sweepInterval <= 0 {
+		sweepInterval = defaultCacheSweepInterval
+	}
 
-	return NewResponseCache(enabled, ttl, maxEntries)
+	var cache *ResponseCache
+	if enhance.PersistCache {
+		persistPath := filepath.Join(cfg.Options.DataDirectory, "cache", fmt.Sprintf("responses-%s.json", agentID))
+		cache = NewPersistentResponseCache(enabled, ttl, maxEntries, persistPath, WithSweepInterval(sweepInterval))
+	} else {
+		cache = NewResponseCache(enabled, ttl, maxEntries, WithSweepInterval(sweepInterval))
+	}
+	if len(enhance.CacheModelTTLMinutes) > 0 {
+		overrides := make(map[string]time.Duration, len(enhance.CacheModelTTLMinutes))
+		for modelID, minutes := range enhance.CacheModelTTLMinutes {
+			overrides[modelID] = time.Duration(minutes) * time.Minute
+		}
+		cache.SetTTLOverrides(overrides)
+	}
+	return cache
 }
 
-// createCostEstimator creates a cost estimator based on configuration
-func createCostEstimator(cfg *config.Config) *CostEstimator {
-	enhance := cfg.Options.EnhanceFeatures
-	if enhance == nil {
-		return NewCostEstimator(0.50) // Default
+// createCompletionNotifier creates the run completion notifier for the coder agent
+func createCompletionNotifier(cfg *config.Config, agentID string, registry *notifications.Registry) *completionNotifier {
+	if agentID != "coder" {
+		return nil
 	}
+	return newCompletionNotifier(cfg.Notifications, registry)
+}
 
-	threshold := enhance.MaxCostThreshold
-	if threshold <= 0 {
-		threshold = 0.50 // Default
+// createCostEstimator creates a cost estimator based on configuration for
+// requests sent to a provider of the given type
+func createCostEstimator(cfg *config.Config, providerType catwalk.Type) *CostEstimator {
+	threshold := 0.50 // Default
+	if enhance := cfg.Options.EnhanceFeatures; enhance != nil && enhance.MaxCostThreshold > 0 {
+		threshold = enhance.MaxCostThreshold
 	}
 
-	return NewCostEstimator(threshold)
+	estimator := NewCostEstimator(threshold)
+	estimator.SetProviderType(providerType)
+	if enhance := cfg.Options.EnhanceFeatures; enhance != nil && enhance.SessionBudget > 0 {
+		estimator.SetSessionBudget(enhance.SessionBudget)
+	}
+	return estimator
+}
+
+// SessionUsage returns the token usage and cost recorded for a chat session
+// since the agent started
+func (a *agent) SessionUsage(sessionID string) SessionUsage {
+	return a.costEstimator.SessionUsage(sessionID)
+}
+
+// SessionBudget returns the maximum cost of a chat session, or 0 when there
+// is no budget
+func (a *agent) SessionBudget() float64 {
+	return a.costEstimator.SessionBudget()
 }
 
 // createFeedbackMechanism creates a feedback mechanism based on configuration
-func createFeedbackMechanism(cfg *config.Config) *FeedbackMechanism {
+func createFeedbackMechanism(cfg *config.Config) (*FeedbackMechanism, error) {
 	enhance := cfg.Options.EnhanceFeatures
 	if enhance == nil {
-		return NewFeedbackMechanism(true, 0.7, 2) // Defaults
+		return NewFeedbackMechanism(true, 0.7, 2, nil), nil // Defaults
+	}
+	if err := validateFeedbackWeights(enhance.FeedbackWeights); err != nil {
+		return nil, fmt.Errorf("invalid feedback_weights: %w", err)
 	}
 
 	enabled := enhance.EnableFeedback
@@ -305,7 +392,14 @@ func createFeedbackMechanism(cfg *config.Config) *FeedbackMechanism {
 		maxRetries = 2 // Default
 	}
 
-	return NewFeedbackMechanism(enabled, threshold, maxRetries)
+	fm := NewFeedbackMechanism(enabled, threshold, maxRetries, enhance.FeedbackWeights)
+	if enhance.RecordFeedback {
+		fm.SetSink(NewFileQualitySink(filepath.Join(cfg.Options.DataDirectory, "feedback", "quality.jsonl")))
+	}
+	if enhance.ValidatePaths {
+		fm.SetPathValidation(cfg.WorkingDir())
+	}
+	return fm, nil
 }
 
 func (a *agent) Model() catwalk.Model {
@@ -438,7 +532,12 @@ func (a *agent) Run(ctx context.Context, sessionID string, content string, attac
 		for _, attachment := range attachments {
 			attachmentParts = append(attachmentParts, message.BinaryContent{Path: attachment.FilePath, MIMEType: attachment.MimeType, Data: attachment.Content})
 		}
+		startTime := time.Now()
+		startCost := a.sessionCost(genCtx, sessionID)
 		result := a.processGeneration(genCtx, sessionID, content, attachmentParts)
+		if a.completionNotifier != nil {
+			a.notifyCompletion(sessionID, result, time.Since(startTime), startCost)
+		}
 		if result.Error != nil && !errors.Is(result.Error, ErrRequestCancelled) && !errors.Is(result.Error, context.Canceled) {
 			slog.Error(result.Error.Error())
 		}
@@ -455,6 +554,37 @@ func (a *agent) Run(ctx context.Context, sessionID string, content string, attac
 	return events, nil
 }
 
+// sessionCost returns the accumulated cost of a session, or 0 if it is unknown
+func (a *agent) sessionCost(ctx context.Context, sessionID string) float64 {
+	if a.completionNotifier == nil {
+		return 0
+	}
+	sess, err := a.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return 0
+	}
+	return sess.Cost
+}
+
+// notifyCompletion sends the completion notification for a finished run in the background
+func (a *agent) notifyCompletion(sessionID string, result AgentEvent, duration time.Duration, startCost float64) {
+	summary := runSummary{
+		sessionID: sessionID,
+		duration:  duration,
+		err:       result.Error,
+	}
+	if sess, err := a.sessions.Get(context.Background(), sessionID); err == nil {
+		summary.sessionTitle = sess.Title
+		summary.totalCost = sess.Cost
+		summary.cost = sess.Cost - startCost
+	}
+
+	go func() {
+		defer log.RecoverPanic("agent.notifyCompletion", nil)
+		a.completionNotifier.runFinished(context.Background(), summary)
+	}()
+}
+
 func (a *agent) processGeneration(ctx context.Context, sessionID, content string, attachmentParts []message.ContentPart) AgentEvent {
 	cfg := config.Get()
 	// List existing messages; if none, start title generation asynchronously.
@@ -575,18 +705,40 @@ func (a *agent) createUserMessage(ctx context.Context, sessionID, content string
 	})
 }
 
+// cachedResponse returns the cached response to msgHistory, if there is one,
+// and records the cost the cache hit avoided
+func (a *agent) cachedResponse(ctx context.Context, msgHistory []message.Message, model catwalk.Model) (message.Message, bool) {
+	cachedEntry, found := a.responseCache.Get(ctx, msgHistory, model.ID)
+	if !found {
+		return message.Message{}, false
+	}
+	if a.costEstimator != nil {
+		a.costEstimator.RecordCacheHit(cachedEntry, model)
+	}
+	return cachedEntry.Response, true
+}
+
 func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msgHistory []message.Message) (message.Message, *message.Message, error) {
 	ctx = context.WithValue(ctx, tools.SessionIDContextKey, sessionID)
 
 	// Check cache first to potentially avoid API call
-	if cachedEntry, found := a.responseCache.Get(ctx, msgHistory, a.Model().ID); found {
+	if cached, found := a.cachedResponse(ctx, msgHistory, a.Model()); found {
 		slog.Debug("Using cached response", "session_id", sessionID)
-		return cachedEntry.Response, nil, nil
+		return cached, nil, nil
 	}
 
-	// Estimate cost before making API call
+	// Collect tools (which may block on MCP initialization) so their
+	// definitions count towards the estimate
+	agentTools := slices.Collect(a.tools.Seq())
+
+	// Estimate cost before making API call, counting the tool definitions
+	// sent along with the messages
 	model := a.Model()
-	estimatedUsage, estimatedCost, err := a.costEstimator.EstimateRequestCost(ctx, msgHistory, model, int(model.DefaultMaxTokens))
+	toolInfos := make([]tools.ToolInfo, 0, len(agentTools))
+	for _, tool := range agentTools {
+		toolInfos = append(toolInfos, tool.Info())
+	}
+	estimatedUsage, estimatedCost, err := a.costEstimator.EstimateRequestCostWithTools(ctx, msgHistory, toolInfos, model, int(model.DefaultMaxTokens))
 	if err != nil {
 		slog.Warn("Failed to estimate cost", "error", err)
 	} else {
@@ -596,8 +748,13 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 			"output_tokens", estimatedUsage.OutputTokens,
 		)
 
-		// Check if cost is acceptable
-		if proceed, reason := a.costEstimator.ShouldProceed(estimatedCost); !proceed {
+		// Check if cost is acceptable, holding the session budget against
+		// what the session has cost so far
+		spent := 0.0
+		if sess, err := a.sessions.Get(ctx, sessionID); err == nil {
+			spent = sess.Cost
+		}
+		if proceed, reason := a.costEstimator.ShouldProceedAfter(spent, estimatedCost); !proceed {
 			return message.Message{}, nil, fmt.Errorf("request blocked: %s (estimated cost: $%.4f)", reason, estimatedCost)
 		}
 
@@ -619,8 +776,7 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 		return assistantMsg, nil, fmt.Errorf("failed to create assistant message: %w", err)
 	}
 
-	// Now collect tools (which may block on MCP initialization)
-	eventChan := a.provider.StreamResponse(ctx, msgHistory, slices.Collect(a.tools.Seq()))
+	eventChan := a.provider.StreamResponse(ctx, msgHistory, agentTools)
 
 	// Add the session and message ID into the context if needed by tools.
 	ctx = context.WithValue(ctx, tools.MessageIDContextKey, assistantMsg.ID)
@@ -752,18 +908,23 @@ out:
 			"issues", len(quality.Issues),
 		)
 
-		// If quality is poor and we haven't exceeded retry attempts, attempt improvement
-		if quality.RequiresRetry && len(quality.Issues) > 0 {
-			// Generate improvement prompt
-			improvementPrompt := a.feedbackMech.GenerateImprovementPrompt(ctx, assistantMsg, quality)
-			if improvementPrompt != "" {
-				slog.Debug("Generated improvement prompt", "prompt_length", len(improvementPrompt))
-
-				// Add improvement attempt to prompt queue for next iteration
-				// This allows for iterative improvement without immediate API calls
-				existingPrompts, _ := a.promptQueue.Get(sessionID)
-				updatedPrompts := append(existingPrompts, improvementPrompt)
-				a.promptQueue.Set(sessionID, updatedPrompts)
+		// If quality is poor, regenerate the final answer and keep the best-scoring one
+		if quality.RequiresRetry && len(quality.Issues) > 0 && assistantMsg.FinishReason() == message.FinishReasonEndTurn && len(toolCalls) == 0 {
+			improved, improvedQuality, usage := a.improveResponse(ctx, msgHistory, assistantMsg, quality)
+			if usage != (provider.TokenUsage{}) {
+				if err := a.TrackUsage(ctx, sessionID, a.Model(), usage); err != nil {
+					slog.Warn("Failed to track usage of response improvement", "error", err)
+				}
+			}
+			if improvedQuality != quality {
+				slog.Debug("Replacing response with improved version",
+					"previous_score", quality.Score,
+					"score", improvedQuality.Score,
+				)
+				assistantMsg = improved
+				if err := a.messages.Update(ctx, assistantMsg); err != nil {
+					slog.Warn("Failed to update improved response", "error", err)
+				}
 			}
 		}
 	}
@@ -855,12 +1016,11 @@ func (a *agent) TrackUsage(ctx context.Context, sessionID string, model catwalk.
 		return fmt.Errorf("failed to get session: %w", err)
 	}
 
-	cost := model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
-		model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
-		model.CostPer1MIn/1e6*float64(usage.InputTokens) +
-		model.CostPer1MOut/1e6*float64(usage.OutputTokens)
-
-	sess.Cost += cost
+	if a.costEstimator != nil {
+		sess.Cost += a.costEstimator.RecordSessionUsage(sessionID, usage, model)
+	} else {
+		sess.Cost += ActualCost(usage, model)
+	}
 	sess.CompletionTokens = usage.OutputTokens + usage.CacheReadTokens
 	sess.PromptTokens = usage.InputTokens + usage.CacheCreationTokens
 
@@ -871,6 +1031,29 @@ func (a *agent) TrackUsage(ctx context.Context, sessionID string, model catwalk.
 	return nil
 }
 
+// trackEvaluatorUsage adds the cost of a judge request to the session in ctx.
+// Unlike TrackUsage it leaves the session's token counts alone, as they
+// describe the conversation's context rather than the judge's.
+func (a *agent) trackEvaluatorUsage(ctx context.Context, model catwalk.Model, usage provider.TokenUsage) {
+	sessionID, _ := ctx.Value(tools.SessionIDContextKey).(string)
+	if sessionID == "" {
+		return
+	}
+	sess, err := a.sessions.Get(ctx, sessionID)
+	if err != nil {
+		slog.Warn("Failed to track usage of response evaluation", "error", err)
+		return
+	}
+	if a.costEstimator != nil {
+		sess.Cost += a.costEstimator.RecordSessionUsage(sessionID, usage, model)
+	} else {
+		sess.Cost += ActualCost(usage, model)
+	}
+	if _, err := a.sessions.Save(ctx, sess); err != nil {
+		slog.Warn("Failed to track usage of response evaluation", "error", err)
+	}
+}
+
 func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 	if a.summarizeProvider == nil {
 		return fmt.Errorf("summarize provider not available")
@@ -1020,12 +1203,7 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 		oldSession.CompletionTokens = finalResponse.Usage.OutputTokens
 		oldSession.PromptTokens = 0
 		model := a.summarizeProvider.Model()
-		usage := finalResponse.Usage
-		cost := model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
-			model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
-			model.CostPer1MIn/1e6*float64(usage.InputTokens) +
-			model.CostPer1MOut/1e6*float64(usage.OutputTokens)
-		oldSession.Cost += cost
+		oldSession.Cost += ActualCost(finalResponse.Usage, model)
 		_, err = a.sessions.Save(summarizeCtx, oldSession)
 		if err != nil {
 			event = AgentEvent{
@@ -1075,6 +1253,21 @@ func (a *agent) CancelAll() {
 	}
 }
 
+// Shutdown cancels all active requests, stops the cache sweeper and persists
+// the response cache, then shuts down the task agent.
+func (a *agent) Shutdown() {
+	if a.taskAgent != nil {
+		defer a.taskAgent.Shutdown()
+	}
+	a.CancelAll()
+	if a.responseCache != nil {
+		a.responseCache.Close()
+		if err := a.responseCache.Save(); err != nil {
+			slog.Error("Failed to persist response cache", "error", err)
+		}
+	}
+}
+
 func (a *agent) UpdateModel() error {
 	cfg := config.Get()
 
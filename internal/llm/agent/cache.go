@@ -6,23 +6,34 @@ import (
 	"fmt"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/charmbracelet/crush/internal/llm/provider"
 	"github.com/charmbracelet/crush/internal/message"
 )
 
+// defaultCacheSweepInterval is how often expired entries are swept when the
+// interval is not configured
+const defaultCacheSweepInterval = 5 * time.Minute
+
 // CacheEntry represents a cached response
 type CacheEntry struct {
-	Response   message.Message
-	TokenUsage provider.TokenUsage
-	Timestamp  time.Time
-	TTL        time.Duration
+	Response     message.Message
+	TokenUsage   provider.TokenUsage
+	Timestamp    time.Time
+	LastAccessed time.Time
+	TTL          time.Duration
 }
 
 // IsExpired checks if cache entry has expired
 func (c *CacheEntry) IsExpired() bool {
-	return time.Since(c.Timestamp) > c.TTL
+	return c.expiredAt(time.Now())
+}
+
+// expiredAt reports whether the entry has expired at the given time
+func (c *CacheEntry) expiredAt(now time.Time) bool {
+	return now.Sub(c.Timestamp) > c.TTL
 }
 
 // ResponseCache provides caching for LLM responses to reduce API calls
@@ -32,20 +43,139 @@ type ResponseCache struct {
 	enabled bool
 	// Default TTL for cache entries
 	defaultTTL time.Duration
+	// TTLs of entries for specific models, keyed by model ID
+	ttlOverrides map[string]time.Duration
 	// Maximum cache size
 	maxSize int
+	// Lookup counters used to report cache effectiveness
+	hits   atomic.Int64
+	misses atomic.Int64
+	// Lookups skipped because the request asked for a fresh response
+	bypasses atomic.Int64
+	// File the cache is persisted to, empty for an in-memory cache
+	persistPath string
+	// Interval at which expired entries are swept in the background, 0 disables the sweeper
+	sweepInterval time.Duration
+	// Clock used for expiry, replaceable in tests
+	now       func() time.Time
+	stopSweep chan struct{}
+	sweepDone chan struct{}
+	closeOnce sync.Once
 }
 
-// NewResponseCache creates a new response cache
-func NewResponseCache(enabled bool, defaultTTL time.Duration, maxSize int) *ResponseCache {
-	return &ResponseCache{
-		cache:      make(map[string]*CacheEntry),
-		enabled:    enabled,
-		defaultTTL: defaultTTL,
-		maxSize:    maxSize,
+// ResponseCacheOption configures optional ResponseCache behavior
+type ResponseCacheOption func(*ResponseCache)
+
+// WithSweepInterval starts a background goroutine that removes expired
+// entries every interval, so they stop counting against the maximum size.
+// Call Close to stop it.
+func WithSweepInterval(interval time.Duration) ResponseCacheOption {
+	return func(rc *ResponseCache) {
+		rc.sweepInterval = interval
 	}
 }
 
+// NewResponseCache creates a new in-memory response cache
+func NewResponseCache(enabled bool, defaultTTL time.Duration, maxSize int, opts ...ResponseCacheOption) *ResponseCache {
+	return NewPersistentResponseCache(enabled, defaultTTL, maxSize, "", opts...)
+}
+
+// NewPersistentResponseCache creates a response cache backed by the file at
+// persistPath. Unexpired entries saved by a previous run are loaded
+// immediately; call Save to write the cache back, e.g. on shutdown.
+func NewPersistentResponseCache(enabled bool, defaultTTL time.Duration, maxSize int, persistPath string, opts ...ResponseCacheOption) *ResponseCache {
+	rc := &ResponseCache{
+		cache:       make(map[string]*CacheEntry),
+		enabled:     enabled,
+		defaultTTL:  defaultTTL,
+		maxSize:     maxSize,
+		persistPath: persistPath,
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(rc)
+	}
+	if enabled && persistPath != "" {
+		rc.load()
+	}
+	if enabled && rc.sweepInterval > 0 {
+		rc.stopSweep = make(chan struct{})
+		rc.sweepDone = make(chan struct{})
+		go rc.sweep()
+	}
+	return rc
+}
+
+// SetTTLOverrides sets the TTL of entries cached for specific models, keyed
+// by model ID, replacing any previous overrides. Other models and overrides
+// that aren't positive use the default TTL. Entries already cached keep the
+// TTL they were stored with.
+func (rc *ResponseCache) SetTTLOverrides(overrides map[string]time.Duration) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.ttlOverrides = make(map[string]time.Duration, len(overrides))
+	for modelID, ttl := range overrides {
+		if ttl > 0 {
+			rc.ttlOverrides[modelID] = ttl
+		}
+	}
+}
+
+// ttlFor returns the TTL of entries cached for a model. The caller must hold
+// the lock.
+func (rc *ResponseCache) ttlFor(modelID string) time.Duration {
+	if ttl, ok := rc.ttlOverrides[modelID]; ok {
+		return ttl
+	}
+	return rc.defaultTTL
+}
+
+// sweep periodically removes expired entries until Close is called
+func (rc *ResponseCache) sweep() {
+	defer close(rc.sweepDone)
+
+	ticker := time.NewTicker(rc.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			rc.CleanExpired()
+		case <-rc.stopSweep:
+			return
+		}
+	}
+}
+
+// Close stops the background sweeper, if any. It does not persist the cache;
+// use Save for that. Close is safe to call more than once.
+func (rc *ResponseCache) Close() {
+	rc.closeOnce.Do(func() {
+		if rc.stopSweep == nil {
+			return
+		}
+		close(rc.stopSweep)
+		<-rc.sweepDone
+	})
+}
+
+// cacheBypassKey marks a context whose requests skip the response cache
+type cacheBypassKey struct{}
+
+// WithCacheBypass returns a context whose requests skip the cached response
+// lookup, e.g. when the user asks for a fresh answer. The fresh response is
+// still cached, so later identical requests without the bypass are served it.
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// CacheBypassed reports whether ctx was created by WithCacheBypass
+func CacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
 // generateCacheKey creates a unique key for the request
 func (rc *ResponseCache) generateCacheKey(messages []message.Message, modelID string) string {
 	hasher := sha256.New()
@@ -66,30 +196,40 @@ func (rc *ResponseCache) generateCacheKey(messages []message.Message, modelID st
 	return fmt.Sprintf("%x", hasher.Sum(nil))
 }
 
-// Get retrieves a cached response if available and not expired
+// Get retrieves a cached response if available and not expired. Lookups
+// with a context from WithCacheBypass always miss; they are counted as
+// bypasses rather than misses.
 func (rc *ResponseCache) Get(ctx context.Context, messages []message.Message, modelID string) (*CacheEntry, bool) {
 	if !rc.enabled {
 		return nil, false
 	}
+	if CacheBypassed(ctx) {
+		rc.bypasses.Add(1)
+		slog.Debug("Bypassing response cache for LLM request")
+		return nil, false
+	}
 
 	key := rc.generateCacheKey(messages, modelID)
 
-	rc.mu.RLock()
-	entry, exists := rc.cache[key]
-	rc.mu.RUnlock()
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
 
+	entry, exists := rc.cache[key]
 	if !exists {
+		rc.misses.Add(1)
 		return nil, false
 	}
 
-	if entry.IsExpired() {
+	if entry.expiredAt(rc.now()) {
 		// Clean up expired entry
-		rc.mu.Lock()
 		delete(rc.cache, key)
-		rc.mu.Unlock()
+		rc.misses.Add(1)
 		return nil, false
 	}
 
+	entry.LastAccessed = rc.now()
+	rc.hits.Add(1)
+
 	slog.Debug("Cache hit for LLM request", "key", key[:8])
 	return entry, true
 }
@@ -106,35 +246,37 @@ func (rc *ResponseCache) Set(ctx context.Context, messages []message.Message, mo
 	defer rc.mu.Unlock()
 
 	// Check if we need to evict entries
-	if len(rc.cache) >= rc.maxSize {
-		rc.evictOldest()
+	if _, exists := rc.cache[key]; !exists && len(rc.cache) >= rc.maxSize {
+		rc.evictLeastRecentlyUsed()
 	}
 
+	now := rc.now()
 	rc.cache[key] = &CacheEntry{
-		Response:   response,
-		TokenUsage: usage,
-		Timestamp:  time.Now(),
-		TTL:        rc.defaultTTL,
+		Response:     response,
+		TokenUsage:   usage,
+		Timestamp:    now,
+		LastAccessed: now,
+		TTL:          rc.ttlFor(modelID),
 	}
 
 	slog.Debug("Cached LLM response", "key", key[:8], "input_tokens", usage.InputTokens, "output_tokens", usage.OutputTokens)
 }
 
-// evictOldest removes the oldest cache entry
-func (rc *ResponseCache) evictOldest() {
-	var oldestKey string
-	var oldestTime time.Time
+// evictLeastRecentlyUsed removes the cache entry that was accessed least recently
+func (rc *ResponseCache) evictLeastRecentlyUsed() {
+	var lruKey string
+	var lruTime time.Time
 
 	for key, entry := range rc.cache {
-		if oldestKey == "" || entry.Timestamp.Before(oldestTime) {
-			oldestKey = key
-			oldestTime = entry.Timestamp
+		if lruKey == "" || entry.LastAccessed.Before(lruTime) {
+			lruKey = key
+			lruTime = entry.LastAccessed
 		}
 	}
 
-	if oldestKey != "" {
-		delete(rc.cache, oldestKey)
-		slog.Debug("Evicted oldest cache entry", "key", oldestKey[:8])
+	if lruKey != "" {
+		delete(rc.cache, lruKey)
+		slog.Debug("Evicted least recently used cache entry", "key", lruKey[:8])
 	}
 }
 
@@ -144,6 +286,9 @@ func (rc *ResponseCache) Clear() {
 	defer rc.mu.Unlock()
 
 	rc.cache = make(map[string]*CacheEntry)
+	if err := rc.saveLocked(); err != nil {
+		slog.Warn("Failed to persist cleared response cache", "error", err)
+	}
 	slog.Debug("Cleared response cache")
 }
 
@@ -160,9 +305,10 @@ func (rc *ResponseCache) CleanExpired() int {
 	rc.mu.Lock()
 	defer rc.mu.Unlock()
 
+	now := rc.now()
 	var expiredKeys []string
 	for key, entry := range rc.cache {
-		if entry.IsExpired() {
+		if entry.expiredAt(now) {
 			expiredKeys = append(expiredKeys, key)
 		}
 	}
@@ -186,12 +332,25 @@ func (rc *ResponseCache) GetStats() map[string]interface{} {
 	totalEntries := len(rc.cache)
 	expiredCount := 0
 
+	now := rc.now()
 	for _, entry := range rc.cache {
-		if entry.IsExpired() {
+		if entry.expiredAt(now) {
 			expiredCount++
 		}
 	}
 
+	ttlOverrides := make(map[string]string, len(rc.ttlOverrides))
+	for modelID, ttl := range rc.ttlOverrides {
+		ttlOverrides[modelID] = ttl.String()
+	}
+
+	hits := rc.hits.Load()
+	misses := rc.misses.Load()
+	hitRate := 0.0
+	if lookups := hits + misses; lookups > 0 {
+		hitRate = float64(hits) / float64(lookups)
+	}
+
 	return map[string]interface{}{
 		"enabled":        rc.enabled,
 		"total_entries":  totalEntries,
@@ -199,5 +358,10 @@ func (rc *ResponseCache) GetStats() map[string]interface{} {
 		"active_entries": totalEntries - expiredCount,
 		"max_size":       rc.maxSize,
 		"default_ttl":    rc.defaultTTL.String(),
+		"ttl_overrides":  ttlOverrides,
+		"hits":           hits,
+		"misses":         misses,
+		"hit_rate":       hitRate,
+		"bypasses":       rc.bypasses.Load(),
 	}
 }
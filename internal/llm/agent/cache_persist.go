@@ -0,0 +1,170 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// responseCacheVersion is bumped whenever the on-disk cache format changes.
+// Files written with a different version are ignored on load.
+const responseCacheVersion = 1
+
+type persistedCache struct {
+	Version int                       `json:"version"`
+	SavedAt time.Time                 `json:"saved_at"`
+	Entries map[string]persistedEntry `json:"entries"`
+}
+
+type persistedEntry struct {
+	Response     persistedMessage    `json:"response"`
+	TokenUsage   provider.TokenUsage `json:"token_usage"`
+	Timestamp    time.Time           `json:"timestamp"`
+	LastAccessed time.Time           `json:"last_accessed"`
+	TTL          time.Duration       `json:"ttl"`
+}
+
+type persistedMessage struct {
+	ID        string              `json:"id"`
+	Role      message.MessageRole `json:"role"`
+	SessionID string              `json:"session_id"`
+	Parts     json.RawMessage     `json:"parts"`
+	Model     string              `json:"model"`
+	Provider  string              `json:"provider"`
+	CreatedAt int64               `json:"created_at"`
+	UpdatedAt int64               `json:"updated_at"`
+}
+
+// Save writes the cache to its persistence path. It is a no-op when the cache
+// was created without one.
+func (rc *ResponseCache) Save() error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	return rc.saveLocked()
+}
+
+// saveLocked writes the cache to disk. The caller must hold rc.mu.
+func (rc *ResponseCache) saveLocked() error {
+	if rc.persistPath == "" {
+		return nil
+	}
+
+	data := persistedCache{
+		Version: responseCacheVersion,
+		SavedAt: rc.now(),
+		Entries: make(map[string]persistedEntry, len(rc.cache)),
+	}
+	for key, entry := range rc.cache {
+		if entry.expiredAt(data.SavedAt) {
+			continue
+		}
+		parts, err := message.MarshalParts(entry.Response.Parts)
+		if err != nil {
+			return fmt.Errorf("failed to marshal cached response: %w", err)
+		}
+		data.Entries[key] = persistedEntry{
+			Response: persistedMessage{
+				ID:        entry.Response.ID,
+				Role:      entry.Response.Role,
+				SessionID: entry.Response.SessionID,
+				Parts:     parts,
+				Model:     entry.Response.Model,
+				Provider:  entry.Response.Provider,
+				CreatedAt: entry.Response.CreatedAt,
+				UpdatedAt: entry.Response.UpdatedAt,
+			},
+			TokenUsage:   entry.TokenUsage,
+			Timestamp:    entry.Timestamp,
+			LastAccessed: entry.LastAccessed,
+			TTL:          entry.TTL,
+		}
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(rc.persistPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated cache.
+	tmpPath := rc.persistPath + ".tmp"
+	if err := os.WriteFile(tmpPath, encoded, 0o600); err != nil {
+		return fmt.Errorf("failed to write response cache: %w", err)
+	}
+	if err := os.Rename(tmpPath, rc.persistPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write response cache: %w", err)
+	}
+
+	slog.Debug("Saved response cache", "path", rc.persistPath, "entries", len(data.Entries))
+	return nil
+}
+
+// load reads previously persisted entries, discarding expired ones. A missing,
+// corrupt or outdated file leaves the cache empty instead of failing.
+func (rc *ResponseCache) load() {
+	raw, err := os.ReadFile(rc.persistPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("Failed to read response cache", "path", rc.persistPath, "error", err)
+		}
+		return
+	}
+
+	var data persistedCache
+	if err := json.Unmarshal(raw, &data); err != nil {
+		slog.Warn("Ignoring unreadable response cache", "path", rc.persistPath, "error", err)
+		return
+	}
+	if data.Version != responseCacheVersion {
+		slog.Warn("Ignoring response cache with unsupported version", "path", rc.persistPath, "version", data.Version)
+		return
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	for key, stored := range data.Entries {
+		parts, err := message.UnmarshalParts(stored.Response.Parts)
+		if err != nil {
+			slog.Warn("Skipping unreadable cache entry", "key", key, "error", err)
+			continue
+		}
+		entry := &CacheEntry{
+			Response: message.Message{
+				ID:        stored.Response.ID,
+				Role:      stored.Response.Role,
+				SessionID: stored.Response.SessionID,
+				Parts:     parts,
+				Model:     stored.Response.Model,
+				Provider:  stored.Response.Provider,
+				CreatedAt: stored.Response.CreatedAt,
+				UpdatedAt: stored.Response.UpdatedAt,
+			},
+			TokenUsage:   stored.TokenUsage,
+			Timestamp:    stored.Timestamp,
+			LastAccessed: stored.LastAccessed,
+			TTL:          stored.TTL,
+		}
+		if entry.expiredAt(rc.now()) {
+			continue
+		}
+		rc.cache[key] = entry
+	}
+
+	for len(rc.cache) > rc.maxSize {
+		rc.evictLeastRecentlyUsed()
+	}
+
+	slog.Debug("Loaded response cache", "path", rc.persistPath, "entries", len(rc.cache))
+}
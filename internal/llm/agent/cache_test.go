@@ -0,0 +1,337 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func cacheMessages(text string) []message.Message {
+	return []message.Message{{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: text}},
+	}}
+}
+
+func cacheResponse(text string) message.Message {
+	return message.Message{
+		Role:  message.Assistant,
+		Parts: []message.ContentPart{message.TextContent{Text: text}},
+	}
+}
+
+func TestResponseCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	t.Parallel()
+
+	rc := NewResponseCache(true, time.Hour, 2)
+	ctx := t.Context()
+
+	rc.Set(ctx, cacheMessages("old"), "model", cacheResponse("old answer"), provider.TokenUsage{})
+	time.Sleep(time.Millisecond)
+	rc.Set(ctx, cacheMessages("new"), "model", cacheResponse("new answer"), provider.TokenUsage{})
+	time.Sleep(time.Millisecond)
+
+	// Reusing the oldest entry makes it the most recently used one.
+	_, ok := rc.Get(ctx, cacheMessages("old"), "model")
+	require.True(t, ok)
+	time.Sleep(time.Millisecond)
+
+	rc.Set(ctx, cacheMessages("third"), "model", cacheResponse("third answer"), provider.TokenUsage{})
+	require.Equal(t, 2, rc.Size())
+
+	_, ok = rc.Get(ctx, cacheMessages("old"), "model")
+	require.True(t, ok, "recently accessed entry should survive")
+	_, ok = rc.Get(ctx, cacheMessages("new"), "model")
+	require.False(t, ok, "unused entry should be evicted first")
+	_, ok = rc.Get(ctx, cacheMessages("third"), "model")
+	require.True(t, ok)
+}
+
+func TestResponseCacheOverwriteDoesNotEvict(t *testing.T) {
+	t.Parallel()
+
+	rc := NewResponseCache(true, time.Hour, 2)
+	ctx := t.Context()
+
+	rc.Set(ctx, cacheMessages("a"), "model", cacheResponse("a"), provider.TokenUsage{})
+	rc.Set(ctx, cacheMessages("b"), "model", cacheResponse("b"), provider.TokenUsage{})
+	rc.Set(ctx, cacheMessages("b"), "model", cacheResponse("b2"), provider.TokenUsage{})
+
+	require.Equal(t, 2, rc.Size())
+	_, ok := rc.Get(ctx, cacheMessages("a"), "model")
+	require.True(t, ok)
+}
+
+func TestResponseCacheHitMissStats(t *testing.T) {
+	t.Parallel()
+
+	rc := NewResponseCache(true, time.Hour, 10)
+	ctx := t.Context()
+
+	rc.Set(ctx, cacheMessages("cached"), "model", cacheResponse("answer"), provider.TokenUsage{})
+
+	// 3 hits and 1 miss.
+	for range 3 {
+		_, ok := rc.Get(ctx, cacheMessages("cached"), "model")
+		require.True(t, ok)
+	}
+	_, ok := rc.Get(ctx, cacheMessages("missing"), "model")
+	require.False(t, ok)
+
+	stats := rc.GetStats()
+	require.Equal(t, int64(3), stats["hits"])
+	require.Equal(t, int64(1), stats["misses"])
+	require.InDelta(t, 0.75, stats["hit_rate"], 1e-9)
+}
+
+func TestResponseCacheBypass(t *testing.T) {
+	t.Parallel()
+
+	rc := NewResponseCache(true, time.Hour, 10)
+	ctx := t.Context()
+	bypassCtx := WithCacheBypass(ctx)
+	require.True(t, CacheBypassed(bypassCtx))
+	require.False(t, CacheBypassed(ctx))
+
+	rc.Set(ctx, cacheMessages("what time is it"), "model", cacheResponse("noon"), provider.TokenUsage{})
+	_, ok := rc.Get(bypassCtx, cacheMessages("what time is it"), "model")
+	require.False(t, ok, "a bypassed lookup must miss even though the prompt is cached")
+
+	// The fresh response replaces the cached one for later lookups
+	rc.Set(bypassCtx, cacheMessages("what time is it"), "model", cacheResponse("one o'clock"), provider.TokenUsage{})
+	entry, ok := rc.Get(ctx, cacheMessages("what time is it"), "model")
+	require.True(t, ok)
+	require.Equal(t, "one o'clock", entry.Response.Content().String())
+
+	stats := rc.GetStats()
+	require.Equal(t, int64(1), stats["hits"])
+	require.Equal(t, int64(0), stats["misses"])
+	require.Equal(t, int64(1), stats["bypasses"])
+}
+
+func TestResponseCacheStatsConcurrent(t *testing.T) {
+	t.Parallel()
+
+	rc := NewResponseCache(true, time.Hour, 10)
+	ctx := t.Context()
+	rc.Set(ctx, cacheMessages("cached"), "model", cacheResponse("answer"), provider.TokenUsage{})
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			rc.Get(ctx, cacheMessages("cached"), "model")
+		}()
+		go func() {
+			defer wg.Done()
+			rc.Get(ctx, cacheMessages("missing"), "model")
+		}()
+	}
+	wg.Wait()
+
+	stats := rc.GetStats()
+	require.Equal(t, int64(50), stats["hits"])
+	require.Equal(t, int64(50), stats["misses"])
+	require.InDelta(t, 0.5, stats["hit_rate"], 1e-9)
+}
+
+func TestResponseCachePersistRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), ".crush", "cache", "responses.json")
+	ctx := t.Context()
+
+	rc := NewPersistentResponseCache(true, time.Hour, 10, path)
+	usage := provider.TokenUsage{InputTokens: 100, OutputTokens: 20}
+	rc.Set(ctx, cacheMessages("question"), "model", cacheResponse("answer"), usage)
+	require.NoError(t, rc.Save())
+
+	loaded := NewPersistentResponseCache(true, time.Hour, 10, path)
+	require.Equal(t, 1, loaded.Size())
+
+	entry, ok := loaded.Get(ctx, cacheMessages("question"), "model")
+	require.True(t, ok)
+	require.Equal(t, "answer", entry.Response.Content().Text)
+	require.Equal(t, message.Assistant, entry.Response.Role)
+	require.Equal(t, usage, entry.TokenUsage)
+	require.Equal(t, time.Hour, entry.TTL)
+}
+
+func TestAgentShutdownPersistsTaskAgentCache(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	newAgent := func(id string, taskAgent Service) *agent {
+		return &agent{
+			activeRequests: csync.NewMap[string, context.CancelFunc](),
+			responseCache:  NewPersistentResponseCache(true, time.Hour, 10, filepath.Join(dir, "responses-"+id+".json")),
+			taskAgent:      taskAgent,
+		}
+	}
+	task := newAgent("task", nil)
+	coder := newAgent("coder", task)
+	task.responseCache.Set(t.Context(), cacheMessages("question"), "model", cacheResponse("answer"), provider.TokenUsage{})
+
+	coder.Shutdown()
+
+	loaded := NewPersistentResponseCache(true, time.Hour, 10, filepath.Join(dir, "responses-task.json"))
+	require.Equal(t, 1, loaded.Size())
+}
+
+func TestResponseCachePersistDiscardsExpired(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "responses.json")
+	ctx := t.Context()
+
+	rc := NewPersistentResponseCache(true, time.Hour, 10, path)
+	rc.Set(ctx, cacheMessages("fresh"), "model", cacheResponse("fresh"), provider.TokenUsage{})
+	rc.Set(ctx, cacheMessages("stale"), "model", cacheResponse("stale"), provider.TokenUsage{})
+	require.NoError(t, rc.Save())
+
+	// Age the stale entry on disk past its TTL.
+	var data persistedCache
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(raw, &data))
+	staleKey := rc.generateCacheKey(cacheMessages("stale"), "model")
+	stale := data.Entries[staleKey]
+	stale.Timestamp = time.Now().Add(-2 * time.Hour)
+	data.Entries[staleKey] = stale
+	raw, err = json.Marshal(data)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, raw, 0o600))
+
+	loaded := NewPersistentResponseCache(true, time.Hour, 10, path)
+	require.Equal(t, 1, loaded.Size())
+	_, ok := loaded.Get(ctx, cacheMessages("fresh"), "model")
+	require.True(t, ok)
+}
+
+func TestResponseCachePersistIgnoresUnknownFormat(t *testing.T) {
+	t.Parallel()
+
+	for name, content := range map[string]string{
+		"corrupt":         "{not json",
+		"future version":  `{"version": 99, "entries": {"abc": {"ttl": 1}}}`,
+		"missing version": `{"entries": {}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "responses.json")
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+			rc := NewPersistentResponseCache(true, time.Hour, 10, path)
+			require.Equal(t, 0, rc.Size())
+		})
+	}
+}
+
+func TestResponseCacheClearPersists(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "responses.json")
+	ctx := t.Context()
+
+	rc := NewPersistentResponseCache(true, time.Hour, 10, path)
+	rc.Set(ctx, cacheMessages("question"), "model", cacheResponse("answer"), provider.TokenUsage{})
+	require.NoError(t, rc.Save())
+	rc.Clear()
+
+	loaded := NewPersistentResponseCache(true, time.Hour, 10, path)
+	require.Equal(t, 0, loaded.Size())
+}
+
+// fakeClock is a manually advanced clock for expiry tests
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestResponseCacheSweepsExpiredEntries(t *testing.T) {
+	t.Parallel()
+
+	clock := &fakeClock{now: time.Now()}
+	rc := NewResponseCache(true, time.Minute, 10, WithSweepInterval(5*time.Millisecond), func(rc *ResponseCache) {
+		rc.now = clock.Now
+	})
+	defer rc.Close()
+	ctx := t.Context()
+
+	rc.Set(ctx, cacheMessages("old"), "model", cacheResponse("old answer"), provider.TokenUsage{})
+	clock.Advance(45 * time.Second)
+	rc.Set(ctx, cacheMessages("new"), "model", cacheResponse("new answer"), provider.TokenUsage{})
+
+	// Nothing has expired yet, so the sweeper keeps both entries.
+	time.Sleep(20 * time.Millisecond)
+	require.Equal(t, 2, rc.Size())
+
+	clock.Advance(30 * time.Second)
+	require.Eventually(t, func() bool { return rc.Size() == 1 }, time.Second, 5*time.Millisecond)
+	_, ok := rc.Get(ctx, cacheMessages("new"), "model")
+	require.True(t, ok, "unexpired entry should survive the sweep")
+
+	clock.Advance(time.Minute)
+	require.Eventually(t, func() bool { return rc.Size() == 0 }, time.Second, 5*time.Millisecond)
+}
+
+func TestResponseCacheCloseIsIdempotent(t *testing.T) {
+	t.Parallel()
+
+	rc := NewResponseCache(true, time.Minute, 10, WithSweepInterval(time.Millisecond))
+	rc.Close()
+	rc.Close()
+
+	// Caches without a sweeper can be closed too.
+	NewResponseCache(true, time.Minute, 10).Close()
+	NewResponseCache(false, time.Minute, 10, WithSweepInterval(time.Millisecond)).Close()
+}
+
+func TestResponseCacheModelTTLOverrides(t *testing.T) {
+	t.Parallel()
+
+	clock := &fakeClock{now: time.Now()}
+	rc := NewResponseCache(true, 5*time.Minute, 10, func(rc *ResponseCache) {
+		rc.now = clock.Now
+	})
+	rc.SetTTLOverrides(map[string]time.Duration{"cheap-coder": 2 * time.Hour, "ignored": 0})
+	ctx := t.Context()
+
+	rc.Set(ctx, cacheMessages("reverse a string"), "cheap-coder", cacheResponse("use runes"), provider.TokenUsage{})
+	rc.Set(ctx, cacheMessages("latest news"), "large", cacheResponse("nothing new"), provider.TokenUsage{})
+	rc.Set(ctx, cacheMessages("latest news"), "ignored", cacheResponse("nothing new"), provider.TokenUsage{})
+
+	clock.Advance(10 * time.Minute)
+	_, ok := rc.Get(ctx, cacheMessages("latest news"), "large")
+	require.False(t, ok, "the default TTL applies to models without an override")
+	_, ok = rc.Get(ctx, cacheMessages("latest news"), "ignored")
+	require.False(t, ok, "non-positive overrides fall back to the default TTL")
+	entry, ok := rc.Get(ctx, cacheMessages("reverse a string"), "cheap-coder")
+	require.True(t, ok)
+	require.Equal(t, 2*time.Hour, entry.TTL)
+
+	require.Equal(t, map[string]string{"cheap-coder": "2h0m0s"}, rc.GetStats()["ttl_overrides"])
+}
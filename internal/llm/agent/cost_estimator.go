@@ -1,31 +1,248 @@
 package agent
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
+	"fmt"
 	"log/slog"
 	"strings"
+	"sync"
 
 	"github.com/charmbracelet/catwalk/pkg/catwalk"
 	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/llm/tools"
 	"github.com/charmbracelet/crush/internal/message"
 )
 
+// budgetWarningRatio is the fraction of the session budget at which a warning is logged
+const budgetWarningRatio = 0.8
+
+// providerOverhead describes the hidden tokens a provider adds when framing a
+// request, on top of the text of its messages and tool definitions
+type providerOverhead struct {
+	perRequest int // Priming the assistant reply
+	perMessage int // Role and message delimiters
+	toolsBase  int // Added once when any tool is offered, such as a tool use system prompt
+	perTool    int // Framing around each tool definition
+}
+
+// defaultProviderOverhead applies to providers without measured overheads
+var defaultProviderOverhead = providerOverhead{perMessage: 4, perTool: 8}
+
+// providerOverheads are approximate per-provider overheads. OpenAI frames each
+// message with about 3 tokens and renders function schemas into a namespace
+// declaration; Anthropic prepends a tool use system prompt of about 346 tokens
+// whenever tools are offered.
+var providerOverheads = map[catwalk.Type]providerOverhead{
+	catwalk.TypeOpenAI:    {perRequest: 3, perMessage: 3, toolsBase: 12, perTool: 8},
+	catwalk.TypeAzure:     {perRequest: 3, perMessage: 3, toolsBase: 12, perTool: 8},
+	catwalk.TypeAnthropic: {perMessage: 5, toolsBase: 346, perTool: 10},
+	catwalk.TypeBedrock:   {perMessage: 5, toolsBase: 346, perTool: 10},
+	catwalk.TypeGemini:    {perMessage: 4, perTool: 6},
+	catwalk.TypeVertexAI:  {perMessage: 4, perTool: 6},
+}
+
 // CostEstimator provides cost estimation for LLM requests
 type CostEstimator struct {
 	maxCostThreshold float64 // Maximum cost per request before warning
+	overhead         providerOverhead
+	summarizer       MessageSummarizer
+
+	mu            sync.Mutex
+	sessionBudget float64 // Maximum cumulative cost per session, 0 means unlimited
+	sessionCost   float64
+	avoidedCost   float64
+	budgetWarned  map[string]bool // Sessions warned about nearing the budget
+	sessionUsage  map[string]SessionUsage
+}
+
+// SessionUsage is the cumulative token usage and cost of the requests made
+// for a chat session
+type SessionUsage struct {
+	InputTokens         int64   `json:"input_tokens"`
+	OutputTokens        int64   `json:"output_tokens"`
+	CacheCreationTokens int64   `json:"cache_creation_tokens"`
+	CacheReadTokens     int64   `json:"cache_read_tokens"`
+	Cost                float64 `json:"cost"`
 }
 
 // NewCostEstimator creates a new cost estimator
 func NewCostEstimator(maxCostThreshold float64) *CostEstimator {
 	return &CostEstimator{
 		maxCostThreshold: maxCostThreshold,
+		overhead:         defaultProviderOverhead,
+		summarizer:       ExtractiveSummarizer{},
+		budgetWarned:     make(map[string]bool),
+		sessionUsage:     make(map[string]SessionUsage),
 	}
 }
 
+// SetSummarizer sets how OptimizeMessages shortens older messages
+func (ce *CostEstimator) SetSummarizer(summarizer MessageSummarizer) {
+	ce.summarizer = summarizer
+}
+
+// SetProviderType sets the type of provider requests are sent to, so
+// estimates account for how it frames messages and tool definitions
+func (ce *CostEstimator) SetProviderType(providerType catwalk.Type) {
+	ce.overhead = cmp.Or(providerOverheads[providerType], defaultProviderOverhead)
+}
+
+// SetSessionBudget sets the maximum cumulative cost for the session. A budget
+// of 0 disables the check.
+func (ce *CostEstimator) SetSessionBudget(budget float64) {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	ce.sessionBudget = budget
+	clear(ce.budgetWarned)
+}
+
+// SessionBudget returns the configured session budget
+func (ce *CostEstimator) SessionBudget() float64 {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	return ce.sessionBudget
+}
+
+// SessionCost returns the cumulative actual cost recorded for the session
+func (ce *CostEstimator) SessionCost() float64 {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	return ce.sessionCost
+}
+
+// AddActualUsage records the real token usage of a completed request and
+// returns its cost. Requests made for a chat session should be recorded with
+// RecordSessionUsage instead, so the budget warning follows that session.
+func (ce *CostEstimator) AddActualUsage(usage provider.TokenUsage, model catwalk.Model) float64 {
+	cost := ActualCost(usage, model)
+
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+
+	ce.sessionCost += cost
+	ce.warnNearBudget("", ce.sessionCost, model)
+	return cost
+}
+
+// RecordSessionUsage records the real token usage of a completed request made
+// for a chat session, adding it to both the session's usage and the
+// cumulative cost, and returns its cost
+func (ce *CostEstimator) RecordSessionUsage(sessionID string, usage provider.TokenUsage, model catwalk.Model) float64 {
+	cost := ActualCost(usage, model)
+
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+
+	ce.sessionCost += cost
+	total := ce.sessionUsage[sessionID]
+	total.InputTokens += usage.InputTokens
+	total.OutputTokens += usage.OutputTokens
+	total.CacheCreationTokens += usage.CacheCreationTokens
+	total.CacheReadTokens += usage.CacheReadTokens
+	total.Cost += cost
+	ce.sessionUsage[sessionID] = total
+	ce.warnNearBudget(sessionID, total.Cost, model)
+	return cost
+}
+
+// warnNearBudget logs a warning the first time a session's cost reaches
+// budgetWarningRatio of the budget. The caller must hold ce.mu.
+func (ce *CostEstimator) warnNearBudget(sessionID string, cost float64, model catwalk.Model) {
+	if ce.sessionBudget <= 0 || ce.budgetWarned[sessionID] || cost < ce.sessionBudget*budgetWarningRatio {
+		return
+	}
+	ce.budgetWarned[sessionID] = true
+	slog.Warn("Session cost is approaching budget",
+		"session_id", sessionID,
+		"session_cost", cost,
+		"session_budget", ce.sessionBudget,
+		"model", model.ID,
+	)
+}
+
+// SessionUsage returns the usage recorded for a chat session since the
+// estimator was created
+func (ce *CostEstimator) SessionUsage(sessionID string) SessionUsage {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	return ce.sessionUsage[sessionID]
+}
+
+// RecordCacheHit records a response served from the ResponseCache and
+// returns the cost that was avoided by not calling the model
+func (ce *CostEstimator) RecordCacheHit(entry *CacheEntry, model catwalk.Model) float64 {
+	if entry == nil {
+		return 0
+	}
+	avoided := ActualCost(entry.TokenUsage, model)
+
+	ce.mu.Lock()
+	ce.avoidedCost += avoided
+	total := ce.avoidedCost
+	ce.mu.Unlock()
+
+	slog.Info("Response cache hit avoided request cost",
+		"avoided_cost", avoided,
+		"total_avoided_cost", total,
+		"model", model.ID,
+	)
+	return avoided
+}
+
+// AvoidedCost returns the cumulative cost avoided by response cache hits
+func (ce *CostEstimator) AvoidedCost() float64 {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	return ce.avoidedCost
+}
+
+// cacheReadRate returns the per-million-token price of cached prompt reads,
+// falling back to the regular input price when the model has no cache pricing
+func cacheReadRate(model catwalk.Model) float64 {
+	if model.CostPer1MOutCached > 0 {
+		return model.CostPer1MOutCached
+	}
+	return model.CostPer1MIn
+}
+
+// ActualCost calculates the real cost of a completed request from the token
+// usage reported by the provider. Unlike EstimateRequestCost it bills the
+// output tokens actually produced, and prices cache writes and cache reads at
+// the model's cached rates.
+func ActualCost(usage provider.TokenUsage, model catwalk.Model) float64 {
+	return model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
+		model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
+		model.CostPer1MIn/1e6*float64(usage.InputTokens) +
+		model.CostPer1MOut/1e6*float64(usage.OutputTokens)
+}
+
 // EstimateRequestCost estimates the cost of a request before making it
 func (ce *CostEstimator) EstimateRequestCost(ctx context.Context, messages []message.Message, model catwalk.Model, maxTokens int) (*provider.TokenUsage, float64, error) {
+	return ce.EstimateRequestCostWithCache(ctx, messages, model, maxTokens, 0)
+}
+
+// EstimateRequestCostWithCache estimates the cost of a request where
+// cachedInputTokens of the prompt are expected to be served from the
+// provider's prompt cache and billed at the model's cache read rate
+func (ce *CostEstimator) EstimateRequestCostWithCache(ctx context.Context, messages []message.Message, model catwalk.Model, maxTokens int, cachedInputTokens int) (*provider.TokenUsage, float64, error) {
+	return ce.estimateRequestCost(messages, nil, model, maxTokens, cachedInputTokens)
+}
+
+// EstimateRequestCostWithTools estimates the cost of a request that offers
+// the given tools, whose definitions are sent as part of the prompt
+func (ce *CostEstimator) EstimateRequestCostWithTools(ctx context.Context, messages []message.Message, toolInfos []tools.ToolInfo, model catwalk.Model, maxTokens int) (*provider.TokenUsage, float64, error) {
+	return ce.estimateRequestCost(messages, toolInfos, model, maxTokens, 0)
+}
+
+func (ce *CostEstimator) estimateRequestCost(messages []message.Message, toolInfos []tools.ToolInfo, model catwalk.Model, maxTokens int, cachedInputTokens int) (*provider.TokenUsage, float64, error) {
 	// Estimate input tokens
-	inputTokens := ce.countTokensInMessages(messages)
+	inputTokens := ce.overhead.perRequest + ce.countTokensInMessages(messages, model.ID) + ce.countToolTokens(toolInfos, model.ID)
+	cachedInputTokens = min(cachedInputTokens, inputTokens)
+	if cachedInputTokens < 0 {
+		cachedInputTokens = 0
+	}
 
 	// Estimate output tokens (use maxTokens as upper bound, but use reasonable default)
 	outputTokens := maxTokens
@@ -34,16 +251,19 @@ func (ce *CostEstimator) EstimateRequestCost(ctx context.Context, messages []mes
 	}
 
 	usage := provider.TokenUsage{
-		InputTokens:  int64(inputTokens),
-		OutputTokens: int64(outputTokens),
+		InputTokens:     int64(inputTokens - cachedInputTokens),
+		OutputTokens:    int64(outputTokens),
+		CacheReadTokens: int64(cachedInputTokens),
 	}
 
 	// Calculate cost
 	cost := model.CostPer1MIn/1e6*float64(usage.InputTokens) +
+		cacheReadRate(model)/1e6*float64(usage.CacheReadTokens) +
 		model.CostPer1MOut/1e6*float64(usage.OutputTokens)
 
 	slog.Debug("Cost estimation",
 		"input_tokens", usage.InputTokens,
+		"cached_input_tokens", usage.CacheReadTokens,
 		"output_tokens", usage.OutputTokens,
 		"estimated_cost", cost,
 		"model", model.ID,
@@ -52,32 +272,61 @@ func (ce *CostEstimator) EstimateRequestCost(ctx context.Context, messages []mes
 	return &usage, cost, nil
 }
 
-// ShouldProceed checks if the request should proceed based on cost
+// ShouldProceed checks if the request should proceed based on its estimated
+// cost and the cumulative session cost
 func (ce *CostEstimator) ShouldProceed(estimatedCost float64) (bool, string) {
+	ce.mu.Lock()
+	spent := ce.sessionCost
+	ce.mu.Unlock()
+	return ce.ShouldProceedAfter(spent, estimatedCost)
+}
+
+// ShouldProceedAfter is like ShouldProceed but holds the budget against the
+// given spend, such as the stored cost of the chat session a request is for
+func (ce *CostEstimator) ShouldProceedAfter(spent, estimatedCost float64) (bool, string) {
 	if estimatedCost > ce.maxCostThreshold {
 		return false, "Estimated cost exceeds threshold"
 	}
+
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+
+	if ce.sessionBudget > 0 {
+		projected := spent + estimatedCost
+		if projected > ce.sessionBudget {
+			return false, fmt.Sprintf("Session budget exceeded: $%.4f spent, $%.4f estimated, $%.4f budget",
+				spent, estimatedCost, ce.sessionBudget)
+		}
+		if projected >= ce.sessionBudget*budgetWarningRatio {
+			slog.Warn("Request will bring session cost close to budget",
+				"session_cost", spent,
+				"estimated_cost", estimatedCost,
+				"session_budget", ce.sessionBudget,
+			)
+		}
+	}
+
 	return true, ""
 }
 
-// countTokensInMessages provides a rough token count estimate
-// This is a simplified implementation - real token counting would use the model's tokenizer
-func (ce *CostEstimator) countTokensInMessages(messages []message.Message) int {
+// countTokensInMessages counts the tokens in messages using the model's
+// tokenizer when it is known
+func (ce *CostEstimator) countTokensInMessages(messages []message.Message, modelID string) int {
 	totalTokens := 0
 
 	for _, msg := range messages {
 		// Add base tokens for role and structure
-		totalTokens += 4
+		totalTokens += ce.overhead.perMessage
 
 		for _, part := range msg.Parts {
 			switch p := part.(type) {
 			case message.TextContent:
-				totalTokens += ce.estimateTextTokens(p.Text)
+				totalTokens += ce.CountTokens(p.Text, modelID)
 			case message.ToolCall:
-				totalTokens += ce.estimateTextTokens(p.Name)
-				totalTokens += ce.estimateTextTokens(p.Input)
+				totalTokens += ce.CountTokens(p.Name, modelID)
+				totalTokens += ce.CountTokens(p.Input, modelID)
 			case message.ToolResult:
-				totalTokens += ce.estimateTextTokens(p.Content)
+				totalTokens += ce.CountTokens(p.Content, modelID)
 			}
 		}
 	}
@@ -85,6 +334,42 @@ func (ce *CostEstimator) countTokensInMessages(messages []message.Message) int {
 	return totalTokens
 }
 
+// countToolTokens counts the tokens taken by tool definitions: their names,
+// descriptions and parameter schemas plus the provider's framing
+func (ce *CostEstimator) countToolTokens(toolInfos []tools.ToolInfo, modelID string) int {
+	if len(toolInfos) == 0 {
+		return 0
+	}
+
+	totalTokens := ce.overhead.toolsBase
+	for _, info := range toolInfos {
+		totalTokens += ce.overhead.perTool
+		totalTokens += ce.CountTokens(info.Name, modelID)
+		totalTokens += ce.CountTokens(info.Description, modelID)
+
+		schema := map[string]any{"type": "object", "properties": info.Parameters}
+		if len(info.Required) > 0 {
+			schema["required"] = info.Required
+		}
+		if encoded, err := json.Marshal(schema); err == nil {
+			totalTokens += ce.CountTokens(string(encoded), modelID)
+		}
+	}
+	return totalTokens
+}
+
+// CountTokens counts the tokens in text using the BPE tokenizer for modelID.
+// Models without a known tokenizer fall back to a heuristic estimate.
+func (ce *CostEstimator) CountTokens(text, modelID string) int {
+	if text == "" {
+		return 0
+	}
+	if enc := encoderForModel(modelID); enc != nil {
+		return len(enc.EncodeOrdinary(text))
+	}
+	return ce.estimateTextTokens(text)
+}
+
 // estimateTextTokens provides a rough estimate of tokens in text
 func (ce *CostEstimator) estimateTextTokens(text string) int {
 	// Rough approximation: 1 token per 4 characters for English text
@@ -106,7 +391,7 @@ func (ce *CostEstimator) OptimizeMessages(ctx context.Context, messages []messag
 	}
 
 	optimized := make([]message.Message, 0, len(messages))
-	currentSize := ce.countTokensInMessages(messages)
+	currentSize := ce.countTokensInMessages(messages, "")
 	targetSize := int(float64(currentSize) * (1 - targetReduction))
 
 	slog.Debug("Optimizing messages",
@@ -126,14 +411,14 @@ func (ce *CostEstimator) OptimizeMessages(ctx context.Context, messages []messag
 		}
 
 		// For older messages, check if we need to truncate
-		if ce.countTokensInMessages(optimized) < targetSize {
+		if ce.countTokensInMessages(optimized, "") < targetSize {
 			// Try to summarize or truncate this message
 			summarized := ce.summarizeMessage(msg)
 			optimized = append(optimized, summarized)
 		}
 	}
 
-	finalSize := ce.countTokensInMessages(optimized)
+	finalSize := ce.countTokensInMessages(optimized, "")
 	slog.Debug("Message optimization complete",
 		"original_tokens", currentSize,
 		"final_tokens", finalSize,
@@ -151,10 +436,10 @@ func (ce *CostEstimator) summarizeMessage(msg message.Message) message.Message {
 	for _, part := range msg.Parts {
 		switch p := part.(type) {
 		case message.TextContent:
-			// Truncate long text content
+			// Shorten long text content
 			content := p.Text
 			if len(content) > 500 {
-				content = content[:400] + "... [truncated]"
+				content = ce.summarizer.Summarize(content, 400)
 			}
 			summarized.Parts = append(summarized.Parts, message.TextContent{Text: content})
 		case message.ToolCall:
@@ -164,7 +449,7 @@ func (ce *CostEstimator) summarizeMessage(msg message.Message) message.Message {
 			// Summarize tool results if they're long
 			content := p.Content
 			if len(content) > 1000 {
-				content = content[:800] + "... [result truncated]"
+				content = ce.summarizer.Summarize(content, 800)
 			}
 			summarized.Parts = append(summarized.Parts, message.ToolResult{
 				ToolCallID: p.ToolCallID,
@@ -0,0 +1,371 @@
+package agent
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodingForModel(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"gpt-4o":               "o200k_base",
+		"gpt-4o-mini":          "o200k_base",
+		"openai/gpt-4.1":       "o200k_base",
+		"o3-mini":              "o200k_base",
+		"gpt-5":                "o200k_base",
+		"gpt-4":                "cl100k_base",
+		"gpt-4-turbo":          "cl100k_base",
+		"gpt-3.5-turbo":        "cl100k_base",
+		"claude-sonnet-4":      "",
+		"gemini-2.5-pro":       "",
+		"":                     "",
+		"anthropic/claude-3.5": "",
+	}
+	for modelID, expected := range tests {
+		require.Equal(t, expected, encodingForModel(modelID), modelID)
+	}
+}
+
+func TestCountTokens(t *testing.T) {
+	t.Parallel()
+
+	ce := NewCostEstimator(1)
+
+	// Known cl100k_base token counts.
+	tests := []struct {
+		text     string
+		expected int
+	}{
+		{"hello world", 2},
+		{"tiktoken is great!", 6},
+		{"The quick brown fox jumps over the lazy dog.", 10},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, ce.CountTokens(tt.text, "gpt-4"), tt.text)
+	}
+
+	require.Equal(t, 2, ce.CountTokens("hello world", "gpt-4o"))
+}
+
+func TestCountTokensFallsBackToHeuristic(t *testing.T) {
+	t.Parallel()
+
+	ce := NewCostEstimator(1)
+	text := "The quick brown fox jumps over the lazy dog."
+	require.Equal(t, ce.estimateTextTokens(text), ce.CountTokens(text, "claude-sonnet-4"))
+}
+
+func TestSessionCostTracking(t *testing.T) {
+	t.Parallel()
+
+	model := catwalk.Model{
+		ID:           "test-model",
+		CostPer1MIn:  3,
+		CostPer1MOut: 15,
+	}
+	usage := provider.TokenUsage{InputTokens: 100_000, OutputTokens: 10_000}
+
+	ce := NewCostEstimator(1)
+	ce.SetSessionBudget(2)
+	require.Zero(t, ce.SessionCost())
+
+	for range 4 {
+		require.InDelta(t, 0.45, ce.AddActualUsage(usage, model), 1e-9)
+	}
+	require.InDelta(t, 1.8, ce.SessionCost(), 1e-9)
+
+	ok, reason := ce.ShouldProceed(0.1)
+	require.True(t, ok, reason)
+
+	ok, reason = ce.ShouldProceed(0.3)
+	require.False(t, ok)
+	require.Contains(t, reason, "Session budget exceeded")
+
+	// The per-request threshold is still enforced independently.
+	ok, _ = ce.ShouldProceed(1.5)
+	require.False(t, ok)
+}
+
+func TestSessionCostConcurrent(t *testing.T) {
+	t.Parallel()
+
+	model := catwalk.Model{CostPer1MIn: 1}
+	usage := provider.TokenUsage{InputTokens: 1_000}
+
+	ce := NewCostEstimator(1)
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ce.AddActualUsage(usage, model)
+		}()
+	}
+	wg.Wait()
+
+	require.InDelta(t, 0.05, ce.SessionCost(), 1e-9)
+}
+
+func TestShouldProceedWithoutBudget(t *testing.T) {
+	t.Parallel()
+
+	ce := NewCostEstimator(1)
+	ce.AddActualUsage(provider.TokenUsage{InputTokens: 10_000_000}, catwalk.Model{CostPer1MIn: 10})
+
+	ok, reason := ce.ShouldProceed(0.5)
+	require.True(t, ok, reason)
+}
+
+func TestEstimateRequestCostWithCache(t *testing.T) {
+	t.Parallel()
+
+	model := catwalk.Model{
+		ID:                 "claude-sonnet-4",
+		CostPer1MIn:        3,
+		CostPer1MOut:       15,
+		CostPer1MInCached:  3.75,
+		CostPer1MOutCached: 0.3,
+	}
+	messages := []message.Message{{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: strings.Repeat("word ", 1000)}},
+	}}
+
+	ce := NewCostEstimator(10)
+	fullUsage, fullCost, err := ce.EstimateRequestCost(t.Context(), messages, model, 100)
+	require.NoError(t, err)
+	inputTokens := fullUsage.InputTokens
+	require.InDelta(t, (3*float64(inputTokens)+15*100)/1e6, fullCost, 1e-12)
+
+	cached := int(inputTokens / 2)
+	usage, cost, err := ce.EstimateRequestCostWithCache(t.Context(), messages, model, 100, cached)
+	require.NoError(t, err)
+	require.Equal(t, int64(cached), usage.CacheReadTokens)
+	require.Equal(t, inputTokens-int64(cached), usage.InputTokens)
+
+	expected := (3*float64(inputTokens-int64(cached)) + 0.3*float64(cached) + 15*100) / 1e6
+	require.InDelta(t, expected, cost, 1e-12)
+	require.Less(t, cost, fullCost)
+
+	// Cached tokens are capped at the prompt size.
+	usage, _, err = ce.EstimateRequestCostWithCache(t.Context(), messages, model, 100, int(inputTokens)*10)
+	require.NoError(t, err)
+	require.Zero(t, usage.InputTokens)
+	require.Equal(t, inputTokens, usage.CacheReadTokens)
+
+	// Without cache pricing, cached tokens are billed at the input rate.
+	model.CostPer1MOutCached = 0
+	_, cost, err = ce.EstimateRequestCostWithCache(t.Context(), messages, model, 100, cached)
+	require.NoError(t, err)
+	require.InDelta(t, fullCost, cost, 1e-12)
+}
+
+func TestActualCost(t *testing.T) {
+	t.Parallel()
+
+	model := catwalk.Model{
+		ID:                 "claude-sonnet-4",
+		CostPer1MIn:        3,
+		CostPer1MOut:       15,
+		CostPer1MInCached:  3.75,
+		CostPer1MOutCached: 0.3,
+	}
+	usage := provider.TokenUsage{
+		InputTokens:         200_000,
+		OutputTokens:        1_234,
+		CacheCreationTokens: 50_000,
+		CacheReadTokens:     400_000,
+	}
+
+	// 0.6 input + 0.01851 output + 0.1875 cache write + 0.12 cache read
+	require.InDelta(t, 0.92601, ActualCost(usage, model), 1e-9)
+	require.Zero(t, ActualCost(provider.TokenUsage{}, model))
+
+	// The real output is billed rather than the max_tokens upper bound.
+	messages := []message.Message{{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: "hello"}},
+	}}
+	_, estimated, err := NewCostEstimator(10).EstimateRequestCost(t.Context(), messages, model, 8_192)
+	require.NoError(t, err)
+	actual := ActualCost(provider.TokenUsage{InputTokens: 10, OutputTokens: 20}, model)
+	require.InDelta(t, (3*10+15*20)/1e6, actual, 1e-12)
+	require.Less(t, actual, estimated)
+}
+
+func TestRecordCacheHit(t *testing.T) {
+	t.Parallel()
+
+	model := catwalk.Model{CostPer1MIn: 3, CostPer1MOut: 15}
+	entry := &CacheEntry{TokenUsage: provider.TokenUsage{InputTokens: 1_000_000, OutputTokens: 100_000}}
+
+	ce := NewCostEstimator(1)
+	require.InDelta(t, 4.5, ce.RecordCacheHit(entry, model), 1e-9)
+	require.InDelta(t, 4.5, ce.RecordCacheHit(entry, model), 1e-9)
+	require.InDelta(t, 9.0, ce.AvoidedCost(), 1e-9)
+	require.Zero(t, ce.SessionCost(), "cache hits don't count as spend")
+	require.Zero(t, ce.RecordCacheHit(nil, model))
+}
+
+func TestAgentCachedResponseRecordsAvoidedCost(t *testing.T) {
+	t.Parallel()
+
+	model := catwalk.Model{ID: "model", CostPer1MIn: 3, CostPer1MOut: 15}
+	a := &agent{responseCache: NewResponseCache(true, time.Hour, 10), costEstimator: NewCostEstimator(1)}
+	a.responseCache.Set(t.Context(), cacheMessages("cached"), model.ID, cacheResponse("answer"),
+		provider.TokenUsage{InputTokens: 1_000_000, OutputTokens: 100_000})
+
+	_, found := a.cachedResponse(t.Context(), cacheMessages("missing"), model)
+	require.False(t, found)
+	require.Zero(t, a.costEstimator.AvoidedCost())
+
+	response, found := a.cachedResponse(t.Context(), cacheMessages("cached"), model)
+	require.True(t, found)
+	require.Equal(t, "answer", response.Content().Text)
+	require.InDelta(t, 4.5, a.costEstimator.AvoidedCost(), 1e-9)
+}
+
+func TestEstimateRequestCostWithTools(t *testing.T) {
+	t.Parallel()
+
+	model := catwalk.Model{ID: "gpt-4o", CostPer1MIn: 2.5, CostPer1MOut: 10}
+	messages := []message.Message{{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: "List the files in this directory"}},
+	}}
+	toolInfos := []tools.ToolInfo{
+		{
+			Name:        "ls",
+			Description: "List files and directories in a path",
+			Parameters: map[string]any{
+				"path": map[string]any{"type": "string", "description": "The directory to list"},
+			},
+			Required: []string{"path"},
+		},
+		{
+			Name:        "view",
+			Description: "Read the contents of a file, optionally a range of lines",
+			Parameters: map[string]any{
+				"file_path": map[string]any{"type": "string", "description": "The file to read"},
+				"offset":    map[string]any{"type": "integer", "description": "Line to start from"},
+			},
+			Required: []string{"file_path"},
+		},
+	}
+
+	ce := NewCostEstimator(10)
+	ce.SetProviderType(catwalk.TypeOpenAI)
+
+	without, withoutCost, err := ce.EstimateRequestCostWithTools(t.Context(), messages, nil, model, 100)
+	require.NoError(t, err)
+	with, withCost, err := ce.EstimateRequestCostWithTools(t.Context(), messages, toolInfos, model, 100)
+	require.NoError(t, err)
+
+	toolTokens := ce.countToolTokens(toolInfos, model.ID)
+	require.Greater(t, toolTokens, 2*providerOverheads[catwalk.TypeOpenAI].perTool)
+	require.Equal(t, without.InputTokens+int64(toolTokens), with.InputTokens)
+	require.Greater(t, withCost, withoutCost)
+
+	// Without tools the estimate matches a plain estimate
+	plain, _, err := ce.EstimateRequestCost(t.Context(), messages, model, 100)
+	require.NoError(t, err)
+	require.Equal(t, without.InputTokens, plain.InputTokens)
+}
+
+func TestProviderOverhead(t *testing.T) {
+	t.Parallel()
+
+	model := catwalk.Model{ID: "claude-sonnet-4", CostPer1MIn: 3, CostPer1MOut: 15}
+	messages := []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "hi"}}},
+		{Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: "hello"}}},
+	}
+	toolInfos := []tools.ToolInfo{{Name: "ls", Description: "List files"}}
+
+	inputTokens := func(providerType catwalk.Type, toolInfos []tools.ToolInfo) int64 {
+		ce := NewCostEstimator(10)
+		ce.SetProviderType(providerType)
+		usage, _, err := ce.EstimateRequestCostWithTools(t.Context(), messages, toolInfos, model, 100)
+		require.NoError(t, err)
+		return usage.InputTokens
+	}
+
+	// Unknown providers keep the flat framing of 4 tokens per message
+	custom := NewCostEstimator(10)
+	custom.SetProviderType("custom")
+	require.Equal(t, defaultProviderOverhead, custom.overhead)
+	require.Equal(t, 4, custom.overhead.perMessage)
+	require.Equal(t, inputTokens(catwalk.TypeGemini, nil), inputTokens("custom", nil))
+
+	// Anthropic's tool use system prompt dominates small tool sets
+	anthropicTools := inputTokens(catwalk.TypeAnthropic, toolInfos) - inputTokens(catwalk.TypeAnthropic, nil)
+	openaiTools := inputTokens(catwalk.TypeOpenAI, toolInfos) - inputTokens(catwalk.TypeOpenAI, nil)
+	require.GreaterOrEqual(t, anthropicTools, int64(346))
+	require.Less(t, openaiTools, anthropicTools)
+	require.Greater(t, openaiTools, int64(0))
+}
+
+func TestRecordSessionUsage(t *testing.T) {
+	t.Parallel()
+
+	model := catwalk.Model{CostPer1MIn: 3, CostPer1MOut: 15}
+	usage := provider.TokenUsage{InputTokens: 100_000, OutputTokens: 10_000, CacheReadTokens: 5_000}
+
+	ce := NewCostEstimator(1)
+	ce.RecordSessionUsage("a", usage, model)
+	ce.RecordSessionUsage("a", usage, model)
+	ce.RecordSessionUsage("b", usage, model)
+
+	require.Equal(t, int64(200_000), ce.SessionUsage("a").InputTokens)
+	require.Equal(t, int64(20_000), ce.SessionUsage("a").OutputTokens)
+	require.Equal(t, int64(10_000), ce.SessionUsage("a").CacheReadTokens)
+	require.InDelta(t, 0.9, ce.SessionUsage("a").Cost, 1e-9)
+	require.InDelta(t, 0.45, ce.SessionUsage("b").Cost, 1e-9)
+	require.Zero(t, ce.SessionUsage("missing"))
+	require.InDelta(t, 1.35, ce.SessionCost(), 1e-9)
+}
+
+func TestBudgetWarningIsPerSession(t *testing.T) {
+	t.Parallel()
+
+	model := catwalk.Model{CostPer1MIn: 1}
+	ce := NewCostEstimator(10)
+	ce.SetSessionBudget(1)
+
+	// Together the sessions pass 80% of the budget, but neither does alone
+	ce.RecordSessionUsage("a", provider.TokenUsage{InputTokens: 500_000}, model)
+	ce.RecordSessionUsage("b", provider.TokenUsage{InputTokens: 500_000}, model)
+	require.Empty(t, ce.budgetWarned)
+
+	ce.RecordSessionUsage("a", provider.TokenUsage{InputTokens: 400_000}, model)
+	require.Equal(t, map[string]bool{"a": true}, ce.budgetWarned)
+}
+
+func TestShouldProceedAfter(t *testing.T) {
+	t.Parallel()
+
+	ce := NewCostEstimator(1)
+	ce.SetSessionBudget(2)
+
+	ok, reason := ce.ShouldProceedAfter(1.5, 0.4)
+	require.True(t, ok, reason)
+
+	ok, reason = ce.ShouldProceedAfter(1.8, 0.4)
+	require.False(t, ok)
+	require.Contains(t, reason, "$1.8000 spent")
+
+	// The spend of other sessions doesn't count against this one
+	ce.AddActualUsage(provider.TokenUsage{InputTokens: 1_000_000}, catwalk.Model{CostPer1MIn: 5})
+	ok, reason = ce.ShouldProceedAfter(0, 0.4)
+	require.True(t, ok, reason)
+}
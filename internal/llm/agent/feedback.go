@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
@@ -26,18 +28,93 @@ type FeedbackMechanism struct {
 	minQualityThreshold float64
 	maxRetryAttempts    int
 	enabled             bool
+
+	// Weights maps metric names to their share of the overall score. The
+	// weights are normalized to sum to 1.
+	Weights map[string]float64
+
+	// sink optionally records every evaluation
+	sink QualitySink
+
+	// evaluator scores responses in place of the heuristic metrics when set
+	evaluator Evaluator
+
+	// workingDir enables checking file paths cited in responses when set
+	workingDir string
+}
+
+// SetSink configures where evaluations are recorded; nil disables recording
+func (fm *FeedbackMechanism) SetSink(sink QualitySink) {
+	fm.sink = sink
 }
 
-// NewFeedbackMechanism creates a new feedback mechanism
-func NewFeedbackMechanism(enabled bool, minQualityThreshold float64, maxRetryAttempts int) *FeedbackMechanism {
+// defaultFeedbackWeights returns the metric weights used when none are configured
+func defaultFeedbackWeights() map[string]float64 {
+	return map[string]float64{
+		"completeness":     0.3,
+		"clarity":          0.2,
+		"relevance":        0.25,
+		"specificity":      0.15,
+		"error_indicators": 0.1,
+	}
+}
+
+// NewFeedbackMechanism creates a new feedback mechanism. Custom weights
+// override the default metric weights; pass nil to use the defaults.
+func NewFeedbackMechanism(enabled bool, minQualityThreshold float64, maxRetryAttempts int, weights map[string]float64) *FeedbackMechanism {
 	return &FeedbackMechanism{
 		minQualityThreshold: minQualityThreshold,
 		maxRetryAttempts:    maxRetryAttempts,
 		enabled:             enabled,
+		Weights:             normalizeWeights(weights),
+	}
+}
+
+// validateFeedbackWeights reports an error for weights keyed by a metric the
+// feedback mechanism doesn't compute, such as a misspelled metric name
+func validateFeedbackWeights(weights map[string]float64) error {
+	known := defaultFeedbackWeights()
+	for _, metric := range slices.Sorted(maps.Keys(weights)) {
+		if _, ok := known[metric]; !ok {
+			return fmt.Errorf("unknown feedback weight metric %q, expected one of: %s",
+				metric, strings.Join(slices.Sorted(maps.Keys(known)), ", "))
+		}
 	}
+	return nil
 }
 
-// EvaluateResponse analyzes the quality of a response
+// normalizeWeights scales weights so they sum to 1, ignoring negative values
+// and unknown metrics. It falls back to the default weights when no positive
+// weight is given for a known metric.
+func normalizeWeights(weights map[string]float64) map[string]float64 {
+	known := defaultFeedbackWeights()
+	total := 0.0
+	for metric, weight := range weights {
+		if _, ok := known[metric]; !ok {
+			slog.Warn("Ignoring unknown feedback weight", "metric", metric, "weight", weight)
+			continue
+		}
+		if weight < 0 {
+			slog.Warn("Ignoring negative feedback weight", "metric", metric, "weight", weight)
+			continue
+		}
+		total += weight
+	}
+	if total == 0 {
+		return known
+	}
+
+	normalized := make(map[string]float64, len(weights))
+	for metric, weight := range weights {
+		if _, ok := known[metric]; ok && weight > 0 {
+			normalized[metric] = weight / total
+		}
+	}
+	return normalized
+}
+
+// EvaluateResponse analyzes the quality of a response with the configured
+// evaluator, falling back to the heuristic metrics if it fails
 func (fm *FeedbackMechanism) EvaluateResponse(ctx context.Context, userMessage message.Message, response message.Message) *ResponseQuality {
 	if !fm.enabled {
 		return &ResponseQuality{
@@ -48,6 +125,47 @@ func (fm *FeedbackMechanism) EvaluateResponse(ctx context.Context, userMessage m
 		}
 	}
 
+	var quality *ResponseQuality
+	if fm.evaluator != nil {
+		var err error
+		quality, err = fm.evaluator.Evaluate(ctx, userMessage, response)
+		if err != nil {
+			slog.Warn("Response evaluator failed, using heuristic metrics", "error", err)
+			quality = nil
+		}
+	}
+	if quality == nil {
+		quality = fm.heuristicQuality(userMessage, response)
+	}
+
+	// Determine if retry is needed
+	quality.RequiresRetry = quality.Score < fm.minQualityThreshold
+
+	slog.Debug("Response quality evaluation",
+		"score", quality.Score,
+		"confidence", quality.Confidence,
+		"requires_retry", quality.RequiresRetry,
+		"issues_count", len(quality.Issues),
+	)
+
+	if fm.sink != nil {
+		if err := fm.sink.Record(ctx, userMessage, response, quality); err != nil {
+			slog.Warn("Failed to record response quality", "error", err)
+		}
+	}
+
+	return quality
+}
+
+// Evaluate scores a response with the heuristic text metrics, making the
+// feedback mechanism the default Evaluator. Unlike EvaluateResponse it
+// doesn't decide whether to retry or record the evaluation.
+func (fm *FeedbackMechanism) Evaluate(ctx context.Context, userMessage message.Message, response message.Message) (*ResponseQuality, error) {
+	return fm.heuristicQuality(userMessage, response), nil
+}
+
+// heuristicQuality scores a response from its text alone
+func (fm *FeedbackMechanism) heuristicQuality(userMessage message.Message, response message.Message) *ResponseQuality {
 	quality := &ResponseQuality{
 		Issues:      []string{},
 		Suggestions: []string{},
@@ -66,23 +184,35 @@ func (fm *FeedbackMechanism) EvaluateResponse(ctx context.Context, userMessage m
 	quality.Metrics["specificity"] = fm.calculateSpecificity(responseText)
 	quality.Metrics["error_indicators"] = fm.detectErrorIndicators(responseText)
 
+	// Citing files that don't exist is a strong sign of hallucination
+	var missingPaths []string
+	if fm.workingDir != "" {
+		missingPaths = missingPathReferences(fm.workingDir, responseText)
+		if len(missingPaths) > 0 {
+			quality.Metrics["error_indicators"] = max(0.0, quality.Metrics["error_indicators"]-missingPathPenalty*float64(len(missingPaths)))
+		}
+	}
+
+	// Evaluate code separately, since the prose metrics misjudge code-heavy answers
+	blocks, codeRatio := extractCodeBlocks(responseText)
+	var code codeAnalysis
+	if len(blocks) > 0 {
+		code = fm.analyzeCode(userText, blocks)
+		quality.Metrics["code_quality"] = code.score
+	}
+
 	// Calculate overall quality score
-	quality.Score = fm.calculateOverallScore(quality.Metrics)
+	quality.Score = fm.calculateOverallScore(quality.Metrics, codeRatio)
 	quality.Confidence = fm.calculateConfidence(quality.Metrics, responseText)
 
 	// Check for specific issues
 	fm.analyzeIssues(quality, userText, responseText)
-
-	// Determine if retry is needed
-	quality.RequiresRetry = quality.Score < fm.minQualityThreshold
-
-	slog.Debug("Response quality evaluation",
-		"score", quality.Score,
-		"confidence", quality.Confidence,
-		"requires_retry", quality.RequiresRetry,
-		"issues_count", len(quality.Issues),
-	)
-
+	quality.Issues = append(quality.Issues, code.issues...)
+	quality.Suggestions = append(quality.Suggestions, code.suggestions...)
+	if len(missingPaths) > 0 {
+		quality.Issues = append(quality.Issues, "Response references files that don't exist: "+strings.Join(missingPaths, ", "))
+		quality.Suggestions = append(quality.Suggestions, "Check file paths against the project before citing them")
+	}
 	return quality
 }
 
@@ -107,13 +237,13 @@ func (fm *FeedbackMechanism) calculateCompleteness(userText, responseText string
 	userWords := len(strings.Fields(userText))
 	responseWords := len(strings.Fields(responseText))
 
-	// Heuristic: response should be proportional to request complexity
+	// Heuristic: response should be proportional to request complexity,
+	// except for questions where a short answer is the complete one
 	if userWords > 0 {
-		ratio := float64(responseWords) / float64(userWords)
-		if ratio < 0.5 {
-			return 0.3 // Too short
+		if expected := expectedResponseWords(userText, userWords); responseWords < expected {
+			return lengthCompleteness(responseWords, expected) // Too short
 		}
-		if ratio > 10 {
+		if float64(responseWords)/float64(userWords) > 10 {
 			return 0.7 // Might be too verbose
 		}
 	}
@@ -208,6 +338,11 @@ func (fm *FeedbackMechanism) calculateRelevance(userText, responseText string) f
 	}
 
 	relevanceScore := float64(overlap) / float64(len(userWordSet))
+
+	// A brief answer such as "4" or "Yes" rarely repeats the question's words
+	if len(responseWords) <= maxBriefAnswerWords && expectsBriefAnswer(userText) {
+		relevanceScore = maxFloat64(relevanceScore, briefAnswerRelevance)
+	}
 	return minFloat64(relevanceScore, 1.0)
 }
 
@@ -287,22 +422,25 @@ func (fm *FeedbackMechanism) detectErrorIndicators(responseText string) float64
 	return max(0.0, 1.0-float64(errorCount)*0.2)
 }
 
-// calculateOverallScore combines individual metrics into an overall quality score
-func (fm *FeedbackMechanism) calculateOverallScore(metrics map[string]float64) float64 {
-	weights := map[string]float64{
-		"completeness":     0.3,
-		"clarity":          0.2,
-		"relevance":        0.25,
-		"specificity":      0.15,
-		"error_indicators": 0.1,
+// calculateOverallScore combines individual metrics into an overall quality score.
+// When the response contains code, the prose metrics are down-weighted in
+// proportion to codeRatio, the share of the response that is code.
+func (fm *FeedbackMechanism) calculateOverallScore(metrics map[string]float64, codeRatio float64) float64 {
+	weights := fm.Weights
+	if len(weights) == 0 {
+		weights = defaultFeedbackWeights()
+	}
+	if _, hasCode := metrics["code_quality"]; hasCode {
+		weights = codeAwareWeights(weights, codeRatio)
 	}
 
 	totalScore := 0.0
 	totalWeight := 0.0
 
-	for metric, score := range metrics {
+	// Sum in a fixed order so identical metrics always produce identical scores
+	for _, metric := range slices.Sorted(maps.Keys(metrics)) {
 		if weight, exists := weights[metric]; exists {
-			totalScore += score * weight
+			totalScore += metrics[metric] * weight
 			totalWeight += weight
 		}
 	}
@@ -314,6 +452,26 @@ func (fm *FeedbackMechanism) calculateOverallScore(metrics map[string]float64) f
 	return totalScore / totalWeight
 }
 
+// codeAwareWeights scales down the prose metric weights for a response whose
+// codeRatio share is code and adds a weight for the code_quality metric
+func codeAwareWeights(weights map[string]float64, codeRatio float64) map[string]float64 {
+	adjusted := make(map[string]float64, len(weights)+1)
+	for metric, weight := range weights {
+		adjusted[metric] = weight
+	}
+
+	proseScale := 1 - codeRatio*proseDownweight
+	for _, metric := range proseMetrics {
+		if weight, ok := adjusted[metric]; ok {
+			adjusted[metric] = weight * proseScale
+		}
+	}
+	if _, ok := adjusted["code_quality"]; !ok {
+		adjusted["code_quality"] = codeQualityWeight
+	}
+	return adjusted
+}
+
 // calculateConfidence estimates confidence in the quality assessment
 func (fm *FeedbackMechanism) calculateConfidence(metrics map[string]float64, responseText string) float64 {
 	// Base confidence depends on response length
@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	// maxBriefQuestionWords bounds how long a question expecting a brief
+	// answer can be
+	maxBriefQuestionWords = 15
+	// maxBriefAnswerWords bounds how long an answer can be and still count
+	// as brief
+	maxBriefAnswerWords = 25
+	// briefAnswerRelevance is the relevance given to a brief answer to a
+	// brief question, which usually shares no keywords with it
+	briefAnswerRelevance = 0.7
+	// maxExpectedWords caps how many words any response is expected to have,
+	// so long requests don't demand answers of proportional length
+	maxExpectedWords = 40
+)
+
+var (
+	// arithmeticPattern matches arithmetic such as "2+2" or "12 * 3"
+	arithmeticPattern = regexp.MustCompile(`\d\s*[-+*/x×÷^%]\s*\d`)
+
+	// yesNoStarters begin questions answered by yes or no
+	yesNoStarters = []string{
+		"is", "are", "was", "were", "am", "do", "does", "did", "can", "could",
+		"will", "would", "should", "shall", "has", "have", "had", "may", "must",
+	}
+
+	// factStarters begin questions asking for a single fact
+	factStarters = []string{
+		"what is", "what's", "what are", "who", "whom", "whose", "when", "where",
+		"which", "how many", "how much", "how old", "how long", "how far",
+	}
+
+	// elaborationWords ask for more than a brief answer even in a short question
+	elaborationWords = []string{
+		"why", "explain", "describe", "how do", "how does", "how to", "how can",
+		"compare", "difference", "write", "implement", "list", "show me",
+		"walk me", "example", "steps", "pros and cons",
+	}
+)
+
+// expectsBriefAnswer reports whether a request is a yes/no question, an
+// arithmetic question or a single-fact lookup, where a one word or one
+// sentence answer is complete
+func expectsBriefAnswer(userText string) bool {
+	text := strings.ToLower(strings.TrimSpace(userText))
+	words := strings.Fields(text)
+	if len(words) == 0 || len(words) > maxBriefQuestionWords || strings.Contains(text, "```") {
+		return false
+	}
+	for _, word := range elaborationWords {
+		if strings.Contains(text, word) {
+			return false
+		}
+	}
+
+	if arithmeticPattern.MatchString(text) {
+		return true
+	}
+	if !strings.HasSuffix(text, "?") {
+		return false
+	}
+	for _, starter := range yesNoStarters {
+		if words[0] == starter {
+			return true
+		}
+	}
+	for _, starter := range factStarters {
+		if text == starter || strings.HasPrefix(text, starter+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// expectedResponseWords returns the fewest words a complete response to a
+// request of userWords words is expected to have. Brief questions need a
+// single word; otherwise the expectation grows with the request, at half its
+// length, up to maxExpectedWords.
+func expectedResponseWords(userText string, userWords int) int {
+	if expectsBriefAnswer(userText) {
+		return 1
+	}
+	return min(userWords/2+userWords%2, maxExpectedWords)
+}
+
+// lengthCompleteness scores how complete a response is from its length
+// alone. Responses shorter than expected score between 0.3 and 0.8 in
+// proportion to their length, rather than all scoring 0.3.
+func lengthCompleteness(responseWords, expectedWords int) float64 {
+	if responseWords >= expectedWords {
+		return 0.8
+	}
+	return 0.3 + 0.5*float64(responseWords)/float64(expectedWords)
+}
@@ -0,0 +1,263 @@
+package agent
+
+import (
+	"strings"
+)
+
+const (
+	// codeQualityWeight is the weight of the code_quality metric when it is
+	// not configured explicitly
+	codeQualityWeight = 0.35
+	// proseDownweight is how much the prose metrics are scaled down for a
+	// response that consists entirely of code
+	proseDownweight = 0.75
+)
+
+// proseMetrics are the metrics tuned for natural language responses
+var proseMetrics = []string{"completeness", "clarity", "relevance", "specificity"}
+
+// codeLanguage describes how a language is referred to in a request and
+// tagged on a fenced code block
+type codeLanguage struct {
+	name     string
+	mentions []string // words or phrases identifying the language in a request
+	fences   []string // info strings used on fenced code blocks
+}
+
+var codeLanguages = []codeLanguage{
+	{name: "go", mentions: []string{"golang", "in go", "go code", "go function", "go program"}, fences: []string{"go", "golang"}},
+	{name: "python", mentions: []string{"python"}, fences: []string{"python", "py", "python3"}},
+	{name: "javascript", mentions: []string{"javascript", "node", "nodejs"}, fences: []string{"javascript", "js", "jsx", "node"}},
+	{name: "typescript", mentions: []string{"typescript"}, fences: []string{"typescript", "ts", "tsx"}},
+	{name: "rust", mentions: []string{"rust"}, fences: []string{"rust", "rs"}},
+	{name: "java", mentions: []string{"java"}, fences: []string{"java"}},
+	{name: "ruby", mentions: []string{"ruby"}, fences: []string{"ruby", "rb"}},
+	{name: "php", mentions: []string{"php"}, fences: []string{"php"}},
+	{name: "csharp", mentions: []string{"c#", "csharp"}, fences: []string{"csharp", "cs", "c#"}},
+	{name: "kotlin", mentions: []string{"kotlin"}, fences: []string{"kotlin", "kt"}},
+	{name: "shell", mentions: []string{"bash", "shell"}, fences: []string{"bash", "sh", "shell", "zsh"}},
+	{name: "sql", mentions: []string{"sql"}, fences: []string{"sql"}},
+}
+
+// codePlaceholders are markers of code that was left unfinished
+var codePlaceholders = []string{
+	"// todo",
+	"# todo",
+	"todo:",
+	"your code here",
+	"implement this",
+	"implement me",
+}
+
+// codeBlock is a fenced code block found in a response
+type codeBlock struct {
+	language string
+	body     string
+}
+
+// codeAnalysis holds the result of evaluating the code in a response
+type codeAnalysis struct {
+	score       float64
+	issues      []string
+	suggestions []string
+}
+
+// extractCodeBlocks returns the fenced code blocks in text along with the
+// share of the text's characters that belong to them
+func extractCodeBlocks(text string) ([]codeBlock, float64) {
+	var blocks []codeBlock
+	var current *codeBlock
+	var body []string
+	codeChars := 0
+
+	for line := range strings.SplitSeq(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			if current == nil {
+				info := strings.Fields(strings.TrimPrefix(trimmed, "```"))
+				current = &codeBlock{}
+				if len(info) > 0 {
+					current.language = strings.ToLower(info[0])
+				}
+				body = nil
+			} else {
+				current.body = strings.Join(body, "\n")
+				codeChars += len(current.body)
+				blocks = append(blocks, *current)
+				current = nil
+			}
+			continue
+		}
+		if current != nil {
+			body = append(body, line)
+		}
+	}
+
+	// An unterminated fence still counts as code.
+	if current != nil {
+		current.body = strings.Join(body, "\n")
+		codeChars += len(current.body)
+		blocks = append(blocks, *current)
+	}
+
+	if len(blocks) == 0 || len(text) == 0 {
+		return blocks, 0
+	}
+	return blocks, minFloat64(float64(codeChars)/float64(len(text)), 1.0)
+}
+
+// analyzeCode evaluates code-specific quality signals: whether the code is in
+// the language the user asked about, whether brackets are balanced and
+// whether placeholders were left in
+func (fm *FeedbackMechanism) analyzeCode(userText string, blocks []codeBlock) codeAnalysis {
+	analysis := codeAnalysis{score: 1.0}
+
+	if requested := requestedLanguages(userText); len(requested) > 0 {
+		tagged, matched := false, false
+		for _, block := range blocks {
+			if block.language == "" {
+				continue
+			}
+			tagged = true
+			if lang, ok := fenceLanguage(block.language); ok && requested[lang] {
+				matched = true
+			}
+		}
+		if tagged && !matched {
+			analysis.score -= 0.3
+			analysis.issues = append(analysis.issues, "Code is not in the requested language")
+			analysis.suggestions = append(analysis.suggestions, "Write the code in the language the user asked about")
+		}
+	}
+
+	for _, block := range blocks {
+		if !bracketsBalanced(block.body) {
+			analysis.score -= 0.3
+			analysis.issues = append(analysis.issues, "Code has unbalanced brackets")
+			analysis.suggestions = append(analysis.suggestions, "Make sure the code is complete and syntactically valid")
+			break
+		}
+	}
+
+	for _, block := range blocks {
+		if hasPlaceholder(block.body) {
+			analysis.score -= 0.3
+			analysis.issues = append(analysis.issues, "Code contains placeholders")
+			analysis.suggestions = append(analysis.suggestions, "Replace placeholders with a complete implementation")
+			break
+		}
+	}
+
+	analysis.score = maxFloat64(0.0, analysis.score)
+	return analysis
+}
+
+// requestedLanguages returns the languages mentioned in the user's request
+func requestedLanguages(userText string) map[string]bool {
+	lower := strings.ToLower(userText)
+	words := make(map[string]bool)
+	for _, word := range strings.Fields(lower) {
+		words[strings.Trim(word, ".,;:!?()[]{}\"'`")] = true
+	}
+
+	requested := make(map[string]bool)
+	for _, lang := range codeLanguages {
+		for _, mention := range lang.mentions {
+			if strings.Contains(mention, " ") {
+				if strings.Contains(lower, mention) {
+					requested[lang.name] = true
+				}
+			} else if words[mention] {
+				requested[lang.name] = true
+			}
+		}
+	}
+	return requested
+}
+
+// fenceLanguage maps a code fence info string to a known language
+func fenceLanguage(fence string) (string, bool) {
+	for _, lang := range codeLanguages {
+		for _, f := range lang.fences {
+			if f == fence {
+				return lang.name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// bracketsBalanced reports whether (), [] and {} are balanced in code,
+// ignoring brackets inside strings and character literals on a single line.
+// Single quotes only delimit character literals such as '(' so that Rust
+// lifetimes and apostrophes in comments do not hide brackets.
+func bracketsBalanced(code string) bool {
+	pairs := map[rune]rune{')': '(', ']': '[', '}': '{'}
+	var stack []rune
+
+	for line := range strings.SplitSeq(code, "\n") {
+		var quote rune
+		escaped := false
+		runes := []rune(line)
+		for i := 0; i < len(runes); i++ {
+			r := runes[i]
+			if r == '\'' && quote == 0 {
+				i += charLiteralLen(runes[i:]) - 1
+				continue
+			}
+			if quote != 0 {
+				switch {
+				case escaped:
+					escaped = false
+				case r == '\\':
+					escaped = true
+				case r == quote:
+					quote = 0
+				}
+				continue
+			}
+			switch r {
+			case '"', '`':
+				quote = r
+			case '(', '[', '{':
+				stack = append(stack, r)
+			case ')', ']', '}':
+				if len(stack) == 0 || stack[len(stack)-1] != pairs[r] {
+					return false
+				}
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	return len(stack) == 0
+}
+
+// charLiteralLen returns the length of the character literal at the start of
+// runes, such as 'x' or '\n', or 1 when the quote does not start one
+func charLiteralLen(runes []rune) int {
+	if len(runes) >= 3 && runes[1] != '\\' && runes[2] == '\'' {
+		return 3
+	}
+	if len(runes) >= 4 && runes[1] == '\\' && runes[3] == '\'' {
+		return 4
+	}
+	return 1
+}
+
+// hasPlaceholder reports whether code contains an obvious placeholder such as
+// a TODO comment or a line that only holds "..."
+func hasPlaceholder(code string) bool {
+	for line := range strings.SplitSeq(code, "\n") {
+		trimmed := strings.ToLower(strings.TrimSpace(line))
+		for _, placeholder := range codePlaceholders {
+			if strings.Contains(trimmed, placeholder) {
+				return true
+			}
+		}
+		switch trimmed {
+		case "...", "…", "// ...", "# ...", "/* ... */", "-- ...":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// Evaluator scores the quality of a response to a user message
+type Evaluator interface {
+	Evaluate(ctx context.Context, userMsg, response message.Message) (*ResponseQuality, error)
+}
+
+// Evaluator names accepted in the configuration
+const (
+	EvaluatorHeuristic = "heuristic"
+	EvaluatorLLM       = "llm"
+)
+
+// SetEvaluator configures how responses are scored; nil restores the
+// heuristic metrics. The mechanism still decides whether a retry is needed
+// and records evaluations.
+func (fm *FeedbackMechanism) SetEvaluator(evaluator Evaluator) {
+	fm.evaluator = evaluator
+}
+
+// maxJudgedTextLength bounds how much of each message is sent to the judge
+const maxJudgedTextLength = 8000
+
+// LLMEvaluator asks a model, usually a cheap one, to grade responses against
+// a rubric. The provider should be configured with the judge system prompt.
+type LLMEvaluator struct {
+	judge provider.Provider
+
+	// onUsage receives the token usage of each judge request
+	onUsage func(ctx context.Context, model catwalk.Model, usage provider.TokenUsage)
+}
+
+// NewLLMEvaluator creates an evaluator that sends responses to judge
+func NewLLMEvaluator(judge provider.Provider) *LLMEvaluator {
+	return &LLMEvaluator{judge: judge}
+}
+
+// SetUsageHandler configures where the token usage of judge requests is
+// reported, so their cost can be charged to the session; nil discards it
+func (e *LLMEvaluator) SetUsageHandler(onUsage func(ctx context.Context, model catwalk.Model, usage provider.TokenUsage)) {
+	e.onUsage = onUsage
+}
+
+// Evaluate sends the user message and response to the judge and parses its
+// verdict
+func (e *LLMEvaluator) Evaluate(ctx context.Context, userMsg, response message.Message) (*ResponseQuality, error) {
+	request := fmt.Sprintf("<request>\n%s\n</request>\n\n<response>\n%s\n</response>",
+		truncateJudgedText(userMsg.Content().Text), truncateJudgedText(response.Content().Text))
+	resp, err := e.judge.SendMessages(ctx, []message.Message{{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: request}},
+	}}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("judge request failed: %w", err)
+	}
+	// The request is paid for even when the verdict can't be parsed
+	if e.onUsage != nil {
+		e.onUsage(ctx, e.judge.Model(), resp.Usage)
+	}
+	return parseJudgeVerdict(resp.Content)
+}
+
+// judgeVerdict is the JSON object the judge replies with
+type judgeVerdict struct {
+	Score       *float64           `json:"score"`
+	Confidence  *float64           `json:"confidence"`
+	Metrics     map[string]float64 `json:"metrics"`
+	Issues      []string           `json:"issues"`
+	Suggestions []string           `json:"suggestions"`
+}
+
+// parseJudgeVerdict reads the verdict from the judge's reply, which may wrap
+// the JSON object in prose or a code fence. Scores are clamped to [0, 1] and
+// a missing confidence counts as 0.5.
+func parseJudgeVerdict(content string) (*ResponseQuality, error) {
+	start := strings.IndexByte(content, '{')
+	end := strings.LastIndexByte(content, '}')
+	if start < 0 || end < start {
+		return nil, errors.New("judge reply contains no JSON object")
+	}
+
+	var verdict judgeVerdict
+	if err := json.Unmarshal([]byte(content[start:end+1]), &verdict); err != nil {
+		return nil, fmt.Errorf("failed to parse judge reply: %w", err)
+	}
+	if verdict.Score == nil {
+		return nil, errors.New("judge reply has no score")
+	}
+
+	clamp := func(v float64) float64 { return maxFloat64(0, minFloat64(1, v)) }
+	quality := &ResponseQuality{
+		Score:       clamp(*verdict.Score),
+		Confidence:  0.5,
+		Issues:      append([]string{}, verdict.Issues...),
+		Suggestions: append([]string{}, verdict.Suggestions...),
+		Metrics:     make(map[string]float64, len(verdict.Metrics)),
+		Timestamp:   time.Now(),
+	}
+	if verdict.Confidence != nil {
+		quality.Confidence = clamp(*verdict.Confidence)
+	}
+	for metric, score := range verdict.Metrics {
+		quality.Metrics[metric] = clamp(score)
+	}
+	return quality, nil
+}
+
+// truncateJudgedText shortens text to maxJudgedTextLength runes
+func truncateJudgedText(text string) string {
+	runes := []rune(text)
+	if len(runes) <= maxJudgedTextLength {
+		return text
+	}
+	return string(runes[:maxJudgedTextLength]) + "\n[truncated]"
+}
@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeuristicEvaluator(t *testing.T) {
+	t.Parallel()
+
+	fm := NewFeedbackMechanism(true, 0.7, 2, nil)
+	var evaluator Evaluator = fm
+
+	user := feedbackMessage(message.User, "Write a Go function that reverses a string")
+	quality, err := evaluator.Evaluate(t.Context(), user, feedbackMessage(message.Assistant, goReverseAnswer))
+	require.NoError(t, err)
+	require.Equal(t, fm.EvaluateResponse(t.Context(), user, feedbackMessage(message.Assistant, goReverseAnswer)).Score, quality.Score)
+	require.Contains(t, quality.Metrics, "completeness")
+}
+
+func TestLLMEvaluator(t *testing.T) {
+	t.Parallel()
+
+	judge := &stubProvider{responses: []string{"Here is my grade:\n```json\n" +
+		`{"score": 0.4, "confidence": 0.9, "metrics": {"completeness": 0.2, "correctness": 1.5}, "issues": ["the loop never ends"], "suggestions": ["add a stop condition"]}` +
+		"\n```"}}
+	fm := NewFeedbackMechanism(true, 0.7, 2, nil)
+	fm.SetEvaluator(NewLLMEvaluator(judge))
+
+	user := feedbackMessage(message.User, "Write a Go function that reverses a string")
+	quality := fm.EvaluateResponse(t.Context(), user, feedbackMessage(message.Assistant, goReverseAnswer))
+	require.InDelta(t, 0.4, quality.Score, 1e-9)
+	require.InDelta(t, 0.9, quality.Confidence, 1e-9)
+	require.Equal(t, map[string]float64{"completeness": 0.2, "correctness": 1}, quality.Metrics)
+	require.Equal(t, []string{"the loop never ends"}, quality.Issues)
+	require.Equal(t, []string{"add a stop condition"}, quality.Suggestions)
+	require.True(t, quality.RequiresRetry, "the threshold still decides on retries")
+
+	require.Len(t, judge.requests, 1)
+	request := judge.requests[0][0].Content().Text
+	require.Contains(t, request, "<request>\nWrite a Go function that reverses a string\n</request>")
+	require.Contains(t, request, "func Reverse(s string) string")
+}
+
+func TestLLMEvaluatorReportsUsage(t *testing.T) {
+	t.Parallel()
+
+	user := feedbackMessage(message.User, "Write a Go function that reverses a string")
+	response := feedbackMessage(message.Assistant, goReverseAnswer)
+	for _, reply := range []string{`{"score": 0.8}`, "no verdict"} {
+		var reported []provider.TokenUsage
+		evaluator := NewLLMEvaluator(&stubProvider{responses: []string{reply}})
+		evaluator.SetUsageHandler(func(ctx context.Context, model catwalk.Model, usage provider.TokenUsage) {
+			require.Equal(t, "stub", model.ID)
+			reported = append(reported, usage)
+		})
+
+		_, _ = evaluator.Evaluate(t.Context(), user, response)
+		require.Equal(t, []provider.TokenUsage{{InputTokens: 10, OutputTokens: 5}}, reported, reply)
+	}
+}
+
+func TestLLMEvaluatorFallsBackToHeuristics(t *testing.T) {
+	t.Parallel()
+
+	user := feedbackMessage(message.User, "Write a Go function that reverses a string")
+	response := feedbackMessage(message.Assistant, goReverseAnswer)
+	heuristic := NewFeedbackMechanism(true, 0.7, 2, nil).EvaluateResponse(t.Context(), user, response)
+
+	for _, judge := range []*stubProvider{
+		{err: errors.New("rate limited")},
+		{responses: []string{"The response looks good to me."}},
+		{responses: []string{`{"confidence": 0.9}`}},
+	} {
+		fm := NewFeedbackMechanism(true, 0.7, 2, nil)
+		fm.SetEvaluator(NewLLMEvaluator(judge))
+		require.Equal(t, heuristic.Score, fm.EvaluateResponse(t.Context(), user, response).Score)
+	}
+}
+
+func TestParseJudgeVerdict(t *testing.T) {
+	t.Parallel()
+
+	quality, err := parseJudgeVerdict(`{"score": 0.8}`)
+	require.NoError(t, err)
+	require.InDelta(t, 0.8, quality.Score, 1e-9)
+	require.InDelta(t, 0.5, quality.Confidence, 1e-9, "a missing confidence counts as 0.5")
+	require.NotNil(t, quality.Issues)
+	require.NotNil(t, quality.Suggestions)
+
+	_, err = parseJudgeVerdict(`{"score": "high"}`)
+	require.ErrorContains(t, err, "failed to parse judge reply")
+	_, err = parseJudgeVerdict("no verdict")
+	require.ErrorContains(t, err, "no JSON object")
+}
@@ -0,0 +1,117 @@
+package agent
+
+import "github.com/charmbracelet/crush/internal/message"
+
+// QualityTrend describes how evaluations changed across attempts
+type QualityTrend string
+
+const (
+	QualityTrendImproving QualityTrend = "improving"
+	QualityTrendDeclining QualityTrend = "declining"
+	QualityTrendStable    QualityTrend = "stable"
+	// QualityTrendUnknown is reported until there are two evaluations
+	QualityTrendUnknown QualityTrend = "unknown"
+)
+
+// trendThreshold is how much the score must change per attempt before the
+// trend counts as improving or declining
+const trendThreshold = 0.01
+
+// evaluatedResponse is one attempt at a turn along with its evaluation
+type evaluatedResponse struct {
+	response message.Message
+	quality  *ResponseQuality
+}
+
+// EvaluationHistory records the successive evaluations of the attempts at a
+// single turn, such as a response and its regenerations
+type EvaluationHistory struct {
+	attempts []evaluatedResponse
+}
+
+// Record adds the evaluation of the next attempt
+func (h *EvaluationHistory) Record(response message.Message, quality *ResponseQuality) {
+	if quality == nil {
+		return
+	}
+	h.attempts = append(h.attempts, evaluatedResponse{response: response, quality: quality})
+}
+
+// Len returns the number of recorded evaluations
+func (h *EvaluationHistory) Len() int {
+	return len(h.attempts)
+}
+
+// Best returns the highest-scoring attempt, preferring the more confident
+// evaluation and then the earlier attempt on ties. It reports false when
+// nothing was recorded.
+func (h *EvaluationHistory) Best() (message.Message, *ResponseQuality, bool) {
+	if len(h.attempts) == 0 {
+		return message.Message{}, nil, false
+	}
+
+	best := h.attempts[0]
+	for _, attempt := range h.attempts[1:] {
+		if attempt.quality.Score > best.quality.Score ||
+			(attempt.quality.Score == best.quality.Score && attempt.quality.Confidence > best.quality.Confidence) {
+			best = attempt
+		}
+	}
+	return best.response, best.quality, true
+}
+
+// WeightedScore returns the mean score of all attempts weighted by the
+// confidence of each evaluation, or 0 when nothing was recorded
+func (h *EvaluationHistory) WeightedScore() float64 {
+	var sum, weights float64
+	for _, attempt := range h.attempts {
+		sum += attempt.quality.Score * attempt.quality.Confidence
+		weights += attempt.quality.Confidence
+	}
+	if weights == 0 {
+		return 0
+	}
+	return sum / weights
+}
+
+// Trend reports whether scores are improving or declining across attempts.
+// It fits a line through the scores, weighting each by its evaluation's
+// confidence, so a single unsure evaluation doesn't reverse the trend.
+func (h *EvaluationHistory) Trend() QualityTrend {
+	if len(h.attempts) < 2 {
+		return QualityTrendUnknown
+	}
+
+	var weights, meanX, meanY float64
+	for i, attempt := range h.attempts {
+		w := attempt.quality.Confidence
+		weights += w
+		meanX += w * float64(i)
+		meanY += w * attempt.quality.Score
+	}
+	if weights == 0 {
+		return QualityTrendUnknown
+	}
+	meanX /= weights
+	meanY /= weights
+
+	var covariance, variance float64
+	for i, attempt := range h.attempts {
+		w := attempt.quality.Confidence
+		dx := float64(i) - meanX
+		covariance += w * dx * (attempt.quality.Score - meanY)
+		variance += w * dx * dx
+	}
+	if variance == 0 {
+		return QualityTrendUnknown
+	}
+
+	switch slope := covariance / variance; {
+	case slope > trendThreshold:
+		return QualityTrendImproving
+	case slope < -trendThreshold:
+		return QualityTrendDeclining
+	default:
+		return QualityTrendStable
+	}
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+// historyOf records one attempt per score, all evaluated with confidence
+func historyOf(confidence float64, scores ...float64) *EvaluationHistory {
+	var history EvaluationHistory
+	for i, score := range scores {
+		response := message.Message{ID: fmt.Sprintf("attempt-%d", i)}
+		history.Record(response, &ResponseQuality{Score: score, Confidence: confidence})
+	}
+	return &history
+}
+
+func TestEvaluationHistoryBest(t *testing.T) {
+	t.Parallel()
+
+	_, _, ok := (&EvaluationHistory{}).Best()
+	require.False(t, ok)
+
+	// The final attempt regressed, so the second one is best
+	history := historyOf(0.7, 0.4, 0.8, 0.6)
+	response, quality, ok := history.Best()
+	require.True(t, ok)
+	require.Equal(t, "attempt-1", response.ID)
+	require.Equal(t, 0.8, quality.Score)
+	require.Equal(t, 3, history.Len())
+
+	// Ties go to the more confident evaluation, then the earlier attempt
+	history = historyOf(0.5, 0.6, 0.6)
+	history.Record(message.Message{ID: "confident"}, &ResponseQuality{Score: 0.6, Confidence: 0.9})
+	response, _, _ = history.Best()
+	require.Equal(t, "confident", response.ID)
+
+	response, _, _ = historyOf(0.5, 0.6, 0.6).Best()
+	require.Equal(t, "attempt-0", response.ID)
+}
+
+func TestEvaluationHistoryTrend(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		history  *EvaluationHistory
+		expected QualityTrend
+	}{
+		"empty":            {history: &EvaluationHistory{}, expected: QualityTrendUnknown},
+		"single":           {history: historyOf(0.7, 0.5), expected: QualityTrendUnknown},
+		"improving":        {history: historyOf(0.7, 0.3, 0.5, 0.7), expected: QualityTrendImproving},
+		"declining":        {history: historyOf(0.7, 0.8, 0.6, 0.5), expected: QualityTrendDeclining},
+		"stable":           {history: historyOf(0.7, 0.6, 0.6, 0.605), expected: QualityTrendStable},
+		"final regression": {history: historyOf(0.7, 0.4, 0.8, 0.6), expected: QualityTrendImproving},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.expected, tt.history.Trend())
+		})
+	}
+}
+
+func TestEvaluationHistoryWeightsByConfidence(t *testing.T) {
+	t.Parallel()
+
+	// An unsure low score doesn't outweigh two confident improvements
+	var history EvaluationHistory
+	history.Record(message.Message{}, &ResponseQuality{Score: 0.4, Confidence: 0.9})
+	history.Record(message.Message{}, &ResponseQuality{Score: 0.7, Confidence: 0.9})
+	history.Record(message.Message{}, &ResponseQuality{Score: 0.2, Confidence: 0.1})
+	require.Equal(t, QualityTrendImproving, history.Trend())
+	require.InDelta(t, (0.4*0.9+0.7*0.9+0.2*0.1)/1.9, history.WeightedScore(), 1e-9)
+
+	require.Zero(t, (&EvaluationHistory{}).WeightedScore())
+}
@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// maxPathReferences bounds how many path references are checked per response
+	maxPathReferences = 20
+	// missingPathPenalty is subtracted from error_indicators per missing path
+	missingPathPenalty = 0.2
+)
+
+// pathExtensions are the file extensions that make a token look like a file
+// reference rather than prose such as "and/or" or "client/server"
+var pathExtensions = map[string]bool{
+	".go": true, ".mod": true, ".sum": true,
+	".py": true, ".js": true, ".jsx": true, ".mjs": true, ".ts": true, ".tsx": true,
+	".rs": true, ".java": true, ".kt": true, ".rb": true, ".php": true, ".cs": true,
+	".c": true, ".h": true, ".cpp": true, ".hpp": true, ".swift": true,
+	".sh": true, ".sql": true, ".html": true, ".css": true, ".scss": true,
+	".json": true, ".yaml": true, ".yml": true, ".toml": true, ".xml": true,
+	".md": true, ".txt": true, ".env": true, ".lock": true,
+}
+
+// creationWords mark lines that propose new files, whose paths are not
+// expected to exist yet
+var creationWords = []string{"create", "new file", "add a file", "generate", "will be written", "scaffold"}
+
+// SetPathValidation enables checking file paths mentioned in responses
+// against workingDir. References to paths that don't exist are reported as
+// issues and lower the error_indicators metric. An empty workingDir disables
+// the check.
+func (fm *FeedbackMechanism) SetPathValidation(workingDir string) {
+	fm.workingDir = workingDir
+}
+
+// missingPathReferences returns the paths mentioned in the prose of
+// responseText that don't exist below workingDir. Only tokens containing a
+// directory separator and ending in a known file extension are considered,
+// fenced code is skipped, and lines proposing to create files are ignored.
+func missingPathReferences(workingDir, responseText string) []string {
+	var missing []string
+	for _, ref := range pathReferences(responseText) {
+		path := ref
+		if filepath.IsAbs(path) {
+			rel, err := filepath.Rel(workingDir, path)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				// Outside the project, so not something we can judge
+				continue
+			}
+			path = rel
+		}
+		if _, err := os.Stat(filepath.Join(workingDir, path)); os.IsNotExist(err) {
+			missing = append(missing, ref)
+		}
+	}
+	return missing
+}
+
+// pathReferences extracts up to maxPathReferences unique file path references
+// from the prose of text
+func pathReferences(text string) []string {
+	var refs []string
+	seen := make(map[string]bool)
+	inFence := false
+
+	for line := range strings.SplitSeq(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || mentionsCreation(line) {
+			continue
+		}
+
+		for _, token := range strings.Fields(line) {
+			ref, ok := pathToken(token)
+			if !ok || seen[ref] {
+				continue
+			}
+			seen[ref] = true
+			refs = append(refs, ref)
+			if len(refs) == maxPathReferences {
+				return refs
+			}
+		}
+	}
+	return refs
+}
+
+// pathToken cleans up a whitespace separated token and reports whether it
+// looks like a file path
+func pathToken(token string) (string, bool) {
+	token = strings.TrimLeft(token, "`'\"([<{")
+	token = strings.TrimRight(token, "`'\")]>},;!?.:")
+	if strings.Contains(token, "://") || strings.HasPrefix(token, "www.") {
+		return "", false
+	}
+	// Drop a trailing line reference such as main.go:42 or main.go:42:7
+	for {
+		head, tail, found := strings.Cut(token, ":")
+		if !found {
+			break
+		}
+		if tail == "" || strings.Trim(tail, "0123456789:") != "" {
+			return "", false
+		}
+		token = head
+	}
+
+	if !strings.Contains(token, "/") || strings.ContainsAny(token, "*?$~%=&|<>{}\\") {
+		return "", false
+	}
+	base := filepath.Base(token)
+	if !pathExtensions[strings.ToLower(filepath.Ext(base))] || strings.TrimSuffix(base, filepath.Ext(base)) == "" {
+		return "", false
+	}
+	return filepath.Clean(token), true
+}
+
+func mentionsCreation(line string) bool {
+	lower := strings.ToLower(line)
+	for _, word := range creationWords {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
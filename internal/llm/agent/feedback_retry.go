@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// maxFeedbackRetries caps improvement attempts regardless of configuration
+const maxFeedbackRetries = 5
+
+// improveResponse regenerates a low-quality response by sending the feedback
+// mechanism's improvement prompt back to the model. It retries while the
+// response still requires a retry, up to the configured maximum number of
+// attempts, and returns the best-scoring response along with its quality and
+// the token usage of the extra requests.
+func (a *agent) improveResponse(ctx context.Context, msgHistory []message.Message, response message.Message, quality *ResponseQuality) (message.Message, *ResponseQuality, provider.TokenUsage) {
+	var usage provider.TokenUsage
+	if a.feedbackMech == nil || len(msgHistory) == 0 {
+		return response, quality, usage
+	}
+
+	userMessage := msgHistory[len(msgHistory)-1]
+	var history EvaluationHistory
+	history.Record(response, quality)
+	current, currentQuality := response, quality
+
+	attempts := min(a.feedbackMech.maxRetryAttempts, maxFeedbackRetries)
+	for attempt := 1; attempt <= attempts && currentQuality.RequiresRetry; attempt++ {
+		if ctx.Err() != nil {
+			break
+		}
+
+		prompt := a.feedbackMech.GenerateImprovementPrompt(ctx, current, currentQuality)
+		if prompt == "" {
+			break
+		}
+
+		retryHistory := append(slices.Clone(msgHistory), current, message.Message{
+			Role:  message.User,
+			Parts: []message.ContentPart{message.TextContent{Text: prompt}},
+		})
+		resp, err := a.provider.SendMessages(ctx, retryHistory, nil)
+		if err != nil {
+			slog.Warn("Failed to regenerate low-quality response", "attempt", attempt, "error", err)
+			break
+		}
+		usage.InputTokens += resp.Usage.InputTokens
+		usage.OutputTokens += resp.Usage.OutputTokens
+		usage.CacheCreationTokens += resp.Usage.CacheCreationTokens
+		usage.CacheReadTokens += resp.Usage.CacheReadTokens
+		if resp.Content == "" {
+			break
+		}
+
+		current = withTextContent(response, resp.Content)
+		currentQuality = a.feedbackMech.EvaluateResponse(ctx, userMessage, current)
+		history.Record(current, currentQuality)
+		slog.Debug("Regenerated low-quality response",
+			"attempt", attempt,
+			"score", currentQuality.Score,
+			"trend", history.Trend(),
+		)
+	}
+
+	// A later attempt may have regressed, so return the best one
+	best, bestQuality, _ := history.Best()
+	return best, bestQuality, usage
+}
+
+// withTextContent returns a copy of msg whose text content is replaced by text
+func withTextContent(msg message.Message, text string) message.Message {
+	parts := make([]message.ContentPart, 0, len(msg.Parts)+1)
+	replaced := false
+	for _, part := range msg.Parts {
+		if _, ok := part.(message.TextContent); ok {
+			if !replaced {
+				parts = append(parts, message.TextContent{Text: text})
+				replaced = true
+			}
+			continue
+		}
+		parts = append(parts, part)
+	}
+	if !replaced {
+		parts = append(parts, message.TextContent{Text: text})
+	}
+	msg.Parts = parts
+	return msg
+}
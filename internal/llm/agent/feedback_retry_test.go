@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+// stubProvider returns canned responses in order and records each request
+type stubProvider struct {
+	responses []string
+	err       error
+	requests  [][]message.Message
+}
+
+func (p *stubProvider) SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*provider.ProviderResponse, error) {
+	p.requests = append(p.requests, messages)
+	if p.err != nil {
+		return nil, p.err
+	}
+	content := p.responses[min(len(p.requests), len(p.responses))-1]
+	return &provider.ProviderResponse{
+		Content:      content,
+		Usage:        provider.TokenUsage{InputTokens: 10, OutputTokens: 5},
+		FinishReason: message.FinishReasonEndTurn,
+	}, nil
+}
+
+func (p *stubProvider) StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan provider.ProviderEvent {
+	panic("not implemented")
+}
+
+func (p *stubProvider) Model() catwalk.Model {
+	return catwalk.Model{ID: "stub"}
+}
+
+const poorGoAnswer = "```go\nfunc Reverse(s string) string {\n\t// TODO: reverse\n```"
+
+func evaluatePoorAnswer(t *testing.T, fm *FeedbackMechanism) ([]message.Message, message.Message, *ResponseQuality) {
+	t.Helper()
+
+	history := []message.Message{feedbackMessage(message.User, "Write a Go function that reverses a string, handling unicode runes correctly")}
+	response := feedbackMessage(message.Assistant, poorGoAnswer)
+	response.AddFinish(message.FinishReasonEndTurn, "", "")
+	quality := fm.EvaluateResponse(t.Context(), history[0], response)
+	require.True(t, quality.RequiresRetry, "the poor answer should require a retry: %+v", quality)
+	return history, response, quality
+}
+
+func TestImproveResponseUsesImprovedAttempt(t *testing.T) {
+	t.Parallel()
+
+	stub := &stubProvider{responses: []string{goReverseAnswer}}
+	a := &agent{provider: stub, feedbackMech: NewFeedbackMechanism(true, 0.7, 2, nil)}
+	history, response, quality := evaluatePoorAnswer(t, a.feedbackMech)
+
+	improved, improvedQuality, usage := a.improveResponse(t.Context(), history, response, quality)
+
+	require.Len(t, stub.requests, 1, "should stop once the response is good enough")
+	require.Equal(t, goReverseAnswer, improved.Content().Text)
+	require.Equal(t, message.FinishReasonEndTurn, improved.FinishReason())
+	require.False(t, improvedQuality.RequiresRetry)
+	require.Greater(t, improvedQuality.Score, quality.Score)
+	require.Equal(t, provider.TokenUsage{InputTokens: 10, OutputTokens: 5}, usage)
+
+	// The retry sends the poor answer followed by the improvement prompt.
+	request := stub.requests[0]
+	require.Len(t, request, 3)
+	require.Equal(t, poorGoAnswer, request[1].Content().Text)
+	require.Equal(t, message.User, request[2].Role)
+	require.Contains(t, request[2].Content().Text, "Code contains placeholders")
+
+	// The original response is left untouched.
+	require.Equal(t, poorGoAnswer, response.Content().Text)
+}
+
+func TestImproveResponseRespectsMaxRetryAttempts(t *testing.T) {
+	t.Parallel()
+
+	stub := &stubProvider{responses: []string{poorGoAnswer}}
+	a := &agent{provider: stub, feedbackMech: NewFeedbackMechanism(true, 0.7, 3, nil)}
+	history, response, quality := evaluatePoorAnswer(t, a.feedbackMech)
+
+	improved, improvedQuality, _ := a.improveResponse(t.Context(), history, response, quality)
+
+	require.Len(t, stub.requests, 3)
+	require.Same(t, quality, improvedQuality, "no attempt scored better than the original")
+	require.Equal(t, poorGoAnswer, improved.Content().Text)
+}
+
+func TestImproveResponseDisabled(t *testing.T) {
+	t.Parallel()
+
+	stub := &stubProvider{responses: []string{goReverseAnswer}}
+	a := &agent{provider: stub, feedbackMech: NewFeedbackMechanism(true, 0.7, 0, nil)}
+	history, response, quality := evaluatePoorAnswer(t, a.feedbackMech)
+
+	_, improvedQuality, _ := a.improveResponse(t.Context(), history, response, quality)
+	require.Empty(t, stub.requests)
+	require.Same(t, quality, improvedQuality)
+}
+
+func TestImproveResponseProviderError(t *testing.T) {
+	t.Parallel()
+
+	stub := &stubProvider{err: errors.New("rate limited")}
+	a := &agent{provider: stub, feedbackMech: NewFeedbackMechanism(true, 0.7, 2, nil)}
+	history, response, quality := evaluatePoorAnswer(t, a.feedbackMech)
+
+	improved, improvedQuality, _ := a.improveResponse(t.Context(), history, response, quality)
+	require.Len(t, stub.requests, 1)
+	require.Same(t, quality, improvedQuality)
+	require.Equal(t, poorGoAnswer, improved.Content().Text)
+}
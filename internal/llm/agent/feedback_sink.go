@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// maxRecordedTextLength bounds how much of each message is stored in a quality record
+const maxRecordedTextLength = 500
+
+// QualitySink records response quality evaluations for later analysis
+type QualitySink interface {
+	Record(ctx context.Context, userMsg, response message.Message, quality *ResponseQuality) error
+}
+
+// QualityRecord is a single evaluation written by FileQualitySink
+type QualityRecord struct {
+	SessionID   string             `json:"session_id"`
+	MessageID   string             `json:"message_id,omitempty"`
+	Model       string             `json:"model,omitempty"`
+	UserMessage string             `json:"user_message"`
+	Response    string             `json:"response"`
+	Score       float64            `json:"score"`
+	Confidence  float64            `json:"confidence"`
+	Metrics     map[string]float64 `json:"metrics,omitempty"`
+	Issues      []string           `json:"issues,omitempty"`
+	Retry       bool               `json:"requires_retry"`
+	Timestamp   time.Time          `json:"timestamp"`
+}
+
+// FileQualitySink appends quality records as JSON lines to a file
+type FileQualitySink struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileQualitySink creates a sink that appends to the file at path,
+// creating it and its parent directories as needed
+func NewFileQualitySink(path string) *FileQualitySink {
+	return &FileQualitySink{path: path}
+}
+
+// Record appends the evaluation of response to the sink's file
+func (s *FileQualitySink) Record(ctx context.Context, userMsg, response message.Message, quality *ResponseQuality) error {
+	sessionID := response.SessionID
+	if sessionID == "" {
+		sessionID, _ = ctx.Value(tools.SessionIDContextKey).(string)
+	}
+
+	record := QualityRecord{
+		SessionID:   sessionID,
+		MessageID:   response.ID,
+		Model:       response.Model,
+		UserMessage: truncateRecordText(userMsg.Content().Text),
+		Response:    truncateRecordText(response.Content().Text),
+		Score:       quality.Score,
+		Confidence:  quality.Confidence,
+		Metrics:     quality.Metrics,
+		Issues:      quality.Issues,
+		Retry:       quality.RequiresRetry,
+		Timestamp:   quality.Timestamp,
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal quality record: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create feedback directory: %w", err)
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open quality log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(line); err != nil {
+		return fmt.Errorf("failed to write quality record: %w", err)
+	}
+	return nil
+}
+
+// truncateRecordText shortens text to maxRecordedTextLength runes
+func truncateRecordText(text string) string {
+	runes := []rune(text)
+	if len(runes) <= maxRecordedTextLength {
+		return text
+	}
+	return string(runes[:maxRecordedTextLength]) + "..."
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileQualitySinkRecordsEvaluations(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), ".crush", "feedback", "quality.jsonl")
+	fm := NewFeedbackMechanism(true, 0.7, 2, nil)
+	fm.SetSink(NewFileQualitySink(path))
+
+	user := feedbackMessage(message.User, "Write a Go function that reverses a string")
+	good := feedbackMessage(message.Assistant, goReverseAnswer)
+	good.ID = "msg-1"
+	good.SessionID = "session-1"
+	fm.EvaluateResponse(t.Context(), user, good)
+
+	// Without a session on the message, the session ID comes from the context.
+	ctx := context.WithValue(t.Context(), tools.SessionIDContextKey, "session-2")
+	fm.EvaluateResponse(ctx, user, feedbackMessage(message.Assistant, poorGoAnswer))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var records []QualityRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		require.True(t, json.Valid(scanner.Bytes()), "line is not valid JSON: %s", scanner.Text())
+		var record QualityRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.NoError(t, scanner.Err())
+	require.Len(t, records, 2)
+
+	require.Equal(t, "session-1", records[0].SessionID)
+	require.Equal(t, "msg-1", records[0].MessageID)
+	require.Equal(t, user.Content().Text, records[0].UserMessage)
+	require.False(t, records[0].Retry)
+	require.Contains(t, records[0].Metrics, "code_quality")
+
+	require.Equal(t, "session-2", records[1].SessionID)
+	require.True(t, records[1].Retry)
+	require.Contains(t, records[1].Issues, "Code contains placeholders")
+	require.Less(t, records[1].Score, records[0].Score)
+}
+
+func TestFileQualitySinkTruncatesLongMessages(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "quality.jsonl")
+	sink := NewFileQualitySink(path)
+	long := strings.Repeat("a", maxRecordedTextLength*2)
+
+	err := sink.Record(t.Context(),
+		feedbackMessage(message.User, long),
+		feedbackMessage(message.Assistant, "ok"),
+		&ResponseQuality{Score: 1})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var record QualityRecord
+	require.NoError(t, json.Unmarshal(data, &record))
+	require.Len(t, record.UserMessage, maxRecordedTextLength+len("..."))
+}
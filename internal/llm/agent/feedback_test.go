@@ -0,0 +1,299 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeWeights(t *testing.T) {
+	t.Parallel()
+
+	t.Run("defaults when unset", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, defaultFeedbackWeights(), normalizeWeights(nil))
+		require.Equal(t, defaultFeedbackWeights(), normalizeWeights(map[string]float64{"clarity": 0}))
+	})
+
+	t.Run("scaled to sum to one", func(t *testing.T) {
+		t.Parallel()
+		weights := normalizeWeights(map[string]float64{"specificity": 3, "clarity": 1, "relevance": -1})
+		require.InDelta(t, 0.75, weights["specificity"], 1e-9)
+		require.InDelta(t, 0.25, weights["clarity"], 1e-9)
+		require.NotContains(t, weights, "relevance")
+	})
+
+	t.Run("misspelled metrics are ignored", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, defaultFeedbackWeights(), normalizeWeights(map[string]float64{"specifity": 1}))
+
+		weights := normalizeWeights(map[string]float64{"specifity": 1, "clarity": 1})
+		require.Equal(t, map[string]float64{"clarity": 1}, weights)
+	})
+}
+
+func TestValidateFeedbackWeights(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, validateFeedbackWeights(nil))
+	require.NoError(t, validateFeedbackWeights(map[string]float64{"specificity": 3, "clarity": 1}))
+
+	err := validateFeedbackWeights(map[string]float64{"specifity": 1, "clarity": 1})
+	require.ErrorContains(t, err, `unknown feedback weight metric "specifity"`)
+	require.ErrorContains(t, err, "specificity")
+}
+
+func TestCalculateOverallScoreCustomWeights(t *testing.T) {
+	t.Parallel()
+
+	metrics := map[string]float64{
+		"completeness":     0.8,
+		"clarity":          0.2,
+		"relevance":        0.5,
+		"specificity":      1.0,
+		"error_indicators": 1.0,
+	}
+
+	defaults := NewFeedbackMechanism(true, 0.7, 2, nil)
+	// 0.8*0.3 + 0.2*0.2 + 0.5*0.25 + 1.0*0.15 + 1.0*0.1
+	require.InDelta(t, 0.655, defaults.calculateOverallScore(metrics, 0), 1e-9)
+
+	specific := NewFeedbackMechanism(true, 0.7, 2, map[string]float64{
+		"specificity": 3,
+		"clarity":     1,
+	})
+	// 1.0*0.75 + 0.2*0.25
+	require.InDelta(t, 0.8, specific.calculateOverallScore(metrics, 0), 1e-9)
+
+	clarityOnly := NewFeedbackMechanism(true, 0.7, 2, map[string]float64{"clarity": 1})
+	require.InDelta(t, 0.2, clarityOnly.calculateOverallScore(metrics, 0), 1e-9)
+}
+
+func feedbackMessage(role message.MessageRole, text string) message.Message {
+	return message.Message{
+		Role:  role,
+		Parts: []message.ContentPart{message.TextContent{Text: text}},
+	}
+}
+
+const goReverseAnswer = "```go\n" + `func Reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}` + "\n```"
+
+func TestEvaluateResponseCodeHeavy(t *testing.T) {
+	t.Parallel()
+
+	fm := NewFeedbackMechanism(true, 0.7, 2, nil)
+	user := feedbackMessage(message.User, "Write a Go function that reverses a string, handling unicode runes correctly")
+
+	quality := fm.EvaluateResponse(t.Context(), user, feedbackMessage(message.Assistant, goReverseAnswer))
+	require.Equal(t, 1.0, quality.Metrics["code_quality"])
+	require.False(t, quality.RequiresRetry, "a correct code answer should not need a retry: %+v", quality)
+
+	// Without code awareness, the same answer is penalized by the prose metrics.
+	withoutCode := make(map[string]float64, len(quality.Metrics))
+	for metric, score := range quality.Metrics {
+		if metric != "code_quality" {
+			withoutCode[metric] = score
+		}
+	}
+	require.Greater(t, quality.Score, fm.calculateOverallScore(withoutCode, 0))
+}
+
+func TestEvaluateResponseCodeIssues(t *testing.T) {
+	t.Parallel()
+
+	fm := NewFeedbackMechanism(true, 0.7, 2, nil)
+	user := feedbackMessage(message.User, "Write a Go function that reverses a string")
+
+	tests := []struct {
+		name     string
+		response string
+		issue    string
+	}{
+		{
+			name:     "placeholder",
+			response: "```go\nfunc Reverse(s string) string {\n\t// TODO: reverse the string\n\treturn s\n}\n```",
+			issue:    "Code contains placeholders",
+		},
+		{
+			name:     "ellipsis placeholder",
+			response: "```go\nfunc Reverse(s string) string {\n\t...\n}\n```",
+			issue:    "Code contains placeholders",
+		},
+		{
+			name:     "unbalanced braces",
+			response: "```go\nfunc Reverse(s string) string {\n\treturn s\n```",
+			issue:    "Code has unbalanced brackets",
+		},
+		{
+			name:     "wrong language",
+			response: "```python\ndef reverse(s):\n    return s[::-1]\n```",
+			issue:    "Code is not in the requested language",
+		},
+	}
+
+	correct := fm.EvaluateResponse(t.Context(), user, feedbackMessage(message.Assistant, goReverseAnswer))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			quality := fm.EvaluateResponse(t.Context(), user, feedbackMessage(message.Assistant, tt.response))
+			require.Less(t, quality.Metrics["code_quality"], 1.0)
+			require.Contains(t, quality.Issues, tt.issue)
+			require.Less(t, quality.Score, correct.Score)
+		})
+	}
+}
+
+func TestBracketsBalancedIgnoresStrings(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, bracketsBalanced(`fmt.Println("(unclosed")`))
+	require.True(t, bracketsBalanced("items := []int{1, 2}\nspread(...items)"))
+	require.True(t, bracketsBalanced("if r == '(' || r == '\\'' {\n}"))
+	require.True(t, bracketsBalanced("fn first<'a>(s: &'a str) -> &'a str {\n    s\n}"))
+	require.False(t, bracketsBalanced("if x {\n\treturn (y]\n}"))
+}
+
+func TestProseResponseHasNoCodeMetric(t *testing.T) {
+	t.Parallel()
+
+	fm := NewFeedbackMechanism(true, 0.7, 2, nil)
+	quality := fm.EvaluateResponse(t.Context(),
+		feedbackMessage(message.User, "How do I configure the cache?"),
+		feedbackMessage(message.Assistant, "Set enable_cache to true in the configuration file."))
+	require.NotContains(t, quality.Metrics, "code_quality")
+}
+
+func TestPathReferences(t *testing.T) {
+	t.Parallel()
+
+	text := "Edit `internal/llm/agent/agent.go:42` and ./cmd/main.go, see (docs/guide.md).\n" +
+		"This works for client/server and input/output setups, or see https://example.com/a/b.go.\n" +
+		"Globs like src/*.go, variables like $HOME/x.go and bare names like main.go are ignored.\n" +
+		"Create internal/new/feature.go with the following:\n" +
+		"```go\n// see pkg/inside/code.go\n```\n" +
+		"Check internal/llm/agent/agent.go again."
+
+	require.Equal(t, []string{"internal/llm/agent/agent.go", "cmd/main.go", "docs/guide.md"}, pathReferences(text))
+}
+
+func TestEvaluateResponseFlagsMissingPaths(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "internal", "config"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "internal", "config", "load.go"), []byte("package config\n"), 0o644))
+
+	fm := NewFeedbackMechanism(true, 0.7, 2, nil)
+	fm.SetPathValidation(dir)
+	user := feedbackMessage(message.User, "Where is the configuration loaded?")
+
+	real := fm.EvaluateResponse(t.Context(), user, feedbackMessage(message.Assistant,
+		"Configuration is loaded in `internal/config/load.go` by the Load function, which merges global and project settings."))
+	require.Equal(t, 1.0, real.Metrics["error_indicators"])
+	for _, issue := range real.Issues {
+		require.NotContains(t, issue, "don't exist")
+	}
+
+	fake := fm.EvaluateResponse(t.Context(), user, feedbackMessage(message.Assistant,
+		"Configuration is loaded in `internal/config/loader.go` and `internal/settings/merge.go` by the Load function, which merges global and project settings."))
+	require.InDelta(t, 0.6, fake.Metrics["error_indicators"], 1e-9)
+	require.Contains(t, fake.Issues, "Response references files that don't exist: internal/config/loader.go, internal/settings/merge.go")
+	require.Less(t, fake.Score, real.Score)
+
+	// Absolute paths inside the project are checked too, outside ones are not
+	abs := fm.EvaluateResponse(t.Context(), user, feedbackMessage(message.Assistant,
+		"See "+filepath.Join(dir, "internal", "config", "load.go")+" and /etc/unrelated/file.conf.txt for details on loading."))
+	require.Equal(t, 1.0, abs.Metrics["error_indicators"])
+
+	// Without a working directory the check is off
+	off := NewFeedbackMechanism(true, 0.7, 2, nil)
+	quality := off.EvaluateResponse(t.Context(), user, feedbackMessage(message.Assistant,
+		"Configuration is loaded in `internal/config/loader.go` by the Load function, which merges global and project settings."))
+	require.Equal(t, 1.0, quality.Metrics["error_indicators"])
+}
+
+func TestExpectsBriefAnswer(t *testing.T) {
+	t.Parallel()
+
+	brief := []string{
+		"What's 2+2?",
+		"what is 12 * 7",
+		"Is Go statically typed?",
+		"Does this repo use testify?",
+		"Who wrote the Go memory model?",
+		"When was Go 1.0 released?",
+		"How many bytes are in a kilobyte?",
+		"Which port does the web server use?",
+	}
+	for _, question := range brief {
+		require.True(t, expectsBriefAnswer(question), question)
+	}
+
+	elaborate := []string{
+		"Why is Go statically typed?",
+		"Explain what 2+2 is",
+		"How do I configure the web server?",
+		"What is the difference between a slice and an array?",
+		"Write a function that adds 2+2",
+		"Refactor the permission service to support deny rules",
+		"Is there a way to make the cache persist across restarts, and if so, how would you implement it in this codebase?",
+		"",
+	}
+	for _, question := range elaborate {
+		require.False(t, expectsBriefAnswer(question), question)
+	}
+}
+
+func TestCalculateCompletenessShortAnswers(t *testing.T) {
+	t.Parallel()
+
+	fm := NewFeedbackMechanism(true, 0.7, 2, nil)
+	require.Equal(t, 0.8, fm.calculateCompleteness("What's 2+2?", "4"))
+	require.Equal(t, 0.8, fm.calculateCompleteness("Is Go statically typed?", "Yes."))
+	require.Equal(t, 0.8, fm.calculateCompleteness("Which port does the web server use?", "8080"))
+
+	// Requests that need elaborating still penalize short answers, in
+	// proportion to how short they are
+	request := "Explain how the permission service decides which requests to auto approve"
+	oneWord := fm.calculateCompleteness(request, "Patterns.")
+	fewWords := fm.calculateCompleteness(request, "It uses learned patterns.")
+	require.Less(t, oneWord, fewWords)
+	require.Less(t, fewWords, 0.8)
+	require.GreaterOrEqual(t, oneWord, 0.3)
+}
+
+func TestCalculateCompletenessCapsExpectedLength(t *testing.T) {
+	t.Parallel()
+
+	fm := NewFeedbackMechanism(true, 0.7, 2, nil)
+	longRequest := "Please review the following requirements carefully. " + strings.Repeat("The service must handle this case correctly. ", 30)
+	answer := strings.Repeat("word ", maxExpectedWords)
+	require.Equal(t, 0.8, fm.calculateCompleteness(longRequest, answer))
+}
+
+func TestEvaluateResponseShortFactualAnswers(t *testing.T) {
+	t.Parallel()
+
+	fm := NewFeedbackMechanism(true, 0.7, 2, nil)
+	tests := map[string]string{
+		"What's 2+2?":                       "4",
+		"Is Go statically typed?":           "Yes.",
+		"How many bytes are in a kilobyte?": "1024",
+	}
+	for question, answer := range tests {
+		quality := fm.EvaluateResponse(t.Context(), feedbackMessage(message.User, question), feedbackMessage(message.Assistant, answer))
+		require.False(t, quality.RequiresRetry, "%q answered with %q should score well: %+v", question, answer, quality)
+		require.NotContains(t, quality.Issues, "Response may be incomplete")
+	}
+}
@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/notifications"
+)
+
+// completionNotifyTimeout bounds how long sending completion notifications may take
+const completionNotifyTimeout = 30 * time.Second
+
+// completionNotifier sends a notification through the configured services
+// whenever an agent run completes or fails
+type completionNotifier struct {
+	services    []notifications.NotificationService
+	minDuration time.Duration
+	errorsOnly  bool
+}
+
+// runSummary describes a finished agent run
+type runSummary struct {
+	sessionID    string
+	sessionTitle string
+	duration     time.Duration
+	cost         float64
+	totalCost    float64
+	err          error
+}
+
+// newCompletionNotifier returns a notifier sending through the enabled
+// services in registry, or nil when completion notifications are disabled or
+// no service is configured
+func newCompletionNotifier(config *notifications.NotificationConfig, registry *notifications.Registry) *completionNotifier {
+	if config == nil || !config.OnCompletion.Enabled || registry == nil {
+		return nil
+	}
+	var services []notifications.NotificationService
+	for _, svc := range registry.Enabled() {
+		services = append(services, svc.Service)
+	}
+	if len(services) == 0 {
+		slog.Warn("Completion notifications are enabled but no notification service is configured")
+		return nil
+	}
+	return &completionNotifier{
+		services:    services,
+		minDuration: time.Duration(config.OnCompletion.MinDuration) * time.Second,
+		errorsOnly:  config.OnCompletion.ErrorsOnly,
+	}
+}
+
+// shouldNotify reports whether a run with the given summary warrants a notification
+func (n *completionNotifier) shouldNotify(summary runSummary) bool {
+	if errors.Is(summary.err, ErrRequestCancelled) || errors.Is(summary.err, context.Canceled) {
+		return false
+	}
+	if n.errorsOnly && summary.err == nil {
+		return false
+	}
+	return summary.duration >= n.minDuration
+}
+
+// notification builds the notification for a finished run
+func (n *completionNotifier) notification(summary runSummary) *notifications.Notification {
+	session := summary.sessionTitle
+	if session == "" {
+		session = summary.sessionID
+	}
+
+	notification := &notifications.Notification{
+		Title:     "Crush session completed",
+		Message:   fmt.Sprintf("%s finished in %s.", session, summary.duration.Round(time.Second)),
+		Level:     notifications.LevelSuccess,
+		Timestamp: time.Now(),
+		Metadata: map[string]string{
+			"session":    session,
+			"duration":   summary.duration.Round(time.Second).String(),
+			"cost":       fmt.Sprintf("$%.4f", summary.cost),
+			"total_cost": fmt.Sprintf("$%.4f", summary.totalCost),
+		},
+	}
+	if summary.err != nil {
+		notification.Title = "Crush session failed"
+		notification.Message = fmt.Sprintf("%s failed after %s: %v", session, summary.duration.Round(time.Second), summary.err)
+		notification.Level = notifications.LevelError
+		notification.Metadata["error"] = summary.err.Error()
+	}
+	return notification
+}
+
+// runFinished notifies every service about a finished run. Delivery failures
+// are logged and do not affect the run.
+func (n *completionNotifier) runFinished(ctx context.Context, summary runSummary) {
+	if !n.shouldNotify(summary) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, completionNotifyTimeout)
+	defer cancel()
+
+	notification := n.notification(summary)
+	for _, service := range n.services {
+		if err := service.SendNotification(ctx, notification); err != nil && !errors.Is(err, notifications.ErrSuppressed) {
+			slog.Warn("Failed to send completion notification", "session_id", summary.sessionID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/notifications"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingService struct {
+	mu   sync.Mutex
+	sent []*notifications.Notification
+	err  error
+}
+
+func (s *recordingService) SendNotification(ctx context.Context, notification *notifications.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, notification)
+	return s.err
+}
+
+func (s *recordingService) IsEnabled() bool { return true }
+
+func TestNewCompletionNotifierDisabled(t *testing.T) {
+	t.Parallel()
+
+	empty := notifications.NewRegistryFromConfig(nil)
+	require.Nil(t, newCompletionNotifier(nil, empty))
+	require.Nil(t, newCompletionNotifier(&notifications.NotificationConfig{}, empty))
+	require.Nil(t, newCompletionNotifier(&notifications.NotificationConfig{
+		OnCompletion: notifications.CompletionConfig{Enabled: true},
+	}, empty), "no services configured")
+
+	config := &notifications.NotificationConfig{
+		Discord:      notifications.DiscordConfig{Enabled: true, WebhookURL: "https://example.com/hook"},
+		OnCompletion: notifications.CompletionConfig{Enabled: true, MinDuration: 5},
+	}
+	registry := notifications.NewRegistryFromConfig(config)
+	notifier := newCompletionNotifier(config, registry)
+	require.NotNil(t, notifier)
+	require.Len(t, notifier.services, 1)
+	require.Equal(t, 5*time.Second, notifier.minDuration)
+
+	discord, ok := registry.Get("discord")
+	require.True(t, ok)
+	require.Same(t, discord.Service, notifier.services[0], "the notifier shares the registry's rate limited service")
+}
+
+func TestCompletionNotifierRunFinished(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		service := &recordingService{}
+		notifier := &completionNotifier{services: []notifications.NotificationService{service}}
+
+		notifier.runFinished(t.Context(), runSummary{
+			sessionID:    "s1",
+			sessionTitle: "Refactor parser",
+			duration:     90 * time.Second,
+			cost:         0.0125,
+			totalCost:    0.5,
+		})
+
+		require.Len(t, service.sent, 1)
+		sent := service.sent[0]
+		require.Equal(t, "Crush session completed", sent.Title)
+		require.Equal(t, notifications.LevelSuccess, sent.Level)
+		require.Equal(t, "Refactor parser", sent.Metadata["session"])
+		require.Equal(t, "1m30s", sent.Metadata["duration"])
+		require.Equal(t, "$0.0125", sent.Metadata["cost"])
+		require.NotContains(t, sent.Metadata, "error")
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		t.Parallel()
+		service := &recordingService{}
+		notifier := &completionNotifier{services: []notifications.NotificationService{service}, errorsOnly: true}
+
+		notifier.runFinished(t.Context(), runSummary{sessionID: "s1", duration: time.Second, err: errors.New("provider unavailable")})
+
+		require.Len(t, service.sent, 1)
+		sent := service.sent[0]
+		require.Equal(t, "Crush session failed", sent.Title)
+		require.Equal(t, notifications.LevelError, sent.Level)
+		require.Equal(t, "s1", sent.Metadata["session"])
+		require.Equal(t, "provider unavailable", sent.Metadata["error"])
+	})
+
+	t.Run("delivery errors reach every service", func(t *testing.T) {
+		t.Parallel()
+		failing := &recordingService{err: errors.New("webhook down")}
+		working := &recordingService{}
+		notifier := &completionNotifier{services: []notifications.NotificationService{failing, working}}
+
+		notifier.runFinished(t.Context(), runSummary{sessionID: "s1"})
+
+		require.Len(t, failing.sent, 1)
+		require.Len(t, working.sent, 1)
+	})
+}
+
+func TestCompletionNotifierShouldNotify(t *testing.T) {
+	t.Parallel()
+
+	notifier := &completionNotifier{minDuration: time.Minute}
+	require.False(t, notifier.shouldNotify(runSummary{duration: 30 * time.Second}))
+	require.True(t, notifier.shouldNotify(runSummary{duration: 2 * time.Minute}))
+	require.False(t, notifier.shouldNotify(runSummary{duration: 2 * time.Minute, err: ErrRequestCancelled}))
+	require.False(t, notifier.shouldNotify(runSummary{duration: 2 * time.Minute, err: context.Canceled}))
+
+	errorsOnly := &completionNotifier{errorsOnly: true}
+	require.False(t, errorsOnly.shouldNotify(runSummary{}))
+	require.True(t, errorsOnly.shouldNotify(runSummary{err: errors.New("boom")}))
+}
@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// omittedMarker stands in for content dropped by ExtractiveSummarizer
+const omittedMarker = "[...]"
+
+// MessageSummarizer shortens message content to roughly maxChars characters
+// when OptimizeMessages needs to reduce an older message
+type MessageSummarizer interface {
+	Summarize(text string, maxChars int) string
+}
+
+// TruncatingSummarizer keeps only the beginning of the text
+type TruncatingSummarizer struct{}
+
+func (TruncatingSummarizer) Summarize(text string, maxChars int) string {
+	if len(text) <= maxChars {
+		return text
+	}
+	return text[:runeBoundary(text, maxChars)] + "... [truncated]"
+}
+
+// ExtractiveSummarizer keeps the first and last sentences, where messages
+// usually state their subject and their conclusion, along with the sentences
+// and lines in between that mention errors or look like code, as far as the
+// budget allows. Dropped content is replaced by "[...]".
+type ExtractiveSummarizer struct{}
+
+// importantSegmentPattern matches sentences and lines worth keeping from the
+// middle of a message: errors, file locations and code
+var importantSegmentPattern = regexp.MustCompile(`(?i)\b(error|errors|fail|failed|failure|fails|panic|exception|fatal|warning|traceback)\b` +
+	`|[\w./-]+\.\w+:\d+` +
+	`|^\s*(func|def|class|import|package|return)\b` +
+	"|^\\s*```" +
+	`|[{};]\s*$`)
+
+func (ExtractiveSummarizer) Summarize(text string, maxChars int) string {
+	if len(text) <= maxChars {
+		return text
+	}
+
+	segments := splitSegments(text)
+	first, last := 0, len(segments)-1
+	budget := maxChars - len(segments[first]) - len(segments[last]) - len(omittedMarker)
+	if len(segments) < 3 || budget < 0 {
+		return headAndTail(text, maxChars)
+	}
+
+	keep := make([]bool, len(segments))
+	keep[first], keep[last] = true, true
+	for i := first + 1; i < last; i++ {
+		cost := len(segments[i]) + len(omittedMarker) + 1
+		if cost <= budget && importantSegmentPattern.MatchString(strings.TrimSpace(segments[i])) {
+			keep[i] = true
+			budget -= cost
+		}
+	}
+
+	var summary strings.Builder
+	omitted := false
+	for i, segment := range segments {
+		if !keep[i] {
+			omitted = true
+			continue
+		}
+		if omitted {
+			summary.WriteString(omittedMarker)
+			summary.WriteString(separatorAfter(segments[i-1]))
+			omitted = false
+		}
+		summary.WriteString(segment)
+	}
+	return summary.String()
+}
+
+// splitSegments splits text into lines, and lines into sentences, keeping the
+// whitespace that follows each segment so joining them restores the text.
+// Sentences end at '.', '!' or '?' followed by whitespace, so dotted
+// identifiers in code stay whole.
+func splitSegments(text string) []string {
+	var segments []string
+	start := 0
+	for i := 0; i < len(text); i++ {
+		end := text[i] == '\n' ||
+			(strings.IndexByte(".!?", text[i]) >= 0 && i+1 < len(text) && (text[i+1] == ' ' || text[i+1] == '\n'))
+		if !end {
+			continue
+		}
+		for i+1 < len(text) && (text[i+1] == ' ' || text[i+1] == '\n' || text[i+1] == '\t') {
+			i++
+		}
+		segments = append(segments, text[start:i+1])
+		start = i + 1
+	}
+	if start < len(text) {
+		segments = append(segments, text[start:])
+	}
+	return segments
+}
+
+// separatorAfter returns the whitespace ending segment, or a space when it has
+// none, so the omitted marker sits on its own line after a line break
+func separatorAfter(segment string) string {
+	if strings.Contains(segment[len(strings.TrimRight(segment, " \t\n")):], "\n") {
+		return "\n"
+	}
+	return " "
+}
+
+// headAndTail keeps the beginning and end of text when it has too few
+// sentences to choose from
+func headAndTail(text string, maxChars int) string {
+	half := (maxChars - len(omittedMarker) - 2) / 2
+	if half < 1 {
+		half = 1
+	}
+	head := text[:runeBoundary(text, half)]
+	tail := text[runeBoundary(text, len(text)-half):]
+	return head + " " + omittedMarker + " " + tail
+}
+
+// runeBoundary moves i back to the start of the UTF-8 character it falls in
+func runeBoundary(text string, i int) int {
+	for i > 0 && i < len(text) && !utf8.RuneStart(text[i]) {
+		i--
+	}
+	return i
+}
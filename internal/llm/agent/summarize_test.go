@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+// longMessage builds a message with an opening, a long middle and a conclusion
+func longMessage() string {
+	var b strings.Builder
+	b.WriteString("I looked into why the login tests fail on CI. ")
+	for i := range 30 {
+		b.WriteString("The session store was checked again and nothing unusual showed up there. ")
+		if i == 12 {
+			b.WriteString("The logs show error: token expired at auth/session.go:42 for every request. ")
+		}
+	}
+	b.WriteString("In conclusion, the fix is to refresh the token before each test run.")
+	return b.String()
+}
+
+func TestExtractiveSummarizerKeepsOpeningAndConclusion(t *testing.T) {
+	t.Parallel()
+
+	text := longMessage()
+	summary := ExtractiveSummarizer{}.Summarize(text, 400)
+
+	require.LessOrEqual(t, len(summary), 400)
+	require.True(t, strings.HasPrefix(summary, "I looked into why the login tests fail on CI."), summary)
+	require.True(t, strings.HasSuffix(summary, "In conclusion, the fix is to refresh the token before each test run."), summary)
+	require.Contains(t, summary, "error: token expired at auth/session.go:42")
+	require.Contains(t, summary, omittedMarker)
+	require.NotContains(t, summary, "session store was checked")
+
+	// Truncation loses the conclusion
+	truncated := TruncatingSummarizer{}.Summarize(text, 400)
+	require.NotContains(t, truncated, "In conclusion")
+}
+
+func TestExtractiveSummarizerKeepsCodeLines(t *testing.T) {
+	t.Parallel()
+
+	var b strings.Builder
+	b.WriteString("Here is the updated handler:\n")
+	for range 40 {
+		b.WriteString("// explanatory comment about the handler\n")
+	}
+	b.WriteString("func handle(w http.ResponseWriter, r *http.Request) {\n")
+	b.WriteString("That should resolve it.")
+
+	summary := ExtractiveSummarizer{}.Summarize(b.String(), 300)
+	require.True(t, strings.HasPrefix(summary, "Here is the updated handler:\n[...]\n"), summary)
+	require.Contains(t, summary, "func handle(w http.ResponseWriter, r *http.Request) {\n")
+	require.True(t, strings.HasSuffix(summary, "That should resolve it."), summary)
+}
+
+func TestExtractiveSummarizerShortOrUnsplittableText(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "short text", ExtractiveSummarizer{}.Summarize("short text", 400))
+
+	// A single run-on sentence keeps its start and end
+	text := "start " + strings.Repeat("x", 1000) + " end"
+	summary := ExtractiveSummarizer{}.Summarize(text, 100)
+	require.LessOrEqual(t, len(summary), 100)
+	require.True(t, strings.HasPrefix(summary, "start "))
+	require.True(t, strings.HasSuffix(summary, " end"))
+
+	// Cuts never split a multi-byte character
+	summary = ExtractiveSummarizer{}.Summarize(strings.Repeat("é", 500), 101)
+	require.True(t, strings.ToValidUTF8(summary, "?") == summary)
+}
+
+func TestSplitSegments(t *testing.T) {
+	t.Parallel()
+
+	text := "First sentence. Second one! Call fmt.Println(x) here?\nline two\n\nlast"
+	segments := splitSegments(text)
+	require.Equal(t, []string{"First sentence. ", "Second one! ", "Call fmt.Println(x) here?\n", "line two\n\n", "last"}, segments)
+	require.Equal(t, text, strings.Join(segments, ""))
+}
+
+func TestOptimizeMessagesUsesSummarizer(t *testing.T) {
+	t.Parallel()
+
+	messages := []message.Message{{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: longMessage()}},
+	}}
+	for range 5 {
+		messages = append(messages, message.Message{
+			Role:  message.Assistant,
+			Parts: []message.ContentPart{message.TextContent{Text: "ok"}},
+		})
+	}
+
+	ce := NewCostEstimator(1)
+	optimized := ce.OptimizeMessages(t.Context(), messages, 0.3)
+	summary := optimized[0].Content().String()
+	require.Contains(t, summary, "In conclusion")
+	require.Less(t, len(summary), len(longMessage()))
+
+	ce.SetSummarizer(TruncatingSummarizer{})
+	optimized = ce.OptimizeMessages(t.Context(), messages, 0.3)
+	require.True(t, strings.HasSuffix(optimized[0].Content().String(), "... [truncated]"))
+}
@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// encodingPrefixes maps model ID prefixes to tiktoken encodings for models
+// tiktoken-go doesn't resolve on its own
+var encodingPrefixes = []struct {
+	prefix   string
+	encoding string
+}{
+	{"gpt-5", tiktoken.MODEL_O200K_BASE},
+	{"gpt-4.5", tiktoken.MODEL_O200K_BASE},
+	{"gpt-4.1", tiktoken.MODEL_O200K_BASE},
+	{"gpt-4o", tiktoken.MODEL_O200K_BASE},
+	{"chatgpt-4o", tiktoken.MODEL_O200K_BASE},
+	{"o1", tiktoken.MODEL_O200K_BASE},
+	{"o3", tiktoken.MODEL_O200K_BASE},
+	{"o4", tiktoken.MODEL_O200K_BASE},
+	{"gpt-4", tiktoken.MODEL_CL100K_BASE},
+	{"gpt-3.5", tiktoken.MODEL_CL100K_BASE},
+}
+
+var (
+	setBpeLoader = sync.OnceFunc(func() {
+		// Use the embedded BPE files so token counting never hits the network.
+		tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+	})
+	encoders   = map[string]*tiktoken.Tiktoken{}
+	encodersMu sync.Mutex
+)
+
+// encodingForModel returns the tiktoken encoding name for a model ID, or ""
+// when the model's tokenizer is unknown
+func encodingForModel(modelID string) string {
+	id := strings.ToLower(modelID)
+	// Strip routing prefixes such as "openai/gpt-4o".
+	if idx := strings.LastIndex(id, "/"); idx != -1 {
+		id = id[idx+1:]
+	}
+	if id == "" {
+		return ""
+	}
+	if encoding, ok := tiktoken.MODEL_TO_ENCODING[id]; ok {
+		return encoding
+	}
+	for _, p := range encodingPrefixes {
+		if strings.HasPrefix(id, p.prefix) {
+			return p.encoding
+		}
+	}
+	return ""
+}
+
+// encoderForModel returns a cached tokenizer for the model, or nil when the
+// model's tokenizer is unknown or fails to load
+func encoderForModel(modelID string) *tiktoken.Tiktoken {
+	encoding := encodingForModel(modelID)
+	if encoding == "" {
+		return nil
+	}
+
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	if enc, ok := encoders[encoding]; ok {
+		return enc
+	}
+
+	setBpeLoader()
+	enc, err := tiktoken.GetEncoding(encoding)
+	if err != nil {
+		slog.Warn("Failed to load tokenizer, falling back to estimates", "encoding", encoding, "error", err)
+	}
+	// Cache failures too so a broken encoding isn't reloaded on every call.
+	encoders[encoding] = enc
+	return enc
+}
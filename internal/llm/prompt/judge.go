@@ -0,0 +1,10 @@
+package prompt
+
+import _ "embed"
+
+//go:embed judge.md
+var judgePrompt []byte
+
+func JudgePrompt() string {
+	return string(judgePrompt)
+}
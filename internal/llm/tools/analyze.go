@@ -1,14 +1,20 @@
 package tools
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"io"
+	"maps"
+	"math"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -16,8 +22,92 @@ import (
 )
 
 type AnalyzeParams struct {
-	Path string `json:"path"`
-	Type string `json:"type"` // "structure", "complexity", "dependencies", "patterns"
+	Path   string `json:"path"`
+	Type   string `json:"type"`             // "structure", "complexity", "dependencies", "patterns", "metrics", "coverage"
+	Format string `json:"format,omitempty"` // "markdown" (default) or "json"
+	// Also analyze vendored and generated directories such as node_modules and dist
+	IncludeVendored bool `json:"include_vendored,omitempty"`
+	// Files larger than this many bytes are skipped, 0 uses defaultAnalyzeMaxFileSize
+	MaxFileSize int64 `json:"max_file_size,omitempty"`
+	// Go coverage profile to read for coverage analysis instead of running go test
+	CoverageProfile string `json:"coverage_profile,omitempty"`
+	// Statement coverage percentage below which Go files are reported, 0 uses defaultCoverageThreshold
+	CoverageThreshold float64 `json:"coverage_threshold,omitempty"`
+}
+
+// defaultAnalyzeMaxFileSize keeps large binaries and generated blobs that
+// happen to match a source extension from being read into memory
+const defaultAnalyzeMaxFileSize = 10 * 1024 * 1024
+
+// analyzeOptions controls which files a directory analysis reads
+type analyzeOptions struct {
+	includeVendored   bool
+	maxFileSize       int64
+	coverageProfile   string
+	coverageThreshold float64
+}
+
+// errFileTooLarge is returned for files over the analysis size cap
+var errFileTooLarge = errors.New("file exceeds the analysis size limit")
+
+// readAnalysisFile reads a file unless it is larger than maxSize bytes
+func readAnalysisFile(path string, maxSize int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxSize {
+		return nil, fmt.Errorf("%w: %s is %d bytes, limit is %d", errFileTooLarge, path, info.Size(), maxSize)
+	}
+
+	// Guard against files growing between the stat and the read
+	content, err := io.ReadAll(io.LimitReader(f, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > maxSize {
+		return nil, fmt.Errorf("%w: %s is over %d bytes", errFileTooLarge, path, maxSize)
+	}
+	return content, nil
+}
+
+// markPartial records that a directory walk was cut short by cancellation.
+// It reports whether err was a cancellation; other errors are left to the caller.
+func markPartial(result *AnalysisResult, err error) bool {
+	if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	result.Details["partial"] = true
+	result.Suggestions = append(result.Suggestions, "Analysis was cancelled before it finished - results only cover the files visited so far")
+	return true
+}
+
+// analyzeSkipDirs lists vendored, generated and VCS directories that are
+// skipped by directory analysis unless explicitly included
+var analyzeSkipDirs = map[string]bool{
+	".git":         true,
+	".hg":          true,
+	".svn":         true,
+	"node_modules": true,
+	"vendor":       true,
+	"dist":         true,
+	"target":       true,
+	"__pycache__":  true,
+	".venv":        true,
+	"venv":         true,
+	".next":        true,
+}
+
+// skipAnalysisDir reports whether the directory at path, below the analyzed
+// root, should be pruned from a directory walk
+func skipAnalysisDir(root, path string, info os.FileInfo, includeVendored bool) bool {
+	return !includeVendored && info.IsDir() && path != root && analyzeSkipDirs[info.Name()]
 }
 
 type AnalysisResult struct {
@@ -55,8 +145,29 @@ func (t *analyzeTool) Info() ToolInfo {
 				},
 				"type": map[string]any{
 					"type":        "string",
-					"description": "Type of analysis: structure, complexity, dependencies, patterns",
-					"enum":        []string{"structure", "complexity", "dependencies", "patterns"},
+					"description": "Type of analysis: structure, complexity, dependencies, patterns, metrics for a combined repository summary, or coverage to find files with low or no test coverage (directories only)",
+					"enum":        []string{"structure", "complexity", "dependencies", "patterns", "metrics", "coverage"},
+				},
+				"coverage_profile": map[string]any{
+					"type":        "string",
+					"description": "Existing Go coverage profile (go test -coverprofile) within the working directory to use for coverage analysis. Without it, go test runs in the analyzed directory",
+				},
+				"coverage_threshold": map[string]any{
+					"type":        "number",
+					"description": "Report Go files with statement coverage below this percentage (default: 60)",
+				},
+				"include_vendored": map[string]any{
+					"type":        "boolean",
+					"description": "Include vendored, generated and VCS directories (node_modules, vendor, dist, .git, ...) in directory analysis (default: false)",
+				},
+				"max_file_size": map[string]any{
+					"type":        "integer",
+					"description": "Skip files larger than this many bytes (default: 10485760, i.e. 10 MB)",
+				},
+				"format": map[string]any{
+					"type":        "string",
+					"description": "Output format: markdown (default) or json for the full machine-readable result",
+					"enum":        []string{"markdown", "json"},
 				},
 			},
 			"required": []string{"path", "type"},
@@ -79,6 +190,12 @@ func (t *analyzeTool) Run(ctx context.Context, params ToolCall) (ToolResponse, e
 		return NewTextErrorResponse("Path parameter is required"), nil
 	}
 
+	switch analyzeParams.Format {
+	case "", "markdown", "json":
+	default:
+		return NewTextErrorResponse(fmt.Sprintf("Unsupported format: %s (use markdown or json)", analyzeParams.Format)), nil
+	}
+
 	path := analyzeParams.Path
 	if !filepath.IsAbs(path) {
 		path = filepath.Join(t.workingDir, path)
@@ -86,11 +203,15 @@ func (t *analyzeTool) Run(ctx context.Context, params ToolCall) (ToolResponse, e
 
 	// Check permissions
 	sessionID, _ := GetContextValues(ctx)
+	description := fmt.Sprintf("Analyze %s (%s)", path, analyzeParams.Type)
+	if analyzeParams.Type == "coverage" && analyzeParams.CoverageProfile == "" {
+		description = fmt.Sprintf("Run go test -coverprofile in %s to analyze test coverage", path)
+	}
 	if !t.permissions.Request(permission.CreatePermissionRequest{
 		SessionID:   sessionID,
 		ToolCallID:  params.ID,
 		ToolName:    AnalyzeToolName,
-		Description: fmt.Sprintf("Analyze %s (%s)", path, analyzeParams.Type),
+		Description: description,
 		Action:      "analyze:" + analyzeParams.Type,
 		Path:        path,
 		Params:      analyzeParams,
@@ -98,18 +219,35 @@ func (t *analyzeTool) Run(ctx context.Context, params ToolCall) (ToolResponse, e
 		return NewTextErrorResponse("Permission denied"), nil
 	}
 
+	opts := analyzeOptions{
+		includeVendored:   analyzeParams.IncludeVendored,
+		maxFileSize:       analyzeParams.MaxFileSize,
+		coverageProfile:   analyzeParams.CoverageProfile,
+		coverageThreshold: analyzeParams.CoverageThreshold,
+	}
+	if opts.maxFileSize <= 0 {
+		opts.maxFileSize = defaultAnalyzeMaxFileSize
+	}
+
 	// Perform analysis based on type
-	result, err := t.performAnalysis(path, analyzeParams.Type)
+	result, err := t.performAnalysis(ctx, path, analyzeParams.Type, opts)
 	if err != nil {
 		return NewTextErrorResponse(fmt.Sprintf("Analysis failed: %v", err)), nil
 	}
 
 	// Format result
+	if analyzeParams.Format == "json" {
+		output, err := formatAnalysisResultJSON(result)
+		if err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("Failed to encode analysis result: %v", err)), nil
+		}
+		return NewTextResponse(output), nil
+	}
 	output := t.formatAnalysisResult(result)
 	return NewTextResponse(output), nil
 }
 
-func (t *analyzeTool) performAnalysis(path, analysisType string) (*AnalysisResult, error) {
+func (t *analyzeTool) performAnalysis(ctx context.Context, path, analysisType string, opts analyzeOptions) (*AnalysisResult, error) {
 	stat, err := os.Stat(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to access path: %w", err)
@@ -122,17 +260,21 @@ func (t *analyzeTool) performAnalysis(path, analysisType string) (*AnalysisResul
 	}
 
 	if stat.IsDir() {
-		return t.analyzeDirectory(path, analysisType, result)
+		return t.analyzeDirectory(ctx, path, analysisType, opts, result)
 	}
-	return t.analyzeFile(path, analysisType, result)
+	return t.analyzeFile(path, analysisType, opts.maxFileSize, result)
 }
 
-func (t *analyzeTool) analyzeDirectory(dirPath, analysisType string, result *AnalysisResult) (*AnalysisResult, error) {
+func (t *analyzeTool) analyzeDirectory(ctx context.Context, dirPath, analysisType string, opts analyzeOptions, result *AnalysisResult) (*AnalysisResult, error) {
 	switch analysisType {
 	case "structure":
-		return t.analyzeDirectoryStructure(dirPath, result)
+		return t.analyzeDirectoryStructure(ctx, dirPath, opts, result)
 	case "complexity":
-		return t.analyzeDirectoryComplexity(dirPath, result)
+		return t.analyzeDirectoryComplexity(ctx, dirPath, opts, result)
+	case "metrics":
+		return t.analyzeDirectoryMetrics(ctx, dirPath, opts, result)
+	case "coverage":
+		return t.analyzeDirectoryCoverage(ctx, dirPath, opts, result)
 	case "dependencies":
 		return t.analyzeDirectoryDependencies(dirPath, result)
 	case "patterns":
@@ -142,33 +284,41 @@ func (t *analyzeTool) analyzeDirectory(dirPath, analysisType string, result *Ana
 	}
 }
 
-func (t *analyzeTool) analyzeFile(filePath, analysisType string, result *AnalysisResult) (*AnalysisResult, error) {
+func (t *analyzeTool) analyzeFile(filePath, analysisType string, maxFileSize int64, result *AnalysisResult) (*AnalysisResult, error) {
 	ext := strings.ToLower(filepath.Ext(filePath))
 
 	switch analysisType {
 	case "structure":
-		return t.analyzeFileStructure(filePath, ext, result)
+		return t.analyzeFileStructure(filePath, ext, maxFileSize, result)
 	case "complexity":
-		return t.analyzeFileComplexity(filePath, ext, result)
+		return t.analyzeFileComplexity(filePath, ext, maxFileSize, result)
 	case "dependencies":
 		return t.analyzeFileDependencies(filePath, ext, result)
 	case "patterns":
 		return t.analyzeFilePatterns(filePath, ext, result)
+	case "metrics", "coverage":
+		return nil, fmt.Errorf("%s analysis requires a directory", analysisType)
 	default:
 		return nil, fmt.Errorf("unsupported analysis type: %s", analysisType)
 	}
 }
 
-func (t *analyzeTool) analyzeDirectoryStructure(dirPath string, result *AnalysisResult) (*AnalysisResult, error) {
+func (t *analyzeTool) analyzeDirectoryStructure(ctx context.Context, dirPath string, opts analyzeOptions, result *AnalysisResult) (*AnalysisResult, error) {
 	structure := make(map[string]interface{})
 	fileCount := 0
 	dirCount := 0
 	languages := make(map[string]int)
 
 	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 		if err != nil {
 			return nil // Skip errors
 		}
+		if skipAnalysisDir(dirPath, path, info, opts.includeVendored) {
+			return filepath.SkipDir
+		}
 
 		if info.IsDir() {
 			dirCount++
@@ -183,7 +333,7 @@ func (t *analyzeTool) analyzeDirectoryStructure(dirPath string, result *Analysis
 		return nil
 	})
 
-	if err != nil {
+	if err != nil && !markPartial(result, err) {
 		return nil, err
 	}
 
@@ -192,7 +342,7 @@ func (t *analyzeTool) analyzeDirectoryStructure(dirPath string, result *Analysis
 	structure["languages"] = languages
 
 	result.Summary = fmt.Sprintf("Directory contains %d files and %d directories", fileCount, dirCount)
-	result.Details = structure
+	maps.Copy(result.Details, structure)
 
 	// Add suggestions
 	if fileCount > 1000 {
@@ -205,8 +355,8 @@ func (t *analyzeTool) analyzeDirectoryStructure(dirPath string, result *Analysis
 	return result, nil
 }
 
-func (t *analyzeTool) analyzeFileStructure(filePath, ext string, result *AnalysisResult) (*AnalysisResult, error) {
-	content, err := os.ReadFile(filePath)
+func (t *analyzeTool) analyzeFileStructure(filePath, ext string, maxFileSize int64, result *AnalysisResult) (*AnalysisResult, error) {
+	content, err := readAnalysisFile(filePath, maxFileSize)
 	if err != nil {
 		return nil, err
 	}
@@ -289,6 +439,12 @@ func (t *analyzeTool) analyzeGoFileStructure(filePath string, content []byte, re
 		result.Suggestions = append(result.Suggestions, "High number of imports - consider dependency analysis")
 	}
 
+	unused := unusedGoImports(node)
+	structure["unused_imports"] = unused
+	for _, imp := range unused {
+		result.Suggestions = append(result.Suggestions, fmt.Sprintf("Remove unused import %s", imp))
+	}
+
 	return result, nil
 }
 
@@ -336,8 +492,8 @@ func (t *analyzeTool) analyzePythonFileStructure(content []byte, result *Analysi
 	return result, nil
 }
 
-func (t *analyzeTool) analyzeFileComplexity(filePath, ext string, result *AnalysisResult) (*AnalysisResult, error) {
-	content, err := os.ReadFile(filePath)
+func (t *analyzeTool) analyzeFileComplexity(filePath, ext string, maxFileSize int64, result *AnalysisResult) (*AnalysisResult, error) {
+	content, err := readAnalysisFile(filePath, maxFileSize)
 	if err != nil {
 		return nil, err
 	}
@@ -355,18 +511,23 @@ func (t *analyzeTool) analyzeFileComplexity(filePath, ext string, result *Analys
 	}
 
 	// Count control flow statements
-	ifCount := strings.Count(contentStr, "if ") + strings.Count(contentStr, "if(")
-	forCount := strings.Count(contentStr, "for ") + strings.Count(contentStr, "for(")
-	whileCount := strings.Count(contentStr, "while ") + strings.Count(contentStr, "while(")
-	switchCount := strings.Count(contentStr, "switch ") + strings.Count(contentStr, "switch(")
+	flow := fileControlFlow(ext, content)
 
 	complexity["lines_of_code"] = nonEmptyLines
-	complexity["if_statements"] = ifCount
-	complexity["loops"] = forCount + whileCount
-	complexity["switch_statements"] = switchCount
-	complexity["cyclomatic_complexity"] = ifCount + forCount + whileCount + switchCount + 1
-
-	result.Summary = fmt.Sprintf("Cyclomatic complexity: %d", complexity["cyclomatic_complexity"])
+	complexity["if_statements"] = flow.ifs
+	complexity["loops"] = flow.loops
+	complexity["switch_statements"] = flow.switches
+	cyclomatic := flow.cyclomatic()
+	complexity["cyclomatic_complexity"] = cyclomatic
+
+	volume := halsteadVolume(ext, content)
+	mi := maintainabilityIndex(volume, cyclomatic, nonEmptyLines)
+	grade := maintainabilityGrade(mi)
+	complexity["halstead_volume"] = math.Round(volume*10) / 10
+	complexity["maintainability_index"] = mi
+	complexity["maintainability_grade"] = grade
+
+	result.Summary = fmt.Sprintf("Cyclomatic complexity: %d, maintainability index: %.1f (grade %s)", cyclomatic, mi, grade)
 	result.Details = complexity
 
 	// Add suggestions based on complexity
@@ -376,46 +537,115 @@ func (t *analyzeTool) analyzeFileComplexity(filePath, ext string, result *Analys
 	if nonEmptyLines > 300 {
 		result.Suggestions = append(result.Suggestions, "Large file - consider splitting into smaller modules")
 	}
+	if grade == "D" || grade == "F" {
+		result.Suggestions = append(result.Suggestions, "Low maintainability index - flatten nested logic and split long functions")
+	}
 
 	return result, nil
 }
 
-func (t *analyzeTool) analyzeDirectoryComplexity(dirPath string, result *AnalysisResult) (*AnalysisResult, error) {
+// fileComplexity is the complexity of a single file in a directory analysis
+type fileComplexity struct {
+	Path        string `json:"path"`
+	Complexity  int    `json:"complexity"`
+	LinesOfCode int    `json:"lines_of_code"`
+}
+
+// String formats the file complexity for Markdown output
+func (f fileComplexity) String() string {
+	return fmt.Sprintf("%s (%d)", f.Path, f.Complexity)
+}
+
+// mostComplexFilesLimit is how many of the most complex files are reported
+const mostComplexFilesLimit = 5
+
+func (t *analyzeTool) analyzeDirectoryComplexity(ctx context.Context, dirPath string, opts analyzeOptions, result *AnalysisResult) (*AnalysisResult, error) {
 	// Analyze complexity across all files in directory
 	totalComplexity := 0
-	fileCount := 0
+	totalLines := 0
+	totalMaintainability := 0.0
+	var files []fileComplexity
+	var skippedLarge []string
 
 	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			return nil
+		}
+		if skipAnalysisDir(dirPath, path, info, opts.includeVendored) {
+			return filepath.SkipDir
+		}
+		if info.IsDir() {
 			return nil
 		}
 
 		ext := strings.ToLower(filepath.Ext(path))
 		if ext == ".go" || ext == ".js" || ext == ".py" || ext == ".ts" {
-			fileResult, err := t.analyzeFileComplexity(path, ext, &AnalysisResult{Details: make(map[string]interface{})})
+			if info.Size() > opts.maxFileSize {
+				relPath, _ := filepath.Rel(dirPath, path)
+				skippedLarge = append(skippedLarge, filepath.ToSlash(relPath))
+				return nil
+			}
+			fileResult, err := t.analyzeFileComplexity(path, ext, opts.maxFileSize, &AnalysisResult{Details: make(map[string]interface{})})
 			if err == nil {
 				if cc, ok := fileResult.Details["cyclomatic_complexity"].(int); ok {
+					loc, _ := fileResult.Details["lines_of_code"].(int)
+					relPath, _ := filepath.Rel(dirPath, path)
+					files = append(files, fileComplexity{Path: filepath.ToSlash(relPath), Complexity: cc, LinesOfCode: loc})
 					totalComplexity += cc
-					fileCount++
+					totalLines += loc
+					mi, _ := fileResult.Details["maintainability_index"].(float64)
+					totalMaintainability += mi
 				}
 			}
 		}
 		return nil
 	})
 
-	if err != nil {
+	if err != nil && !markPartial(result, err) {
 		return nil, err
 	}
 
+	fileCount := len(files)
 	avgComplexity := 0
+	avgMaintainability := 0.0
 	if fileCount > 0 {
 		avgComplexity = totalComplexity / fileCount
+		avgMaintainability = math.Round(totalMaintainability/float64(fileCount)*10) / 10
+	}
+
+	slices.SortStableFunc(files, func(a, b fileComplexity) int {
+		if c := cmp.Compare(b.Complexity, a.Complexity); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+	maxComplexity := 0
+	if fileCount > 0 {
+		maxComplexity = files[0].Complexity
 	}
+	mostComplex := files[:min(mostComplexFilesLimit, fileCount)]
 
 	result.Details["total_complexity"] = totalComplexity
 	result.Details["average_complexity"] = avgComplexity
+	result.Details["max_complexity"] = maxComplexity
+	result.Details["lines_of_code"] = totalLines
 	result.Details["analyzed_files"] = fileCount
+	result.Details["most_complex_files"] = mostComplex
 	result.Summary = fmt.Sprintf("Average complexity: %d across %d files", avgComplexity, fileCount)
+	if fileCount > 0 {
+		grade := maintainabilityGrade(avgMaintainability)
+		result.Details["average_maintainability_index"] = avgMaintainability
+		result.Details["maintainability_grade"] = grade
+		result.Summary += fmt.Sprintf(", average maintainability index %.1f (grade %s)", avgMaintainability, grade)
+	}
+
+	if len(skippedLarge) > 0 {
+		result.Details["skipped_large_files"] = skippedLarge
+		result.Suggestions = append(result.Suggestions, fmt.Sprintf("Skipped %d files larger than %d bytes - raise max_file_size to include them", len(skippedLarge), opts.maxFileSize))
+	}
 
 	if avgComplexity > 15 {
 		result.Suggestions = append(result.Suggestions, "High average complexity - consider code refactoring")
@@ -424,6 +654,44 @@ func (t *analyzeTool) analyzeDirectoryComplexity(dirPath string, result *Analysi
 	return result, nil
 }
 
+// analyzeDirectoryMetrics combines the structure and complexity passes into a
+// single summary of the repository's health
+func (t *analyzeTool) analyzeDirectoryMetrics(ctx context.Context, dirPath string, opts analyzeOptions, result *AnalysisResult) (*AnalysisResult, error) {
+	structure, err := t.analyzeDirectoryStructure(ctx, dirPath, opts, &AnalysisResult{Details: make(map[string]interface{})})
+	if err != nil {
+		return nil, err
+	}
+	complexity, err := t.analyzeDirectoryComplexity(ctx, dirPath, opts, &AnalysisResult{Details: make(map[string]interface{})})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range []string{"total_files", "total_directories", "languages"} {
+		result.Details[key] = structure.Details[key]
+	}
+	for _, key := range []string{"lines_of_code", "analyzed_files", "average_complexity", "max_complexity", "most_complex_files"} {
+		result.Details[key] = complexity.Details[key]
+	}
+	if structure.Details["partial"] != nil || complexity.Details["partial"] != nil {
+		result.Details["partial"] = true
+	}
+	if skipped, ok := complexity.Details["skipped_large_files"]; ok {
+		result.Details["skipped_large_files"] = skipped
+	}
+
+	result.Summary = fmt.Sprintf("%d files in %d directories, %d lines of code, average complexity %d (max %d)",
+		structure.Details["total_files"], structure.Details["total_directories"],
+		complexity.Details["lines_of_code"], complexity.Details["average_complexity"], complexity.Details["max_complexity"])
+	for _, suggestion := range append(structure.Suggestions, complexity.Suggestions...) {
+		// Both passes note a cancellation
+		if !slices.Contains(result.Suggestions, suggestion) {
+			result.Suggestions = append(result.Suggestions, suggestion)
+		}
+	}
+
+	return result, nil
+}
+
 func (t *analyzeTool) analyzeFileDependencies(filePath, ext string, result *AnalysisResult) (*AnalysisResult, error) {
 	// Implement dependency analysis for different file types
 	result.Summary = "Dependency analysis not yet implemented for this file type"
@@ -448,6 +716,25 @@ func (t *analyzeTool) analyzeDirectoryPatterns(dirPath string, result *AnalysisR
 	return result, nil
 }
 
+// formatAnalysisResultJSON encodes the full analysis result. Empty details and
+// suggestions are encoded as an empty object and array rather than null, so
+// consumers can rely on their shape.
+func formatAnalysisResultJSON(result *AnalysisResult) (string, error) {
+	encoded := *result
+	if encoded.Details == nil {
+		encoded.Details = map[string]interface{}{}
+	}
+	if encoded.Suggestions == nil {
+		encoded.Suggestions = []string{}
+	}
+
+	data, err := json.MarshalIndent(encoded, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
 func (t *analyzeTool) formatAnalysisResult(result *AnalysisResult) string {
 	var output strings.Builder
 
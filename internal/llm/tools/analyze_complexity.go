@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// controlFlow counts the branching statements behind cyclomatic complexity
+type controlFlow struct {
+	ifs      int
+	loops    int
+	switches int
+}
+
+// cyclomatic returns the cyclomatic complexity: one plus every branch point
+func (c controlFlow) cyclomatic() int {
+	return c.ifs + c.loops + c.switches + 1
+}
+
+// goControlFlow counts the control flow statements of Go source from its AST
+func goControlFlow(content []byte) (controlFlow, bool) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", content, parser.SkipObjectResolution)
+	if err != nil {
+		return controlFlow{}, false
+	}
+
+	var flow controlFlow
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.IfStmt:
+			flow.ifs++
+		case *ast.ForStmt, *ast.RangeStmt:
+			flow.loops++
+		case *ast.SwitchStmt, *ast.TypeSwitchStmt:
+			flow.switches++
+		}
+		return true
+	})
+	return flow, true
+}
+
+// controlFlowKeywordPattern matches a control flow keyword starting a
+// statement, followed by a space or an opening parenthesis
+var controlFlowKeywordPattern = regexp.MustCompile(`\b(if|elif|for|while|switch)[ (]`)
+
+// heuristicControlFlow counts control flow keywords in source of any C-like or
+// script language, ignoring those in comments and string literals
+func heuristicControlFlow(ext string, content []byte) controlFlow {
+	var flow controlFlow
+	for _, match := range controlFlowKeywordPattern.FindAllStringSubmatch(stripCommentsAndStrings(ext, string(content)), -1) {
+		switch match[1] {
+		case "if", "elif":
+			flow.ifs++
+		case "for", "while":
+			flow.loops++
+		case "switch":
+			flow.switches++
+		}
+	}
+	return flow
+}
+
+// fileControlFlow counts the control flow statements of a file, using the Go
+// AST for Go sources that parse and the keyword heuristic otherwise
+func fileControlFlow(ext string, content []byte) controlFlow {
+	if ext == ".go" {
+		if flow, ok := goControlFlow(content); ok {
+			return flow
+		}
+	}
+	return heuristicControlFlow(ext, content)
+}
+
+// hashCommentExts are the languages whose line comments start with '#'
+// rather than "//"
+var hashCommentExts = map[string]bool{
+	".py": true, ".rb": true, ".sh": true, ".bash": true, ".zsh": true,
+	".pl": true, ".r": true, ".yaml": true, ".yml": true, ".toml": true,
+}
+
+// stripCommentsAndStrings replaces the comments and string literals of source
+// with a space each, keeping line breaks. Languages in hashCommentExts use '#'
+// line comments and Python's triple-quoted strings; all others use "//" and
+// "/* */" comments. Quotes, apostrophes and backticks delimit strings, and
+// backslashes escape within them.
+func stripCommentsAndStrings(ext, source string) string {
+	hashComments := hashCommentExts[ext]
+
+	var out strings.Builder
+	out.Grow(len(source))
+	for i := 0; i < len(source); {
+		c := source[i]
+		rest := source[i:]
+		switch {
+		case hashComments && c == '#', !hashComments && strings.HasPrefix(rest, "//"):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			out.WriteByte(' ')
+			i += end
+		case !hashComments && strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				end = len(rest)
+			} else {
+				end += 4
+			}
+			out.WriteByte(' ')
+			out.WriteString(strings.Repeat("\n", strings.Count(rest[:end], "\n")))
+			i += end
+		case hashComments && (strings.HasPrefix(rest, `"""`) || strings.HasPrefix(rest, "'''")):
+			end := strings.Index(rest[3:], rest[:3])
+			if end < 0 {
+				end = len(rest)
+			} else {
+				end += 6
+			}
+			out.WriteByte(' ')
+			out.WriteString(strings.Repeat("\n", strings.Count(rest[:end], "\n")))
+			i += end
+		case c == '"' || c == '\'' || c == '`':
+			end := stringLiteralEnd(rest)
+			out.WriteByte(' ')
+			out.WriteString(strings.Repeat("\n", strings.Count(rest[:end], "\n")))
+			i += end
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.String()
+}
+
+// stringLiteralEnd returns the length of the string literal at the start of
+// s. Only backtick strings span lines, so an unterminated quote ends at the
+// line break.
+func stringLiteralEnd(s string) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote != '`':
+			i++
+		case s[i] == quote:
+			return i + 1
+		case s[i] == '\n' && quote != '`':
+			return i
+		}
+	}
+	return len(s)
+}
@@ -0,0 +1,326 @@
+package tools
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// defaultCoverageThreshold is the statement coverage percentage below which a
+// Go file is reported
+const defaultCoverageThreshold = 60.0
+
+// coverageSuggestionLimit caps how many files are named in suggestions; the
+// details always list all of them
+const coverageSuggestionLimit = 10
+
+// fileCoverageResult is the statement coverage of a single Go file
+type fileCoverageResult struct {
+	Path       string  `json:"path"`
+	Coverage   float64 `json:"coverage"`
+	Statements int     `json:"statements"`
+}
+
+// String formats the file coverage for Markdown output
+func (f fileCoverageResult) String() string {
+	return fmt.Sprintf("%s (%.1f%%)", f.Path, f.Coverage)
+}
+
+// coverageBlock identifies a block in a coverage profile. Profiles merged from
+// several test binaries list the same block more than once.
+type coverageBlock struct {
+	file     string
+	position string
+}
+
+// parseCoverageProfile reads a Go coverage profile, as written by
+// go test -coverprofile, into per-file statement counts
+func parseCoverageProfile(r io.Reader) (map[string]*fileCoverageResult, error) {
+	statements := make(map[coverageBlock]int)
+	covered := make(map[coverageBlock]bool)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "mode:") {
+			continue
+		}
+
+		// import/path/file.go:startLine.startCol,endLine.endCol numStmts count
+		fields := strings.Fields(text)
+		// The line itself isn't quoted, as the file may not be a profile at all
+		if len(fields) != 3 || !strings.Contains(fields[0], ":") {
+			return nil, fmt.Errorf("invalid coverage profile line %d: expected file:position statements count", line)
+		}
+		colon := strings.LastIndexByte(fields[0], ':')
+		numStmts, err1 := strconv.Atoi(fields[1])
+		count, err2 := strconv.Atoi(fields[2])
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid coverage profile line %d: statement and hit counts must be integers", line)
+		}
+
+		block := coverageBlock{file: fields[0][:colon], position: fields[0][colon+1:]}
+		statements[block] = numStmts
+		covered[block] = covered[block] || count > 0
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read coverage profile: %w", err)
+	}
+
+	files := make(map[string]*fileCoverageResult)
+	coveredStatements := make(map[string]int)
+	for block, numStmts := range statements {
+		file := files[block.file]
+		if file == nil {
+			file = &fileCoverageResult{Path: block.file}
+			files[block.file] = file
+		}
+		file.Statements += numStmts
+		if covered[block] {
+			coveredStatements[block.file] += numStmts
+		}
+	}
+	for name, file := range files {
+		file.Coverage = 100
+		if file.Statements > 0 {
+			file.Coverage = math.Round(float64(coveredStatements[name])*1000/float64(file.Statements)) / 10
+		}
+	}
+	return files, nil
+}
+
+// goModulePath returns the module path declared by dir's go.mod, or "" if it
+// has none
+func goModulePath(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	for line := range strings.Lines(string(data)) {
+		if path, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+			return strings.Trim(strings.TrimSpace(path), `"`)
+		}
+	}
+	return ""
+}
+
+// runGoCoverage runs the module's tests and returns the coverage profile they
+// wrote. Failing tests still produce a profile for the packages that ran, so
+// only a missing profile is an error.
+func runGoCoverage(ctx context.Context, dir string) ([]byte, error) {
+	profile, err := os.CreateTemp("", "crush-coverage-*.out")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create coverage profile: %w", err)
+	}
+	profile.Close()
+	defer os.Remove(profile.Name())
+
+	cmd := exec.CommandContext(ctx, "go", "test", "-coverprofile="+profile.Name(), "./...")
+	cmd.Dir = dir
+	output, runErr := cmd.CombinedOutput()
+
+	data, err := os.ReadFile(profile.Name())
+	if err != nil || len(data) == 0 {
+		if runErr != nil {
+			return nil, fmt.Errorf("go test failed: %w\n%s", runErr, truncateOutput(string(output)))
+		}
+		return nil, fmt.Errorf("go test wrote no coverage profile")
+	}
+	return data, nil
+}
+
+var (
+	// testFilePrefixes and testFileSuffixes mark a file as the test of the
+	// source file with the remaining name, e.g. test_app.py or app.test.js
+	testFilePrefixes = []string{"test_"}
+	testFileSuffixes = []string{".test", ".spec", "_test", "_spec", "Test", "Tests", "Spec"}
+)
+
+// testedSourceName returns the name of the source file a test file covers,
+// and false for files that aren't tests by naming convention. Files inside a
+// __tests__, test, tests or spec directory are tests of the file with the
+// same name.
+func testedSourceName(relPath string) (string, bool) {
+	ext := filepath.Ext(relPath)
+	stem := strings.TrimSuffix(filepath.Base(relPath), ext)
+	for _, prefix := range testFilePrefixes {
+		if name, ok := strings.CutPrefix(stem, prefix); ok && name != "" {
+			return name + ext, true
+		}
+	}
+	for _, suffix := range testFileSuffixes {
+		if name, ok := strings.CutSuffix(stem, suffix); ok && name != "" {
+			return name + ext, true
+		}
+	}
+	for dir := range strings.SplitSeq(filepath.ToSlash(filepath.Dir(relPath)), "/") {
+		switch dir {
+		case "__tests__", "test", "tests", "spec":
+			return filepath.Base(relPath), true
+		}
+	}
+	return "", false
+}
+
+// coverageSourceExts are the non-Go languages checked for test files
+var coverageSourceExts = map[string]bool{
+	".js": true, ".jsx": true, ".ts": true, ".tsx": true, ".mjs": true,
+	".py": true, ".rb": true, ".java": true, ".kt": true, ".rs": true,
+	".php": true, ".cs": true, ".swift": true,
+}
+
+// analyzeDirectoryCoverage reports Go files whose statement coverage is below
+// the threshold, from the given profile or by running go test, and source
+// files of other languages that have no test file by naming convention
+func (t *analyzeTool) analyzeDirectoryCoverage(ctx context.Context, dirPath string, opts analyzeOptions, result *AnalysisResult) (*AnalysisResult, error) {
+	threshold := cmp.Or(opts.coverageThreshold, defaultCoverageThreshold)
+	result.Details["coverage_threshold"] = threshold
+
+	// Walk the tree for Go sources and for other sources and their tests
+	var goFiles, sources []string
+	tested := make(map[string]bool)
+	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			return nil
+		}
+		if skipAnalysisDir(dirPath, path, info, opts.includeVendored) {
+			return filepath.SkipDir
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, _ := filepath.Rel(dirPath, path)
+		relPath = filepath.ToSlash(relPath)
+		ext := strings.ToLower(filepath.Ext(path))
+		switch {
+		case ext == ".go":
+			if !strings.HasSuffix(relPath, "_test.go") {
+				goFiles = append(goFiles, relPath)
+			}
+		case coverageSourceExts[ext]:
+			if name, ok := testedSourceName(relPath); ok {
+				tested[name] = true
+			} else {
+				sources = append(sources, relPath)
+			}
+		}
+		return nil
+	})
+	if err != nil && !markPartial(result, err) {
+		return nil, err
+	}
+
+	var suggestions []string
+	if len(goFiles) > 0 || opts.coverageProfile != "" {
+		below, err := t.goCoverage(ctx, dirPath, opts.coverageProfile, threshold, result)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range below {
+			suggestions = append(suggestions, fmt.Sprintf("Add tests for %s (%.1f%% of statements covered)", file.Path, file.Coverage))
+		}
+	}
+
+	var untested []string
+	for _, source := range sources {
+		if !tested[filepath.Base(source)] {
+			untested = append(untested, source)
+			suggestions = append(suggestions, fmt.Sprintf("Add tests for %s (no test file found)", source))
+		}
+	}
+	if len(sources) > 0 {
+		result.Details["source_files"] = len(sources)
+		result.Details["untested_files"] = untested
+		summary := fmt.Sprintf("%d of %d other source files have no test file", len(untested), len(sources))
+		if result.Summary != "" {
+			summary = result.Summary + "; " + summary
+		}
+		result.Summary = summary
+	}
+	if result.Summary == "" {
+		result.Summary = "No source files found to check for tests"
+	}
+
+	if len(suggestions) > coverageSuggestionLimit {
+		more := len(suggestions) - coverageSuggestionLimit
+		suggestions = append(suggestions[:coverageSuggestionLimit], fmt.Sprintf("...and %d more files with missing or low coverage", more))
+	}
+	result.Suggestions = append(result.Suggestions, suggestions...)
+	return result, nil
+}
+
+// goCoverage records the Go statement coverage of dirPath in result and
+// returns the files below threshold, least covered first
+func (t *analyzeTool) goCoverage(ctx context.Context, dirPath, profilePath string, threshold float64, result *AnalysisResult) ([]fileCoverageResult, error) {
+	var data []byte
+	var err error
+	if profilePath != "" {
+		profilePath, err = ValidatePathSecurity(profilePath, t.workingDir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid coverage profile path: %w", err)
+		}
+		data, err = os.ReadFile(profilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read coverage profile: %w", err)
+		}
+	} else {
+		data, err = runGoCoverage(ctx, dirPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	profile, err := parseCoverageProfile(strings.NewReader(string(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	// Profiles name files by import path; report them relative to dirPath
+	modulePath := goModulePath(dirPath)
+	var files, below []fileCoverageResult
+	totalStatements, coveredStatements := 0, 0.0
+	for _, file := range profile {
+		if modulePath != "" {
+			if rel, ok := strings.CutPrefix(file.Path, modulePath+"/"); ok {
+				file.Path = rel
+			}
+		}
+		files = append(files, *file)
+		totalStatements += file.Statements
+		coveredStatements += file.Coverage * float64(file.Statements) / 100
+		if file.Coverage < threshold {
+			below = append(below, *file)
+		}
+	}
+	byCoverage := func(a, b fileCoverageResult) int {
+		return cmp.Or(cmp.Compare(a.Coverage, b.Coverage), strings.Compare(a.Path, b.Path))
+	}
+	slices.SortFunc(files, byCoverage)
+	slices.SortFunc(below, byCoverage)
+
+	total := 100.0
+	if totalStatements > 0 {
+		total = math.Round(coveredStatements*1000/float64(totalStatements)) / 10
+	}
+	result.Details["go_coverage"] = total
+	result.Details["go_files"] = files
+	result.Details["files_below_threshold"] = below
+	result.Summary = fmt.Sprintf("Go statement coverage %.1f%% across %d files, %d below %.0f%%", total, len(files), len(below), threshold)
+	return below, nil
+}
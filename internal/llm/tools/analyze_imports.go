@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"go/ast"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// unusedImport is an import of a Go file that is never referenced
+type unusedImport struct {
+	Path string `json:"path"`
+	Name string `json:"name"`
+}
+
+// String formats the import the way it is written in source
+func (u unusedImport) String() string {
+	if u.Name != guessImportName(u.Path) {
+		return u.Name + " " + strconv.Quote(u.Path)
+	}
+	return strconv.Quote(u.Path)
+}
+
+// majorVersionPattern matches the major version element of a module path
+// ("v2") or the version suffix of a gopkg.in path ("yaml.v3")
+var majorVersionPattern = regexp.MustCompile(`(^|\.)v\d+$`)
+
+// guessImportName guesses the package name of an import from its path, as
+// goimports does: the last path element without a major version or a go-
+// prefix or -go suffix
+func guessImportName(path string) string {
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	if majorVersionPattern.MatchString(name) {
+		if trimmed := majorVersionPattern.ReplaceAllString(name, ""); trimmed != "" {
+			name = trimmed
+		} else if len(elems) > 1 {
+			name = elems[len(elems)-2]
+		}
+	}
+	name = strings.TrimPrefix(name, "go-")
+	name = strings.TrimSuffix(strings.TrimSuffix(name, "-go"), ".go")
+	return name
+}
+
+// unusedGoImports returns the imports of a parsed Go file that are never
+// referenced, in import order. Blank and dot imports are skipped. Without
+// type information the name of an unnamed import is guessed from its path, so
+// when the file qualifies identifiers with a name no import accounts for, the
+// guess may be wrong and only named imports are reported. The file must be
+// parsed with object resolution, which tells package qualifiers apart from
+// local identifiers.
+func unusedGoImports(file *ast.File) []unusedImport {
+	// Count the qualifiers of selector expressions that resolve to nothing in
+	// the file, which is how package references look
+	qualifiers := make(map[string]int)
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && len(gen.Specs) > 0 {
+			if _, isImport := gen.Specs[0].(*ast.ImportSpec); isImport {
+				continue
+			}
+		}
+		ast.Inspect(decl, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if ident, ok := sel.X.(*ast.Ident); ok && ident.Obj == nil {
+					qualifiers[ident.Name]++
+				}
+			}
+			return true
+		})
+	}
+
+	unused := []unusedImport{}
+	explicit := make(map[string]bool)
+	imported := make(map[string]bool)
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := guessImportName(path)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name == "_" || name == "." {
+			continue
+		}
+
+		imported[name] = true
+		if qualifiers[name] == 0 {
+			unused = append(unused, unusedImport{Path: path, Name: name})
+			explicit[path] = spec.Name != nil
+		}
+	}
+
+	for qualifier := range qualifiers {
+		if !imported[qualifier] {
+			return slices.DeleteFunc(unused, func(u unusedImport) bool { return !explicit[u.Path] })
+		}
+	}
+	return unused
+}
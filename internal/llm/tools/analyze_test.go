@@ -0,0 +1,671 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/stretchr/testify/require"
+)
+
+func runAnalyze(t *testing.T, workingDir string, params AnalyzeParams) ToolResponse {
+	t.Helper()
+
+	input, err := json.Marshal(params)
+	require.NoError(t, err)
+
+	tool := NewAnalyzeTool(permission.NewPermissionService(workingDir, true, nil), workingDir)
+	resp, err := tool.Run(context.Background(), ToolCall{Name: AnalyzeToolName, Input: string(input)})
+	require.NoError(t, err)
+	return resp
+}
+
+// writeFiles creates the given files, relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+const complexGoSource = `package main
+
+func classify(n int) string {
+	if n < 0 {
+		return "negative"
+	}
+	for i := 0; i < n; i++ {
+		if i%2 == 0 {
+			continue
+		}
+	}
+	switch n {
+	case 0:
+		return "zero"
+	}
+	return "positive"
+}
+`
+
+func TestAnalyzeJSONFormat(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":     complexGoSource,
+		"web/app.js":  "function main() {}\n",
+		"README.md":   "# Project\n",
+		"docs/api.md": "# API\n",
+	})
+
+	resp := runAnalyze(t, dir, AnalyzeParams{Path: ".", Type: "structure", Format: "json"})
+	require.False(t, resp.IsError, resp.Content)
+
+	var result struct {
+		Type    string `json:"type"`
+		Summary string `json:"summary"`
+		Details struct {
+			TotalFiles int            `json:"total_files"`
+			Languages  map[string]int `json:"languages"`
+		} `json:"details"`
+		Suggestions []string `json:"suggestions"`
+		Timestamp   string   `json:"timestamp"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(resp.Content), &result))
+	require.Equal(t, "structure", result.Type)
+	require.Equal(t, 4, result.Details.TotalFiles)
+	require.Equal(t, map[string]int{".go": 1, ".js": 1, ".md": 2}, result.Details.Languages)
+	require.NotNil(t, result.Suggestions)
+	require.NotEmpty(t, result.Timestamp)
+
+	resp = runAnalyze(t, dir, AnalyzeParams{Path: "main.go", Type: "complexity", Format: "json"})
+	require.False(t, resp.IsError, resp.Content)
+
+	var complexity AnalysisResult
+	require.NoError(t, json.Unmarshal([]byte(resp.Content), &complexity))
+	require.EqualValues(t, 5, complexity.Details["cyclomatic_complexity"])
+}
+
+func TestAnalyzeFormats(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"main.go": complexGoSource})
+
+	resp := runAnalyze(t, dir, AnalyzeParams{Path: "main.go", Type: "complexity"})
+	require.False(t, resp.IsError)
+	require.Contains(t, resp.Content, "# Complexity Analysis")
+
+	resp = runAnalyze(t, dir, AnalyzeParams{Path: "main.go", Type: "complexity", Format: "xml"})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "Unsupported format")
+}
+
+func TestAnalyzeSkipsVendoredDirectories(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":                   "package main\n\nfunc main() {}\n",
+		"vendor/lib/complex.go":     complexGoSource,
+		"vendor/lib/more.go":        complexGoSource,
+		"node_modules/pkg/index.js": "if (a) { for (;;) {} }\n",
+		"dist/bundle.js":            "if (a) {}\n",
+		".git/hooks/pre-commit.py":  "if x:\n    pass\n",
+		"pkg/vendor/nested.go":      complexGoSource,
+	})
+
+	complexity := func(includeVendored bool) AnalysisResult {
+		resp := runAnalyze(t, dir, AnalyzeParams{Path: ".", Type: "complexity", Format: "json", IncludeVendored: includeVendored})
+		require.False(t, resp.IsError, resp.Content)
+		var result AnalysisResult
+		require.NoError(t, json.Unmarshal([]byte(resp.Content), &result))
+		return result
+	}
+
+	// Only main.go is analyzed, nested vendor directories are pruned too
+	result := complexity(false)
+	require.EqualValues(t, 1, result.Details["analyzed_files"])
+	require.EqualValues(t, 1, result.Details["total_complexity"])
+
+	result = complexity(true)
+	require.EqualValues(t, 7, result.Details["analyzed_files"])
+
+	resp := runAnalyze(t, dir, AnalyzeParams{Path: ".", Type: "structure", Format: "json"})
+	require.False(t, resp.IsError, resp.Content)
+	var structure AnalysisResult
+	require.NoError(t, json.Unmarshal([]byte(resp.Content), &structure))
+	require.EqualValues(t, 1, structure.Details["total_files"])
+	require.Equal(t, map[string]any{".go": float64(1)}, structure.Details["languages"])
+}
+
+func TestAnalyzeMetrics(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":        "package main\n\nfunc main() {}\n",
+		"cmd/complex.go": complexGoSource,
+		"web/app.js":     "if (a) {\n  run()\n}\n",
+		"scripts/x.py":   "for x in y:\n    if x:\n        pass\n",
+		"README.md":      "# Project\n",
+		"vendor/dep.go":  complexGoSource,
+	}
+	for i := range 5 {
+		files[filepath.Join("pkg", string(rune('a'+i))+".go")] = "package pkg\n"
+	}
+	writeFiles(t, dir, files)
+
+	resp := runAnalyze(t, dir, AnalyzeParams{Path: ".", Type: "metrics", Format: "json"})
+	require.False(t, resp.IsError, resp.Content)
+
+	var result struct {
+		Type    string `json:"type"`
+		Summary string `json:"summary"`
+		Details struct {
+			TotalFiles        int              `json:"total_files"`
+			TotalDirectories  int              `json:"total_directories"`
+			Languages         map[string]int   `json:"languages"`
+			LinesOfCode       int              `json:"lines_of_code"`
+			AnalyzedFiles     int              `json:"analyzed_files"`
+			AverageComplexity int              `json:"average_complexity"`
+			MaxComplexity     int              `json:"max_complexity"`
+			MostComplexFiles  []fileComplexity `json:"most_complex_files"`
+		} `json:"details"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(resp.Content), &result))
+
+	details := result.Details
+	require.Equal(t, "metrics", result.Type)
+	require.Equal(t, 10, details.TotalFiles)
+	require.Equal(t, 5, details.TotalDirectories)
+	require.Equal(t, map[string]int{".go": 7, ".js": 1, ".py": 1, ".md": 1}, details.Languages)
+	require.Equal(t, 9, details.AnalyzedFiles)
+	require.Equal(t, 2+16+3+3+5, details.LinesOfCode)
+	require.Equal(t, 5, details.MaxComplexity)
+	require.Equal(t, (5+3+2+1+5)/9, details.AverageComplexity)
+	require.Len(t, details.MostComplexFiles, mostComplexFilesLimit)
+	require.Equal(t, fileComplexity{Path: "cmd/complex.go", Complexity: 5, LinesOfCode: 16}, details.MostComplexFiles[0])
+	require.Equal(t, "scripts/x.py", details.MostComplexFiles[1].Path)
+	require.Equal(t, "web/app.js", details.MostComplexFiles[2].Path)
+	require.NotEmpty(t, result.Summary)
+
+	resp = runAnalyze(t, dir, AnalyzeParams{Path: "main.go", Type: "metrics"})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "requires a directory")
+
+	resp = runAnalyze(t, dir, AnalyzeParams{Path: ".", Type: "metrics"})
+	require.False(t, resp.IsError)
+	require.Contains(t, resp.Content, "cmd/complex.go (5)")
+}
+
+// cancelAfterContext reports cancellation once Err has been checked n times,
+// simulating a cancel that arrives in the middle of a directory walk
+type cancelAfterContext struct {
+	context.Context
+	remaining int
+}
+
+func (c *cancelAfterContext) Err() error {
+	if c.remaining <= 0 {
+		return context.Canceled
+	}
+	c.remaining--
+	return nil
+}
+
+func TestAnalyzeHonorsCancellation(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := make(map[string]string)
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		files[name+".go"] = complexGoSource
+	}
+	writeFiles(t, dir, files)
+	tool := &analyzeTool{permissions: permission.NewPermissionService(dir, true, nil), workingDir: dir}
+	opts := analyzeOptions{maxFileSize: defaultAnalyzeMaxFileSize}
+
+	// The root and two files are visited before the cancel arrives
+	result, err := tool.performAnalysis(&cancelAfterContext{Context: t.Context(), remaining: 3}, dir, "structure", opts)
+	require.NoError(t, err)
+	require.Equal(t, true, result.Details["partial"])
+	require.Equal(t, 2, result.Details["total_files"])
+	require.Contains(t, result.Suggestions[len(result.Suggestions)-1], "cancelled")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	result, err = tool.performAnalysis(ctx, dir, "metrics", opts)
+	require.NoError(t, err)
+	require.Equal(t, true, result.Details["partial"])
+	require.Equal(t, 0, result.Details["analyzed_files"])
+	require.Len(t, result.Suggestions, 1, "the cancellation note is only reported once")
+
+	// Analyses that finish are not marked partial
+	result, err = tool.performAnalysis(t.Context(), dir, "complexity", opts)
+	require.NoError(t, err)
+	require.NotContains(t, result.Details, "partial")
+	require.Equal(t, 6, result.Details["analyzed_files"])
+}
+
+func TestAnalyzeSkipsOversizedFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":      complexGoSource,
+		"generated.go": "package main\n\n" + strings.Repeat("var x = 1 // if for switch\n", 100),
+	})
+
+	resp := runAnalyze(t, dir, AnalyzeParams{Path: ".", Type: "complexity", Format: "json", MaxFileSize: 1024})
+	require.False(t, resp.IsError, resp.Content)
+
+	var result struct {
+		Details struct {
+			AnalyzedFiles     int      `json:"analyzed_files"`
+			TotalComplexity   int      `json:"total_complexity"`
+			SkippedLargeFiles []string `json:"skipped_large_files"`
+		} `json:"details"`
+		Suggestions []string `json:"suggestions"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(resp.Content), &result))
+	require.Equal(t, 1, result.Details.AnalyzedFiles)
+	require.Equal(t, 5, result.Details.TotalComplexity)
+	require.Equal(t, []string{"generated.go"}, result.Details.SkippedLargeFiles)
+	require.Contains(t, strings.Join(result.Suggestions, "\n"), "max_file_size")
+
+	// A single oversized file is reported instead of being read
+	resp = runAnalyze(t, dir, AnalyzeParams{Path: "generated.go", Type: "structure", MaxFileSize: 1024})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "exceeds the analysis size limit")
+
+	// The default limit is large enough for ordinary sources
+	resp = runAnalyze(t, dir, AnalyzeParams{Path: "generated.go", Type: "structure"})
+	require.False(t, resp.IsError, resp.Content)
+}
+
+// deeplyNestedGoSource returns a Go file whose functions nest loops and
+// conditionals several levels deep
+func deeplyNestedGoSource(functions int) string {
+	var b strings.Builder
+	b.WriteString("package nested\n")
+	for i := range functions {
+		fmt.Fprintf(&b, `
+func process%d(items [][]int, limit int) (int, error) {
+	total := 0
+	for i, row := range items {
+		if len(row) == 0 {
+			continue
+		}
+		for j, value := range row {
+			if value < 0 {
+				if i > j {
+					return 0, fmt.Errorf("negative value %%d at %%d,%%d", value, i, j)
+				}
+				switch {
+				case value < -limit:
+					total -= limit
+				case value%%2 == 0:
+					total += value / 2
+				default:
+					for k := 0; k < -value; k++ {
+						if total > limit {
+							break
+						}
+						total++
+					}
+				}
+			} else if value > limit {
+				total += limit
+			} else {
+				total += value
+			}
+		}
+	}
+	return total, nil
+}
+`, i)
+	}
+	return b.String()
+}
+
+func TestAnalyzeMaintainabilityIndex(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"simple.go": "package simple\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n",
+		"nested.go": deeplyNestedGoSource(12),
+		"simple.py": "def add(a, b):\n    return a + b\n",
+	})
+
+	analyze := func(path string) AnalysisResult {
+		t.Helper()
+		resp := runAnalyze(t, dir, AnalyzeParams{Path: path, Type: "complexity", Format: "json"})
+		require.False(t, resp.IsError, resp.Content)
+		var result AnalysisResult
+		require.NoError(t, json.Unmarshal([]byte(resp.Content), &result))
+		return result
+	}
+
+	simple := analyze("simple.go")
+	require.Equal(t, "A", simple.Details["maintainability_grade"])
+	require.Contains(t, simple.Summary, "(grade A)")
+
+	nested := analyze("nested.go")
+	require.Contains(t, []any{"D", "F"}, nested.Details["maintainability_grade"])
+	require.Less(t, nested.Details["maintainability_index"], simple.Details["maintainability_index"])
+	require.Greater(t, nested.Details["halstead_volume"], simple.Details["halstead_volume"])
+	require.Contains(t, nested.Suggestions, "Low maintainability index - flatten nested logic and split long functions")
+
+	// Other languages fall back to the token heuristic
+	python := analyze("simple.py")
+	require.Equal(t, "A", python.Details["maintainability_grade"])
+
+	directory := analyze(".")
+	require.Contains(t, directory.Details, "average_maintainability_index")
+	require.Contains(t, directory.Summary, "average maintainability index")
+}
+
+func TestHalsteadVolume(t *testing.T) {
+	t.Parallel()
+
+	// The volume grows with the size of the program
+	small := halsteadVolume(".go", []byte("package p\n\nfunc f(a, b int) int { return a + b }\n"))
+	larger := halsteadVolume(".go", []byte("package p\n\nfunc f(a, b int) int { return a + b }\n\nfunc g(a, b int) int { return a*b - f(a, b) }\n"))
+	require.Greater(t, small, 0.0)
+	require.Greater(t, larger, small)
+
+	// Unparseable Go falls back to the heuristic instead of reporting zero
+	require.Greater(t, halsteadVolume(".go", []byte("func broken( {")), 0.0)
+	require.Equal(t, 0.0, halsteadVolume(".js", nil))
+}
+
+func TestComplexityIgnoresCommentsAndStrings(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		ext      string
+		source   string
+		expected controlFlow
+	}{
+		"javascript": {
+			ext: ".js",
+			source: `// if you do this, the for loop breaks
+/* while (true) { switch (x) {} }
+   if (a) */
+function run(items) {
+  const label = "for loop";
+  const note = 'if (ok)';
+  const tpl = ` + "`while ${x}`" + `;
+  if (items.length) {
+    for (const item of items) {
+      console.log(item); // while here
+    }
+  }
+}
+`,
+			expected: controlFlow{ifs: 1, loops: 1},
+		},
+		"python": {
+			ext: ".py",
+			source: `# if this fails, for each item retry
+def run(items):
+    """Run every item.
+
+    for item in items: if item: while True
+    """
+    label = "for loop"
+    if items:
+        for item in items:
+            pass
+    elif label == 'while ':
+        pass
+`,
+			expected: controlFlow{ifs: 2, loops: 1},
+		},
+		"go": {
+			ext: ".go",
+			source: `package main
+
+// if the input is empty, for example
+func run(items []string) {
+	label := "for loop"
+	raw := ` + "`switch (x)`" + `
+	for _, item := range items {
+		if item == label || item == raw {
+			return
+		}
+	}
+}
+`,
+			expected: controlFlow{ifs: 1, loops: 1},
+		},
+		"escaped quotes": {
+			ext:      ".ts",
+			source:   "const s = \"say \\\"if (x)\\\" \"; if (s) {}\n",
+			expected: controlFlow{ifs: 1},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.expected, fileControlFlow(tt.ext, []byte(tt.source)))
+		})
+	}
+}
+
+func TestAnalyzeComplexityIgnoresComments(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.js": "// if you do this, for every file\nconst msg = \"while (waiting) switch off\";\nif (msg) { run(); }\n",
+	})
+
+	resp := runAnalyze(t, dir, AnalyzeParams{Path: "app.js", Type: "complexity", Format: "json"})
+	require.False(t, resp.IsError, resp.Content)
+
+	var complexity AnalysisResult
+	require.NoError(t, json.Unmarshal([]byte(resp.Content), &complexity))
+	require.EqualValues(t, 2, complexity.Details["cyclomatic_complexity"])
+	require.EqualValues(t, 0, complexity.Details["loops"])
+}
+
+const sampleCoverageProfile = `mode: set
+example.com/app/main.go:5.13,7.2 2 1
+example.com/app/main.go:9.13,12.2 2 0
+example.com/app/store/store.go:3.20,5.2 1 0
+example.com/app/store/store.go:7.20,10.2 3 0
+example.com/app/store/store.go:7.20,10.2 3 1
+example.com/app/util.go:3.15,4.2 4 1
+`
+
+func TestParseCoverageProfile(t *testing.T) {
+	t.Parallel()
+
+	files, err := parseCoverageProfile(strings.NewReader(sampleCoverageProfile))
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	require.Equal(t, fileCoverageResult{Path: "example.com/app/main.go", Coverage: 50, Statements: 4}, *files["example.com/app/main.go"])
+	// A block merged from two test binaries counts once, covered by either
+	require.Equal(t, fileCoverageResult{Path: "example.com/app/store/store.go", Coverage: 75, Statements: 4}, *files["example.com/app/store/store.go"])
+	require.Equal(t, 100.0, files["example.com/app/util.go"].Coverage)
+
+	_, err = parseCoverageProfile(strings.NewReader("mode: set\nnot a profile line\n"))
+	require.ErrorContains(t, err, "line 2")
+	require.NotContains(t, err.Error(), "not a profile line")
+
+	_, err = parseCoverageProfile(strings.NewReader("a.go:1.1,2.2 one 1\n"))
+	require.ErrorContains(t, err, "line 1")
+	require.NotContains(t, err.Error(), "a.go")
+}
+
+func TestTestedSourceName(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"web/app.test.js":          "app.js",
+		"web/app.spec.ts":          "app.ts",
+		"pkg/test_models.py":       "models.py",
+		"pkg/models_test.py":       "models.py",
+		"src/__tests__/button.tsx": "button.tsx",
+		"tests/helpers.py":         "helpers.py",
+		"src/UserServiceTest.java": "UserService.java",
+		"lib/parser_spec.rb":       "parser.rb",
+	}
+	for path, expected := range tests {
+		name, ok := testedSourceName(path)
+		require.True(t, ok, path)
+		require.Equal(t, expected, name, path)
+	}
+
+	for _, path := range []string{"web/app.js", "pkg/latest.py", "test.py"} {
+		_, ok := testedSourceName(path)
+		require.False(t, ok, path)
+	}
+}
+
+func TestAnalyzeCoverage(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":             "module example.com/app\n\ngo 1.24\n",
+		"main.go":            "package main\n",
+		"util.go":            "package main\n",
+		"store/store.go":     "package store\n",
+		"coverage.out":       sampleCoverageProfile,
+		"web/app.js":         "export const app = 1;\n",
+		"web/app.test.js":    "test('app', () => {});\n",
+		"web/router.js":      "export const router = 1;\n",
+		"scripts/deploy.py":  "print('deploy')\n",
+		"tests/test_util.py": "def test_util(): pass\n",
+	})
+
+	resp := runAnalyze(t, dir, AnalyzeParams{Path: ".", Type: "coverage", Format: "json", CoverageProfile: "coverage.out", CoverageThreshold: 80})
+	require.False(t, resp.IsError, resp.Content)
+
+	var result struct {
+		Summary string `json:"summary"`
+		Details struct {
+			GoCoverage          float64              `json:"go_coverage"`
+			FilesBelowThreshold []fileCoverageResult `json:"files_below_threshold"`
+			UntestedFiles       []string             `json:"untested_files"`
+		} `json:"details"`
+		Suggestions []string `json:"suggestions"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(resp.Content), &result))
+	require.Equal(t, 75.0, result.Details.GoCoverage)
+	require.Equal(t, []fileCoverageResult{
+		{Path: "main.go", Coverage: 50, Statements: 4},
+		{Path: "store/store.go", Coverage: 75, Statements: 4},
+	}, result.Details.FilesBelowThreshold)
+	require.ElementsMatch(t, []string{"web/router.js", "scripts/deploy.py"}, result.Details.UntestedFiles)
+	require.Contains(t, result.Summary, "Go statement coverage 75.0% across 3 files, 2 below 80%")
+	require.Contains(t, result.Summary, "2 of 3 other source files have no test file")
+	require.Contains(t, result.Suggestions, "Add tests for main.go (50.0% of statements covered)")
+	require.Contains(t, result.Suggestions, "Add tests for web/router.js (no test file found)")
+
+	resp = runAnalyze(t, dir, AnalyzeParams{Path: "main.go", Type: "coverage"})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "coverage analysis requires a directory")
+
+	resp = runAnalyze(t, dir, AnalyzeParams{Path: ".", Type: "coverage", CoverageProfile: "missing.out"})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "failed to read coverage profile")
+
+	// Profiles outside the working directory are rejected before being read
+	outside := filepath.Join(t.TempDir(), "secrets.txt")
+	require.NoError(t, os.WriteFile(outside, []byte("API_KEY=hunter2\n"), 0o600))
+	for _, profile := range []string{"../secrets.txt", outside} {
+		resp = runAnalyze(t, dir, AnalyzeParams{Path: ".", Type: "coverage", CoverageProfile: profile})
+		require.True(t, resp.IsError, profile)
+		require.Contains(t, resp.Content, "invalid coverage profile path", profile)
+		require.NotContains(t, resp.Content, "hunter2", profile)
+	}
+}
+
+func TestAnalyzeGoFileFlagsUnusedImports(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"main.go": `package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	_ "embed"
+	. "math"
+	yaml "gopkg.in/yaml.v3"
+	"github.com/aymanbagabas/go-udiff"
+)
+
+func main() {
+	fmt.Println(Pi, udiff.Unified("a", "b", "", ""))
+	// os.Exit(1) in a comment doesn't count
+	var strings []string
+	_ = strings
+}
+`})
+
+	resp := runAnalyze(t, dir, AnalyzeParams{Path: "main.go", Type: "structure", Format: "json"})
+	require.False(t, resp.IsError, resp.Content)
+
+	var result AnalysisResult
+	require.NoError(t, json.Unmarshal([]byte(resp.Content), &result))
+	require.Equal(t, []any{
+		map[string]any{"path": "os", "name": "os"},
+		map[string]any{"path": "strings", "name": "strings"},
+		map[string]any{"path": "gopkg.in/yaml.v3", "name": "yaml"},
+	}, result.Details["unused_imports"])
+	require.Contains(t, result.Suggestions, `Remove unused import "os"`)
+	require.Contains(t, result.Suggestions, `Remove unused import "gopkg.in/yaml.v3"`)
+}
+
+func TestUnusedGoImportsKeepsUnnamedImportsWithUnknownQualifiers(t *testing.T) {
+	t.Parallel()
+
+	// tea isn't the last element of the import path, so the unnamed import
+	// can't be matched to its use and isn't reported
+	file, err := parser.ParseFile(token.NewFileSet(), "", `package main
+
+import (
+	"github.com/charmbracelet/bubbletea"
+	unused "strings"
+)
+
+func main() { tea.NewProgram(nil) }
+`, 0)
+	require.NoError(t, err)
+	require.Equal(t, []unusedImport{{Path: "strings", Name: "unused"}}, unusedGoImports(file))
+	require.Equal(t, `unused "strings"`, unusedGoImports(file)[0].String())
+}
+
+func TestGuessImportName(t *testing.T) {
+	t.Parallel()
+
+	for path, want := range map[string]string{
+		"fmt":                                    "fmt",
+		"net/http":                               "http",
+		"gopkg.in/yaml.v3":                       "yaml",
+		"github.com/google/go-github/v60/github": "github",
+		"github.com/jackc/pgx/v5":                "pgx",
+		"github.com/aymanbagabas/go-udiff":       "udiff",
+		"github.com/mattn/go-sqlite3":            "sqlite3",
+		"github.com/kkdai/youtube-go":            "youtube",
+	} {
+		require.Equal(t, want, guessImportName(path), path)
+	}
+}
@@ -1,14 +1,21 @@
 package tools
 
 import (
+	"cmp"
+	"container/heap"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/charmbracelet/crush/internal/diff"
 	"github.com/charmbracelet/crush/internal/permission"
 )
 
@@ -18,8 +25,10 @@ type BatchParams struct {
 }
 
 type BatchOperation struct {
-	Type   string                 `json:"type"` // "file_search", "text_replace", "file_copy", "dir_analysis"
+	Type   string                 `json:"type"` // "file_search", "file_read", "text_replace", "regex_replace", "go_rename", "file_copy", "dir_analysis", "pattern_find"
 	Params map[string]interface{} `json:"params"`
+	// Indices of the operations that must succeed before this one runs
+	DependsOn []int `json:"depends_on,omitempty"` // 1-based operation numbers
 }
 
 type BatchResult struct {
@@ -29,6 +38,8 @@ type BatchResult struct {
 	Result         interface{} `json:"result"`
 	Error          string      `json:"error,omitempty"`
 	Duration       string      `json:"duration"`
+	// Skipped is set when the operation didn't run because a dependency failed
+	Skipped bool `json:"skipped,omitempty"`
 }
 
 type batchTool struct {
@@ -60,13 +71,18 @@ func (t *batchTool) Info() ToolInfo {
 						"properties": map[string]any{
 							"type": map[string]any{
 								"type":        "string",
-								"description": "Operation type: file_search, text_replace, file_copy, dir_analysis, pattern_find",
-								"enum":        []string{"file_search", "text_replace", "file_copy", "dir_analysis", "pattern_find"},
+								"description": "Operation type: file_search, file_read, text_replace, regex_replace, go_rename, file_copy, dir_analysis, pattern_find. file_read takes file, offset (0-based line to start from) and limit (number of lines, default 2000); content beyond 250KB is truncated. text_replace takes file, old_text, new_text and return_diff (include a unified diff of the change in the result). regex_replace takes file, pattern (RE2), replacement ($1 refers to capture groups) and all (replace every match instead of only the first). go_rename takes path (a Go file or package directory), old_name and new_name; it renames the identifier and its references using the Go AST, across the whole package when the identifier is package-level, a field or a method, leaving comments, strings and unrelated identifiers untouched, and gofmts the result. file_copy (alias copy) takes source and destination; it keeps file modes and copies directories recursively, recreating symlinks with their original targets, which may point outside the copy. dir_analysis takes path, max_depth (levels below path to descend, 0 for unlimited) and follow_symlinks (traverse symlinked directories, default false)",
+								"enum":        []string{"file_search", "file_read", "text_replace", "regex_replace", "go_rename", "file_copy", "copy", "dir_analysis", "pattern_find"},
 							},
 							"params": map[string]any{
 								"type":        "object",
 								"description": "Operation-specific parameters",
 							},
+							"depends_on": map[string]any{
+								"type":        "array",
+								"description": "Numbers of the operations that must succeed before this one runs, counting from 1 as in the result headers (## Operation 1). If one fails, this operation is skipped",
+								"items":       map[string]any{"type": "integer"},
+							},
 						},
 						"required": []string{"type", "params"},
 					},
@@ -96,6 +112,9 @@ func (t *batchTool) Run(ctx context.Context, params ToolCall) (ToolResponse, err
 	if len(batchParams.Operations) == 0 {
 		return NewTextErrorResponse("No operations specified"), nil
 	}
+	if _, err := operationOrder(batchParams.Operations); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("Invalid operation dependencies: %v", err)), nil
+	}
 
 	sessionID, _ := GetContextValues(ctx)
 
@@ -125,68 +144,143 @@ func (t *batchTool) Run(ctx context.Context, params ToolCall) (ToolResponse, err
 	return NewTextResponse(output), nil
 }
 
-func (t *batchTool) executeSequential(ctx context.Context, operations []BatchOperation) []BatchResult {
-	results := make([]BatchResult, len(operations))
-
+// operationOrder validates the dependencies of operations and returns their
+// indices in an order where every operation comes after its dependencies,
+// keeping the given order otherwise. Dependency cycles are rejected.
+//
+// Dependencies and error messages number operations from 1, like the result
+// headers, while the returned indices are 0-based.
+func operationOrder(operations []BatchOperation) ([]int, error) {
 	for i, op := range operations {
-		start := time.Now()
-		result, err := t.executeOperation(ctx, op)
-		duration := time.Since(start)
+		for _, dep := range op.DependsOn {
+			if dep < 1 || dep > len(operations) {
+				return nil, fmt.Errorf("operation %d depends on operation %d, which does not exist", i+1, dep)
+			}
+			if dep == i+1 {
+				return nil, fmt.Errorf("operation %d depends on itself", i+1)
+			}
+		}
+	}
 
-		results[i] = BatchResult{
-			OperationIndex: i,
-			Type:           op.Type,
-			Success:        err == nil,
-			Result:         result,
-			Duration:       duration.String(),
+	order := make([]int, 0, len(operations))
+	scheduled := make([]bool, len(operations))
+	for len(order) < len(operations) {
+		next := -1
+		for i, op := range operations {
+			if !scheduled[i] && !slices.ContainsFunc(op.DependsOn, func(dep int) bool { return !scheduled[dep-1] }) {
+				next = i
+				break
+			}
 		}
+		if next < 0 {
+			var cycle []string
+			for i := range operations {
+				if !scheduled[i] {
+					cycle = append(cycle, fmt.Sprint(i+1))
+				}
+			}
+			return nil, fmt.Errorf("dependency cycle between operations %s", strings.Join(cycle, ", "))
+		}
+		scheduled[next] = true
+		order = append(order, next)
+	}
+	return order, nil
+}
 
-		if err != nil {
-			results[i].Error = err.Error()
+// failedDependency returns the number of the first dependency of op that did
+// not succeed
+func failedDependency(op BatchOperation, results []BatchResult) (int, bool) {
+	for _, dep := range op.DependsOn {
+		if !results[dep-1].Success {
+			return dep, true
 		}
 	}
+	return 0, false
+}
 
+// failedResults fails every operation with err. Run rejects invalid
+// dependencies before executing anything, so this only guards other callers.
+func failedResults(operations []BatchOperation, err error) []BatchResult {
+	results := make([]BatchResult, len(operations))
+	for i, op := range operations {
+		results[i] = BatchResult{OperationIndex: i, Type: op.Type, Error: err.Error(), Duration: time.Duration(0).String()}
+	}
 	return results
 }
 
-func (t *batchTool) executeParallel(ctx context.Context, operations []BatchOperation) []BatchResult {
+// runOperation executes an operation, or skips it if one of its dependencies
+// failed. The dependencies' results must already be in results.
+func (t *batchTool) runOperation(ctx context.Context, index int, op BatchOperation, results []BatchResult) BatchResult {
+	if dep, failed := failedDependency(op, results); failed {
+		return BatchResult{
+			OperationIndex: index,
+			Type:           op.Type,
+			Error:          fmt.Sprintf("skipped because operation %d did not succeed", dep),
+			Duration:       time.Duration(0).String(),
+			Skipped:        true,
+		}
+	}
+
+	start := time.Now()
+	result, err := t.executeOperation(ctx, op)
+	duration := time.Since(start)
+
+	batchResult := BatchResult{
+		OperationIndex: index,
+		Type:           op.Type,
+		Success:        err == nil,
+		Result:         result,
+		Duration:       duration.String(),
+	}
+	if err != nil {
+		batchResult.Error = err.Error()
+	}
+	return batchResult
+}
+
+// executeSequential runs the operations one at a time in the given order,
+// except that an operation depending on a later one runs after it
+func (t *batchTool) executeSequential(ctx context.Context, operations []BatchOperation) []BatchResult {
+	order, err := operationOrder(operations)
+	if err != nil {
+		return failedResults(operations, err)
+	}
+
 	results := make([]BatchResult, len(operations))
-	resultChan := make(chan struct {
-		index  int
-		result BatchResult
-	}, len(operations))
 
-	// Start all operations
-	for i, op := range operations {
-		go func(index int, operation BatchOperation) {
-			start := time.Now()
-			result, err := t.executeOperation(ctx, operation)
-			duration := time.Since(start)
-
-			batchResult := BatchResult{
-				OperationIndex: index,
-				Type:           operation.Type,
-				Success:        err == nil,
-				Result:         result,
-				Duration:       duration.String(),
-			}
+	for _, i := range order {
+		results[i] = t.runOperation(ctx, i, operations[i], results)
+	}
+	return results
+}
 
-			if err != nil {
-				batchResult.Error = err.Error()
-			}
+// executeParallel runs the operations concurrently, each starting as soon as
+// its dependencies have finished
+func (t *batchTool) executeParallel(ctx context.Context, operations []BatchOperation) []BatchResult {
+	if _, err := operationOrder(operations); err != nil {
+		// A cycle would leave its operations waiting on each other forever
+		return failedResults(operations, err)
+	}
+
+	results := make([]BatchResult, len(operations))
 
-			resultChan <- struct {
-				index  int
-				result BatchResult
-			}{index: index, result: batchResult}
-		}(i, op)
+	// Each operation's channel is closed once its result is stored
+	done := make([]chan struct{}, len(operations))
+	for i := range done {
+		done[i] = make(chan struct{})
 	}
 
-	// Collect results
-	for i := 0; i < len(operations); i++ {
-		res := <-resultChan
-		results[res.index] = res.result
+	var wg sync.WaitGroup
+	for i, op := range operations {
+		wg.Go(func() {
+			defer close(done[i])
+			for _, dep := range op.DependsOn {
+				<-done[dep-1]
+			}
+			results[i] = t.runOperation(ctx, i, op, results)
+		})
 	}
+	wg.Wait()
 
 	return results
 }
@@ -195,9 +289,15 @@ func (t *batchTool) executeOperation(ctx context.Context, op BatchOperation) (in
 	switch op.Type {
 	case "file_search":
 		return t.executeFileSearch(op.Params)
+	case "file_read":
+		return t.executeFileRead(op.Params)
 	case "text_replace":
 		return t.executeTextReplace(op.Params)
-	case "file_copy":
+	case "regex_replace":
+		return t.executeRegexReplace(op.Params)
+	case "go_rename":
+		return t.executeGoRename(op.Params)
+	case "file_copy", "copy":
 		return t.executeFileCopy(op.Params)
 	case "dir_analysis":
 		return t.executeDirAnalysis(op.Params)
@@ -254,6 +354,71 @@ func (t *batchTool) executeFileSearch(params map[string]interface{}) (interface{
 	}, nil
 }
 
+func (t *batchTool) executeFileRead(params map[string]interface{}) (interface{}, error) {
+	file, ok := params["file"].(string)
+	if !ok {
+		return nil, fmt.Errorf("file parameter required for file_read")
+	}
+
+	offset := 0
+	if value, ok := params["offset"].(float64); ok {
+		if value < 0 {
+			return nil, fmt.Errorf("offset must not be negative")
+		}
+		offset = int(value)
+	}
+	limit := DefaultReadLimit
+	if value, ok := params["limit"].(float64); ok {
+		if value <= 0 {
+			return nil, fmt.Errorf("limit must be positive")
+		}
+		limit = int(value)
+	}
+
+	filePath, err := ValidatePathSecurity(file, t.workingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("path is a directory: %s", file)
+	}
+
+	content, totalLines, err := readTextFile(filePath, offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	linesRead := min(limit, max(totalLines-offset, 0))
+
+	result := map[string]interface{}{
+		"file":        filePath,
+		"offset":      offset,
+		"total_lines": totalLines,
+		"truncated":   false,
+	}
+
+	// Keep huge reads from flooding the batch output, cutting at a line boundary
+	if len(content) > MaxReadSize {
+		content = content[:MaxReadSize]
+		if i := strings.LastIndex(content, "\n"); i > 0 {
+			content = content[:i]
+		}
+		linesRead = strings.Count(content, "\n") + 1
+		result["truncated"] = true
+		result["note"] = fmt.Sprintf("content truncated to %d bytes; use offset %d to continue", MaxReadSize, offset+linesRead)
+	} else if offset+linesRead < totalLines {
+		result["note"] = fmt.Sprintf("file has more lines; use offset %d to continue", offset+linesRead)
+	}
+
+	result["content"] = content
+	result["lines_read"] = linesRead
+	return result, nil
+}
+
 func (t *batchTool) executeTextReplace(params map[string]interface{}) (interface{}, error) {
 	filePath, ok := params["file"].(string)
 	if !ok {
@@ -270,6 +435,8 @@ func (t *batchTool) executeTextReplace(params map[string]interface{}) (interface
 		return nil, fmt.Errorf("new_text parameter required for text_replace")
 	}
 
+	returnDiff, _ := params["return_diff"].(bool)
+
 	if !filepath.IsAbs(filePath) {
 		filePath = filepath.Join(t.workingDir, filePath)
 	}
@@ -296,12 +463,94 @@ func (t *batchTool) executeTextReplace(params map[string]interface{}) (interface
 		return nil, fmt.Errorf("failed to write file: %w", err)
 	}
 
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"file":         filePath,
 		"old_text":     oldText,
 		"new_text":     newText,
 		"replacements": replacementCount,
 		"modified":     true,
+	}
+	if returnDiff {
+		diffName := filePath
+		if rel, err := filepath.Rel(t.workingDir, filePath); err == nil && filepath.IsLocal(rel) {
+			diffName = rel
+		}
+		unified, additions, removals := diff.GenerateDiff(originalContent, replacedContent, filepath.ToSlash(diffName))
+		result["diff"] = unified
+		result["additions"] = additions
+		result["removals"] = removals
+	}
+	return result, nil
+}
+
+func (t *batchTool) executeRegexReplace(params map[string]interface{}) (interface{}, error) {
+	file, ok := params["file"].(string)
+	if !ok {
+		return nil, fmt.Errorf("file parameter required for regex_replace")
+	}
+
+	pattern, ok := params["pattern"].(string)
+	if !ok {
+		return nil, fmt.Errorf("pattern parameter required for regex_replace")
+	}
+
+	replacement, ok := params["replacement"].(string)
+	if !ok {
+		return nil, fmt.Errorf("replacement parameter required for regex_replace")
+	}
+
+	replaceAll, _ := params["all"].(bool)
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	filePath, err := ValidatePathSecurity(file, t.workingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var replacedContent []byte
+	replacementCount := 0
+	if replaceAll {
+		replacementCount = len(re.FindAllIndex(content, -1))
+		replacedContent = re.ReplaceAll(content, []byte(replacement))
+	} else if match := re.FindSubmatchIndex(content); match != nil {
+		replacementCount = 1
+		replacedContent = append(replacedContent, content[:match[0]]...)
+		replacedContent = re.Expand(replacedContent, []byte(replacement), content, match)
+		replacedContent = append(replacedContent, content[match[1]:]...)
+	}
+
+	if replacementCount == 0 {
+		return map[string]interface{}{
+			"file":         filePath,
+			"pattern":      pattern,
+			"replacements": 0,
+			"modified":     false,
+		}, nil
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if err := os.WriteFile(filePath, replacedContent, info.Mode().Perm()); err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return map[string]interface{}{
+		"file":         filePath,
+		"pattern":      pattern,
+		"replacement":  replacement,
+		"replacements": replacementCount,
+		"modified":     true,
 	}, nil
 }
 
@@ -316,39 +565,152 @@ func (t *batchTool) executeFileCopy(params map[string]interface{}) (interface{},
 		return nil, fmt.Errorf("destination parameter required for file_copy")
 	}
 
-	if !filepath.IsAbs(source) {
-		source = filepath.Join(t.workingDir, source)
+	sourcePath, err := ValidatePathSecurity(source, t.workingDir)
+	if err != nil {
+		return nil, err
 	}
-	if !filepath.IsAbs(destination) {
-		destination = filepath.Join(t.workingDir, destination)
+	destPath, err := ValidatePathSecurity(destination, t.workingDir)
+	if err != nil {
+		return nil, err
 	}
 
-	sourceContent, err := os.ReadFile(source)
+	sourceInfo, err := os.Stat(sourcePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read source file: %w", err)
+		return nil, fmt.Errorf("failed to stat source: %w", err)
+	}
+
+	if _, rel, inside := withinRoots(destPath, []string{sourcePath}); inside && (rel == "." || sourceInfo.IsDir()) {
+		return nil, fmt.Errorf("cannot copy %s into itself", source)
 	}
 
 	// Create destination directory if needed
-	destDir := filepath.Dir(destination)
-	if err := os.MkdirAll(destDir, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
 		return nil, fmt.Errorf("failed to create destination directory: %w", err)
 	}
 
-	err = os.WriteFile(destination, sourceContent, 0644)
+	copied := &copyStats{}
+	if sourceInfo.IsDir() {
+		err = copyDir(sourcePath, destPath, copied)
+	} else {
+		err = copyFile(sourcePath, destPath, sourceInfo.Mode().Perm(), copied)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to write destination file: %w", err)
+		return nil, err
 	}
 
-	sourceInfo, _ := os.Stat(source)
 	return map[string]interface{}{
-		"source":      source,
-		"destination": destination,
-		"size_bytes":  len(sourceContent),
-		"copied":      true,
-		"source_info": sourceInfo,
+		"source":       sourcePath,
+		"destination":  destPath,
+		"directory":    sourceInfo.IsDir(),
+		"mode":         sourceInfo.Mode().String(),
+		"files_copied": copied.files,
+		"size_bytes":   copied.bytes,
+		"copied":       true,
 	}, nil
 }
 
+// copyStats counts what a file_copy operation wrote
+type copyStats struct {
+	files int
+	bytes int64
+}
+
+// copyFile copies a regular file, giving the destination the source's mode
+func copyFile(source, destination string, mode os.FileMode, copied *copyStats) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to read source file: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("failed to write destination file: %w", err)
+	}
+	n, err := io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write destination file: %w", err)
+	}
+	// OpenFile only applies the mode to new files and is subject to the umask
+	if err := os.Chmod(destination, mode); err != nil {
+		return fmt.Errorf("failed to set destination mode: %w", err)
+	}
+
+	copied.files++
+	copied.bytes += n
+	return nil
+}
+
+// copyDir copies the tree under source to destination, preserving modes.
+// Symlinks are recreated with the same target rather than followed, so no
+// file outside the source tree is read, though the copied links may still
+// point outside it.
+func copyDir(source, destination string, copied *copyStats) error {
+	// Directories are created writable so their contents can be copied, and
+	// get their source modes once the walk is done, so read-only directories
+	// can be copied too
+	type dirMode struct {
+		path string
+		mode os.FileMode
+	}
+	var dirModes []dirMode
+
+	err := filepath.WalkDir(source, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to read source: %w", err)
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(destination, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		switch {
+		case d.IsDir():
+			if err := os.MkdirAll(target, 0o700); err != nil {
+				return fmt.Errorf("failed to create directory: %w", err)
+			}
+			if err := os.Chmod(target, info.Mode().Perm()|0o700); err != nil {
+				return fmt.Errorf("failed to set directory mode: %w", err)
+			}
+			dirModes = append(dirModes, dirMode{path: target, mode: info.Mode().Perm()})
+			return nil
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return fmt.Errorf("failed to read symlink: %w", err)
+			}
+			if err := os.Symlink(link, target); err != nil {
+				return fmt.Errorf("failed to create symlink: %w", err)
+			}
+			return nil
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm(), copied)
+		default:
+			// Sockets, devices and pipes can't be copied meaningfully
+			return nil
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Deepest directories first, so a read-only parent is restricted last
+	for _, dir := range slices.Backward(dirModes) {
+		if err := os.Chmod(dir.path, dir.mode); err != nil {
+			return fmt.Errorf("failed to set directory mode: %w", err)
+		}
+	}
+	return nil
+}
+
 func (t *batchTool) executeDirAnalysis(params map[string]interface{}) (interface{}, error) {
 	analysisPath := t.workingDir
 	if path, ok := params["path"].(string); ok {
@@ -359,22 +721,36 @@ func (t *batchTool) executeDirAnalysis(params map[string]interface{}) (interface
 		}
 	}
 
-	analysis := map[string]interface{}{
-		"path":          analysisPath,
-		"total_files":   0,
-		"total_dirs":    0,
-		"total_size":    int64(0),
-		"file_types":    make(map[string]int),
-		"largest_files": []map[string]interface{}{},
+	maxDepth := 0
+	if depth, ok := params["max_depth"].(float64); ok {
+		if depth < 0 {
+			return nil, fmt.Errorf("max_depth must not be negative")
+		}
+		maxDepth = int(depth)
 	}
+	followSymlinks, _ := params["follow_symlinks"].(bool)
 
-	var largestFiles []map[string]interface{}
+	rootInfo, err := os.Stat(analysisPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot access %s: %w", analysisPath, err)
+	}
 
-	err := filepath.Walk(analysisPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Skip errors
-		}
+	analysis := map[string]interface{}{
+		"path":             analysisPath,
+		"total_files":      0,
+		"total_dirs":       0,
+		"total_size":       int64(0),
+		"file_types":       make(map[string]int),
+		"largest_files":    []map[string]interface{}{},
+		"skipped_symlinks": 0,
+	}
+	if maxDepth > 0 {
+		analysis["max_depth"] = maxDepth
+	}
 
+	largest := newLargestFiles(largestFilesLimit)
+
+	visit := func(path string, info os.FileInfo) {
 		if info.IsDir() {
 			analysis["total_dirs"] = analysis["total_dirs"].(int) + 1
 		} else {
@@ -391,37 +767,171 @@ func (t *batchTool) executeDirAnalysis(params map[string]interface{}) (interface
 
 			// Track largest files
 			relPath, _ := filepath.Rel(analysisPath, path)
-			fileInfo := map[string]interface{}{
-				"path":     relPath,
-				"size":     info.Size(),
-				"modified": info.ModTime(),
-			}
-
-			largestFiles = append(largestFiles, fileInfo)
+			largest.add(largeFile{path: relPath, size: info.Size(), modified: info.ModTime()})
+		}
+	}
 
-			// Keep only top 10 largest files
-			if len(largestFiles) > 10 {
-				// Simple bubble sort to keep largest
-				for i := 0; i < len(largestFiles)-1; i++ {
-					for j := 0; j < len(largestFiles)-i-1; j++ {
-						if largestFiles[j]["size"].(int64) < largestFiles[j+1]["size"].(int64) {
-							largestFiles[j], largestFiles[j+1] = largestFiles[j+1], largestFiles[j]
-						}
-					}
-				}
-				largestFiles = largestFiles[:10]
-			}
+	visit(analysisPath, rootInfo)
+	if rootInfo.IsDir() {
+		walker := &dirAnalysisWalker{
+			maxDepth:       maxDepth,
+			followSymlinks: followSymlinks,
+			visited:        make(map[string]bool),
+			visit:          visit,
 		}
+		if realRoot, err := filepath.EvalSymlinks(analysisPath); err == nil {
+			walker.visited[realRoot] = true
+		}
+		walker.walk(analysisPath, 0)
+		analysis["skipped_symlinks"] = walker.skippedSymlinks
+	}
 
-		return nil
+	analysis["largest_files"] = largest.sorted()
+	return analysis, nil
+}
+
+// largestFilesLimit is how many of the largest files dir_analysis reports
+const largestFilesLimit = 10
+
+type largeFile struct {
+	path     string
+	size     int64
+	modified time.Time
+}
+
+// smallerFile reports whether a ranks below b. Among equal sizes the later
+// path ranks lower, so the result does not depend on walk order.
+func smallerFile(a, b largeFile) bool {
+	if a.size != b.size {
+		return a.size < b.size
+	}
+	return a.path > b.path
+}
+
+// largeFileHeap is a min-heap of files ordered by size, so the smallest of
+// the tracked files is the one replaced when a larger file is found
+type largeFileHeap []largeFile
+
+func (h largeFileHeap) Len() int { return len(h) }
+
+func (h largeFileHeap) Less(i, j int) bool { return smallerFile(h[i], h[j]) }
+
+func (h largeFileHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *largeFileHeap) Push(x any) { *h = append(*h, x.(largeFile)) }
+
+func (h *largeFileHeap) Pop() any {
+	old := *h
+	file := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return file
+}
+
+// largestFiles keeps the limit largest files seen, each add costing O(log limit)
+type largestFiles struct {
+	limit int
+	files largeFileHeap
+}
+
+func newLargestFiles(limit int) *largestFiles {
+	return &largestFiles{limit: limit, files: make(largeFileHeap, 0, limit)}
+}
+
+func (l *largestFiles) add(file largeFile) {
+	if l.limit <= 0 {
+		return
+	}
+	if len(l.files) < l.limit {
+		heap.Push(&l.files, file)
+		return
+	}
+	// Replace the smallest tracked file when the new one ranks above it
+	if smallerFile(l.files[0], file) {
+		l.files[0] = file
+		heap.Fix(&l.files, 0)
+	}
+}
+
+// sorted returns the tracked files from largest to smallest
+func (l *largestFiles) sorted() []map[string]interface{} {
+	files := slices.Clone(l.files)
+	slices.SortFunc(files, func(a, b largeFile) int {
+		if c := cmp.Compare(b.size, a.size); c != 0 {
+			return c
+		}
+		return strings.Compare(a.path, b.path)
 	})
 
+	result := make([]map[string]interface{}, 0, len(files))
+	for _, file := range files {
+		result = append(result, map[string]interface{}{
+			"path":     file.path,
+			"size":     file.size,
+			"modified": file.modified,
+		})
+	}
+	return result
+}
+
+// dirAnalysisWalker walks a directory tree for dir_analysis, bounding the
+// depth and only traversing symlinked directories when asked to
+type dirAnalysisWalker struct {
+	maxDepth       int // 0 means unlimited
+	followSymlinks bool
+	// Real paths of directories already walked, so symlink cycles are entered only once
+	visited         map[string]bool
+	visit           func(path string, info os.FileInfo)
+	skippedSymlinks int
+}
+
+// walk visits the entries of dir, which lies depth levels below the analysis root
+func (w *dirAnalysisWalker) walk(dir string, depth int) {
+	entries, err := os.ReadDir(dir)
 	if err != nil {
-		return nil, err
+		return // Skip unreadable directories
 	}
 
-	analysis["largest_files"] = largestFiles
-	return analysis, nil
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			if !w.followSymlinks {
+				w.skippedSymlinks++
+				continue
+			}
+			target, err := os.Stat(path)
+			if err != nil {
+				// Dangling link
+				w.skippedSymlinks++
+				continue
+			}
+			info = target
+		}
+
+		if !info.IsDir() {
+			w.visit(path, info)
+			continue
+		}
+
+		if w.followSymlinks {
+			realPath, err := filepath.EvalSymlinks(path)
+			if err != nil || w.visited[realPath] {
+				// Already walked through another path, e.g. a symlink cycle
+				w.skippedSymlinks++
+				continue
+			}
+			w.visited[realPath] = true
+		}
+
+		w.visit(path, info)
+		if w.maxDepth == 0 || depth+1 < w.maxDepth {
+			w.walk(path, depth+1)
+		}
+	}
 }
 
 func (t *batchTool) executePatternFind(params map[string]interface{}) (interface{}, error) {
@@ -521,9 +1031,11 @@ func (t *batchTool) formatBatchResults(results []BatchResult) string {
 	for _, result := range results {
 		output.WriteString(fmt.Sprintf("## Operation %d: %s\n", result.OperationIndex+1, result.Type))
 		output.WriteString(fmt.Sprintf("**Status:** %s | **Duration:** %s\n\n",
-			map[bool]string{true: "✅ Success", false: "❌ Failed"}[result.Success], result.Duration))
+			batchStatus(result), result.Duration))
 
-		if !result.Success {
+		if result.Skipped {
+			output.WriteString(fmt.Sprintf("**Skipped:** %s\n\n", result.Error))
+		} else if !result.Success {
 			output.WriteString(fmt.Sprintf("**Error:** %s\n\n", result.Error))
 		} else {
 			// Format result based on operation type
@@ -533,12 +1045,29 @@ func (t *batchTool) formatBatchResults(results []BatchResult) string {
 					output.WriteString(fmt.Sprintf("Found %v matches for query '%v'\n\n",
 						resultMap["match_count"], resultMap["query"]))
 				}
-			case "text_replace":
+			case "file_read":
+				if resultMap, ok := result.Result.(map[string]interface{}); ok {
+					output.WriteString(fmt.Sprintf("Read %v of %v lines from %v starting at line %v\n\n",
+						resultMap["lines_read"], resultMap["total_lines"], filepath.Base(resultMap["file"].(string)), resultMap["offset"].(int)+1))
+					output.WriteString(fmt.Sprintf("```\n%v\n```\n\n", resultMap["content"]))
+					if note, ok := resultMap["note"]; ok {
+						output.WriteString(fmt.Sprintf("**Note:** %v\n\n", note))
+					}
+				}
+			case "text_replace", "regex_replace":
 				if resultMap, ok := result.Result.(map[string]interface{}); ok {
 					output.WriteString(fmt.Sprintf("Made %v replacements in %v\n\n",
 						resultMap["replacements"], filepath.Base(resultMap["file"].(string))))
+					if unified, ok := resultMap["diff"].(string); ok && unified != "" {
+						output.WriteString(fmt.Sprintf("```diff\n%v```\n\n", unified))
+					}
+				}
+			case "go_rename":
+				if resultMap, ok := result.Result.(map[string]interface{}); ok {
+					output.WriteString(fmt.Sprintf("Renamed %v to %v in %v places across %d files\n\n",
+						resultMap["old_name"], resultMap["new_name"], resultMap["replacements"], len(resultMap["files"].([]string))))
 				}
-			case "file_copy":
+			case "file_copy", "copy":
 				if resultMap, ok := result.Result.(map[string]interface{}); ok {
 					output.WriteString(fmt.Sprintf("Copied %v bytes to %v\n\n",
 						resultMap["size_bytes"], filepath.Base(resultMap["destination"].(string))))
@@ -559,3 +1088,15 @@ func (t *batchTool) formatBatchResults(results []BatchResult) string {
 
 	return output.String()
 }
+
+// batchStatus labels the outcome of an operation
+func batchStatus(result BatchResult) string {
+	switch {
+	case result.Success:
+		return "✅ Success"
+	case result.Skipped:
+		return "⏭️ Skipped"
+	default:
+		return "❌ Failed"
+	}
+}
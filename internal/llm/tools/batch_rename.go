@@ -0,0 +1,278 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// renameImporter stands in for the imports of the package a rename runs in.
+// Renames only follow the package's own declarations, so imports resolve to
+// empty packages instead of being loaded.
+type renameImporter struct{}
+
+func (renameImporter) Import(path string) (*types.Package, error) {
+	pkg := types.NewPackage(path, guessImportName(path))
+	pkg.MarkComplete()
+	return pkg, nil
+}
+
+// renamePackageFiles returns the files of the Go package a rename at path
+// covers and the names of the files the path itself selects. For a directory
+// both are the files of the package its non-test files belong to, so external
+// _test packages are left out. For a file they are the files of its package in
+// the same directory and the file alone, since renaming a package-level
+// declaration has to reach the rest of the package.
+func renamePackageFiles(fset *token.FileSet, path string) ([]*ast.File, map[string][]byte, map[string]bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to stat path: %w", err)
+	}
+
+	dir := path
+	if !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	var paths []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".go") {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	if !info.IsDir() && !slices.Contains(paths, path) {
+		paths = append(paths, path)
+	}
+	if len(paths) == 0 {
+		return nil, nil, nil, fmt.Errorf("no Go files found in %s", path)
+	}
+
+	var files []*ast.File
+	sources := make(map[string][]byte)
+	for _, file := range paths {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		parsed, err := parser.ParseFile(fset, file, src, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			// Other files of the directory don't have to parse when only one
+			// file was asked for
+			if !info.IsDir() && file != path {
+				continue
+			}
+			return nil, nil, nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(file), err)
+		}
+		files = append(files, parsed)
+		sources[file] = src
+	}
+
+	var pkgName string
+	for _, file := range files {
+		filename := fset.Position(file.Package).Filename
+		if !info.IsDir() && filename == path {
+			pkgName = file.Name.Name
+			break
+		}
+		if info.IsDir() && pkgName == "" && !strings.HasSuffix(filename, "_test.go") {
+			pkgName = file.Name.Name
+		}
+	}
+	if pkgName == "" {
+		pkgName = files[0].Name.Name
+	}
+	files = slices.DeleteFunc(files, func(file *ast.File) bool { return file.Name.Name != pkgName })
+
+	selected := make(map[string]bool)
+	for _, file := range files {
+		if filename := fset.Position(file.Package).Filename; info.IsDir() || filename == path {
+			selected[filename] = true
+		}
+	}
+	return files, sources, selected, nil
+}
+
+// renameTargets returns the objects a rename of oldName changes: the package
+// level declaration of that name, leaving locals that shadow it alone, or when
+// there is none every local variable, parameter, field and method of that
+// name in the package. Embedded fields are left out, as they are named by
+// their type.
+func renameTargets(pkg *types.Package, info *types.Info, oldName string) map[types.Object]bool {
+	targets := make(map[types.Object]bool)
+	if obj := pkg.Scope().Lookup(oldName); obj != nil {
+		targets[obj] = true
+		return targets
+	}
+	for _, obj := range info.Defs {
+		if obj == nil || obj.Name() != oldName || obj.Pkg() != pkg {
+			continue
+		}
+		// An embedded field is named by its type, which isn't renamed
+		if v, ok := obj.(*types.Var); ok && v.Embedded() {
+			continue
+		}
+		targets[obj] = true
+	}
+	// Type switch variables are declared once per case clause
+	for _, obj := range info.Implicits {
+		if v, ok := obj.(*types.Var); ok && v.Name() == oldName {
+			targets[v] = true
+		}
+	}
+	return targets
+}
+
+// isMember reports whether obj is a struct field or a method
+func isMember(obj types.Object) bool {
+	switch obj := obj.(type) {
+	case *types.Var:
+		return obj.IsField()
+	case *types.Func:
+		return obj.Signature().Recv() != nil
+	}
+	return false
+}
+
+func (t *batchTool) executeGoRename(params map[string]interface{}) (interface{}, error) {
+	path, ok := params["path"].(string)
+	if !ok {
+		return nil, fmt.Errorf("path parameter required for go_rename")
+	}
+
+	oldName, ok := params["old_name"].(string)
+	if !ok {
+		return nil, fmt.Errorf("old_name parameter required for go_rename")
+	}
+
+	newName, ok := params["new_name"].(string)
+	if !ok {
+		return nil, fmt.Errorf("new_name parameter required for go_rename")
+	}
+
+	for _, name := range []string{oldName, newName} {
+		if !token.IsIdentifier(name) || name == "_" {
+			return nil, fmt.Errorf("%q is not a valid Go identifier", name)
+		}
+	}
+	if oldName == newName {
+		return nil, fmt.Errorf("old_name and new_name are both %q", oldName)
+	}
+
+	target, err := ValidatePathSecurity(path, t.workingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	files, sources, selected, err := renamePackageFiles(fset, target)
+	if err != nil {
+		return nil, err
+	}
+
+	// Type check without the imports: errors about imported identifiers are
+	// expected and don't affect the package's own declarations
+	info := &types.Info{
+		Defs:      make(map[*ast.Ident]types.Object),
+		Uses:      make(map[*ast.Ident]types.Object),
+		Implicits: make(map[ast.Node]types.Object),
+	}
+	conf := types.Config{Importer: renameImporter{}, FakeImportC: true, Error: func(error) {}}
+	pkg, _ := conf.Check(files[0].Name.Name, fset, files, info)
+
+	targets := renameTargets(pkg, info, oldName)
+	// Locals, fields and methods are only renamed when declared in the files
+	// path selects; a package-level declaration may be declared in any file
+	maps.DeleteFunc(targets, func(obj types.Object, _ bool) bool {
+		return obj.Parent() != pkg.Scope() && !selected[fset.Position(obj.Pos()).Filename]
+	})
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no declaration of %s found in %s", oldName, target)
+	}
+	// Fields and methods are only reached through selectors, so they can't
+	// clash with other identifiers unless members themselves are renamed
+	renamesMembers := slices.ContainsFunc(slices.Collect(maps.Keys(targets)), isMember)
+	renames := make(map[token.Pos]bool)
+	for _, idents := range []map[*ast.Ident]types.Object{info.Defs, info.Uses} {
+		for ident, obj := range idents {
+			if ident.Name == newName && obj != nil && (renamesMembers || !isMember(obj)) {
+				return nil, fmt.Errorf("%s is already declared or used in the package; renaming to it could change what identifiers refer to", newName)
+			}
+			if targets[obj] {
+				renames[ident.Pos()] = true
+			}
+		}
+	}
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			sw, ok := n.(*ast.TypeSwitchStmt)
+			if !ok {
+				return true
+			}
+			if assign, ok := sw.Assign.(*ast.AssignStmt); ok {
+				for _, clause := range sw.Body.List {
+					if targets[info.Implicits[clause]] {
+						renames[assign.Lhs[0].Pos()] = true
+						break
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	// Rewrite each file back to front so earlier offsets stay valid
+	offsets := make(map[string][]int)
+	for pos := range renames {
+		position := fset.Position(pos)
+		offsets[position.Filename] = append(offsets[position.Filename], position.Offset)
+	}
+
+	changed := slices.Sorted(maps.Keys(offsets))
+	// Format every file before writing any, so a file that fails to format
+	// doesn't leave the package half renamed
+	rewritten := make(map[string][]byte, len(changed))
+	for _, file := range changed {
+		fileOffsets := offsets[file]
+		src := bytes.Clone(sources[file])
+		slices.Sort(fileOffsets)
+		for _, offset := range slices.Backward(fileOffsets) {
+			src = slices.Replace(src, offset, offset+len(oldName), []byte(newName)...)
+		}
+		formatted, err := format.Source(src)
+		if err != nil {
+			return nil, fmt.Errorf("failed to format %s: %w", filepath.Base(file), err)
+		}
+		rewritten[file] = formatted
+	}
+
+	for _, file := range changed {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat file: %w", err)
+		}
+		if err := os.WriteFile(file, rewritten[file], info.Mode().Perm()); err != nil {
+			return nil, fmt.Errorf("failed to write file: %w", err)
+		}
+	}
+
+	return map[string]interface{}{
+		"path":         target,
+		"old_name":     oldName,
+		"new_name":     newName,
+		"replacements": len(renames),
+		"files":        changed,
+		"modified":     true,
+	}, nil
+}
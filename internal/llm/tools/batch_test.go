@@ -0,0 +1,784 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/stretchr/testify/require"
+)
+
+func runBatchOperation(t *testing.T, workingDir string, op BatchOperation) BatchResult {
+	t.Helper()
+
+	tool := &batchTool{
+		permissions: permission.NewPermissionService(workingDir, true, nil),
+		workingDir:  workingDir,
+	}
+	return tool.executeSequential(context.Background(), []BatchOperation{op})[0]
+}
+
+func TestBatchRegexReplace(t *testing.T) {
+	t.Parallel()
+
+	const source = "func oldName() {}\nfunc oldNameHelper() {}\n// call oldName()\n"
+
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+		want   string
+		count  int
+	}{
+		{
+			name:   "first match only",
+			params: map[string]interface{}{"pattern": `\boldName\b`, "replacement": "newName"},
+			want:   "func newName() {}\nfunc oldNameHelper() {}\n// call oldName()\n",
+			count:  1,
+		},
+		{
+			name:   "all matches with word boundary",
+			params: map[string]interface{}{"pattern": `\boldName\b`, "replacement": "newName", "all": true},
+			want:   "func newName() {}\nfunc oldNameHelper() {}\n// call newName()\n",
+			count:  2,
+		},
+		{
+			name:   "capture groups",
+			params: map[string]interface{}{"pattern": `func (\w+)\(\)`, "replacement": "func ${1}V2()", "all": true},
+			want:   "func oldNameV2() {}\nfunc oldNameHelperV2() {}\n// call oldName()\n",
+			count:  2,
+		},
+		{
+			name:   "no match",
+			params: map[string]interface{}{"pattern": `missing`, "replacement": "x", "all": true},
+			want:   source,
+			count:  0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(source), 0o644))
+
+			tt.params["file"] = "main.go"
+			result := runBatchOperation(t, dir, BatchOperation{Type: "regex_replace", Params: tt.params})
+			require.True(t, result.Success, result.Error)
+			require.Equal(t, tt.count, result.Result.(map[string]interface{})["replacements"])
+
+			content, err := os.ReadFile(filepath.Join(dir, "main.go"))
+			require.NoError(t, err)
+			require.Equal(t, tt.want, string(content))
+		})
+	}
+}
+
+func TestBatchTextReplaceDiff(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	source := "package main\n\nfunc main() {\n\tlog(\"old\")\n\tcount := 1\n\tlog(\"old\")\n}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(source), 0o644))
+
+	result := runBatchOperation(t, dir, BatchOperation{Type: "text_replace", Params: map[string]interface{}{
+		"file": "main.go", "old_text": `log("old")`, "new_text": `log("new")`, "return_diff": true,
+	}})
+	require.True(t, result.Success, result.Error)
+
+	resultMap := result.Result.(map[string]interface{})
+	require.Equal(t, 2, resultMap["replacements"])
+	require.Equal(t, 2, resultMap["additions"])
+	require.Equal(t, 2, resultMap["removals"])
+
+	unified := resultMap["diff"].(string)
+	require.Contains(t, unified, "--- a/main.go\n+++ b/main.go\n")
+	require.Contains(t, unified, "-\tlog(\"old\")\n+\tlog(\"new\")\n \tcount := 1\n-\tlog(\"old\")\n+\tlog(\"new\")\n")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(source), 0o644))
+	result = runBatchOperation(t, dir, BatchOperation{Type: "text_replace", Params: map[string]interface{}{
+		"file": "main.go", "old_text": `log("old")`, "new_text": `log("new")`,
+	}})
+	require.True(t, result.Success, result.Error)
+	require.NotContains(t, result.Result.(map[string]interface{}), "diff")
+}
+
+func TestBatchRegexReplaceErrors(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644))
+
+	result := runBatchOperation(t, dir, BatchOperation{Type: "regex_replace", Params: map[string]interface{}{
+		"file": "main.go", "pattern": `(unclosed`, "replacement": "x",
+	}})
+	require.False(t, result.Success)
+	require.Contains(t, result.Error, "invalid pattern")
+
+	result = runBatchOperation(t, dir, BatchOperation{Type: "regex_replace", Params: map[string]interface{}{
+		"file": "../outside.go", "pattern": `main`, "replacement": "x",
+	}})
+	require.False(t, result.Success)
+	require.Contains(t, result.Error, "path traversal")
+
+	result = runBatchOperation(t, dir, BatchOperation{Type: "regex_replace", Params: map[string]interface{}{
+		"file": "main.go", "replacement": "x",
+	}})
+	require.False(t, result.Success)
+
+	// The formatted output reports the replacement count
+	tool := &batchTool{permissions: permission.NewPermissionService(dir, true, nil), workingDir: dir}
+	input, err := json.Marshal(BatchParams{Operations: []BatchOperation{{Type: "regex_replace", Params: map[string]interface{}{
+		"file": "main.go", "pattern": `package (\w+)`, "replacement": "package ${1}_test",
+	}}}})
+	require.NoError(t, err)
+	resp, err := tool.Run(context.Background(), ToolCall{Name: BatchToolName, Input: string(input)})
+	require.NoError(t, err)
+	require.Contains(t, resp.Content, "Made 1 replacements in main.go")
+}
+
+func TestBatchDirAnalysisMaxDepth(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	deep := dir
+	for _, name := range []string{"a", "b", "c", "d"} {
+		deep = filepath.Join(deep, name)
+		require.NoError(t, os.MkdirAll(deep, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(deep, name+".txt"), []byte(name), 0o644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "root.go"), []byte("package main\n"), 0o644))
+
+	result := runBatchOperation(t, dir, BatchOperation{Type: "dir_analysis", Params: map[string]interface{}{}})
+	require.True(t, result.Success, result.Error)
+	analysis := result.Result.(map[string]interface{})
+	require.Equal(t, 5, analysis["total_files"])
+	require.Equal(t, 5, analysis["total_dirs"], "root plus a/b/c/d")
+
+	// Depth one only sees the root's direct entries
+	result = runBatchOperation(t, dir, BatchOperation{Type: "dir_analysis", Params: map[string]interface{}{"max_depth": float64(1)}})
+	require.True(t, result.Success, result.Error)
+	analysis = result.Result.(map[string]interface{})
+	require.Equal(t, 1, analysis["total_files"])
+	require.Equal(t, 2, analysis["total_dirs"])
+	require.Equal(t, 1, analysis["max_depth"])
+
+	result = runBatchOperation(t, dir, BatchOperation{Type: "dir_analysis", Params: map[string]interface{}{"max_depth": float64(3)}})
+	require.True(t, result.Success, result.Error)
+	analysis = result.Result.(map[string]interface{})
+	require.Equal(t, 3, analysis["total_files"], "root.go, a/a.txt and a/b/b.txt")
+	require.Equal(t, 4, analysis["total_dirs"])
+
+	result = runBatchOperation(t, dir, BatchOperation{Type: "dir_analysis", Params: map[string]interface{}{"max_depth": float64(-1)}})
+	require.False(t, result.Success)
+}
+
+func TestBatchDirAnalysisSymlinks(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main\n"), 0o644))
+	// A self-referential link that would recurse forever if followed naively
+	require.NoError(t, os.Symlink(dir, filepath.Join(dir, "src", "loop")))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "src", "main.go"), filepath.Join(dir, "link.go")))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "missing"), filepath.Join(dir, "dangling")))
+
+	// By default symlinks are not traversed
+	result := runBatchOperation(t, dir, BatchOperation{Type: "dir_analysis", Params: map[string]interface{}{}})
+	require.True(t, result.Success, result.Error)
+	analysis := result.Result.(map[string]interface{})
+	require.Equal(t, 1, analysis["total_files"])
+	require.Equal(t, 2, analysis["total_dirs"])
+	require.Equal(t, 3, analysis["skipped_symlinks"])
+
+	// Following symlinks counts link targets but walks the cycle only once
+	result = runBatchOperation(t, dir, BatchOperation{Type: "dir_analysis", Params: map[string]interface{}{"follow_symlinks": true}})
+	require.True(t, result.Success, result.Error)
+	analysis = result.Result.(map[string]interface{})
+	require.Equal(t, 2, analysis["total_files"], "main.go and the file link")
+	require.Equal(t, 2, analysis["total_dirs"])
+	require.Equal(t, 2, analysis["skipped_symlinks"], "the cycle and the dangling link")
+}
+
+func TestLargestFilesKeepsTopTen(t *testing.T) {
+	t.Parallel()
+
+	sizes := []int64{5, 120, 7, 3000, 42, 42, 999, 1, 64, 512, 2048, 8, 77, 300, 42}
+	largest := newLargestFiles(largestFilesLimit)
+	for i, size := range sizes {
+		largest.add(largeFile{path: fmt.Sprintf("file%02d", i), size: size})
+	}
+
+	var got []int64
+	var paths []string
+	for _, file := range largest.sorted() {
+		got = append(got, file["size"].(int64))
+		paths = append(paths, file["path"].(string))
+	}
+	require.Equal(t, []int64{3000, 2048, 999, 512, 300, 120, 77, 64, 42, 42}, got)
+	// Ties keep the earliest paths in a stable order
+	require.Equal(t, []string{"file04", "file05"}, paths[8:])
+
+	few := newLargestFiles(largestFilesLimit)
+	few.add(largeFile{path: "small", size: 1})
+	few.add(largeFile{path: "big", size: 10})
+	require.Len(t, few.sorted(), 2)
+	require.Equal(t, "big", few.sorted()[0]["path"])
+}
+
+// bubbleLargestFiles is the previous top-ten implementation, kept to compare
+// against in benchmarks
+func bubbleLargestFiles(largestFiles []map[string]interface{}, file map[string]interface{}) []map[string]interface{} {
+	largestFiles = append(largestFiles, file)
+	if len(largestFiles) > 10 {
+		for i := 0; i < len(largestFiles)-1; i++ {
+			for j := 0; j < len(largestFiles)-i-1; j++ {
+				if largestFiles[j]["size"].(int64) < largestFiles[j+1]["size"].(int64) {
+					largestFiles[j], largestFiles[j+1] = largestFiles[j+1], largestFiles[j]
+				}
+			}
+		}
+		largestFiles = largestFiles[:10]
+	}
+	return largestFiles
+}
+
+func syntheticFileSizes(n int) []int64 {
+	sizes := make([]int64, n)
+	seed := int64(1)
+	for i := range sizes {
+		seed = (seed*1103515245 + 12345) % (1 << 31)
+		sizes[i] = seed % 1_000_000
+	}
+	return sizes
+}
+
+func BenchmarkLargestFilesHeap(b *testing.B) {
+	sizes := syntheticFileSizes(100_000)
+	now := time.Now()
+
+	for b.Loop() {
+		largest := newLargestFiles(largestFilesLimit)
+		for i, size := range sizes {
+			largest.add(largeFile{path: strconv.Itoa(i), size: size, modified: now})
+		}
+		_ = largest.sorted()
+	}
+}
+
+func BenchmarkLargestFilesBubbleSort(b *testing.B) {
+	sizes := syntheticFileSizes(100_000)
+	now := time.Now()
+
+	for b.Loop() {
+		var largest []map[string]interface{}
+		for i, size := range sizes {
+			largest = bubbleLargestFiles(largest, map[string]interface{}{"path": strconv.Itoa(i), "size": size, "modified": now})
+		}
+	}
+}
+
+func TestBatchFileRead(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("one\ntwo\nthree\nfour\n"), 0o644))
+
+	result := runBatchOperation(t, dir, BatchOperation{Type: "file_read", Params: map[string]interface{}{"file": "notes.txt"}})
+	require.True(t, result.Success, result.Error)
+	resultMap := result.Result.(map[string]interface{})
+	require.Equal(t, "one\ntwo\nthree\nfour", resultMap["content"])
+	require.Equal(t, 4, resultMap["lines_read"])
+	require.Equal(t, 4, resultMap["total_lines"])
+	require.NotContains(t, resultMap, "note")
+
+	result = runBatchOperation(t, dir, BatchOperation{Type: "file_read", Params: map[string]interface{}{
+		"file": "notes.txt", "offset": float64(1), "limit": float64(2),
+	}})
+	require.True(t, result.Success, result.Error)
+	resultMap = result.Result.(map[string]interface{})
+	require.Equal(t, "two\nthree", resultMap["content"])
+	require.Equal(t, 2, resultMap["lines_read"])
+	require.Equal(t, "file has more lines; use offset 3 to continue", resultMap["note"])
+
+	result = runBatchOperation(t, dir, BatchOperation{Type: "file_read", Params: map[string]interface{}{"file": "../outside.txt"}})
+	require.False(t, result.Success)
+	require.Contains(t, result.Error, "path traversal")
+
+	result = runBatchOperation(t, dir, BatchOperation{Type: "file_read", Params: map[string]interface{}{"file": "notes.txt", "limit": float64(0)}})
+	require.False(t, result.Success)
+}
+
+func TestBatchFileReadTruncatesHugeContent(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	line := strings.Repeat("x", 999) + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "big.txt"), []byte(strings.Repeat(line, 300)), 0o644))
+
+	result := runBatchOperation(t, dir, BatchOperation{Type: "file_read", Params: map[string]interface{}{"file": "big.txt"}})
+	require.True(t, result.Success, result.Error)
+	resultMap := result.Result.(map[string]interface{})
+	require.Equal(t, true, resultMap["truncated"])
+	require.LessOrEqual(t, len(resultMap["content"].(string)), MaxReadSize)
+	require.Equal(t, 256, resultMap["lines_read"])
+	require.Contains(t, resultMap["note"], "use offset 256 to continue")
+}
+
+func TestBatchFileReadOutputIncludesContent(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.go"), []byte("package b\n"), 0o644))
+
+	tool := &batchTool{permissions: permission.NewPermissionService(dir, true, nil), workingDir: dir}
+	input, err := json.Marshal(BatchParams{Operations: []BatchOperation{
+		{Type: "file_read", Params: map[string]interface{}{"file": "a.go"}},
+		{Type: "file_read", Params: map[string]interface{}{"file": "b.go"}},
+	}})
+	require.NoError(t, err)
+	resp, err := tool.Run(context.Background(), ToolCall{Name: BatchToolName, Input: string(input)})
+	require.NoError(t, err)
+	require.Contains(t, resp.Content, "Read 1 of 1 lines from a.go starting at line 1")
+	require.Contains(t, resp.Content, "package a")
+	require.Contains(t, resp.Content, "package b")
+}
+
+func TestBatchFileCopyPreservesMode(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "run.sh"), []byte("#!/bin/sh\necho hi\n"), 0o755))
+
+	result := runBatchOperation(t, dir, BatchOperation{
+		Type:   "file_copy",
+		Params: map[string]interface{}{"source": "run.sh", "destination": "bin/run.sh"},
+	})
+	require.True(t, result.Success, result.Error)
+
+	info, err := os.Stat(filepath.Join(dir, "bin", "run.sh"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+	content, err := os.ReadFile(filepath.Join(dir, "bin", "run.sh"))
+	require.NoError(t, err)
+	require.Equal(t, "#!/bin/sh\necho hi\n", string(content))
+}
+
+func TestBatchFileCopyDirectory(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src", "nested", "deeper"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "top.txt"), []byte("top"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "nested", "tool"), []byte("tool"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "nested", "deeper", "leaf.txt"), []byte("leaf"), 0o600))
+
+	result := runBatchOperation(t, dir, BatchOperation{
+		Type:   "copy",
+		Params: map[string]interface{}{"source": "src", "destination": "dst"},
+	})
+	require.True(t, result.Success, result.Error)
+	summary := result.Result.(map[string]interface{})
+	require.Equal(t, 3, summary["files_copied"])
+	require.EqualValues(t, 11, summary["size_bytes"])
+
+	for path, want := range map[string]os.FileMode{
+		"top.txt":                0o644,
+		"nested/tool":            0o700,
+		"nested/deeper/leaf.txt": 0o600,
+	} {
+		info, err := os.Stat(filepath.Join(dir, "dst", path))
+		require.NoError(t, err, path)
+		require.Equal(t, want, info.Mode().Perm(), path)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "dst", "nested", "deeper", "leaf.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "leaf", string(content))
+}
+
+func TestBatchFileCopyReadOnlyDirectory(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src", "nested"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "nested", "leaf.txt"), []byte("leaf"), 0o444))
+	require.NoError(t, os.Chmod(filepath.Join(dir, "src", "nested"), 0o555))
+	require.NoError(t, os.Chmod(filepath.Join(dir, "src"), 0o555))
+	t.Cleanup(func() {
+		// Let TempDir remove the read-only trees
+		for _, path := range []string{"src", "src/nested", "dst", "dst/nested"} {
+			_ = os.Chmod(filepath.Join(dir, path), 0o755)
+		}
+	})
+
+	result := runBatchOperation(t, dir, BatchOperation{
+		Type:   "file_copy",
+		Params: map[string]interface{}{"source": "src", "destination": "dst"},
+	})
+	require.True(t, result.Success, result.Error)
+
+	for path, want := range map[string]os.FileMode{
+		"":                0o555,
+		"nested":          0o555,
+		"nested/leaf.txt": 0o444,
+	} {
+		info, err := os.Stat(filepath.Join(dir, "dst", path))
+		require.NoError(t, err, path)
+		require.Equal(t, want, info.Mode().Perm(), path)
+	}
+}
+
+func TestBatchFormatsCopyAlias(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0o644))
+
+	tool := &batchTool{permissions: permission.NewPermissionService(dir, true, nil), workingDir: dir}
+	results := tool.executeSequential(context.Background(), []BatchOperation{
+		{Type: "copy", Params: map[string]interface{}{"source": "a.txt", "destination": "b.txt"}},
+	})
+	require.True(t, results[0].Success, results[0].Error)
+	require.Contains(t, tool.formatBatchResults(results), "Copied 5 bytes to b.txt")
+}
+
+func TestBatchFileCopyErrors(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "a.txt"), []byte("a"), 0o644))
+
+	tests := []struct {
+		name        string
+		source      string
+		destination string
+		wantErr     string
+	}{
+		{name: "directory into itself", source: "src", destination: "src/copy", wantErr: "into itself"},
+		{name: "directory onto itself", source: "src", destination: "src", wantErr: "into itself"},
+		{name: "file onto itself", source: "src/a.txt", destination: "src/a.txt", wantErr: "into itself"},
+		{name: "source outside working directory", source: "../outside", destination: "dst", wantErr: "path traversal"},
+		{name: "destination outside working directory", source: "src/a.txt", destination: "../a.txt", wantErr: "path traversal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := runBatchOperation(t, dir, BatchOperation{
+				Type:   "file_copy",
+				Params: map[string]interface{}{"source": tt.source, "destination": tt.destination},
+			})
+			require.False(t, result.Success)
+			require.Contains(t, result.Error, tt.wantErr)
+		})
+	}
+
+	_, err := os.Stat(filepath.Join(dir, "src", "copy"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func runBatch(t *testing.T, workingDir string, params BatchParams) ToolResponse {
+	t.Helper()
+
+	input, err := json.Marshal(params)
+	require.NoError(t, err)
+
+	tool := NewBatchTool(permission.NewPermissionService(workingDir, true, nil), workingDir)
+	resp, err := tool.Run(context.Background(), ToolCall{Name: BatchToolName, Input: string(input)})
+	require.NoError(t, err)
+	return resp
+}
+
+func TestOperationOrder(t *testing.T) {
+	t.Parallel()
+
+	order, err := operationOrder([]BatchOperation{
+		{Type: "file_copy", DependsOn: []int{3}},
+		{Type: "file_read"},
+		{Type: "file_copy", DependsOn: []int{2}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 0}, order)
+
+	_, err = operationOrder([]BatchOperation{
+		{Type: "file_read"},
+		{Type: "file_copy", DependsOn: []int{3}},
+		{Type: "file_copy", DependsOn: []int{2}},
+	})
+	require.EqualError(t, err, "dependency cycle between operations 2, 3")
+
+	_, err = operationOrder([]BatchOperation{{Type: "file_read", DependsOn: []int{1}}})
+	require.EqualError(t, err, "operation 1 depends on itself")
+
+	_, err = operationOrder([]BatchOperation{{Type: "file_read", DependsOn: []int{4}}})
+	require.EqualError(t, err, "operation 1 depends on operation 4, which does not exist")
+
+	// Operations are numbered from 1, as in the result headers
+	_, err = operationOrder([]BatchOperation{{Type: "file_read"}, {Type: "file_read", DependsOn: []int{0}}})
+	require.EqualError(t, err, "operation 2 depends on operation 0, which does not exist")
+}
+
+func TestBatchDependencyChain(t *testing.T) {
+	t.Parallel()
+
+	for _, parallel := range []bool{false, true} {
+		t.Run(fmt.Sprintf("parallel=%v", parallel), func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{"a.txt": "hello"})
+
+			tool := &batchTool{permissions: permission.NewPermissionService(dir, true, nil), workingDir: dir}
+			operations := []BatchOperation{
+				{Type: "file_copy", Params: map[string]interface{}{"source": "a.txt", "destination": "b.txt"}},
+				{Type: "file_copy", Params: map[string]interface{}{"source": "b.txt", "destination": "c.txt"}, DependsOn: []int{1}},
+				{Type: "file_read", Params: map[string]interface{}{"file": "c.txt"}, DependsOn: []int{2}},
+			}
+			var results []BatchResult
+			if parallel {
+				results = tool.executeParallel(context.Background(), operations)
+			} else {
+				results = tool.executeSequential(context.Background(), operations)
+			}
+			for _, result := range results {
+				require.True(t, result.Success, result.Error)
+			}
+			require.Equal(t, "hello", results[2].Result.(map[string]interface{})["content"])
+		})
+	}
+}
+
+func TestBatchDependencyFailureSkipsDependents(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	tool := &batchTool{permissions: permission.NewPermissionService(dir, true, nil), workingDir: dir}
+	results := tool.executeParallel(context.Background(), []BatchOperation{
+		{Type: "file_copy", Params: map[string]interface{}{"source": "missing.txt", "destination": "b.txt"}},
+		{Type: "file_read", Params: map[string]interface{}{"file": "b.txt"}, DependsOn: []int{1}},
+		{Type: "file_read", Params: map[string]interface{}{"file": "b.txt"}, DependsOn: []int{2}},
+		{Type: "file_search", Params: map[string]interface{}{"query": "x"}},
+	})
+
+	require.False(t, results[0].Success)
+	require.False(t, results[0].Skipped)
+	require.True(t, results[1].Skipped)
+	require.Equal(t, "skipped because operation 1 did not succeed", results[1].Error)
+	require.True(t, results[2].Skipped)
+	require.Equal(t, "skipped because operation 2 did not succeed", results[2].Error)
+	require.False(t, results[3].Skipped)
+
+	formatted := tool.formatBatchResults(results)
+	require.Contains(t, formatted, "## Operation 2: file_read\n")
+	require.Contains(t, formatted, "**Skipped:** skipped because operation 1 did not succeed")
+}
+
+func TestBatchRejectsDependencyCycle(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	resp := runBatch(t, dir, BatchParams{
+		Parallel: true,
+		Operations: []BatchOperation{
+			{Type: "file_read", Params: map[string]interface{}{"file": "a.txt"}, DependsOn: []int{2}},
+			{Type: "file_read", Params: map[string]interface{}{"file": "b.txt"}, DependsOn: []int{1}},
+		},
+	})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "Invalid operation dependencies: dependency cycle between operations 1, 2")
+}
+
+func TestBatchGoRename(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"util.go": `package util
+
+import "strings"
+
+// parse splits the input; parse is used by Run
+func parse(s string) []string {
+	return strings.Fields(s)
+}
+
+type config struct{ parse bool }
+
+func shadow() string {
+	parse := "local"
+	return parse
+}
+`,
+		"run.go": `package util
+
+func Run(c config) []string {
+	if c.parse {
+		return parse("parse me")
+	}
+	return nil
+}
+`,
+		"util_ext_test.go": "package util_test\n\nfunc parse() {}\n",
+	})
+
+	result := runBatchOperation(t, dir, BatchOperation{Type: "go_rename", Params: map[string]interface{}{
+		"path": ".", "old_name": "parse", "new_name": "tokenize",
+	}})
+	require.True(t, result.Success, result.Error)
+	resultMap := result.Result.(map[string]interface{})
+	require.Equal(t, 2, resultMap["replacements"])
+	require.Equal(t, []string{filepath.Join(dir, "run.go"), filepath.Join(dir, "util.go")}, resultMap["files"])
+
+	util, err := os.ReadFile(filepath.Join(dir, "util.go"))
+	require.NoError(t, err)
+	require.Contains(t, string(util), "// parse splits the input; parse is used by Run\nfunc tokenize(s string)")
+	require.Contains(t, string(util), "type config struct{ parse bool }")
+	require.Contains(t, string(util), "parse := \"local\"\n\treturn parse\n")
+
+	run, err := os.ReadFile(filepath.Join(dir, "run.go"))
+	require.NoError(t, err)
+	require.Contains(t, string(run), "if c.parse {\n\t\treturn tokenize(\"parse me\")")
+
+	ext, err := os.ReadFile(filepath.Join(dir, "util_ext_test.go"))
+	require.NoError(t, err)
+	require.Equal(t, "package util_test\n\nfunc parse() {}\n", string(ext))
+}
+
+func TestBatchGoRenameLocals(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"main.go": `package main
+
+import "fmt"
+
+func main() {
+	count := 3
+	fmt.Println(count) // print count
+	switch count := any(count).(type) {
+	case int:
+		fmt.Println(count + 1)
+	}
+}
+`})
+
+	result := runBatchOperation(t, dir, BatchOperation{Type: "go_rename", Params: map[string]interface{}{
+		"path": "main.go", "old_name": "count", "new_name": "total",
+	}})
+	require.True(t, result.Success, result.Error)
+	require.Equal(t, 5, result.Result.(map[string]interface{})["replacements"])
+
+	content, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	require.NoError(t, err)
+	require.Equal(t, `package main
+
+import "fmt"
+
+func main() {
+	total := 3
+	fmt.Println(total) // print count
+	switch total := any(total).(type) {
+	case int:
+		fmt.Println(total + 1)
+	}
+}
+`, string(content))
+}
+
+func TestBatchGoRenameFileRenamesPackage(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"util.go": "package util\n\ntype config struct{ verbose bool }\n\nfunc parse(c config) bool {\n\tcount := 1\n\treturn c.verbose && count > 0\n}\n",
+		"run.go":  "package util\n\nfunc Run() bool {\n\tcount := 2\n\treturn parse(config{verbose: count > 1})\n}\n",
+	})
+
+	// A package-level function or a field declared in util.go is also renamed
+	// where run.go refers to it
+	for _, rename := range [][2]string{{"parse", "tokenize"}, {"verbose", "debug"}} {
+		result := runBatchOperation(t, dir, BatchOperation{Type: "go_rename", Params: map[string]interface{}{
+			"path": "util.go", "old_name": rename[0], "new_name": rename[1],
+		}})
+		require.True(t, result.Success, result.Error)
+		require.Equal(t, []string{filepath.Join(dir, "run.go"), filepath.Join(dir, "util.go")}, result.Result.(map[string]interface{})["files"])
+	}
+
+	// A local stays within the file path names
+	result := runBatchOperation(t, dir, BatchOperation{Type: "go_rename", Params: map[string]interface{}{
+		"path": "util.go", "old_name": "count", "new_name": "total",
+	}})
+	require.True(t, result.Success, result.Error)
+	require.Equal(t, []string{filepath.Join(dir, "util.go")}, result.Result.(map[string]interface{})["files"])
+
+	util, err := os.ReadFile(filepath.Join(dir, "util.go"))
+	require.NoError(t, err)
+	require.Equal(t, "package util\n\ntype config struct{ debug bool }\n\nfunc tokenize(c config) bool {\n\ttotal := 1\n\treturn c.debug && total > 0\n}\n", string(util))
+	run, err := os.ReadFile(filepath.Join(dir, "run.go"))
+	require.NoError(t, err)
+	require.Equal(t, "package util\n\nfunc Run() bool {\n\tcount := 2\n\treturn tokenize(config{debug: count > 1})\n}\n", string(run))
+}
+
+func TestBatchOperationTypesIncludeGoRename(t *testing.T) {
+	t.Parallel()
+
+	info := NewBatchTool(permission.NewPermissionService(t.TempDir(), true, nil), t.TempDir()).Info()
+	operations := info.Parameters["properties"].(map[string]any)["operations"].(map[string]any)
+	items := operations["items"].(map[string]any)
+	typ := items["properties"].(map[string]any)["type"].(map[string]any)
+	require.Contains(t, typ["enum"], "go_rename")
+}
+
+func TestBatchGoRenameRejectsConflicts(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	const source = "package util\n\nfunc parse() {}\n\nfunc tokenize() { parse() }\n"
+	writeFiles(t, dir, map[string]string{"util.go": source})
+
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+		err    string
+	}{
+		{
+			name:   "name in use",
+			params: map[string]interface{}{"path": "util.go", "old_name": "parse", "new_name": "tokenize"},
+			err:    "tokenize is already declared or used",
+		},
+		{
+			name:   "invalid identifier",
+			params: map[string]interface{}{"path": "util.go", "old_name": "parse", "new_name": "func"},
+			err:    "not a valid Go identifier",
+		},
+		{
+			name:   "missing declaration",
+			params: map[string]interface{}{"path": "util.go", "old_name": "missing", "new_name": "found"},
+			err:    "no declaration of missing",
+		},
+		{
+			name:   "outside working directory",
+			params: map[string]interface{}{"path": "../util.go", "old_name": "parse", "new_name": "split"},
+			err:    "path traversal not allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := runBatchOperation(t, dir, BatchOperation{Type: "go_rename", Params: tt.params})
+			require.False(t, result.Success)
+			require.Contains(t, result.Error, tt.err)
+		})
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "util.go"))
+	require.NoError(t, err)
+	require.Equal(t, source, string(content))
+}
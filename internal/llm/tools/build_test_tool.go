@@ -0,0 +1,191 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/language"
+	"github.com/charmbracelet/crush/internal/permission"
+)
+
+type BuildTestParams struct {
+	Action   string `json:"action"`             // "build", "test"
+	Language string `json:"language,omitempty"` // Optional override
+	Timeout  int    `json:"timeout,omitempty"`  // Seconds
+}
+
+type BuildTestResult struct {
+	Action   string `json:"action"`
+	Language string `json:"language"`
+	Command  string `json:"command"`
+	Success  bool   `json:"success"`
+	ExitCode int    `json:"exit_code"`
+	Output   string `json:"output"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+	// DetectedLanguages lists every language found when the project is polyglot
+	DetectedLanguages []language.DetectionResult `json:"detected_languages,omitempty"`
+}
+
+type buildTestTool struct {
+	permissions permission.Service
+	workingDir  string
+}
+
+const (
+	BuildTestToolName = "build_test"
+
+	defaultBuildTestTimeout = 10 * time.Minute
+	maxBuildTestTimeout     = 30 * time.Minute
+)
+
+func NewBuildTestTool(permissions permission.Service, workingDir string) BaseTool {
+	return &buildTestTool{
+		permissions: permissions,
+		workingDir:  workingDir,
+	}
+}
+
+func (t *buildTestTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        BuildTestToolName,
+		Description: "Build or test the project using its language's build and test commands, such as go build or cargo test. Reports the command output and exit status. Commands can be overridden per project in .crush/language.json.",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"action": map[string]any{
+					"type":        "string",
+					"enum":        []string{"build", "test"},
+					"description": "Action to perform: build the project or run its tests",
+				},
+				"language": map[string]any{
+					"type":        "string",
+					"description": "Override language detection (optional). Set this when the result lists several detected_languages and another one should be used",
+				},
+				"timeout": map[string]any{
+					"type":        "integer",
+					"description": fmt.Sprintf("Timeout in seconds (optional, default %d, max %d)", int(defaultBuildTestTimeout.Seconds()), int(maxBuildTestTimeout.Seconds())),
+				},
+			},
+			"required": []string{"action"},
+		},
+	}
+}
+
+func (t *buildTestTool) Name() string {
+	return BuildTestToolName
+}
+
+func (t *buildTestTool) Run(ctx context.Context, params ToolCall) (ToolResponse, error) {
+	var buildParams BuildTestParams
+	if err := json.Unmarshal([]byte(params.Input), &buildParams); err != nil {
+		return NewTextErrorResponse("Invalid parameters"), nil
+	}
+	if buildParams.Action != "build" && buildParams.Action != "test" {
+		return NewTextErrorResponse(fmt.Sprintf("Invalid action %q: must be build or test", buildParams.Action)), nil
+	}
+
+	config, err := language.LoadProjectLanguageConfig(t.workingDir)
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("Invalid language configuration: %v", err)), nil
+	}
+
+	languageName := buildParams.Language
+	var detected []language.DetectionResult
+	if languageName == "" {
+		results, err := language.DetectLanguages(t.workingDir)
+		if err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("Failed to detect language: %v", err)), nil
+		}
+		languageName = language.PrimaryLanguage(results).Name
+		if len(results) > 1 {
+			detected = results
+		}
+	}
+
+	lang, exists := config.Languages[languageName]
+	if !exists {
+		return NewTextErrorResponse(fmt.Sprintf("Unsupported language: %s", languageName)), nil
+	}
+	command := lang.BuildCommand
+	if buildParams.Action == "test" {
+		command = lang.TestCommand
+	}
+	if command == "" {
+		return NewTextErrorResponse(fmt.Sprintf("No %s command configured for %s", buildParams.Action, languageName)), nil
+	}
+
+	// Builds and tests run project code, so they always need permission
+	sessionID, _ := GetContextValues(ctx)
+	if sessionID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID is required for running build and test commands")
+	}
+	granted := t.permissions.Request(permission.CreatePermissionRequest{
+		SessionID:   sessionID,
+		ToolCallID:  params.ID,
+		ToolName:    BuildTestToolName,
+		Action:      buildParams.Action,
+		Path:        t.workingDir,
+		Description: fmt.Sprintf("Run %s command: %s", buildParams.Action, command),
+	})
+	if !granted {
+		return NewTextErrorResponse(fmt.Sprintf("Permission denied to %s the project", buildParams.Action)), nil
+	}
+
+	timeout := defaultBuildTestTimeout
+	if buildParams.Timeout > 0 {
+		timeout = min(time.Duration(buildParams.Timeout)*time.Second, maxBuildTestTimeout)
+	}
+
+	result := t.runCommand(ctx, command, timeout)
+	result.Action = buildParams.Action
+	result.Language = languageName
+	result.DetectedLanguages = detected
+
+	output, _ := json.Marshal(result)
+	return NewTextResponse(string(output)), nil
+}
+
+// runCommand runs command in the working directory, logging its output line
+// by line as it is produced and capturing it for the result
+func (t *buildTestTool) runCommand(ctx context.Context, command string, timeout time.Duration) *BuildTestResult {
+	result := &BuildTestResult{Command: command, ExitCode: -1}
+
+	parts := strings.Fields(command)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
+	cmd.Dir = t.workingDir
+
+	start := time.Now()
+	output, err := runStreaming(command, cmd, logBuildTestOutput)
+	result.Duration = time.Since(start).Round(time.Millisecond).String()
+	result.Output = truncateOutput(string(output.combined))
+
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.Error = fmt.Sprintf("command timed out after %s", timeout)
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		result.Error = err.Error()
+	case err != nil:
+		result.Error = err.Error()
+	default:
+		result.ExitCode = 0
+		result.Success = true
+	}
+	return result
+}
+
+// logBuildTestOutput logs a line of build or test output at debug level
+func logBuildTestOutput(command, line string) {
+	slog.Debug("Build/test output", "command", command, "line", line)
+}
@@ -0,0 +1,125 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/stretchr/testify/require"
+)
+
+// goProject writes a minimal Go module with the given files to a temp dir
+func goProject(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/fake\n\ngo 1.21\n"
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	return dir
+}
+
+// newBuildTestTool returns a build/test tool for dir whose permission
+// requests are all granted, and a context carrying a session for them
+func newBuildTestTool(dir string) (BaseTool, context.Context) {
+	tool := NewBuildTestTool(permission.NewPermissionService(dir, true, nil), dir)
+	return tool, context.WithValue(context.Background(), SessionIDContextKey, "session")
+}
+
+func runBuildTest(t *testing.T, ctx context.Context, tool BaseTool, params BuildTestParams) (ToolResponse, BuildTestResult) {
+	t.Helper()
+
+	input, err := json.Marshal(params)
+	require.NoError(t, err)
+	resp, err := tool.Run(ctx, ToolCall{ID: "call-1", Name: BuildTestToolName, Input: string(input)})
+	require.NoError(t, err)
+
+	var result BuildTestResult
+	if !resp.IsError {
+		require.NoError(t, json.Unmarshal([]byte(resp.Content), &result))
+	}
+	return resp, result
+}
+
+func TestBuildTestBuildsGoProject(t *testing.T) {
+	t.Parallel()
+
+	dir := goProject(t, map[string]string{"main.go": "package main\n\nfunc main() {}\n"})
+	tool, ctx := newBuildTestTool(dir)
+	resp, result := runBuildTest(t, ctx, tool, BuildTestParams{Action: "build"})
+	require.False(t, resp.IsError, resp.Content)
+	require.Equal(t, "go", result.Language)
+	require.Equal(t, "go build", result.Command)
+	require.True(t, result.Success, result.Output)
+	require.Equal(t, 0, result.ExitCode)
+	require.NotEmpty(t, result.Duration)
+}
+
+func TestBuildTestReportsBuildFailure(t *testing.T) {
+	t.Parallel()
+
+	dir := goProject(t, map[string]string{"main.go": "package main\n\nfunc main() { undefinedCall() }\n"})
+	tool, ctx := newBuildTestTool(dir)
+	resp, result := runBuildTest(t, ctx, tool, BuildTestParams{Action: "build"})
+	require.False(t, resp.IsError, resp.Content)
+	require.False(t, result.Success)
+	require.Equal(t, 1, result.ExitCode)
+	require.Contains(t, result.Output, "undefined: undefinedCall")
+}
+
+func TestBuildTestRunsTests(t *testing.T) {
+	t.Parallel()
+
+	dir := goProject(t, map[string]string{
+		"main.go":      "package main\n\nfunc add(a, b int) int { return a + b }\n\nfunc main() {}\n",
+		"main_test.go": "package main\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif add(1, 2) != 4 {\n\t\tt.Fatal(\"bad sum\")\n\t}\n}\n",
+	})
+	tool, ctx := newBuildTestTool(dir)
+	resp, result := runBuildTest(t, ctx, tool, BuildTestParams{Action: "test", Language: "go"})
+	require.False(t, resp.IsError, resp.Content)
+	require.Equal(t, "go test", result.Command)
+	require.False(t, result.Success)
+	require.Equal(t, 1, result.ExitCode)
+	require.Contains(t, result.Output, "bad sum")
+}
+
+func TestBuildTestRequiresPermission(t *testing.T) {
+	t.Parallel()
+
+	dir := goProject(t, map[string]string{"main.go": "package main\n\nfunc main() {}\n"})
+	smart := permission.NewSmartPermissionService(permission.NewPermissionService(dir, true, nil), dir, false)
+	require.NoError(t, smart.SetDenyRules([]string{BuildTestToolName + ":build:**"}))
+
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, "session")
+	resp, _ := runBuildTest(t, ctx, NewBuildTestTool(smart, dir), BuildTestParams{Action: "build"})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "Permission denied to build the project")
+}
+
+func TestBuildTestRequiresSession(t *testing.T) {
+	t.Parallel()
+
+	dir := goProject(t, map[string]string{"main.go": "package main\n\nfunc main() {}\n"})
+	input, err := json.Marshal(BuildTestParams{Action: "build"})
+	require.NoError(t, err)
+	// Permission can't be requested without a session, so the build must not run
+	_, err = NewBuildTestTool(permission.NewPermissionService(dir, true, nil), dir).Run(context.Background(), ToolCall{Name: BuildTestToolName, Input: string(input)})
+	require.ErrorContains(t, err, "session ID is required")
+}
+
+func TestBuildTestRejectsInvalidAction(t *testing.T) {
+	t.Parallel()
+
+	resp, _ := runBuildTest(t, context.Background(), NewBuildTestTool(nil, t.TempDir()), BuildTestParams{Action: "deploy"})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "must be build or test")
+}
@@ -4,15 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/charmbracelet/crush/internal/checkpoint"
 	"github.com/charmbracelet/crush/internal/permission"
 )
 
 type CheckpointParams struct {
-	Action  string `json:"action"` // "create", "list", "restore", "delete"
-	Message string `json:"message,omitempty"`
-	ID      string `json:"id,omitempty"`
+	Action  string   `json:"action"` // "create", "list", "restore", "delete"
+	Message string   `json:"message,omitempty"`
+	ID      string   `json:"id,omitempty"`
+	Tags    []string `json:"tags,omitempty"` // Labels for create
+	Tag     string   `json:"tag,omitempty"`  // Filter for list
 }
 
 type checkpointTool struct {
@@ -51,6 +54,15 @@ func (t *checkpointTool) Info() ToolInfo {
 					"type":        "string",
 					"description": "Checkpoint ID (required for restore and delete actions)",
 				},
+				"tags": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "string"},
+					"description": "Labels grouping the checkpoint, such as before-refactor or experiment-A (optional, create action only)",
+				},
+				"tag": map[string]any{
+					"type":        "string",
+					"description": "Only list checkpoints carrying this label (optional, list action only)",
+				},
 			},
 			"required": []string{"action"},
 		},
@@ -72,10 +84,10 @@ func (t *checkpointTool) Run(ctx context.Context, params ToolCall) (ToolResponse
 		if checkpointParams.Message == "" {
 			return NewTextErrorResponse("Message is required for creating checkpoints"), nil
 		}
-		return t.createCheckpoint(ctx, checkpointParams.Message)
+		return t.createCheckpoint(ctx, checkpointParams.Message, checkpointParams.Tags)
 
 	case "list":
-		return t.listCheckpoints(ctx)
+		return t.listCheckpoints(ctx, checkpointParams.Tag)
 
 	case "restore":
 		if checkpointParams.ID == "" {
@@ -94,8 +106,9 @@ func (t *checkpointTool) Run(ctx context.Context, params ToolCall) (ToolResponse
 	}
 }
 
-func (t *checkpointTool) createCheckpoint(ctx context.Context, message string) (ToolResponse, error) {
-	checkpoint, err := t.checkpointService.CreateCheckpoint(ctx, message)
+func (t *checkpointTool) createCheckpoint(ctx context.Context, message string, tags []string) (ToolResponse, error) {
+	sessionID, _ := GetContextValues(ctx)
+	checkpoint, err := t.checkpointService.CreateCheckpoint(ctx, sessionID, message, tags)
 	if err != nil {
 		return NewTextErrorResponse(fmt.Sprintf("Failed to create checkpoint: %v", err)), nil
 	}
@@ -104,24 +117,36 @@ func (t *checkpointTool) createCheckpoint(ctx context.Context, message string) (
 		"action":     "create",
 		"success":    true,
 		"checkpoint": checkpoint,
-		"message":    fmt.Sprintf("Created checkpoint '%s' (ID: %s)", checkpoint.Message, checkpoint.ID),
+		"message":    fmt.Sprintf("Created checkpoint '%s' (ID: %s)%s", checkpoint.Message, checkpoint.ID, formatTags(checkpoint.Tags)),
 	}
 
 	output, _ := json.Marshal(result)
 	return NewTextResponse(string(output)), nil
 }
 
-func (t *checkpointTool) listCheckpoints(ctx context.Context) (ToolResponse, error) {
+func (t *checkpointTool) listCheckpoints(ctx context.Context, tag string) (ToolResponse, error) {
 	checkpoints, err := t.checkpointService.ListCheckpoints(ctx)
 	if err != nil {
 		return NewTextErrorResponse(fmt.Sprintf("Failed to list checkpoints: %v", err)), nil
 	}
+	if tag != "" {
+		checkpoints = checkpoints.WithTag(tag)
+	}
+
+	summary := make([]string, 0, len(checkpoints.Checkpoints))
+	for _, checkpoint := range checkpoints.Checkpoints {
+		summary = append(summary, fmt.Sprintf("%s: %s%s", checkpoint.ID, checkpoint.Message, formatTags(checkpoint.Tags)))
+	}
 
 	result := map[string]interface{}{
 		"action":      "list",
 		"success":     true,
 		"checkpoints": checkpoints.Checkpoints,
 		"count":       len(checkpoints.Checkpoints),
+		"summary":     summary,
+	}
+	if tag != "" {
+		result["tag"] = tag
 	}
 
 	output, _ := json.Marshal(result)
@@ -129,7 +154,8 @@ func (t *checkpointTool) listCheckpoints(ctx context.Context) (ToolResponse, err
 }
 
 func (t *checkpointTool) restoreCheckpoint(ctx context.Context, toolCallID, id string) (ToolResponse, error) {
-	err := t.checkpointService.RestoreCheckpoint(ctx, id)
+	sessionID, _ := GetContextValues(ctx)
+	err := t.checkpointService.RestoreCheckpoint(ctx, sessionID, toolCallID, id)
 	if err != nil {
 		return NewTextErrorResponse(fmt.Sprintf("Failed to restore checkpoint: %v", err)), nil
 	}
@@ -160,4 +186,12 @@ func (t *checkpointTool) deleteCheckpoint(ctx context.Context, id string) (ToolR
 
 	output, _ := json.Marshal(result)
 	return NewTextResponse(string(output)), nil
-}
\ No newline at end of file
+}
+
+// formatTags renders tags as a suffix such as " [before-refactor, experiment-A]"
+func formatTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(tags, ", ") + "]"
+}
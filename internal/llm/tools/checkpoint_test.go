@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"sync"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingPermissions records permission requests and answers them with grant
+type recordingPermissions struct {
+	permission.Service
+
+	mu       sync.Mutex
+	grant    bool
+	requests []permission.CreatePermissionRequest
+}
+
+func (p *recordingPermissions) Request(opts permission.CreatePermissionRequest) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, opts)
+	return p.grant
+}
+
+// initGitRepo creates a git repository with a single commit and returns its directory
+func initGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"README.md": "# Project\n"})
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	return dir
+}
+
+func TestCheckpointRestoreRequestsPermission(t *testing.T) {
+	t.Parallel()
+
+	dir := initGitRepo(t)
+	permissions := &recordingPermissions{grant: false}
+	tool := NewCheckpointTool(permissions, dir)
+
+	input, err := json.Marshal(CheckpointParams{Action: "restore", ID: "HEAD"})
+	require.NoError(t, err)
+
+	// No message ID in the context, which previously skipped the prompt
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, "session-1")
+	resp, err := tool.Run(ctx, ToolCall{ID: "call-1", Name: CheckpointToolName, Input: string(input)})
+	require.NoError(t, err)
+
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "permission denied")
+	require.Len(t, permissions.requests, 1)
+	request := permissions.requests[0]
+	require.Equal(t, "session-1", request.SessionID)
+	require.Equal(t, "call-1", request.ToolCallID)
+	require.Equal(t, "restore", request.Action)
+	require.Equal(t, dir, request.Path)
+}
+
+func TestCheckpointListFiltersByTag(t *testing.T) {
+	t.Parallel()
+
+	dir := initGitRepo(t)
+	tool := NewCheckpointTool(permission.NewPermissionService(dir, true, nil), dir)
+	run := func(params CheckpointParams) map[string]any {
+		t.Helper()
+		input, err := json.Marshal(params)
+		require.NoError(t, err)
+		resp, err := tool.Run(t.Context(), ToolCall{ID: "call-1", Name: CheckpointToolName, Input: string(input)})
+		require.NoError(t, err)
+		require.False(t, resp.IsError, resp.Content)
+
+		var result map[string]any
+		require.NoError(t, json.Unmarshal([]byte(resp.Content), &result))
+		return result
+	}
+
+	writeFiles(t, dir, map[string]string{"README.md": "# Refactor\n"})
+	created := run(CheckpointParams{Action: "create", Message: "before refactor", Tags: []string{"before-refactor"}})
+	require.Contains(t, created["message"], "[before-refactor]")
+
+	writeFiles(t, dir, map[string]string{"README.md": "# Experiment\n"})
+	run(CheckpointParams{Action: "create", Message: "trying things", Tags: []string{"experiment-A"}})
+
+	listed := run(CheckpointParams{Action: "list", Tag: "before-refactor"})
+	require.EqualValues(t, 1, listed["count"])
+	checkpoints := listed["checkpoints"].([]any)
+	require.Equal(t, []any{"before-refactor"}, checkpoints[0].(map[string]any)["tags"])
+	summary := listed["summary"].([]any)
+	require.Len(t, summary, 1)
+	require.Contains(t, summary[0], "before refactor [before-refactor]")
+
+	all := run(CheckpointParams{Action: "list"})
+	require.EqualValues(t, 3, all["count"])
+}
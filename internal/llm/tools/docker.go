@@ -1,13 +1,22 @@
 package tools
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/charmbracelet/crush/internal/permission"
 )
@@ -22,6 +31,17 @@ type DockerAppBuilderParams struct {
 	Command     string            `json:"command,omitempty"`
 	Port        string            `json:"port,omitempty"`
 	Environment map[string]string `json:"environment,omitempty"`
+	EnvFile     string            `json:"env_file,omitempty"`
+	Shell       bool              `json:"shell,omitempty"`
+	Registry    string            `json:"registry,omitempty"`
+	Tag         string            `json:"tag,omitempty"`
+	PruneFiles  bool              `json:"prune_files,omitempty"`
+	NoCache     bool              `json:"no_cache,omitempty"`
+	PullBase    bool              `json:"pull_base,omitempty"`
+	Dockerfile  string            `json:"dockerfile,omitempty"`
+	HealthPath  string            `json:"health_path,omitempty"`
+	Network     string            `json:"network,omitempty"`
+	DryRun      bool              `json:"dry_run,omitempty"`
 }
 
 type DockerResponseMetadata struct {
@@ -30,8 +50,58 @@ type DockerResponseMetadata struct {
 	ImageID     string `json:"image_id,omitempty"`
 	ContainerID string `json:"container_id,omitempty"`
 	URL         string `json:"url,omitempty"`
+	ExitCode    *int   `json:"exit_code,omitempty"`
+	Reference   string `json:"reference,omitempty"`
+	FreedBytes  int64  `json:"freed_bytes,omitempty"`
+	BuildSteps  int    `json:"build_steps,omitempty"`
+	CachedSteps int    `json:"cached_steps,omitempty"`
+	ProjectType string `json:"project_type,omitempty"`
+	Network     string `json:"network,omitempty"`
+	// BaseImages lists the base images a build used, pinned by digest where
+	// the build output names it
+	BaseImages []string `json:"base_images,omitempty"`
+	// Healthy reports whether the image passed the validate action
+	Healthy *bool `json:"healthy,omitempty"`
+	// Containers lists the Crush app containers for the list action
+	Containers []DockerContainer `json:"containers,omitempty"`
+	// RemovedContainers and RemovedImages list what the clean action removed,
+	// or would remove on a dry run
+	RemovedContainers []string `json:"removed_containers,omitempty"`
+	RemovedImages     []string `json:"removed_images,omitempty"`
+	// DryRun reports that the clean action only listed what it would remove
+	DryRun bool `json:"dry_run,omitempty"`
 }
 
+// DockerContainer describes a Crush app container
+type DockerContainer struct {
+	Name    string `json:"name"`
+	Project string `json:"project"`
+	State   string `json:"state"`
+	Status  string `json:"status"`
+	Ports   string `json:"ports,omitempty"`
+	// Networks lists the Docker networks the container is attached to
+	Networks []string `json:"networks,omitempty"`
+}
+
+// DockerActions are the actions the Docker tool accepts
+var DockerActions = []string{"create_project", "build", "run", "stop", "list", "exec", "push", "pull", "remove", "validate", "clean"}
+
+var (
+	// registryPattern matches a registry host with an optional port and
+	// repository namespace, e.g. "ghcr.io/acme" or "localhost:5000"
+	registryPattern = regexp.MustCompile(`^[a-zA-Z0-9](?:[a-zA-Z0-9.-]*[a-zA-Z0-9])?(?::[0-9]+)?(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	// tagPattern matches a valid image tag
+	tagPattern = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127}$`)
+	// networkPattern matches a valid Docker network name
+	networkPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,63}$`)
+	// envKeyPattern matches a portable environment variable name
+	envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// defaultAppPort is the container port used when neither the port parameter
+// nor the project's Dockerfile names one
+const defaultAppPort = "3000"
+
 type dockerTool struct {
 	permissions permission.Service
 }
@@ -91,6 +161,18 @@ func (d *dockerTool) Run(ctx context.Context, call ToolCall) (ToolResponse, erro
 		return d.stopApp(ctx, params)
 	case "list":
 		return d.listContainers(ctx)
+	case "exec":
+		return d.execInContainer(ctx, params)
+	case "push":
+		return d.pushImage(ctx, params)
+	case "pull":
+		return d.pullImage(ctx, params)
+	case "remove":
+		return d.removeApp(ctx, params)
+	case "validate":
+		return d.validateImage(ctx, params)
+	case "clean":
+		return d.cleanApps(ctx, params)
 	default:
 		return NewTextErrorResponse(fmt.Sprintf("Unknown action: %s", params.Action)), nil
 	}
@@ -106,16 +188,29 @@ func (d *dockerTool) createProject(ctx context.Context, params DockerAppBuilderP
 		return NewTextErrorResponse("project_name and project_type are required for create_project action"), nil
 	}
 
+	// Detect the template for existing code, which only needs containerizing
+	projectType := params.ProjectType
+	if projectType == autoProjectType {
+		detected, err := detectProjectType(params.Files)
+		if err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("Failed to detect project type: %v", err)), nil
+		}
+		projectType = detected
+	}
+
 	projectDir := filepath.Join("/tmp", "crush-apps", params.ProjectName)
 	if err := os.MkdirAll(projectDir, 0755); err != nil {
 		return NewTextErrorResponse(fmt.Sprintf("Failed to create project directory: %v", err)), nil
 	}
 
 	// Generate project files based on type
-	projectFiles, err := d.generateProjectFiles(params.ProjectType, params.ProjectName)
+	projectFiles, err := d.generateProjectFiles(projectType, params.ProjectName)
 	if err != nil {
 		return NewTextErrorResponse(fmt.Sprintf("Failed to generate project files: %v", err)), nil
 	}
+	if params.ProjectType == autoProjectType {
+		projectFiles = containerizationFiles(projectFiles, params.Files)
+	}
 
 	// Add any custom files provided
 	for filename, content := range params.Files {
@@ -134,11 +229,12 @@ func (d *dockerTool) createProject(ctx context.Context, params DockerAppBuilderP
 	}
 
 	content := fmt.Sprintf("✅ Project '%s' created successfully!\n\nLocation: %s\nType: %s\nGenerated files: %s\n\nNext steps:\n1. Build the project: {\"action\": \"build\", \"project_name\": \"%s\"}\n2. Run the project: {\"action\": \"run\", \"project_name\": \"%s\"}", 
-		params.ProjectName, projectDir, params.ProjectType, strings.Join(getKeys(projectFiles), ", "), params.ProjectName, params.ProjectName)
+		params.ProjectName, projectDir, projectTypeLabel(params.ProjectType, projectType), strings.Join(getKeys(projectFiles), ", "), params.ProjectName, params.ProjectName)
 
 	metadata := DockerResponseMetadata{
 		Action:      "create_project",
 		ProjectName: params.ProjectName,
+		ProjectType: projectType,
 	}
 
 	return WithResponseMetadata(NewTextResponse(content), metadata), nil
@@ -154,37 +250,222 @@ func (d *dockerTool) buildApp(ctx context.Context, params DockerAppBuilderParams
 		return NewTextErrorResponse(fmt.Sprintf("Project directory %s does not exist. Create the project first using create_project action.", projectDir)), nil
 	}
 
+	dockerfile, err := projectDockerfile(projectDir, params.Dockerfile)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
 	// Build the Docker image
 	imageName := fmt.Sprintf("crush-app-%s", strings.ToLower(params.ProjectName))
 	
-	cmd := exec.CommandContext(ctx, "docker", "build", "-t", imageName, projectDir)
+	args := []string{"build"}
+	if params.NoCache {
+		args = append(args, "--no-cache")
+	}
+	if params.PullBase {
+		args = append(args, "--pull")
+	}
+	if params.Dockerfile != "" {
+		args = append(args, "-f", dockerfile)
+	}
+	// The label identifies the image as this project's after a rebuild
+	// leaves it untagged, so clean can remove it
+	args = append(args, "--label", crushAppLabel+"="+strings.ToLower(params.ProjectName))
+	args = append(args, "-t", imageName, projectDir)
+	cmd := exec.CommandContext(ctx, "docker", args...)
 	output, err := cmd.CombinedOutput()
 	
 	if err != nil {
 		return NewTextErrorResponse(fmt.Sprintf("❌ Docker build failed: %v\n\nOutput:\n%s", err, string(output))), nil
 	}
 
-	content := fmt.Sprintf("✅ Successfully built Docker image: %s\n\nBuild output:\n%s\n\nNext step: Run the app with {\"action\": \"run\", \"project_name\": \"%s\"}", 
-		imageName, string(output), params.ProjectName)
+	cache := parseBuildCache(string(output))
+	baseImages := parseBaseImages(string(output))
+	summary := cache.summary(params.NoCache)
+	for _, image := range baseImages {
+		summary += "\nBase image: " + image
+	}
+	content := fmt.Sprintf("✅ Successfully built Docker image: %s\n%s\n\nBuild output:\n%s\n\nNext step: Run the app with {\"action\": \"run\", \"project_name\": \"%s\"}", 
+		imageName, summary, string(output), params.ProjectName)
 
 	metadata := DockerResponseMetadata{
 		Action:      "build",
 		ProjectName: params.ProjectName,
 		ImageID:     imageName,
+		BuildSteps:  cache.steps,
+		CachedSteps: cache.cached,
+		BaseImages:  baseImages,
 	}
 
 	return WithResponseMetadata(NewTextResponse(content), metadata), nil
 }
 
+// projectDockerfile returns the path of the Dockerfile a build uses: name,
+// relative to the project directory, or the project's Dockerfile by default.
+// It fails with instructions when the file is missing, instead of leaving
+// docker to report that it cannot locate it.
+func projectDockerfile(projectDir, name string) (string, error) {
+	path, err := dockerfilePath(projectDir, name)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		name = "Dockerfile"
+	}
+
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		return "", fmt.Errorf("no %s found in %s. Create the project with the create_project action, or add a Dockerfile to it through the files parameter, before building", name, projectDir)
+	case err != nil:
+		return "", fmt.Errorf("failed to check %s: %w", path, err)
+	case info.IsDir():
+		return "", fmt.Errorf("%s is a directory, not a Dockerfile", path)
+	}
+	return path, nil
+}
+
+// dockerfilePath returns the path of the Dockerfile name, relative to the
+// project directory, or of the project's Dockerfile when name is empty
+func dockerfilePath(projectDir, name string) (string, error) {
+	if name == "" {
+		return filepath.Join(projectDir, "Dockerfile"), nil
+	}
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("dockerfile must be a path inside the project directory, got %q", name)
+	}
+	return filepath.Join(projectDir, name), nil
+}
+
+var (
+	// buildKitStepPattern matches a numbered Dockerfile step in BuildKit plain
+	// progress output, e.g. "#5 [2/4] WORKDIR /app" or "#7 [build 3/6] RUN go build"
+	buildKitStepPattern = regexp.MustCompile(`^#(\d+) \[(?:[^\]]+ )?\d+/\d+\] (\S+)`)
+	// buildKitCachedPattern matches a BuildKit step that was served from cache
+	buildKitCachedPattern = regexp.MustCompile(`^#(\d+) CACHED$`)
+	// legacyStepPattern matches a step of the legacy builder, e.g. "Step 2/4 : WORKDIR /app"
+	legacyStepPattern = regexp.MustCompile(`^Step \d+/\d+ : (\S+)`)
+	// buildKitBasePattern matches the FROM step of a BuildKit build, which
+	// names the base image by digest, e.g. "#3 [1/4] FROM docker.io/library/node:18-alpine@sha256:..."
+	buildKitBasePattern = regexp.MustCompile(`^#\d+ \[(?:[^\]]+ )?\d+/\d+\] FROM (\S+@sha256:[0-9a-f]+)`)
+	// legacyBasePattern matches the FROM step of the legacy builder
+	legacyBasePattern = regexp.MustCompile(`^Step \d+/\d+ : FROM (\S+)`)
+	// pullDigestPattern matches the digest the legacy builder reports after
+	// pulling a base image
+	pullDigestPattern = regexp.MustCompile(`^Digest: (sha256:[0-9a-f]+)$`)
+)
+
+// parseBaseImages returns the base images named by the FROM steps of docker
+// build output, in build order. BuildKit always names them by digest; the
+// legacy builder only reports a digest when it pulled the image, so its base
+// images are otherwise listed by tag.
+func parseBaseImages(output string) []string {
+	var images []string
+	legacyImage := -1
+	for line := range strings.Lines(output) {
+		line = strings.TrimSpace(line)
+		if m := buildKitBasePattern.FindStringSubmatch(line); m != nil {
+			if !slices.Contains(images, m[1]) {
+				images = append(images, m[1])
+			}
+		} else if m := legacyBasePattern.FindStringSubmatch(line); m != nil {
+			images = append(images, m[1])
+			legacyImage = len(images) - 1
+		} else if m := pullDigestPattern.FindStringSubmatch(line); m != nil && legacyImage >= 0 {
+			images[legacyImage] += "@" + m[1]
+			legacyImage = -1
+		} else if strings.HasPrefix(line, "Step ") {
+			legacyImage = -1
+		}
+	}
+	return images
+}
+
+// buildCache counts how many Dockerfile steps of a build were cached
+type buildCache struct {
+	steps  int
+	cached int
+}
+
+// parseBuildCache counts the steps and cache hits in docker build output,
+// understanding both BuildKit plain progress and the legacy builder. FROM
+// steps only pull the base image, which is never cached, so they are ignored.
+func parseBuildCache(output string) buildCache {
+	var cache buildCache
+	steps := make(map[string]bool)
+	cached := make(map[string]bool)
+	for line := range strings.Lines(output) {
+		line = strings.TrimSpace(line)
+		if m := legacyStepPattern.FindStringSubmatch(line); m != nil {
+			if !strings.EqualFold(m[1], "FROM") {
+				cache.steps++
+			}
+		} else if line == "---> Using cache" {
+			cache.cached++
+		} else if m := buildKitStepPattern.FindStringSubmatch(line); m != nil {
+			if !strings.EqualFold(m[2], "FROM") {
+				steps[m[1]] = true
+			}
+		} else if m := buildKitCachedPattern.FindStringSubmatch(line); m != nil {
+			cached[m[1]] = true
+		}
+	}
+
+	cache.steps += len(steps)
+	for id := range cached {
+		if steps[id] {
+			cache.cached++
+		}
+	}
+	return cache
+}
+
+// summary describes the cache usage for the build response
+func (c buildCache) summary(noCache bool) string {
+	switch {
+	case noCache:
+		return "Build cache: disabled (no_cache), all steps rebuilt"
+	case c.steps == 0:
+		return "Build cache: unknown (no steps found in build output)"
+	case c.cached == c.steps:
+		return fmt.Sprintf("Build cache: all %d steps cached", c.steps)
+	case c.cached == 0:
+		return fmt.Sprintf("Build cache: no cached steps, all %d steps rebuilt", c.steps)
+	default:
+		return fmt.Sprintf("Build cache: %d/%d steps cached, %d rebuilt", c.cached, c.steps, c.steps-c.cached)
+	}
+}
+
 func (d *dockerTool) runApp(ctx context.Context, params DockerAppBuilderParams) (ToolResponse, error) {
 	if params.ProjectName == "" {
 		return NewTextErrorResponse("project_name is required for run action"), nil
 	}
 
 	imageName := fmt.Sprintf("crush-app-%s", strings.ToLower(params.ProjectName))
-	port := params.Port
-	if port == "" {
-		port = "3000" // Default port
+	projectDir := filepath.Join("/tmp", "crush-apps", params.ProjectName)
+	dockerfile, err := dockerfilePath(projectDir, params.Dockerfile)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	hostPort, containerPort, err := resolveRunPorts(params.Port, dockerfile)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	if err := validateEnvironment(params.Environment); err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	envFile, err := projectEnvFile(projectDir, params.EnvFile)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	networkCreated := false
+	if params.Network != "" {
+		if !networkPattern.MatchString(params.Network) {
+			return NewTextErrorResponse(fmt.Sprintf("invalid network name %q: use letters, digits, '_', '.' and '-'", params.Network)), nil
+		}
+		if networkCreated, err = ensureNetwork(ctx, params.Network); err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("❌ Failed to create network %s: %v", params.Network, err)), nil
+		}
 	}
 
 	// Build run command
@@ -193,12 +474,16 @@ func (d *dockerTool) runApp(ctx context.Context, params DockerAppBuilderParams)
 	// Check if container already exists and remove it
 	exec.Command("docker", "rm", "-f", containerName).Run()
 	
-	runArgs := []string{"run", "-d", "-p", fmt.Sprintf("%s:%s", port, port)}
+	runArgs := []string{"run", "-d", "-p", fmt.Sprintf("%s:%s", hostPort, containerPort)}
+
+	// Containers on the same network reach each other by project name
+	networkAlias := strings.ToLower(params.ProjectName)
+	if params.Network != "" {
+		runArgs = append(runArgs, "--network", params.Network, "--network-alias", networkAlias)
+	}
 	
 	// Add environment variables
-	for key, value := range params.Environment {
-		runArgs = append(runArgs, "-e", fmt.Sprintf("%s=%s", key, value))
-	}
+	runArgs = append(runArgs, containerEnvArgs(envFile, params.Environment)...)
 	
 	// Add container name
 	runArgs = append(runArgs, "--name", containerName)
@@ -219,21 +504,359 @@ func (d *dockerTool) runApp(ctx context.Context, params DockerAppBuilderParams)
 	}
 
 	containerID := strings.TrimSpace(string(output))
-	appURL := fmt.Sprintf("http://localhost:%s", port)
+	appURL := fmt.Sprintf("http://localhost:%s", hostPort)
+
+	// docker run -d succeeds as soon as the container is created, so check
+	// that it didn't crash right after starting
+	if state, ok := d.containerStateAfterStart(ctx, containerName); ok && state.exited() {
+		exitCode := state.exitCode
+		content := fmt.Sprintf("❌ Container %s exited right after starting (status: %s, exit code: %d)\n\nContainer ID: %s",
+			containerName, state.status, exitCode, containerID)
+		if logs := containerLogTail(ctx, containerName); logs != "" {
+			content += fmt.Sprintf("\n\nLast %d log lines:\n%s", containerLogLines, logs)
+		}
+		metadata := DockerResponseMetadata{
+			Action:      "run",
+			ProjectName: params.ProjectName,
+			ContainerID: containerID,
+			ExitCode:    &exitCode,
+		}
+		return WithResponseMetadata(NewTextErrorResponse(content), metadata), nil
+	}
 	
 	content := fmt.Sprintf("✅ Successfully started container: %s\n\nContainer ID: %s\nApp URL: %s\n\nThe app is now running! You can:\n- Visit %s in your browser\n- Stop it with: {\"action\": \"stop\", \"project_name\": \"%s\"}\n- View logs with: docker logs %s", 
 		containerName, containerID, appURL, appURL, params.ProjectName, containerName)
+	if params.Network != "" {
+		verb := "Joined"
+		if networkCreated {
+			verb = "Created and joined"
+		}
+		content += fmt.Sprintf("\n\n%s network %s. Other containers on it reach this app at http://%s:%s", verb, params.Network, networkAlias, containerPort)
+	}
 
 	metadata := DockerResponseMetadata{
 		Action:      "run",
 		ProjectName: params.ProjectName,
 		ContainerID: containerID,
 		URL:         appURL,
+		Network:     params.Network,
 	}
 
 	return WithResponseMetadata(NewTextResponse(content), metadata), nil
 }
 
+// projectEnvFile validates an env_file parameter, relative to the project
+// directory, and returns its absolute path, or "" when none is given
+func projectEnvFile(projectDir, envFile string) (string, error) {
+	if envFile == "" {
+		return "", nil
+	}
+	path, err := ValidatePathSecurity(envFile, projectDir)
+	if err != nil {
+		return "", fmt.Errorf("invalid env_file: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("env_file %s not found in project directory %s", envFile, projectDir)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("env_file %s is a directory", envFile)
+	}
+	return path, nil
+}
+
+// ensureNetwork creates the named Docker network unless it already exists,
+// and reports whether it was created
+func ensureNetwork(ctx context.Context, name string) (bool, error) {
+	output, err := exec.CommandContext(ctx, "docker", "network", "ls", "--filter", "name=^"+name+"$", "--format", "{{.Name}}").Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to list networks: %w", err)
+	}
+	for line := range strings.Lines(string(output)) {
+		if strings.TrimSpace(line) == name {
+			return false, nil
+		}
+	}
+
+	if output, err := exec.CommandContext(ctx, "docker", "network", "create", name).CombinedOutput(); err != nil {
+		return false, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return true, nil
+}
+
+// containerEnvArgs returns the docker run arguments that set a container's
+// environment. Docker applies -e after --env-file, so explicit variables
+// override the file's.
+func containerEnvArgs(envFile string, environment map[string]string) []string {
+	var args []string
+	if envFile != "" {
+		args = append(args, "--env-file", envFile)
+	}
+	for _, key := range slices.Sorted(maps.Keys(environment)) {
+		args = append(args, "-e", fmt.Sprintf("%s=%s", key, environment[key]))
+	}
+	return args
+}
+
+// validateEnvironment checks the variables passed to docker run. Names must
+// be portable identifiers, since docker splits -e at the first '=', and values
+// may not contain control characters such as newlines, which would corrupt the
+// container's environment.
+func validateEnvironment(environment map[string]string) error {
+	for _, key := range slices.Sorted(maps.Keys(environment)) {
+		if !envKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid environment variable name %q: use letters, digits and '_', not starting with a digit", key)
+		}
+		if i := strings.IndexFunc(environment[key], unicode.IsControl); i >= 0 {
+			return fmt.Errorf("invalid value for environment variable %s: control character %q at offset %d", key, environment[key][i], i)
+		}
+	}
+	return nil
+}
+
+// containerLogLines is how many log lines are shown for a crashed container
+const containerLogLines = 20
+
+// containerStartGrace is how long run waits before checking that a new
+// container is still up
+var containerStartGrace = time.Second
+
+// containerState is the state docker inspect reports for a container
+type containerState struct {
+	status   string
+	exitCode int
+}
+
+// exited reports whether the container is no longer running
+func (s containerState) exited() bool {
+	return s.status == "exited" || s.status == "dead"
+}
+
+// containerStateAfterStart waits containerStartGrace and inspects the
+// container's state. It reports false when the state can't be determined.
+func (d *dockerTool) containerStateAfterStart(ctx context.Context, containerName string) (containerState, bool) {
+	select {
+	case <-ctx.Done():
+		return containerState{}, false
+	case <-time.After(containerStartGrace):
+	}
+
+	output, err := exec.CommandContext(ctx, "docker", "inspect", "--format", "{{.State.Status}} {{.State.ExitCode}}", containerName).Output()
+	if err != nil {
+		return containerState{}, false
+	}
+	return parseContainerState(string(output))
+}
+
+// parseContainerState parses the "status exit_code" line written by
+// docker inspect --format '{{.State.Status}} {{.State.ExitCode}}'
+func parseContainerState(output string) (containerState, bool) {
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return containerState{}, false
+	}
+	exitCode, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return containerState{}, false
+	}
+	return containerState{status: fields[0], exitCode: exitCode}, true
+}
+
+// containerLogTail returns the last containerLogLines lines a container logged
+func containerLogTail(ctx context.Context, containerName string) string {
+	output, err := exec.CommandContext(ctx, "docker", "logs", "--tail", strconv.Itoa(containerLogLines), containerName).CombinedOutput()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimRight(string(output), "\n")
+}
+
+// resolveRunPorts returns the host and container ports for run. The port
+// parameter is either a single port used on both sides or host_port:container_port.
+// Without one, the container port comes from the EXPOSE instruction of the
+// Dockerfile at dockerfile, falling back to defaultAppPort.
+func resolveRunPorts(port, dockerfile string) (string, string, error) {
+	if port == "" {
+		exposed := defaultAppPort
+		if content, err := os.ReadFile(dockerfile); err == nil {
+			if p := exposedPort(string(content)); p != "" {
+				exposed = p
+			}
+		}
+		return exposed, exposed, nil
+	}
+
+	hostPort, containerPort, found := strings.Cut(port, ":")
+	if !found {
+		containerPort = hostPort
+	}
+	for _, p := range []string{hostPort, containerPort} {
+		if n, err := strconv.Atoi(p); err != nil || n < 1 || n > 65535 {
+			return "", "", fmt.Errorf("invalid port %q: use a port number or host_port:container_port", port)
+		}
+	}
+	return hostPort, containerPort, nil
+}
+
+// exposedPort returns the first numeric port exposed by a Dockerfile, or an
+// empty string when it exposes none. Protocol suffixes such as /tcp are dropped
+// and ports given through build variables are ignored.
+func exposedPort(dockerfile string) string {
+	for line := range strings.Lines(dockerfile) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "EXPOSE") {
+			continue
+		}
+		for _, field := range fields[1:] {
+			port, _, _ := strings.Cut(field, "/")
+			if n, err := strconv.Atoi(port); err == nil && n >= 1 && n <= 65535 {
+				return port
+			}
+		}
+	}
+	return ""
+}
+
+// defaultHealthPath is the endpoint validate checks, as served by the
+// project templates
+const defaultHealthPath = "/health"
+
+var (
+	// healthCheckTimeout is how long validate waits for a healthy response
+	healthCheckTimeout = 30 * time.Second
+	// healthCheckInterval is the delay between health checks
+	healthCheckInterval = 500 * time.Millisecond
+)
+
+// validateImage starts a throwaway container from the project's image, waits
+// for its health endpoint to respond with a 2xx status and removes the
+// container again, so a build can be checked without leaving anything running
+func (d *dockerTool) validateImage(ctx context.Context, params DockerAppBuilderParams) (ToolResponse, error) {
+	if params.ProjectName == "" {
+		return NewTextErrorResponse("project_name is required for validate action"), nil
+	}
+
+	imageName := fmt.Sprintf("crush-app-%s", strings.ToLower(params.ProjectName))
+	projectDir := filepath.Join("/tmp", "crush-apps", params.ProjectName)
+	dockerfile, err := dockerfilePath(projectDir, params.Dockerfile)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	_, containerPort, err := resolveRunPorts(params.Port, dockerfile)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	if err := validateEnvironment(params.Environment); err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	envFile, err := projectEnvFile(projectDir, params.EnvFile)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	healthPath := params.HealthPath
+	if healthPath == "" {
+		healthPath = defaultHealthPath
+	} else if !strings.HasPrefix(healthPath, "/") {
+		healthPath = "/" + healthPath
+	}
+
+	// Publish the container port on a random local port so validation never
+	// conflicts with a running instance of the app
+	containerName := fmt.Sprintf("crush-app-%s-validate", strings.ToLower(params.ProjectName))
+	exec.Command("docker", "rm", "-f", containerName).Run()
+
+	runArgs := []string{"run", "-d", "--rm", "-p", "127.0.0.1::" + containerPort}
+	runArgs = append(runArgs, containerEnvArgs(envFile, params.Environment)...)
+	runArgs = append(runArgs, "--name", containerName, imageName)
+	output, err := exec.CommandContext(ctx, "docker", runArgs...).CombinedOutput()
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("❌ Validation failed: the container did not start: %v\n\nOutput:\n%s", err, string(output))), nil
+	}
+	// Tear down without ctx so the container is removed even when the
+	// validation was canceled
+	defer exec.Command("docker", "rm", "-f", containerName).Run()
+
+	portOutput, err := exec.CommandContext(ctx, "docker", "port", containerName, containerPort).Output()
+	address, _, _ := strings.Cut(strings.TrimSpace(string(portOutput)), "\n")
+	if err != nil || address == "" {
+		return NewTextErrorResponse(fmt.Sprintf("❌ Validation failed: container port %s is not published", containerPort)), nil
+	}
+
+	healthURL := "http://" + strings.TrimSpace(address) + healthPath
+	start := time.Now()
+	check := waitForHealthy(ctx, containerName, healthURL)
+	elapsed := time.Since(start).Round(100 * time.Millisecond)
+
+	metadata := DockerResponseMetadata{
+		Action:      "validate",
+		ProjectName: params.ProjectName,
+		ImageID:     imageName,
+		Healthy:     &check.healthy,
+	}
+	if !check.healthy {
+		content := fmt.Sprintf("❌ Image %s is not healthy: %s\n\nHealth endpoint: %s", imageName, check.reason, healthPath)
+		if logs := containerLogTail(context.WithoutCancel(ctx), containerName); logs != "" {
+			content += fmt.Sprintf("\n\nLast %d log lines:\n%s", containerLogLines, logs)
+		}
+		return WithResponseMetadata(NewTextErrorResponse(content), metadata), nil
+	}
+
+	content := fmt.Sprintf("✅ Image %s is healthy: GET %s returned %d after %s\n\nThe validation container was removed. Run the app with {\"action\": \"run\", \"project_name\": \"%s\"}",
+		imageName, healthPath, check.status, elapsed, params.ProjectName)
+	return WithResponseMetadata(NewTextResponse(content), metadata), nil
+}
+
+// healthCheck is the outcome of waiting for a container to become healthy
+type healthCheck struct {
+	healthy bool
+	status  int
+	reason  string
+}
+
+// waitForHealthy polls url until it responds with a 2xx status, the container
+// stops running or healthCheckTimeout passes
+func waitForHealthy(ctx context.Context, containerName, url string) healthCheck {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	client := &http.Client{Timeout: 2 * time.Second}
+	reason := "no response from the health endpoint"
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return healthCheck{reason: err.Error()}
+		}
+		if resp, err := client.Do(req); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				return healthCheck{healthy: true, status: resp.StatusCode}
+			}
+			reason = fmt.Sprintf("the health endpoint returned %d", resp.StatusCode)
+		}
+
+		// A container started with --rm disappears once it exits, so a
+		// failed inspect means it stopped too
+		output, err := exec.CommandContext(ctx, "docker", "inspect", "--format", "{{.State.Status}} {{.State.ExitCode}}", containerName).Output()
+		if ctx.Err() == nil {
+			if err != nil {
+				return healthCheck{reason: "the container stopped before becoming healthy"}
+			}
+			if state, ok := parseContainerState(string(output)); ok && state.exited() {
+				return healthCheck{reason: fmt.Sprintf("the container exited with code %d before becoming healthy", state.exitCode)}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.Canceled {
+				return healthCheck{reason: "validation was canceled"}
+			}
+			return healthCheck{reason: fmt.Sprintf("%s within %s", reason, healthCheckTimeout)}
+		case <-time.After(healthCheckInterval):
+		}
+	}
+}
+
 func (d *dockerTool) stopApp(ctx context.Context, params DockerAppBuilderParams) (ToolResponse, error) {
 	if params.ProjectName == "" {
 		return NewTextErrorResponse("project_name is required for stop action"), nil
@@ -264,23 +887,354 @@ func (d *dockerTool) stopApp(ctx context.Context, params DockerAppBuilderParams)
 	return WithResponseMetadata(NewTextResponse(content), metadata), nil
 }
 
+func (d *dockerTool) execInContainer(ctx context.Context, params DockerAppBuilderParams) (ToolResponse, error) {
+	if params.ProjectName == "" || params.Command == "" {
+		return NewTextErrorResponse("project_name and command are required for exec action"), nil
+	}
+
+	containerName := fmt.Sprintf("crush-app-%s-instance", strings.ToLower(params.ProjectName))
+
+	var command []string
+	if params.Shell {
+		command = []string{"sh", "-c", params.Command}
+	} else {
+		var err error
+		command, err = splitCommandArgs(params.Command)
+		if err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("Invalid command: %v", err)), nil
+		}
+	}
+
+	execArgs := append([]string{"exec", containerName}, command...)
+	cmd := exec.CommandContext(ctx, "docker", execArgs...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	exitCode := 0
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return NewTextErrorResponse(fmt.Sprintf("❌ Docker exec failed: %v", err)), nil
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	// Exit code 125 means docker itself failed, e.g. the container is not running
+	if exitCode == 125 {
+		return NewTextErrorResponse(fmt.Sprintf("❌ Docker exec failed in %s:\n\n%s", containerName, stderr.String())), nil
+	}
+
+	status := "✅"
+	if exitCode != 0 {
+		status = "⚠️"
+	}
+	content := fmt.Sprintf("%s Command exited with code %d in container %s\n\nCommand: %s\n\nStdout:\n%s\n\nStderr:\n%s",
+		status, exitCode, containerName, strings.Join(command, " "), stdout.String(), stderr.String())
+
+	metadata := DockerResponseMetadata{
+		Action:      "exec",
+		ProjectName: params.ProjectName,
+		ContainerID: containerName,
+		ExitCode:    &exitCode,
+	}
+
+	return WithResponseMetadata(NewTextResponse(content), metadata), nil
+}
+
+// registryReference validates the registry and tag parameters and returns the
+// local image name along with its reference in the registry
+func registryReference(params DockerAppBuilderParams) (string, string, error) {
+	if params.ProjectName == "" || params.Registry == "" {
+		return "", "", fmt.Errorf("project_name and registry are required for %s action", params.Action)
+	}
+	registry := strings.TrimSuffix(params.Registry, "/")
+	if !registryPattern.MatchString(registry) {
+		return "", "", fmt.Errorf("invalid registry: %q", params.Registry)
+	}
+	tag := params.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+	if !tagPattern.MatchString(tag) {
+		return "", "", fmt.Errorf("invalid tag: %q", params.Tag)
+	}
+
+	imageName := fmt.Sprintf("crush-app-%s", strings.ToLower(params.ProjectName))
+	return imageName, fmt.Sprintf("%s/%s:%s", registry, imageName, tag), nil
+}
+
+func (d *dockerTool) pushImage(ctx context.Context, params DockerAppBuilderParams) (ToolResponse, error) {
+	imageName, reference, err := registryReference(params)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
+	// Credentials come from the user's existing docker login
+	if output, err := exec.CommandContext(ctx, "docker", "tag", imageName, reference).CombinedOutput(); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("❌ Failed to tag %s as %s: %v\n\nOutput:\n%s\n\nBuild the image first with {\"action\": \"build\", \"project_name\": \"%s\"}", imageName, reference, err, string(output), params.ProjectName)), nil
+	}
+
+	output, err := exec.CommandContext(ctx, "docker", "push", reference).CombinedOutput()
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("❌ Docker push failed: %v\n\nOutput:\n%s\n\nMake sure you are logged in with docker login.", err, string(output))), nil
+	}
+
+	content := fmt.Sprintf("✅ Successfully pushed %s\n\nPush output:\n%s", reference, string(output))
+
+	metadata := DockerResponseMetadata{
+		Action:      "push",
+		ProjectName: params.ProjectName,
+		ImageID:     imageName,
+		Reference:   reference,
+	}
+
+	return WithResponseMetadata(NewTextResponse(content), metadata), nil
+}
+
+func (d *dockerTool) pullImage(ctx context.Context, params DockerAppBuilderParams) (ToolResponse, error) {
+	imageName, reference, err := registryReference(params)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
+	output, err := exec.CommandContext(ctx, "docker", "pull", reference).CombinedOutput()
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("❌ Docker pull failed: %v\n\nOutput:\n%s", err, string(output))), nil
+	}
+
+	// Retag so the run action picks up the pulled image
+	if tagOutput, err := exec.CommandContext(ctx, "docker", "tag", reference, imageName).CombinedOutput(); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("❌ Failed to tag %s as %s: %v\n\nOutput:\n%s", reference, imageName, err, string(tagOutput))), nil
+	}
+
+	content := fmt.Sprintf("✅ Successfully pulled %s and tagged it as %s\n\nPull output:\n%s\n\nNext step: Run the app with {\"action\": \"run\", \"project_name\": \"%s\"}",
+		reference, imageName, string(output), params.ProjectName)
+
+	metadata := DockerResponseMetadata{
+		Action:      "pull",
+		ProjectName: params.ProjectName,
+		ImageID:     imageName,
+		Reference:   reference,
+	}
+
+	return WithResponseMetadata(NewTextResponse(content), metadata), nil
+}
+
+func (d *dockerTool) removeApp(ctx context.Context, params DockerAppBuilderParams) (ToolResponse, error) {
+	if params.ProjectName == "" {
+		return NewTextErrorResponse("project_name is required for remove action"), nil
+	}
+
+	// Validate the project directory before touching anything
+	appsDir := filepath.Join("/tmp", "crush-apps")
+	projectDir := ""
+	if params.PruneFiles {
+		var err error
+		projectDir, err = ValidatePathSecurity(params.ProjectName, appsDir)
+		if err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("Invalid project name: %v", err)), nil
+		}
+		if projectDir == appsDir {
+			return NewTextErrorResponse(fmt.Sprintf("Invalid project name: %s", params.ProjectName)), nil
+		}
+	}
+
+	imageName := fmt.Sprintf("crush-app-%s", strings.ToLower(params.ProjectName))
+	containerName := fmt.Sprintf("crush-app-%s-instance", strings.ToLower(params.ProjectName))
+
+	// Stop and remove any container still using the image
+	exec.CommandContext(ctx, "docker", "rm", "-f", containerName).Run()
+
+	var freed int64
+	var report strings.Builder
+
+	imageSize := int64(0)
+	if output, err := exec.CommandContext(ctx, "docker", "image", "inspect", "--format", "{{.Size}}", imageName).Output(); err == nil {
+		imageSize, _ = strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64)
+	}
+
+	// What couldn't be removed is collected so the result never claims success
+	// for a partial removal
+	var failures []string
+	output, err := exec.CommandContext(ctx, "docker", "rmi", imageName).CombinedOutput()
+	if err != nil {
+		if !params.PruneFiles {
+			return NewTextErrorResponse(fmt.Sprintf("❌ Failed to remove image %s: %v\n\nOutput:\n%s", imageName, err, string(output))), nil
+		}
+		failures = append(failures, fmt.Sprintf("image %s: %s", imageName, strings.TrimSpace(string(output))))
+	} else {
+		freed += imageSize
+		report.WriteString(fmt.Sprintf("🗑️ Removed image %s (%s)\n", imageName, formatBytes(imageSize)))
+	}
+
+	if params.PruneFiles {
+		if _, err := os.Stat(projectDir); err == nil {
+			dirSize := directorySize(projectDir)
+			if err := os.RemoveAll(projectDir); err != nil {
+				failures = append(failures, fmt.Sprintf("project directory %s: %v", projectDir, err))
+			} else {
+				freed += dirSize
+				report.WriteString(fmt.Sprintf("🗑️ Removed project directory %s (%s)\n", projectDir, formatBytes(dirSize)))
+			}
+		} else {
+			report.WriteString(fmt.Sprintf("⚠️ Project directory %s does not exist\n", projectDir))
+		}
+	}
+
+	metadata := DockerResponseMetadata{
+		Action:      "remove",
+		ProjectName: params.ProjectName,
+		ImageID:     imageName,
+		FreedBytes:  freed,
+	}
+
+	if len(failures) > 0 {
+		content := fmt.Sprintf("❌ Project '%s' was only partly removed. Failed to remove:\n- %s\n\n%s\nFreed approximately %s.",
+			params.ProjectName, strings.Join(failures, "\n- "), report.String(), formatBytes(freed))
+		return WithResponseMetadata(NewTextErrorResponse(content), metadata), nil
+	}
+
+	content := fmt.Sprintf("✅ Removed project '%s'\n\n%s\nFreed approximately %s.", params.ProjectName, report.String(), formatBytes(freed))
+	return WithResponseMetadata(NewTextResponse(content), metadata), nil
+}
+
+// directorySize returns the total size of the regular files below dir
+func directorySize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// formatBytes formats a byte count for display, e.g. "1.5 MB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// splitCommandArgs splits a command line into arguments without invoking a
+// shell. Single and double quotes group words and backslashes escape the next
+// character; no variables, globs or other shell syntax are expanded.
+func splitCommandArgs(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\\' && quote != '\'':
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("trailing backslash")
+			}
+			i++
+			current.WriteRune(runes[i])
+			inArg = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("command is empty")
+	}
+	return args, nil
+}
+
 func (d *dockerTool) listContainers(ctx context.Context) (ToolResponse, error) {
-	cmd := exec.CommandContext(ctx, "docker", "ps", "-a", "--filter", "name=crush-app", "--format", "table {{.Names}}\t{{.Status}}\t{{.Ports}}")
+	cmd := exec.CommandContext(ctx, "docker", "ps", "-a", "--filter", "name=crush-app", "--format", "{{.Names}}\t{{.State}}\t{{.Status}}\t{{.Ports}}\t{{.Networks}}")
 	output, err := cmd.CombinedOutput()
 	
 	if err != nil {
 		return NewTextErrorResponse(fmt.Sprintf("❌ Failed to list containers: %v\n\nOutput: %s", err, string(output))), nil
 	}
 
-	content := fmt.Sprintf("📋 Crush App Containers:\n\n%s\n\nTo interact with these containers:\n- Stop: {\"action\": \"stop\", \"project_name\": \"PROJECT_NAME\"}\n- View logs: docker logs CONTAINER_NAME", string(output))
+	containers := parseContainerList(string(output))
+	var table strings.Builder
+	table.WriteString("NAMES\tSTATUS\tPORTS\tNETWORKS\n")
+	for _, container := range containers {
+		fmt.Fprintf(&table, "%s\t%s\t%s\t%s\n", container.Name, container.Status, container.Ports, strings.Join(container.Networks, ","))
+	}
+
+	content := fmt.Sprintf("📋 Crush App Containers:\n\n%s\n\nTo interact with these containers:\n- Stop: {\"action\": \"stop\", \"project_name\": \"PROJECT_NAME\"}\n- View logs: docker logs CONTAINER_NAME", table.String())
 
 	metadata := DockerResponseMetadata{
-		Action: "list",
+		Action:     "list",
+		Containers: containers,
 	}
 
 	return WithResponseMetadata(NewTextResponse(content), metadata), nil
 }
 
+// parseContainerList parses docker ps output formatted as tab-separated
+// names, states, statuses, ports and comma-separated networks
+func parseContainerList(output string) []DockerContainer {
+	var containers []DockerContainer
+	for line := range strings.Lines(output) {
+		fields := strings.Split(strings.TrimRight(line, "\r\n"), "\t")
+		if len(fields) < 3 || fields[0] == "" {
+			continue
+		}
+		container := DockerContainer{
+			Name:    fields[0],
+			Project: strings.TrimSuffix(strings.TrimPrefix(fields[0], "crush-app-"), "-instance"),
+			State:   fields[1],
+			Status:  fields[2],
+		}
+		if len(fields) > 3 {
+			container.Ports = fields[3]
+		}
+		if len(fields) > 4 && fields[4] != "" {
+			container.Networks = strings.Split(fields[4], ",")
+		}
+		containers = append(containers, container)
+	}
+	return containers
+}
+
 func (d *dockerTool) generateProjectFiles(projectType, projectName string) (map[string]string, error) {
 	files := make(map[string]string)
 	
@@ -405,7 +1359,7 @@ func getEnv(key, defaultValue string) string {
 
 		files["Dockerfile"] = `FROM golang:1.21-alpine AS builder
 WORKDIR /app
-COPY go.mod go.sum ./
+COPY go.* ./
 RUN go mod download
 COPY . .
 RUN go build -o main .
@@ -544,12 +1498,20 @@ CMD ["nginx", "-g", "daemon off;"]`
 }`
 
 	default:
-		return nil, fmt.Errorf("unsupported project type: %s. Supported types: nodejs, python, go, react, express, fastapi", projectType)
+		return nil, fmt.Errorf("unsupported project type: %s. Supported types: %s", projectType, strings.Join(dockerProjectTypes, ", "))
 	}
 
 	return files, nil
 }
 
+// projectTypeLabel describes the project type reported for create_project
+func projectTypeLabel(requested, resolved string) string {
+	if requested == autoProjectType {
+		return fmt.Sprintf("%s (detected)", resolved)
+	}
+	return resolved
+}
+
 func getKeys(m map[string]string) []string {
 	keys := make([]string, 0, len(m))
 	for k := range m {
@@ -568,26 +1530,78 @@ This tool provides a complete Docker-based application development workflow thro
 ### create_project
 Creates a new application project with scaffolded files:
 - **project_name**: Name for the new project (required)
-- **project_type**: Type of project (required): nodejs, python, go, react, express, fastapi
+- **project_type**: Type of project (required): nodejs, python, go, react, express, fastapi, or auto
 - **files**: Optional custom files to add to the project
 
+With project_type "auto", pass the existing code in **files**. Its language is
+detected (go.mod selects go, package.json selects nodejs or react, Python
+sources select python) and only the Dockerfile is generated, unless files
+already has one, so the code itself is left as provided.
+
 ### build
 Builds a Docker image for the project:
 - **project_name**: Name of the project to build (required)
+- **no_cache**: Rebuild every step without the layer cache, e.g. after a base image update
+- **pull_base**: Pull the newest version of the base images instead of using the locally cached ones, to pick up security patches
+- **dockerfile**: Dockerfile to build with, relative to the project directory (default: Dockerfile). run and validate read the default port from its EXPOSE instruction
+The response reports how many steps were served from the build cache and the
+base images used, pinned by digest where the build output names it.
 
 ### run  
 Runs the Docker container:
 - **project_name**: Name of the project to run (required)
-- **port**: Port to expose (default: 3000)
+- **port**: Port to expose, or host_port:container_port to map a different host port (default: the Dockerfile's EXPOSE port, otherwise 3000)
 - **environment**: Environment variables to set
+- **env_file**: File of KEY=value lines in the project directory, such as .env, passed to docker run --env-file. Variables in environment take precedence
 - **command**: Custom command to run in container
+- **network**: Docker network to attach the container to, created if it doesn't exist. Containers of other projects on the same network reach it by its project name, e.g. http://my-api:3000
+
+A second after starting, the container is inspected. If it has already
+exited, run fails with its exit code and last log lines.
+
+### validate
+Checks that the built image produces a healthy container, without leaving anything running:
+- **project_name**: Name of the project whose image to check (required)
+- **health_path**: Endpoint that must respond with a 2xx status (default: /health)
+- **port**, **environment**, **env_file**: As for run
+
+The container is started with docker run --rm on a random local port, its
+health endpoint is polled for up to 30 seconds and the container is removed
+again. Validation fails early when the container exits.
 
 ### stop
 Stops and removes the running container:
 - **project_name**: Name of the project to stop (required)
 
 ### list
-Lists all Crush app containers and their status
+Lists all Crush app containers with their status, ports and networks
+
+### exec
+Runs a command inside the project's running container and returns its stdout, stderr and exit code:
+- **project_name**: Name of the project whose container to use (required)
+- **command**: Command to run (required). It is split into arguments like a shell would, honoring quotes, but variables, pipes and redirects are not interpreted
+- **shell**: Set to true to run the command through sh -c when shell features are needed
+
+### remove
+Removes the project's image after stopping its container, to free disk space:
+- **project_name**: Name of the project to remove (required)
+- **prune_files**: Also delete the project directory in /tmp/crush-apps/ (default: false)
+
+### clean
+Removes stopped Crush app containers and dangling images left behind by rebuilds, reporting the space reclaimed. Only containers named crush-app-* and images built by this tool are touched:
+- **dry_run**: List what would be removed without removing anything (default: false)
+
+### push
+Tags the project's image and pushes it to a registry, using your existing docker login:
+- **project_name**: Name of the project whose image to push (required)
+- **registry**: Registry and optional namespace, e.g. ghcr.io/acme or localhost:5000 (required)
+- **tag**: Image tag (default: latest)
+
+### pull
+Pulls the project's image from a registry and tags it locally so it can be run:
+- **project_name**: Name of the project whose image to pull (required)
+- **registry**: Registry and optional namespace (required)
+- **tag**: Image tag (default: latest)
 
 ## Project Types Supported:
 
@@ -619,7 +1633,7 @@ func dockerProperties() map[string]any {
 		"action": map[string]any{
 			"type":        "string",
 			"description": "Action to perform",
-			"enum":        []string{"create_project", "build", "run", "stop", "list"},
+			"enum":        DockerActions,
 		},
 		"project_name": map[string]any{
 			"type":        "string",
@@ -628,7 +1642,7 @@ func dockerProperties() map[string]any {
 		"project_type": map[string]any{
 			"type":        "string",
 			"description": "Type of project to create (required for create_project)",
-			"enum":        []string{"nodejs", "python", "go", "react", "express", "fastapi"},
+			"enum":        append(slices.Clone(dockerProjectTypes), autoProjectType),
 		},
 		"files": map[string]any{
 			"type":        "object",
@@ -639,15 +1653,59 @@ func dockerProperties() map[string]any {
 		},
 		"command": map[string]any{
 			"type":        "string",
-			"description": "Custom command to run in the container",
+			"description": "Custom command to run in the container (run) or command to execute in the running container (exec)",
+		},
+		"shell": map[string]any{
+			"type":        "boolean",
+			"description": "Run the exec command through sh -c instead of as a plain argument list (default: false)",
+		},
+		"registry": map[string]any{
+			"type":        "string",
+			"description": "Registry and optional namespace to push to or pull from, e.g. ghcr.io/acme (required for push and pull)",
+		},
+		"tag": map[string]any{
+			"type":        "string",
+			"description": "Image tag for push and pull (default: latest)",
+		},
+		"no_cache": map[string]any{
+			"type":        "boolean",
+			"description": "Build without the layer cache, e.g. after the base image was updated (default: false)",
+		},
+		"pull_base": map[string]any{
+			"type":        "boolean",
+			"description": "Pull the newest base images before building instead of using locally cached ones (default: false)",
+		},
+		"dockerfile": map[string]any{
+			"type":        "string",
+			"description": "Dockerfile to build with, relative to the project directory (default: Dockerfile). run and validate read the default port from its EXPOSE instruction",
+		},
+		"prune_files": map[string]any{
+			"type":        "boolean",
+			"description": "Also delete the project directory when removing a project (default: false)",
+		},
+		"dry_run": map[string]any{
+			"type":        "boolean",
+			"description": "For clean, only list the containers and images that would be removed (default: false)",
 		},
 		"port": map[string]any{
 			"type":        "string",
-			"description": "Port to expose (default: 3000)",
+			"description": "Port to expose, or host_port:container_port to map a different host port (default: the Dockerfile's EXPOSE port, otherwise 3000)",
+		},
+		"health_path": map[string]any{
+			"type":        "string",
+			"description": "Endpoint of the app that must respond with a 2xx status for validate (default: /health)",
+		},
+		"network": map[string]any{
+			"type":        "string",
+			"description": "Docker network to attach the container to for run, created if absent, e.g. crush-net. Containers on it reach each other by project name",
+		},
+		"env_file": map[string]any{
+			"type":        "string",
+			"description": "Env file inside the project directory, e.g. .env, whose variables are set in the container (run). Variables in environment take precedence",
 		},
 		"environment": map[string]any{
 			"type":        "object",
-			"description": "Environment variables to set in the container",
+			"description": "Environment variables to set in the container. Names use letters, digits and underscores and values may not contain control characters such as newlines",
 			"additionalProperties": map[string]any{
 				"type": "string",
 			},
@@ -0,0 +1,171 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// crushAppLabel is the label build sets on images to the project name, so an
+// image that lost its crush-app-* tag to a rebuild can still be told apart
+// from images Crush didn't build
+const crushAppLabel = "crush.app"
+
+// appResource is a container or image the clean action removes
+type appResource struct {
+	id   string
+	name string
+	size int64
+}
+
+// stoppedContainerStates are the states of containers that are not running
+var stoppedContainerStates = []string{"created", "exited", "dead"}
+
+func (d *dockerTool) cleanApps(ctx context.Context, params DockerAppBuilderParams) (ToolResponse, error) {
+	args := []string{"ps", "-a", "--size", "--filter", "name=crush-app-"}
+	for _, state := range stoppedContainerStates {
+		args = append(args, "--filter", "status="+state)
+	}
+	args = append(args, "--format", "{{.Names}}\t{{.State}}\t{{.Size}}")
+	output, err := exec.CommandContext(ctx, "docker", args...).CombinedOutput()
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("❌ Failed to list containers: %v\n\nOutput: %s", err, string(output))), nil
+	}
+	containers := parseStoppedAppContainers(string(output))
+
+	output, err = exec.CommandContext(ctx, "docker", "images", "--filter", "dangling=true", "--quiet", "--no-trunc").Output()
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("❌ Failed to list images: %v", err)), nil
+	}
+	var images []appResource
+	if ids := strings.Fields(string(output)); len(ids) > 0 {
+		inspectArgs := append([]string{"image", "inspect", "--format", "{{.Id}}\t{{index .Config.Labels \"" + crushAppLabel + "\"}}\t{{.Size}}"}, ids...)
+		output, err = exec.CommandContext(ctx, "docker", inspectArgs...).Output()
+		if err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("❌ Failed to inspect images: %v", err)), nil
+		}
+		images = parseDanglingAppImages(string(output))
+	}
+
+	verb := "Removed"
+	if params.DryRun {
+		verb = "Would remove"
+	}
+
+	var report strings.Builder
+	var freed int64
+	metadata := DockerResponseMetadata{Action: "clean", DryRun: params.DryRun}
+
+	// Containers go first, as stopped containers keep their images in use
+	for _, container := range containers {
+		if !params.DryRun {
+			if output, err := exec.CommandContext(ctx, "docker", "rm", container.id).CombinedOutput(); err != nil {
+				fmt.Fprintf(&report, "⚠️ Container %s was not removed: %s\n", container.name, strings.TrimSpace(string(output)))
+				continue
+			}
+		}
+		freed += container.size
+		metadata.RemovedContainers = append(metadata.RemovedContainers, container.name)
+		fmt.Fprintf(&report, "🗑️ %s container %s (%s)\n", verb, container.name, formatBytes(container.size))
+	}
+	for _, image := range images {
+		if !params.DryRun {
+			if output, err := exec.CommandContext(ctx, "docker", "rmi", image.id).CombinedOutput(); err != nil {
+				fmt.Fprintf(&report, "⚠️ Image %s was not removed: %s\n", image.name, strings.TrimSpace(string(output)))
+				continue
+			}
+		}
+		freed += image.size
+		metadata.RemovedImages = append(metadata.RemovedImages, image.id)
+		fmt.Fprintf(&report, "🗑️ %s image %s (%s)\n", verb, image.name, formatBytes(image.size))
+	}
+
+	if len(containers) == 0 && len(images) == 0 {
+		return WithResponseMetadata(NewTextResponse("✅ Nothing to clean: there are no stopped Crush app containers or dangling Crush app images."), metadata), nil
+	}
+
+	var content string
+	if params.DryRun {
+		content = fmt.Sprintf("🧹 Dry run: %d container(s) and %d image(s) would be removed\n\n%s\nWould free approximately %s.",
+			len(metadata.RemovedContainers), len(metadata.RemovedImages), report.String(), formatBytes(freed))
+	} else {
+		metadata.FreedBytes = freed
+		content = fmt.Sprintf("✅ Removed %d container(s) and %d image(s)\n\n%s\nFreed approximately %s.",
+			len(metadata.RemovedContainers), len(metadata.RemovedImages), report.String(), formatBytes(freed))
+	}
+	return WithResponseMetadata(NewTextResponse(content), metadata), nil
+}
+
+// parseStoppedAppContainers parses docker ps output formatted as tab-separated
+// names, states and sizes, keeping the Crush app containers that aren't
+// running. The name filter docker applies matches anywhere in the name, so
+// the prefix is checked again here.
+func parseStoppedAppContainers(output string) []appResource {
+	var containers []appResource
+	for line := range strings.Lines(output) {
+		fields := strings.Split(strings.TrimRight(line, "\r\n"), "\t")
+		if len(fields) < 2 || !strings.HasPrefix(fields[0], "crush-app-") {
+			continue
+		}
+		if !slices.Contains(stoppedContainerStates, strings.ToLower(fields[1])) {
+			continue
+		}
+		container := appResource{id: fields[0], name: fields[0]}
+		if len(fields) > 2 {
+			container.size = parseDockerSize(fields[2])
+		}
+		containers = append(containers, container)
+	}
+	return containers
+}
+
+// parseDanglingAppImages parses docker image inspect output formatted as
+// tab-separated IDs, crush.app labels and sizes, keeping the images Crush
+// built
+func parseDanglingAppImages(output string) []appResource {
+	var images []appResource
+	for line := range strings.Lines(output) {
+		fields := strings.Split(strings.TrimRight(line, "\r\n"), "\t")
+		if len(fields) < 2 || fields[0] == "" || fields[1] == "" || fields[1] == "<no value>" {
+			continue
+		}
+		id := fields[0]
+		shortID := strings.TrimPrefix(id, "sha256:")
+		if len(shortID) > 12 {
+			shortID = shortID[:12]
+		}
+		image := appResource{id: id, name: fmt.Sprintf("%s (crush-app-%s)", shortID, fields[1])}
+		if len(fields) > 2 {
+			image.size, _ = strconv.ParseInt(strings.TrimSpace(fields[2]), 10, 64)
+		}
+		images = append(images, image)
+	}
+	return images
+}
+
+// parseDockerSize parses a size as docker ps --size prints it, e.g. "1.5MB"
+// or "12kB (virtual 150MB)", returning the container's own size in bytes or
+// 0 when it can't be parsed
+func parseDockerSize(size string) int64 {
+	fields := strings.Fields(size)
+	if len(fields) == 0 {
+		return 0
+	}
+	value := fields[0]
+	number := strings.TrimRightFunc(value, func(r rune) bool { return r < '0' || r > '9' })
+	unit := strings.ToLower(value[len(number):])
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0
+	}
+
+	multipliers := map[string]float64{"b": 1, "kb": 1e3, "mb": 1e6, "gb": 1e9, "tb": 1e12, "pb": 1e15}
+	multiplier, ok := multipliers[unit]
+	if !ok {
+		return 0
+	}
+	return int64(n * multiplier)
+}
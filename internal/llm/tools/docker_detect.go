@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/language"
+)
+
+// autoProjectType asks create_project to pick the template from the files
+// provided rather than from an explicit project type
+const autoProjectType = "auto"
+
+// dockerProjectTypes are the project types create_project has templates for
+var dockerProjectTypes = []string{"nodejs", "python", "go", "react", "express", "fastapi"}
+
+// containerFiles are the template files needed to containerize a project,
+// as opposed to the sample application code the templates also generate
+var containerFiles = []string{"Dockerfile", "nginx.conf"}
+
+// detectProjectType picks a project template for existing code by detecting
+// the language of files, keyed by path relative to the project root
+func detectProjectType(files map[string]string) (string, error) {
+	if len(files) == 0 {
+		return "", fmt.Errorf("project_type %q needs files to detect the project type from. Supported types: %s", autoProjectType, strings.Join(dockerProjectTypes, ", "))
+	}
+
+	dir, err := os.MkdirTemp("", "crush-detect-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create detection directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, content := range files {
+		path, err := ValidatePathSecurity(name, dir)
+		if err != nil {
+			return "", fmt.Errorf("invalid file %s: %w", name, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return "", err
+		}
+	}
+
+	langName, _, err := language.DetectLanguage(dir)
+	if err != nil {
+		return "", fmt.Errorf("could not detect the project type: %v. Supported types: %s", err, strings.Join(dockerProjectTypes, ", "))
+	}
+
+	switch langName {
+	case "go":
+		return "go", nil
+	case "python":
+		return "python", nil
+	case "javascript", "typescript":
+		if strings.Contains(files["package.json"], `"react"`) {
+			return "react", nil
+		}
+		return "nodejs", nil
+	default:
+		return "", fmt.Errorf("detected %s, which has no Docker template. Supported types: %s", langName, strings.Join(dockerProjectTypes, ", "))
+	}
+}
+
+// containerizationFiles keeps only the files of a template that containerize
+// an existing project, leaving out any the project already provides
+func containerizationFiles(template, provided map[string]string) map[string]string {
+	files := make(map[string]string)
+	for _, name := range containerFiles {
+		if content, ok := template[name]; ok {
+			if _, exists := provided[name]; !exists {
+				files[name] = content
+			}
+		}
+	}
+	return files
+}
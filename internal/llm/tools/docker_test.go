@@ -0,0 +1,1018 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/stretchr/testify/require"
+)
+
+// stubDocker puts a fake docker binary first on PATH. It appends one line per
+// invocation to the returned file with the arguments separated by tabs,
+// prints the contents of DOCKER_STUB_STDOUT_<subcommand> if set or else
+// DOCKER_STUB_STDOUT, and exits with DOCKER_STUB_EXIT. Containers started by
+// run are inspected without waiting.
+func stubDocker(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("stub docker binary requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	script := `#!/bin/sh
+if [ "$1" = "--version" ]; then
+  echo "Docker version 0.0.0-stub"
+  exit 0
+fi
+line=""
+for arg in "$@"; do
+  line="$line$arg	"
+done
+printf '%s\n' "$line" >> "` + argsFile + `"
+eval "stdout=\${DOCKER_STUB_STDOUT_$1-\$DOCKER_STUB_STDOUT}"
+printf '%s' "$stdout"
+printf 'stub stderr' >&2
+exit "${DOCKER_STUB_EXIT:-0}"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker"), []byte(script), 0o755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	grace := containerStartGrace
+	containerStartGrace = 0
+	t.Cleanup(func() { containerStartGrace = grace })
+	return argsFile
+}
+
+func runDocker(t *testing.T, params DockerAppBuilderParams) (ToolResponse, DockerResponseMetadata) {
+	t.Helper()
+
+	input, err := json.Marshal(params)
+	require.NoError(t, err)
+
+	tool := NewDockerTool(permission.NewPermissionService(t.TempDir(), true, nil))
+	resp, err := tool.Run(context.Background(), ToolCall{Name: DockerToolName, Input: string(input)})
+	require.NoError(t, err)
+
+	var metadata DockerResponseMetadata
+	if resp.Metadata != "" {
+		require.NoError(t, json.Unmarshal([]byte(resp.Metadata), &metadata))
+	}
+	return resp, metadata
+}
+
+// recordedCalls returns the arguments of every recorded docker invocation
+func recordedCalls(t *testing.T, argsFile string) [][]string {
+	t.Helper()
+	data, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+
+	var calls [][]string
+	for line := range strings.Lines(string(data)) {
+		calls = append(calls, strings.Split(strings.TrimSuffix(line, "\t\n"), "\t"))
+	}
+	return calls
+}
+
+func TestDockerExecRunsArgvWithoutShell(t *testing.T) {
+	argsFile := stubDocker(t)
+	t.Setenv("DOCKER_STUB_STDOUT", "hello from container")
+
+	resp, metadata := runDocker(t, DockerAppBuilderParams{
+		Action:      "exec",
+		ProjectName: "My-App",
+		Command:     `ls -la "/app/my dir" 'it''s' $HOME;rm`,
+	})
+
+	require.False(t, resp.IsError, resp.Content)
+	require.Equal(t, [][]string{{"exec", "crush-app-my-app-instance", "ls", "-la", "/app/my dir", "its", "$HOME;rm"}}, recordedCalls(t, argsFile))
+	require.Contains(t, resp.Content, "hello from container")
+	require.Contains(t, resp.Content, "stub stderr")
+	require.NotNil(t, metadata.ExitCode)
+	require.Equal(t, 0, *metadata.ExitCode)
+}
+
+func TestDockerExecShell(t *testing.T) {
+	argsFile := stubDocker(t)
+
+	resp, _ := runDocker(t, DockerAppBuilderParams{
+		Action:      "exec",
+		ProjectName: "app",
+		Command:     "cat /etc/hosts | wc -l",
+		Shell:       true,
+	})
+
+	require.False(t, resp.IsError, resp.Content)
+	require.Equal(t, [][]string{{"exec", "crush-app-app-instance", "sh", "-c", "cat /etc/hosts | wc -l"}}, recordedCalls(t, argsFile))
+}
+
+func TestDockerExecReportsExitCode(t *testing.T) {
+	stubDocker(t)
+	t.Setenv("DOCKER_STUB_EXIT", "3")
+
+	resp, metadata := runDocker(t, DockerAppBuilderParams{Action: "exec", ProjectName: "app", Command: "false"})
+
+	require.False(t, resp.IsError, resp.Content)
+	require.Contains(t, resp.Content, "exited with code 3")
+	require.NotNil(t, metadata.ExitCode)
+	require.Equal(t, 3, *metadata.ExitCode)
+}
+
+func TestDockerExecDockerFailure(t *testing.T) {
+	stubDocker(t)
+	t.Setenv("DOCKER_STUB_EXIT", "125")
+
+	resp, _ := runDocker(t, DockerAppBuilderParams{Action: "exec", ProjectName: "app", Command: "ls"})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "stub stderr")
+}
+
+func TestDockerExecRequiresParams(t *testing.T) {
+	stubDocker(t)
+
+	resp, _ := runDocker(t, DockerAppBuilderParams{Action: "exec", ProjectName: "app"})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "project_name and command are required")
+
+	resp, _ = runDocker(t, DockerAppBuilderParams{Action: "exec", Command: "ls"})
+	require.True(t, resp.IsError)
+
+	resp, _ = runDocker(t, DockerAppBuilderParams{Action: "exec", ProjectName: "app", Command: `echo "unterminated`})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "unterminated")
+}
+
+func TestSplitCommandArgs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"ls", []string{"ls"}},
+		{"  ls   -la\t/app ", []string{"ls", "-la", "/app"}},
+		{`echo "a b" 'c d'`, []string{"echo", "a b", "c d"}},
+		{`echo a\ b`, []string{"echo", "a b"}},
+		{`echo "say \"hi\""`, []string{"echo", `say "hi"`}},
+		{`echo '\n'`, []string{"echo", `\n`}},
+		{`echo ""`, []string{"echo", ""}},
+		{"echo $(whoami) && rm -rf /", []string{"echo", "$(whoami)", "&&", "rm", "-rf", "/"}},
+	}
+	for _, tt := range tests {
+		got, err := splitCommandArgs(tt.command)
+		require.NoError(t, err, tt.command)
+		require.Equal(t, tt.want, got, tt.command)
+	}
+
+	for _, command := range []string{"", "   ", `echo 'open`, `echo "open`, `echo \`} {
+		_, err := splitCommandArgs(command)
+		require.Error(t, err, command)
+	}
+}
+
+func TestDockerPushTagsAndPushes(t *testing.T) {
+	argsFile := stubDocker(t)
+	t.Setenv("DOCKER_STUB_STDOUT", "latest: digest: sha256:abc size: 1234")
+
+	resp, metadata := runDocker(t, DockerAppBuilderParams{
+		Action:      "push",
+		ProjectName: "My-App",
+		Registry:    "registry.example.com:5000/team/",
+		Tag:         "v1.2.0",
+	})
+
+	require.False(t, resp.IsError, resp.Content)
+	reference := "registry.example.com:5000/team/crush-app-my-app:v1.2.0"
+	require.Equal(t, [][]string{
+		{"tag", "crush-app-my-app", reference},
+		{"push", reference},
+	}, recordedCalls(t, argsFile))
+	require.Contains(t, resp.Content, "digest: sha256:abc")
+	require.Equal(t, reference, metadata.Reference)
+}
+
+func TestDockerPullRetagsImage(t *testing.T) {
+	argsFile := stubDocker(t)
+
+	resp, metadata := runDocker(t, DockerAppBuilderParams{Action: "pull", ProjectName: "app", Registry: "ghcr.io/acme"})
+
+	require.False(t, resp.IsError, resp.Content)
+	reference := "ghcr.io/acme/crush-app-app:latest"
+	require.Equal(t, [][]string{
+		{"pull", reference},
+		{"tag", reference, "crush-app-app"},
+	}, recordedCalls(t, argsFile))
+	require.Equal(t, reference, metadata.Reference)
+}
+
+func TestDockerPushFailure(t *testing.T) {
+	stubDocker(t)
+	t.Setenv("DOCKER_STUB_EXIT", "1")
+
+	resp, _ := runDocker(t, DockerAppBuilderParams{Action: "push", ProjectName: "app", Registry: "ghcr.io/acme"})
+	require.True(t, resp.IsError)
+}
+
+func TestDockerRegistryValidation(t *testing.T) {
+	argsFile := stubDocker(t)
+
+	invalid := []DockerAppBuilderParams{
+		{Action: "push", ProjectName: "app"},
+		{Action: "push", Registry: "ghcr.io"},
+		{Action: "push", ProjectName: "app", Registry: "--config=/tmp/evil"},
+		{Action: "push", ProjectName: "app", Registry: "ghcr.io/acme;rm -rf /"},
+		{Action: "push", ProjectName: "app", Registry: "ghcr.io/ACME"},
+		{Action: "pull", ProjectName: "app", Registry: "ghcr.io", Tag: "-v"},
+		{Action: "pull", ProjectName: "app", Registry: "ghcr.io", Tag: "v1 && whoami"},
+		{Action: "pull", ProjectName: "app", Registry: "ghcr.io", Tag: strings.Repeat("a", 129)},
+	}
+	for _, params := range invalid {
+		resp, _ := runDocker(t, params)
+		require.True(t, resp.IsError, "%+v", params)
+	}
+
+	_, err := os.Stat(argsFile)
+	require.True(t, os.IsNotExist(err), "docker must not be invoked for invalid input")
+}
+
+func TestDockerRemoveImage(t *testing.T) {
+	argsFile := stubDocker(t)
+	t.Setenv("DOCKER_STUB_STDOUT", "2097152")
+
+	resp, metadata := runDocker(t, DockerAppBuilderParams{Action: "remove", ProjectName: "My-App"})
+
+	require.False(t, resp.IsError, resp.Content)
+	require.Equal(t, [][]string{
+		{"rm", "-f", "crush-app-my-app-instance"},
+		{"image", "inspect", "--format", "{{.Size}}", "crush-app-my-app"},
+		{"rmi", "crush-app-my-app"},
+	}, recordedCalls(t, argsFile))
+	require.Contains(t, resp.Content, "2.0 MB")
+	require.EqualValues(t, 2097152, metadata.FreedBytes)
+}
+
+func TestDockerRemovePrunesProjectFiles(t *testing.T) {
+	stubDocker(t)
+	t.Setenv("DOCKER_STUB_STDOUT", "1024")
+
+	projectName := filepath.Base(t.TempDir())
+	projectDir := filepath.Join("/tmp", "crush-apps", projectName)
+	writeFiles(t, projectDir, map[string]string{"Dockerfile": "FROM scratch\n", "src/main.go": strings.Repeat("x", 2048)})
+	t.Cleanup(func() { os.RemoveAll(projectDir) })
+
+	resp, metadata := runDocker(t, DockerAppBuilderParams{Action: "remove", ProjectName: projectName, PruneFiles: true})
+
+	require.False(t, resp.IsError, resp.Content)
+	_, err := os.Stat(projectDir)
+	require.True(t, os.IsNotExist(err), "project directory should be removed")
+	require.EqualValues(t, 1024+2048+len("FROM scratch\n"), metadata.FreedBytes)
+}
+
+func TestDockerRemoveReportsPartialFailure(t *testing.T) {
+	stubDocker(t)
+	t.Setenv("DOCKER_STUB_EXIT", "1")
+
+	projectName := filepath.Base(t.TempDir())
+	projectDir := filepath.Join("/tmp", "crush-apps", projectName)
+	writeFiles(t, projectDir, map[string]string{"Dockerfile": "FROM scratch\n"})
+	t.Cleanup(func() { os.RemoveAll(projectDir) })
+
+	resp, metadata := runDocker(t, DockerAppBuilderParams{Action: "remove", ProjectName: projectName, PruneFiles: true})
+
+	// The files are gone but the image isn't, so the removal is not a success
+	require.True(t, resp.IsError, resp.Content)
+	require.Contains(t, resp.Content, "only partly removed")
+	require.Contains(t, resp.Content, "- image crush-app-"+strings.ToLower(projectName)+": stub stderr")
+	require.Contains(t, resp.Content, "Removed project directory")
+	require.NotContains(t, resp.Content, "✅")
+	_, err := os.Stat(projectDir)
+	require.True(t, os.IsNotExist(err), "project directory should still be removed")
+	require.EqualValues(t, len("FROM scratch\n"), metadata.FreedBytes)
+}
+
+func TestDockerRemoveValidation(t *testing.T) {
+	argsFile := stubDocker(t)
+
+	for _, params := range []DockerAppBuilderParams{
+		{Action: "remove"},
+		{Action: "remove", ProjectName: "../../etc", PruneFiles: true},
+		{Action: "remove", ProjectName: ".", PruneFiles: true},
+		{Action: "remove", ProjectName: "/etc", PruneFiles: true},
+	} {
+		resp, _ := runDocker(t, params)
+		require.True(t, resp.IsError, "%+v", params)
+	}
+
+	_, err := os.Stat(argsFile)
+	require.True(t, os.IsNotExist(err), "docker must not be invoked for invalid input")
+
+	// Without prune_files a failed rmi is an error
+	t.Setenv("DOCKER_STUB_EXIT", "1")
+	resp, _ := runDocker(t, DockerAppBuilderParams{Action: "remove", ProjectName: "app"})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "Failed to remove image")
+}
+
+func TestFormatBytes(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "0 B", formatBytes(0))
+	require.Equal(t, "1023 B", formatBytes(1023))
+	require.Equal(t, "1.0 KB", formatBytes(1024))
+	require.Equal(t, "1.5 MB", formatBytes(1536*1024))
+	require.Equal(t, "2.0 GB", formatBytes(2<<30))
+}
+
+func TestExposedPort(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"FROM golang:1.21-alpine\nEXPOSE 8080\nCMD [\"./main\"]\n": "8080",
+		"FROM node\nexpose 5000/tcp 5001\n":                        "5000",
+		"FROM python\nEXPOSE $PORT 8000/udp\n":                     "8000",
+		"FROM nginx\n  EXPOSE 80\n":                                "80",
+		"FROM scratch\nEXPOSE\nEXPOSE 99999\n":                     "",
+		"FROM scratch\n# EXPOSE 9000\n":                            "",
+		"":                                                         "",
+	}
+	for dockerfile, want := range tests {
+		require.Equal(t, want, exposedPort(dockerfile), dockerfile)
+	}
+}
+
+func TestResolveRunPorts(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"Dockerfile": "FROM python:3.11-slim\nEXPOSE 8000\n"})
+	dockerfile := filepath.Join(dir, "Dockerfile")
+
+	tests := []struct {
+		port, dockerfile   string
+		wantHost, wantCont string
+	}{
+		{"", dockerfile, "8000", "8000"},
+		{"", filepath.Join(t.TempDir(), "Dockerfile"), "3000", "3000"},
+		{"9090", dockerfile, "9090", "9090"},
+		{"8081:8000", dockerfile, "8081", "8000"},
+	}
+	for _, tt := range tests {
+		host, container, err := resolveRunPorts(tt.port, tt.dockerfile)
+		require.NoError(t, err, tt.port)
+		require.Equal(t, tt.wantHost, host, tt.port)
+		require.Equal(t, tt.wantCont, container, tt.port)
+	}
+
+	for _, port := range []string{"http", "0", "70000", "8080:", ":8080", "1:2:3"} {
+		_, _, err := resolveRunPorts(port, dockerfile)
+		require.Error(t, err, port)
+	}
+}
+
+func TestDockerRunMapsHostToContainerPort(t *testing.T) {
+	argsFile := stubDocker(t)
+	t.Setenv("DOCKER_STUB_STDOUT", "abc123")
+
+	projectName := filepath.Base(t.TempDir())
+	projectDir := filepath.Join("/tmp", "crush-apps", projectName)
+	writeFiles(t, projectDir, map[string]string{"Dockerfile": "FROM golang:1.21-alpine\nEXPOSE 8080\n"})
+	t.Cleanup(func() { os.RemoveAll(projectDir) })
+
+	resp, metadata := runDocker(t, DockerAppBuilderParams{Action: "run", ProjectName: projectName})
+	require.False(t, resp.IsError, resp.Content)
+	require.Equal(t, "http://localhost:8080", metadata.URL)
+
+	resp, metadata = runDocker(t, DockerAppBuilderParams{Action: "run", ProjectName: projectName, Port: "9000:8080"})
+	require.False(t, resp.IsError, resp.Content)
+	require.Equal(t, "http://localhost:9000", metadata.URL)
+
+	// Each run first removes any previous container and then inspects the new one
+	calls := recordedCalls(t, argsFile)
+	require.Len(t, calls, 6)
+	require.Equal(t, []string{"run", "-d", "-p", "8080:8080"}, calls[1][:4])
+	require.Equal(t, "inspect", calls[2][0])
+	require.Equal(t, []string{"run", "-d", "-p", "9000:8080"}, calls[4][:4])
+
+	resp, _ = runDocker(t, DockerAppBuilderParams{Action: "run", ProjectName: projectName, Port: "not-a-port"})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "host_port:container_port")
+}
+
+func TestDockerRunUsesCustomDockerfilePort(t *testing.T) {
+	argsFile := stubDocker(t)
+	t.Setenv("DOCKER_STUB_STDOUT", "abc123")
+
+	projectName := filepath.Base(t.TempDir())
+	projectDir := filepath.Join("/tmp", "crush-apps", projectName)
+	writeFiles(t, projectDir, map[string]string{
+		"Dockerfile":            "FROM golang:1.21-alpine\nEXPOSE 8080\n",
+		"docker/Dockerfile.dev": "FROM golang:1.21-alpine\nEXPOSE 4000\n",
+	})
+	t.Cleanup(func() { os.RemoveAll(projectDir) })
+
+	resp, metadata := runDocker(t, DockerAppBuilderParams{Action: "run", ProjectName: projectName, Dockerfile: "docker/Dockerfile.dev"})
+	require.False(t, resp.IsError, resp.Content)
+	require.Equal(t, "http://localhost:4000", metadata.URL)
+	require.Equal(t, []string{"run", "-d", "-p", "4000:4000"}, recordedCalls(t, argsFile)[1][:4])
+
+	resp, _ = runDocker(t, DockerAppBuilderParams{Action: "run", ProjectName: projectName, Dockerfile: "../Dockerfile"})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "inside the project directory")
+}
+
+func TestDockerRunPassesEnvFile(t *testing.T) {
+	argsFile := stubDocker(t)
+	t.Setenv("DOCKER_STUB_STDOUT", "abc123")
+
+	projectName := filepath.Base(t.TempDir())
+	projectDir := filepath.Join("/tmp", "crush-apps", projectName)
+	writeFiles(t, projectDir, map[string]string{
+		"Dockerfile": "FROM golang:1.21-alpine\nEXPOSE 8080\n",
+		".env":       "DATABASE_URL=postgres://db\nAPI_KEY=from-file\n",
+	})
+	t.Cleanup(func() { os.RemoveAll(projectDir) })
+
+	resp, _ := runDocker(t, DockerAppBuilderParams{
+		Action:      "run",
+		ProjectName: projectName,
+		EnvFile:     ".env",
+		Environment: map[string]string{"API_KEY": "explicit"},
+	})
+	require.False(t, resp.IsError, resp.Content)
+
+	// The env file comes before -e, which docker applies last
+	calls := recordedCalls(t, argsFile)
+	require.Len(t, calls, 3)
+	require.Equal(t, []string{"run", "-d", "-p", "8080:8080", "--env-file", filepath.Join(projectDir, ".env"), "-e", "API_KEY=explicit"}, calls[1][:8])
+}
+
+func TestDockerRunEnvFileValidation(t *testing.T) {
+	stubDocker(t)
+
+	projectName := filepath.Base(t.TempDir())
+	projectDir := filepath.Join("/tmp", "crush-apps", projectName)
+	writeFiles(t, projectDir, map[string]string{"Dockerfile": "FROM golang:1.21-alpine\n"})
+	t.Cleanup(func() { os.RemoveAll(projectDir) })
+
+	resp, _ := runDocker(t, DockerAppBuilderParams{Action: "run", ProjectName: projectName, EnvFile: "../other/.env"})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "invalid env_file")
+
+	resp, _ = runDocker(t, DockerAppBuilderParams{Action: "run", ProjectName: projectName, EnvFile: "/etc/passwd"})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "invalid env_file")
+
+	resp, _ = runDocker(t, DockerAppBuilderParams{Action: "run", ProjectName: projectName, EnvFile: ".env"})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "env_file .env not found")
+}
+
+const buildKitOutput = `#0 building with "default" instance using docker driver
+
+#1 [internal] load build definition from Dockerfile
+#1 transferring dockerfile: 215B done
+#1 DONE 0.0s
+
+#2 [internal] load metadata for docker.io/library/golang:1.21-alpine
+#2 DONE 0.5s
+
+#3 [1/4] FROM docker.io/library/golang:1.21-alpine@sha256:abc
+#3 DONE 0.0s
+
+#4 [2/4] WORKDIR /app
+#4 CACHED
+
+#5 [3/4] COPY go.mod ./
+#5 CACHED
+
+#6 [4/4] RUN go build -o main .
+#6 0.512 go: downloading example.com/dep v1.0.0
+#6 DONE 3.2s
+
+#7 exporting to image
+#7 DONE 0.1s
+`
+
+const legacyBuildOutput = `Sending build context to Docker daemon  4.096kB
+Step 1/4 : FROM golang:1.21-alpine
+ ---> 0b9e5b1a2c3d
+Step 2/4 : WORKDIR /app
+ ---> Using cache
+ ---> 1a2b3c4d5e6f
+Step 3/4 : COPY . .
+ ---> Using cache
+ ---> 2b3c4d5e6f7a
+Step 4/4 : RUN go build -o main .
+ ---> Using cache
+ ---> 3c4d5e6f7a8b
+Successfully built 3c4d5e6f7a8b
+`
+
+func TestParseBuildCache(t *testing.T) {
+	t.Parallel()
+
+	cache := parseBuildCache(buildKitOutput)
+	require.Equal(t, buildCache{steps: 3, cached: 2}, cache)
+	require.Equal(t, "Build cache: 2/3 steps cached, 1 rebuilt", cache.summary(false))
+
+	cache = parseBuildCache(legacyBuildOutput)
+	require.Equal(t, buildCache{steps: 3, cached: 3}, cache)
+	require.Equal(t, "Build cache: all 3 steps cached", cache.summary(false))
+
+	cache = parseBuildCache("#4 [2/4] WORKDIR /app\n#4 DONE 0.1s\n#5 [build 3/4] RUN make\n#5 DONE 1.0s\n")
+	require.Equal(t, "Build cache: no cached steps, all 2 steps rebuilt", cache.summary(false))
+	require.Equal(t, "Build cache: unknown (no steps found in build output)", parseBuildCache("").summary(false))
+}
+
+func TestDockerBuildReportsCacheUsage(t *testing.T) {
+	argsFile := stubDocker(t)
+	t.Setenv("DOCKER_STUB_STDOUT", buildKitOutput)
+
+	projectName := filepath.Base(t.TempDir())
+	projectDir := filepath.Join("/tmp", "crush-apps", projectName)
+	writeFiles(t, projectDir, map[string]string{"Dockerfile": "FROM golang:1.21-alpine\n"})
+	t.Cleanup(func() { os.RemoveAll(projectDir) })
+
+	resp, metadata := runDocker(t, DockerAppBuilderParams{Action: "build", ProjectName: projectName})
+	require.False(t, resp.IsError, resp.Content)
+	require.Contains(t, resp.Content, "Build cache: 2/3 steps cached, 1 rebuilt")
+	require.Equal(t, 3, metadata.BuildSteps)
+	require.Equal(t, 2, metadata.CachedSteps)
+
+	resp, _ = runDocker(t, DockerAppBuilderParams{Action: "build", ProjectName: projectName, NoCache: true})
+	require.False(t, resp.IsError, resp.Content)
+	require.Contains(t, resp.Content, "Build cache: disabled")
+
+	calls := recordedCalls(t, argsFile)
+	require.Len(t, calls, 2)
+	require.Equal(t, []string{"build", "--label", "crush.app=" + strings.ToLower(projectName), "-t", "crush-app-" + strings.ToLower(projectName), projectDir}, calls[0])
+	require.Equal(t, []string{"build", "--no-cache", "--label", "crush.app=" + strings.ToLower(projectName), "-t", "crush-app-" + strings.ToLower(projectName), projectDir}, calls[1])
+}
+
+func TestParseBaseImages(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, []string{"docker.io/library/golang:1.21-alpine@sha256:abc"}, parseBaseImages(buildKitOutput))
+	require.Equal(t, []string{"golang:1.21-alpine"}, parseBaseImages(legacyBuildOutput))
+
+	pulled := `Step 1/3 : FROM golang:1.21 AS build
+1.21: Pulling from library/golang
+Digest: sha256:0123abcd
+Status: Downloaded newer image for golang:1.21
+ ---> 0b9e5b1a2c3d
+Step 2/3 : RUN go build -o /main .
+ ---> Running in 4f5e6d7c8b9a
+Step 3/3 : FROM alpine:3.19
+ ---> 5c6d7e8f9a0b
+`
+	require.Equal(t, []string{"golang:1.21@sha256:0123abcd", "alpine:3.19"}, parseBaseImages(pulled))
+
+	multiStage := "#3 [build 1/3] FROM docker.io/library/golang:1.21@sha256:aa11\n" +
+		"#3 resolve docker.io/library/golang:1.21@sha256:aa11 done\n" +
+		"#4 [stage-1 1/2] FROM docker.io/library/alpine:3.19@sha256:bb22\n" +
+		"#3 [build 1/3] FROM docker.io/library/golang:1.21@sha256:aa11\n"
+	require.Equal(t, []string{"docker.io/library/golang:1.21@sha256:aa11", "docker.io/library/alpine:3.19@sha256:bb22"}, parseBaseImages(multiStage))
+	require.Empty(t, parseBaseImages(""))
+}
+
+func TestDockerBuildPullsBaseImages(t *testing.T) {
+	argsFile := stubDocker(t)
+	t.Setenv("DOCKER_STUB_STDOUT", buildKitOutput)
+
+	projectName := filepath.Base(t.TempDir())
+	projectDir := filepath.Join("/tmp", "crush-apps", projectName)
+	writeFiles(t, projectDir, map[string]string{"Dockerfile": "FROM golang:1.21-alpine\n"})
+	t.Cleanup(func() { os.RemoveAll(projectDir) })
+
+	resp, metadata := runDocker(t, DockerAppBuilderParams{Action: "build", ProjectName: projectName, PullBase: true})
+	require.False(t, resp.IsError, resp.Content)
+	require.Contains(t, resp.Content, "Base image: docker.io/library/golang:1.21-alpine@sha256:abc")
+	require.Equal(t, []string{"docker.io/library/golang:1.21-alpine@sha256:abc"}, metadata.BaseImages)
+
+	calls := recordedCalls(t, argsFile)
+	require.Len(t, calls, 1)
+	require.Equal(t, []string{"build", "--pull", "--label", "crush.app=" + strings.ToLower(projectName), "-t", "crush-app-" + strings.ToLower(projectName), projectDir}, calls[0])
+}
+
+func TestDockerBuildRequiresDockerfile(t *testing.T) {
+	argsFile := stubDocker(t)
+
+	projectName := filepath.Base(t.TempDir())
+	projectDir := filepath.Join("/tmp", "crush-apps", projectName)
+	writeFiles(t, projectDir, map[string]string{"main.go": "package main\n"})
+	t.Cleanup(func() { os.RemoveAll(projectDir) })
+
+	resp, _ := runDocker(t, DockerAppBuilderParams{Action: "build", ProjectName: projectName})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "no Dockerfile found in "+projectDir)
+	require.Contains(t, resp.Content, "create_project")
+
+	resp, _ = runDocker(t, DockerAppBuilderParams{Action: "build", ProjectName: projectName, Dockerfile: "../Dockerfile"})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "inside the project directory")
+
+	// docker build never ran
+	_, err := os.Stat(argsFile)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestDockerBuildCustomDockerfile(t *testing.T) {
+	argsFile := stubDocker(t)
+
+	projectName := filepath.Base(t.TempDir())
+	projectDir := filepath.Join("/tmp", "crush-apps", projectName)
+	writeFiles(t, projectDir, map[string]string{"docker/Dockerfile.dev": "FROM golang:1.21-alpine\n"})
+	t.Cleanup(func() { os.RemoveAll(projectDir) })
+
+	resp, _ := runDocker(t, DockerAppBuilderParams{Action: "build", ProjectName: projectName, Dockerfile: "docker/Dockerfile.dev"})
+	require.False(t, resp.IsError, resp.Content)
+
+	calls := recordedCalls(t, argsFile)
+	require.Len(t, calls, 1)
+	require.Equal(t, []string{"build", "-f", filepath.Join(projectDir, "docker", "Dockerfile.dev"), "--label", "crush.app=" + strings.ToLower(projectName), "-t", "crush-app-" + strings.ToLower(projectName), projectDir}, calls[0])
+}
+
+func TestDetectProjectType(t *testing.T) {
+	tests := map[string]struct {
+		files    map[string]string
+		expected string
+	}{
+		"go module": {
+			files:    map[string]string{"go.mod": "module example.com/app\n", "cmd/app/main.go": "package main\n"},
+			expected: "go",
+		},
+		"python": {
+			files:    map[string]string{"app.py": "print('hi')\n", "requirements.txt": "flask\n"},
+			expected: "python",
+		},
+		"node": {
+			files:    map[string]string{"package.json": `{"dependencies": {"express": "^4"}}`, "server.js": ""},
+			expected: "nodejs",
+		},
+		"react": {
+			files:    map[string]string{"package.json": `{"dependencies": {"react": "^18"}}`, "src/App.jsx": ""},
+			expected: "react",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			projectType, err := detectProjectType(tt.files)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, projectType)
+		})
+	}
+
+	_, err := detectProjectType(map[string]string{"Cargo.toml": "[package]\n", "src/main.rs": ""})
+	require.ErrorContains(t, err, "detected rust, which has no Docker template")
+	require.ErrorContains(t, err, "Supported types: nodejs, python, go")
+
+	_, err = detectProjectType(map[string]string{"README": "hello"})
+	require.ErrorContains(t, err, "could not detect the project type")
+
+	_, err = detectProjectType(nil)
+	require.ErrorContains(t, err, "needs files")
+
+	_, err = detectProjectType(map[string]string{"../escape.go": ""})
+	require.Error(t, err)
+}
+
+func TestDockerCreateProjectDetectsGoModule(t *testing.T) {
+	stubDocker(t)
+
+	projectName := filepath.Base(t.TempDir())
+	projectDir := filepath.Join("/tmp", "crush-apps", projectName)
+	t.Cleanup(func() { os.RemoveAll(projectDir) })
+
+	mainGo := "package main\n\nfunc main() { println(\"existing\") }\n"
+	resp, metadata := runDocker(t, DockerAppBuilderParams{
+		Action:      "create_project",
+		ProjectName: projectName,
+		ProjectType: "auto",
+		Files:       map[string]string{"go.mod": "module example.com/existing\n\ngo 1.21\n", "main.go": mainGo},
+	})
+	require.False(t, resp.IsError, resp.Content)
+	require.Equal(t, "go", metadata.ProjectType)
+	require.Contains(t, resp.Content, "Type: go (detected)")
+
+	dockerfile, err := os.ReadFile(filepath.Join(projectDir, "Dockerfile"))
+	require.NoError(t, err)
+	require.Contains(t, string(dockerfile), "FROM golang:")
+
+	// The existing code is kept rather than replaced by the template app
+	content, err := os.ReadFile(filepath.Join(projectDir, "main.go"))
+	require.NoError(t, err)
+	require.Equal(t, mainGo, string(content))
+	goMod, err := os.ReadFile(filepath.Join(projectDir, "go.mod"))
+	require.NoError(t, err)
+	require.NotContains(t, string(goMod), "gin-gonic")
+}
+
+func TestDockerCreateProjectAutoKeepsProvidedDockerfile(t *testing.T) {
+	stubDocker(t)
+
+	projectName := filepath.Base(t.TempDir())
+	projectDir := filepath.Join("/tmp", "crush-apps", projectName)
+	t.Cleanup(func() { os.RemoveAll(projectDir) })
+
+	resp, _ := runDocker(t, DockerAppBuilderParams{
+		Action:      "create_project",
+		ProjectName: projectName,
+		ProjectType: "auto",
+		Files:       map[string]string{"go.mod": "module example.com/existing\n", "Dockerfile": "FROM scratch\n"},
+	})
+	require.False(t, resp.IsError, resp.Content)
+
+	dockerfile, err := os.ReadFile(filepath.Join(projectDir, "Dockerfile"))
+	require.NoError(t, err)
+	require.Equal(t, "FROM scratch\n", string(dockerfile))
+}
+
+func TestDockerCreateProjectAutoDetectionFailure(t *testing.T) {
+	stubDocker(t)
+
+	resp, _ := runDocker(t, DockerAppBuilderParams{
+		Action:      "create_project",
+		ProjectName: filepath.Base(t.TempDir()),
+		ProjectType: "auto",
+		Files:       map[string]string{"notes.txt": "nothing to build"},
+	})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "Supported types: nodejs, python, go, react, express, fastapi")
+}
+
+func TestParseContainerState(t *testing.T) {
+	t.Parallel()
+
+	state, ok := parseContainerState("exited 137\n")
+	require.True(t, ok)
+	require.Equal(t, containerState{status: "exited", exitCode: 137}, state)
+	require.True(t, state.exited())
+
+	state, ok = parseContainerState("running 0")
+	require.True(t, ok)
+	require.False(t, state.exited())
+
+	for _, output := range []string{"", "abc123", "exited one"} {
+		_, ok := parseContainerState(output)
+		require.False(t, ok, output)
+	}
+}
+
+func TestDockerRunReportsContainerThatExited(t *testing.T) {
+	argsFile := stubDocker(t)
+	t.Setenv("DOCKER_STUB_STDOUT", "abc123")
+	t.Setenv("DOCKER_STUB_STDOUT_inspect", "exited 1\n")
+	t.Setenv("DOCKER_STUB_STDOUT_logs", "starting server\npanic: listen tcp :3000: bind: address already in use\n")
+
+	projectName := filepath.Base(t.TempDir())
+	resp, metadata := runDocker(t, DockerAppBuilderParams{Action: "run", ProjectName: projectName})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "exited right after starting (status: exited, exit code: 1)")
+	require.Contains(t, resp.Content, "panic: listen tcp :3000")
+	require.True(t, strings.HasPrefix(metadata.ContainerID, "abc123"), metadata.ContainerID)
+	require.NotNil(t, metadata.ExitCode)
+	require.Equal(t, 1, *metadata.ExitCode)
+	require.Empty(t, metadata.URL)
+
+	calls := recordedCalls(t, argsFile)
+	containerName := "crush-app-" + strings.ToLower(projectName) + "-instance"
+	require.Equal(t, []string{"inspect", "--format", "{{.State.Status}} {{.State.ExitCode}}", containerName}, calls[2])
+	require.Equal(t, []string{"logs", "--tail", "20", containerName}, calls[3])
+}
+
+func TestDockerRunKeepsRunningContainer(t *testing.T) {
+	stubDocker(t)
+	t.Setenv("DOCKER_STUB_STDOUT", "abc123")
+	t.Setenv("DOCKER_STUB_STDOUT_inspect", "running 0\n")
+
+	resp, metadata := runDocker(t, DockerAppBuilderParams{Action: "run", ProjectName: filepath.Base(t.TempDir())})
+	require.False(t, resp.IsError, resp.Content)
+	require.Nil(t, metadata.ExitCode)
+	require.Equal(t, "http://localhost:3000", metadata.URL)
+}
+
+func TestDockerRunJoinsNetwork(t *testing.T) {
+	tests := []struct {
+		name     string
+		networks string
+		created  bool
+	}{
+		{name: "creates missing network", networks: "", created: true},
+		{name: "reuses existing network", networks: "crush-net\n", created: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			argsFile := stubDocker(t)
+			t.Setenv("DOCKER_STUB_STDOUT", "abc123")
+			t.Setenv("DOCKER_STUB_STDOUT_network", tt.networks)
+			t.Setenv("DOCKER_STUB_STDOUT_inspect", "running 0\n")
+
+			resp, metadata := runDocker(t, DockerAppBuilderParams{Action: "run", ProjectName: "My-API", Network: "crush-net"})
+			require.False(t, resp.IsError, resp.Content)
+			require.Equal(t, "crush-net", metadata.Network)
+			require.Contains(t, resp.Content, "reach this app at http://my-api:3000")
+
+			calls := recordedCalls(t, argsFile)
+			require.Equal(t, []string{"network", "ls", "--filter", "name=^crush-net$", "--format", "{{.Name}}"}, calls[0])
+			if tt.created {
+				require.Equal(t, []string{"network", "create", "crush-net"}, calls[1])
+				calls = slices.Delete(calls, 1, 2)
+			}
+			require.Equal(t, []string{"rm", "-f", "crush-app-my-api-instance"}, calls[1])
+			require.Equal(t, []string{"run", "-d", "-p", "3000:3000", "--network", "crush-net", "--network-alias", "my-api", "--name", "crush-app-my-api-instance", "crush-app-my-api"}, calls[2])
+		})
+	}
+}
+
+func TestDockerRunRejectsInvalidNetwork(t *testing.T) {
+	argsFile := stubDocker(t)
+
+	resp, _ := runDocker(t, DockerAppBuilderParams{Action: "run", ProjectName: "api", Network: "crush net;rm"})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "invalid network name")
+	_, err := os.Stat(argsFile)
+	require.True(t, os.IsNotExist(err), "docker must not be called")
+}
+
+func TestDockerRejectsInvalidEnvironment(t *testing.T) {
+	tests := []struct {
+		name        string
+		action      string
+		environment map[string]string
+		want        string
+	}{
+		{name: "space in name", action: "run", environment: map[string]string{"BAD KEY": "x"}, want: `invalid environment variable name "BAD KEY"`},
+		{name: "equals in name", action: "run", environment: map[string]string{"A=B": "x"}, want: `invalid environment variable name "A=B"`},
+		{name: "leading digit", action: "validate", environment: map[string]string{"1X": "x"}, want: `invalid environment variable name "1X"`},
+		{name: "newline in value", action: "run", environment: map[string]string{"TOKEN": "a\nb"}, want: "invalid value for environment variable TOKEN"},
+		{name: "escape in value", action: "validate", environment: map[string]string{"TOKEN": "a\x1b[2J"}, want: "invalid value for environment variable TOKEN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			argsFile := stubDocker(t)
+
+			resp, _ := runDocker(t, DockerAppBuilderParams{Action: tt.action, ProjectName: "api", Environment: tt.environment})
+			require.True(t, resp.IsError)
+			require.Contains(t, resp.Content, tt.want)
+			_, err := os.Stat(argsFile)
+			require.True(t, os.IsNotExist(err), "docker must not be called")
+		})
+	}
+}
+
+func TestContainerEnvArgs(t *testing.T) {
+	t.Parallel()
+
+	args := containerEnvArgs(".env", map[string]string{"PORT": "8080", "API_KEY": "secret=1"})
+	require.Equal(t, []string{"--env-file", ".env", "-e", "API_KEY=secret=1", "-e", "PORT=8080"}, args)
+}
+
+func TestDockerClean(t *testing.T) {
+	for _, dryRun := range []bool{false, true} {
+		t.Run(fmt.Sprintf("dry_run=%v", dryRun), func(t *testing.T) {
+			argsFile := stubDocker(t)
+			t.Setenv("DOCKER_STUB_STDOUT_ps", strings.Join([]string{
+				"crush-app-web-instance\texited\t1.5MB (virtual 150MB)",
+				"crush-app-api-validate\tcreated\t0B (virtual 80MB)",
+				"crush-app-db-instance\trunning\t2kB (virtual 90MB)",
+				"my-crush-app-web\texited\t3MB (virtual 10MB)",
+				"postgres\texited\t5MB (virtual 300MB)",
+			}, "\n"))
+			t.Setenv("DOCKER_STUB_STDOUT_images", "sha256:aaaaaaaaaaaaaaaa\nsha256:bbbbbbbbbbbbbbbb\nsha256:cccccccccccccccc\n")
+			t.Setenv("DOCKER_STUB_STDOUT_image", strings.Join([]string{
+				"sha256:aaaaaaaaaaaaaaaa\tweb\t2000000",
+				"sha256:bbbbbbbbbbbbbbbb\t<no value>\t9000000",
+				"sha256:cccccccccccccccc\t\t7000000",
+			}, "\n"))
+
+			resp, metadata := runDocker(t, DockerAppBuilderParams{Action: "clean", DryRun: dryRun})
+			require.False(t, resp.IsError, resp.Content)
+			require.Equal(t, []string{"crush-app-web-instance", "crush-app-api-validate"}, metadata.RemovedContainers)
+			require.Equal(t, []string{"sha256:aaaaaaaaaaaaaaaa"}, metadata.RemovedImages)
+			require.Equal(t, dryRun, metadata.DryRun)
+			require.Contains(t, resp.Content, "2 container(s) and 1 image(s)")
+			require.Contains(t, resp.Content, "aaaaaaaaaaaa (crush-app-web)")
+
+			var removals [][]string
+			for _, call := range recordedCalls(t, argsFile) {
+				if call[0] == "rm" || call[0] == "rmi" {
+					removals = append(removals, call)
+				}
+			}
+			if dryRun {
+				require.Empty(t, removals)
+				require.Zero(t, metadata.FreedBytes)
+				return
+			}
+			require.Equal(t, [][]string{
+				{"rm", "crush-app-web-instance"},
+				{"rm", "crush-app-api-validate"},
+				{"rmi", "sha256:aaaaaaaaaaaaaaaa"},
+			}, removals)
+			require.Equal(t, int64(3500000), metadata.FreedBytes)
+		})
+	}
+}
+
+func TestDockerCleanNothingToRemove(t *testing.T) {
+	argsFile := stubDocker(t)
+	t.Setenv("DOCKER_STUB_STDOUT_ps", "postgres\texited\t5MB\n")
+
+	resp, metadata := runDocker(t, DockerAppBuilderParams{Action: "clean"})
+	require.False(t, resp.IsError, resp.Content)
+	require.Contains(t, resp.Content, "Nothing to clean")
+	require.Empty(t, metadata.RemovedContainers)
+	for _, call := range recordedCalls(t, argsFile) {
+		require.NotEqual(t, "image", call[0], "no dangling images to inspect")
+		require.NotEqual(t, "rm", call[0])
+	}
+}
+
+func TestParseDockerSize(t *testing.T) {
+	t.Parallel()
+
+	for size, want := range map[string]int64{
+		"0B":                   0,
+		"512B":                 512,
+		"12kB (virtual 150MB)": 12000,
+		"1.5MB":                1500000,
+		"2.25GB (virtual 3GB)": 2250000000,
+		"":                     0,
+		"unknown":              0,
+	} {
+		require.Equal(t, want, parseDockerSize(size), size)
+	}
+}
+
+func TestParseContainerListNetworks(t *testing.T) {
+	t.Parallel()
+
+	containers := parseContainerList("crush-app-web-instance\trunning\tUp 1 minute\t0.0.0.0:3000->3000/tcp\tbridge,crush-net\ncrush-app-api-instance\texited\tExited (0)\t\t\n")
+	require.Equal(t, []DockerContainer{
+		{Name: "crush-app-web-instance", Project: "web", State: "running", Status: "Up 1 minute", Ports: "0.0.0.0:3000->3000/tcp", Networks: []string{"bridge", "crush-net"}},
+		{Name: "crush-app-api-instance", Project: "api", State: "exited", Status: "Exited (0)"},
+	}, containers)
+}
+
+func TestDockerValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		inspect string
+		healthy bool
+		message string
+	}{
+		{name: "healthy image", status: http.StatusOK, inspect: "running 0\n", healthy: true, message: "returned 200"},
+		{name: "unhealthy endpoint", status: http.StatusServiceUnavailable, inspect: "running 0\n", message: "the health endpoint returned 503 within"},
+		{name: "crashing container", status: http.StatusServiceUnavailable, inspect: "exited 2\n", message: "exited with code 2 before becoming healthy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/ready" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(tt.status)
+			}))
+			t.Cleanup(server.Close)
+
+			argsFile := stubDocker(t)
+			t.Setenv("DOCKER_STUB_STDOUT_run", "abc123")
+			t.Setenv("DOCKER_STUB_STDOUT_port", strings.TrimPrefix(server.URL, "http://")+"\n")
+			t.Setenv("DOCKER_STUB_STDOUT_inspect", tt.inspect)
+			t.Setenv("DOCKER_STUB_STDOUT_logs", "")
+
+			timeout, interval := healthCheckTimeout, healthCheckInterval
+			healthCheckTimeout, healthCheckInterval = 200*time.Millisecond, 10*time.Millisecond
+			t.Cleanup(func() { healthCheckTimeout, healthCheckInterval = timeout, interval })
+
+			projectName := filepath.Base(t.TempDir())
+			resp, metadata := runDocker(t, DockerAppBuilderParams{Action: "validate", ProjectName: projectName, Port: "8080", HealthPath: "ready"})
+			require.Equal(t, !tt.healthy, resp.IsError, resp.Content)
+			require.Contains(t, resp.Content, tt.message)
+			require.NotNil(t, metadata.Healthy)
+			require.Equal(t, tt.healthy, *metadata.Healthy)
+
+			calls := recordedCalls(t, argsFile)
+			containerName := "crush-app-" + strings.ToLower(projectName) + "-validate"
+			require.Equal(t, []string{"rm", "-f", containerName}, calls[0])
+			require.Equal(t, []string{"run", "-d", "--rm", "-p", "127.0.0.1::8080", "--name", containerName, "crush-app-" + strings.ToLower(projectName)}, calls[1])
+			require.Equal(t, []string{"port", containerName, "8080"}, calls[2])
+			require.Equal(t, []string{"rm", "-f", containerName}, calls[len(calls)-1], "the validation container must be removed")
+		})
+	}
+}
@@ -1,34 +1,59 @@
 package tools
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
 
+	"github.com/charmbracelet/crush/internal/diff"
 	"github.com/charmbracelet/crush/internal/language"
 	"github.com/charmbracelet/crush/internal/permission"
 )
 
 type LintFormatParams struct {
-	Action string   `json:"action"` // "lint", "format", "both"
-	Files  []string `json:"files,omitempty"`
-	Language string `json:"language,omitempty"` // Optional override
+	Action   string   `json:"action"` // "lint", "format", "both"
+	Files    []string `json:"files,omitempty"`
+	Language string   `json:"language,omitempty"` // Optional override
+	DryRun   bool     `json:"dry_run,omitempty"`  // Report formatting changes without applying them
 }
 
 type LintFormatResult struct {
-	Action   string                   `json:"action"`
-	Success  bool                     `json:"success"`
-	Results  map[string]interface{}   `json:"results"`
-	Errors   []string                 `json:"errors,omitempty"`
-	Language string                   `json:"language"`
+	Action   string                 `json:"action"`
+	Success  bool                   `json:"success"`
+	Results  map[string]interface{} `json:"results"`
+	Findings []LintFinding          `json:"findings,omitempty"`
+	Errors   []string               `json:"errors,omitempty"`
+	Language string                 `json:"language"`
+	// DetectedLanguages lists every language found when the project is polyglot
+	DetectedLanguages []language.DetectionResult `json:"detected_languages,omitempty"`
+}
+
+// LintFinding is a single issue reported by a linter
+type LintFinding struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity,omitempty"`
+	Message  string `json:"message"`
+	Rule     string `json:"rule,omitempty"`
 }
 
 type lintFormatTool struct {
 	permissions permission.Service
 	workingDir  string
+	// onOutput receives each line of linter and formatter output as it is
+	// written, so long runs show progress before they finish
+	onOutput func(command, line string)
 }
 
 const LintFormatToolName = "lint_format"
@@ -37,13 +62,19 @@ func NewLintFormatTool(permissions permission.Service, workingDir string) BaseTo
 	return &lintFormatTool{
 		permissions: permissions,
 		workingDir:  workingDir,
+		onOutput:    logLintOutput,
 	}
 }
 
+// logLintOutput logs a line of linter or formatter output at debug level
+func logLintOutput(command, line string) {
+	slog.Debug("Lint format output", "command", command, "line", line)
+}
+
 func (t *lintFormatTool) Info() ToolInfo {
 	return ToolInfo{
 		Name:        LintFormatToolName,
-		Description: "Lint and format code files using language-specific tools. Supports Go, Python, JavaScript/TypeScript, PHP, Rust, and Java.",
+		Description: "Lint and format code files using language-specific tools. Supports Go, Python, JavaScript/TypeScript, PHP, Rust, Java, C#, Ruby, and Kotlin.",
 		Parameters: map[string]any{
 			"type": "object",
 			"properties": map[string]any{
@@ -59,7 +90,11 @@ func (t *lintFormatTool) Info() ToolInfo {
 				},
 				"language": map[string]any{
 					"type":        "string",
-					"description": "Override language detection (optional)",
+					"description": "Override language detection (optional). Set this when the result lists several detected_languages and another one should be used",
+				},
+				"dry_run": map[string]any{
+					"type":        "boolean",
+					"description": "Show the changes formatting would make without modifying any files (optional)",
 				},
 			},
 			"required": []string{"action"},
@@ -77,60 +112,96 @@ func (t *lintFormatTool) Run(ctx context.Context, params ToolCall) (ToolResponse
 		return NewTextErrorResponse("Invalid parameters"), nil
 	}
 
+	// Validate file paths so external tools can't touch files outside the working directory
+	files := make([]string, 0, len(lintParams.Files))
+	for _, file := range lintParams.Files {
+		validated, err := ValidatePathSecurityRelative(file, t.workingDir)
+		if err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("Invalid file path: %v", err)), nil
+		}
+		files = append(files, validated)
+	}
+	lintParams.Files = files
+
+	// Project overrides in .crush/language.json take precedence over the defaults
+	config, err := language.LoadProjectLanguageConfig(t.workingDir)
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("Invalid language configuration: %v", err)), nil
+	}
+
 	// Detect language if not provided
 	languageName := lintParams.Language
-	var langConfig *language.SupportedLanguage
-	
+	var detected []language.DetectionResult
+
 	if languageName == "" {
-		detectedLang, detectedConfig, err := language.DetectLanguage(t.workingDir)
+		results, err := language.DetectLanguages(t.workingDir)
 		if err != nil {
 			return NewTextErrorResponse(fmt.Sprintf("Failed to detect language: %v", err)), nil
 		}
-		languageName = detectedLang
-		langConfig = detectedConfig
-	} else {
-		config := language.DefaultLanguageConfig()
-		if lang, exists := config.Languages[languageName]; exists {
-			langConfig = &lang
-		} else {
-			return NewTextErrorResponse(fmt.Sprintf("Unsupported language: %s", languageName)), nil
+		languageName = language.PrimaryLanguage(results).Name
+		if len(results) > 1 {
+			detected = results
 		}
 	}
 
+	lang, exists := config.Languages[languageName]
+	if !exists {
+		return NewTextErrorResponse(fmt.Sprintf("Unsupported language: %s", languageName)), nil
+	}
+	langConfig := &lang
+
 	result := &LintFormatResult{
-		Action:   lintParams.Action,
-		Language: languageName,
-		Results:  make(map[string]interface{}),
-		Success:  true,
+		Action:            lintParams.Action,
+		Language:          languageName,
+		Results:           make(map[string]interface{}),
+		Success:           true,
+		DetectedLanguages: detected,
 	}
 
-	// Request permission for potentially modifying operations
-	if lintParams.Action == "format" || lintParams.Action == "both" {
+	// Formatting in place modifies files, and commands overridden in the
+	// project's .crush/language.json come from the repository itself, so
+	// either needs permission. The prompt shows the commands that will run.
+	runsLint := (lintParams.Action == "lint" || lintParams.Action == "both") && langConfig.LintCommand != ""
+	runsFormat := (lintParams.Action == "format" || lintParams.Action == "both") && langConfig.FormatCommand != ""
+	defaults := language.DefaultLanguageConfig().Languages[languageName]
+	var commands []string
+	needsPermission := false
+	if runsLint {
+		commands = append(commands, commandLine(langConfig.LintCommand, lintParams.Files))
+		needsPermission = needsPermission || langConfig.LintCommand != defaults.LintCommand
+	}
+	if runsFormat {
+		commands = append(commands, commandLine(langConfig.FormatCommand, lintParams.Files))
+		needsPermission = needsPermission || !lintParams.DryRun || langConfig.FormatCommand != defaults.FormatCommand
+	}
+	if needsPermission {
 		sessionID, _ := GetContextValues(ctx)
-		if sessionID != "" && params.ID != "" {
-			granted := t.permissions.Request(permission.CreatePermissionRequest{
-				SessionID:   sessionID,
-				ToolCallID:  params.ID,
-				ToolName:    LintFormatToolName,
-				Action:      "format",
-				Path:        t.workingDir,
-				Description: fmt.Sprintf("Format %s code files", languageName),
-			})
-			if !granted {
-				return NewTextErrorResponse("Permission denied to format files"), nil
-			}
+		if sessionID == "" {
+			return ToolResponse{}, fmt.Errorf("session ID is required for running lint and format commands")
+		}
+		granted := t.permissions.Request(permission.CreatePermissionRequest{
+			SessionID:   sessionID,
+			ToolCallID:  params.ID,
+			ToolName:    LintFormatToolName,
+			Action:      lintParams.Action,
+			Path:        t.workingDir,
+			Description: fmt.Sprintf("Run %s %s command: %s", languageName, lintParams.Action, strings.Join(commands, "; ")),
+		})
+		if !granted {
+			return NewTextErrorResponse(fmt.Sprintf("Permission denied to %s files", lintParams.Action)), nil
 		}
 	}
 
 	// Perform linting if requested
 	if lintParams.Action == "lint" || lintParams.Action == "both" {
 		if langConfig.LintCommand != "" {
-			lintResult, err := t.runLinter(langConfig.LintCommand, lintParams.Files)
+			lintResult, findings, err := t.runLinter(ctx, langConfig.LintCommand, lintParams.Files)
 			if err != nil {
 				result.Errors = append(result.Errors, fmt.Sprintf("Lint error: %v", err))
 				result.Success = false
 			}
 			result.Results["lint"] = lintResult
+			result.Findings = findings
 		} else {
 			result.Results["lint"] = "No linter configured for " + languageName
 		}
@@ -139,7 +210,13 @@ func (t *lintFormatTool) Run(ctx context.Context, params ToolCall) (ToolResponse
 	// Perform formatting if requested
 	if lintParams.Action == "format" || lintParams.Action == "both" {
 		if langConfig.FormatCommand != "" {
-			formatResult, err := t.runFormatter(langConfig.FormatCommand, lintParams.Files)
+			var formatResult map[string]interface{}
+			var err error
+			if lintParams.DryRun {
+				formatResult, err = t.runFormatterDryRun(ctx, langConfig.FormatCommand, lintParams.Files)
+			} else {
+				formatResult, err = t.runFormatter(ctx, langConfig.FormatCommand, lintParams.Files)
+			}
 			if err != nil {
 				result.Errors = append(result.Errors, fmt.Sprintf("Format error: %v", err))
 				result.Success = false
@@ -154,23 +231,106 @@ func (t *lintFormatTool) Run(ctx context.Context, params ToolCall) (ToolResponse
 	return NewTextResponse(string(output)), nil
 }
 
-func (t *lintFormatTool) runLinter(command string, files []string) (map[string]interface{}, error) {
+// commandLine returns command with files appended, as it is shown in
+// permission prompts
+func commandLine(command string, files []string) string {
+	if len(files) == 0 {
+		return command
+	}
+	return command + " " + strings.Join(files, " ")
+}
+
+// structuredLinter describes how to get machine-readable output from a linter
+type structuredLinter struct {
+	command string
+	parse   func(output []byte, workingDir string) ([]LintFinding, error)
+	// resolve, when set, picks the command for the installed linter version
+	// in place of command
+	resolve func(ctx context.Context) string
+}
+
+// structuredLintCommands maps lint commands to their JSON output variants
+var structuredLintCommands = map[string]structuredLinter{
+	"golangci-lint run": {command: "golangci-lint run --out-format json", parse: parseGolangciLintOutput, resolve: golangciLintJSONCommand},
+	"eslint":            {command: "eslint -f json", parse: parseESLintOutput},
+	"pylint":            {command: "pylint --output-format=json", parse: parsePylintOutput},
+}
+
+// golangciLintVersionPattern captures the major version from the output of
+// golangci-lint version, e.g. "golangci-lint has version 2.1.6 built with..."
+var golangciLintVersionPattern = regexp.MustCompile(`version v?(\d+)\.`)
+
+// golangciLintJSONCommand returns the JSON output variant of golangci-lint
+// run for the installed version. v2 replaced --out-format with per-format
+// output flags.
+func golangciLintJSONCommand(ctx context.Context) string {
+	output, err := exec.CommandContext(ctx, "golangci-lint", "version").CombinedOutput()
+	if err == nil {
+		if match := golangciLintVersionPattern.FindSubmatch(output); match != nil && string(match[1]) != "1" {
+			return "golangci-lint run --output.json.path stdout"
+		}
+	}
+	return "golangci-lint run --out-format json"
+}
+
+func (t *lintFormatTool) runLinter(ctx context.Context, command string, files []string) (map[string]interface{}, []LintFinding, error) {
+	linter, structured := structuredLintCommands[command]
+	if !structured {
+		result, _, err := t.runLintCommand(ctx, command, files)
+		return result, nil, err
+	}
+
+	structuredCommand := linter.command
+	if linter.resolve != nil {
+		structuredCommand = linter.resolve(ctx)
+	}
+	result, stdout, err := t.runLintCommand(ctx, structuredCommand, files)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(stdout) == 0 && result["success"] == true {
+		return result, nil, nil
+	}
+
+	parseErr := fmt.Errorf("no output")
+	if len(stdout) > 0 {
+		findings, err := linter.parse(stdout, t.workingDir)
+		if err == nil {
+			return result, findings, nil
+		}
+		parseErr = err
+	}
+
+	// The linter may not support the JSON flags, e.g. a different major
+	// version, so fall back to the plain command and its raw output
+	slog.Debug("Structured lint output unavailable, running plain command", "command", structuredCommand, "error", parseErr)
+	result, _, err = t.runLintCommand(ctx, command, files)
+	if err != nil {
+		return nil, nil, err
+	}
+	result["structured_error"] = fmt.Sprintf("%s: %v", structuredCommand, parseErr)
+	return result, nil, nil
+}
+
+// runLintCommand runs a lint command with files appended and returns its
+// result along with what it wrote to stdout
+func (t *lintFormatTool) runLintCommand(ctx context.Context, command string, files []string) (map[string]interface{}, []byte, error) {
 	parts := strings.Fields(command)
 	if len(parts) == 0 {
-		return nil, fmt.Errorf("empty lint command")
+		return nil, nil, fmt.Errorf("empty lint command")
 	}
 
-	cmd := exec.Command(parts[0], parts[1:]...)
+	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
 	if len(files) > 0 {
 		cmd.Args = append(cmd.Args, files...)
 	}
 	cmd.Dir = t.workingDir
 
-	output, err := cmd.CombinedOutput()
-	
+	output, err := runStreaming(command, cmd, t.onOutput)
+
 	result := map[string]interface{}{
 		"command": command,
-		"output":  string(output),
+		"output":  string(output.combined),
 		"success": err == nil,
 	}
 
@@ -178,26 +338,198 @@ func (t *lintFormatTool) runLinter(command string, files []string) (map[string]i
 		result["error"] = err.Error()
 	}
 
-	return result, nil
+	return result, output.stdout, nil
+}
+
+// commandOutput is the output a command wrote while runStreaming ran it
+type commandOutput struct {
+	stdout []byte
+	// combined holds stdout and stderr interleaved line by line, in the order
+	// the lines were written
+	combined []byte
 }
 
-func (t *lintFormatTool) runFormatter(command string, files []string) (map[string]interface{}, error) {
+// runStreaming runs cmd, passing every line it writes to stdout or stderr to
+// onOutput, if set, as soon as the line is complete, and returns all of its
+// output once it exits. The error is that of cmd.Run, so a non-zero exit
+// status is reported as an *exec.ExitError.
+func runStreaming(command string, cmd *exec.Cmd, onOutput func(command, line string)) (commandOutput, error) {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return commandOutput{}, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return commandOutput{}, err
+	}
+	if err := cmd.Start(); err != nil {
+		return commandOutput{}, err
+	}
+
+	type outputLine struct {
+		text   string
+		stdout bool
+	}
+	lines := make(chan outputLine)
+	var readers sync.WaitGroup
+	read := func(r io.Reader, isStdout bool) {
+		// ReadString has no line length limit, unlike bufio.Scanner, which
+		// matters for linters that print their JSON report on one line
+		reader := bufio.NewReader(r)
+		for {
+			text, err := reader.ReadString('\n')
+			if text != "" {
+				lines <- outputLine{text: text, stdout: isStdout}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+	readers.Go(func() { read(stdout, true) })
+	readers.Go(func() { read(stderr, false) })
+	go func() {
+		readers.Wait()
+		close(lines)
+	}()
+
+	var stdoutBuf, combined bytes.Buffer
+	for line := range lines {
+		combined.WriteString(line.text)
+		if line.stdout {
+			stdoutBuf.WriteString(line.text)
+		}
+		if onOutput != nil {
+			onOutput(command, strings.TrimRight(line.text, "\r\n"))
+		}
+	}
+
+	// The pipes must be drained before Wait, which closes them
+	err = cmd.Wait()
+	return commandOutput{stdout: stdoutBuf.Bytes(), combined: combined.Bytes()}, err
+}
+
+// parseGolangciLintOutput parses `golangci-lint run --out-format json` output
+func parseGolangciLintOutput(output []byte, _ string) ([]LintFinding, error) {
+	var report struct {
+		Issues []struct {
+			FromLinter string `json:"FromLinter"`
+			Text       string `json:"Text"`
+			Severity   string `json:"Severity"`
+			Pos        struct {
+				Filename string `json:"Filename"`
+				Line     int    `json:"Line"`
+				Column   int    `json:"Column"`
+			} `json:"Pos"`
+		} `json:"Issues"`
+	}
+	if err := json.Unmarshal(output, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse golangci-lint output: %w", err)
+	}
+
+	findings := make([]LintFinding, 0, len(report.Issues))
+	for _, issue := range report.Issues {
+		severity := issue.Severity
+		if severity == "" {
+			severity = "error"
+		}
+		findings = append(findings, LintFinding{
+			File:     issue.Pos.Filename,
+			Line:     issue.Pos.Line,
+			Column:   issue.Pos.Column,
+			Severity: severity,
+			Message:  issue.Text,
+			Rule:     issue.FromLinter,
+		})
+	}
+	return findings, nil
+}
+
+// parseESLintOutput parses `eslint -f json` output
+func parseESLintOutput(output []byte, workingDir string) ([]LintFinding, error) {
+	var report []struct {
+		FilePath string `json:"filePath"`
+		Messages []struct {
+			RuleID   string `json:"ruleId"`
+			Severity int    `json:"severity"`
+			Message  string `json:"message"`
+			Line     int    `json:"line"`
+			Column   int    `json:"column"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(output, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse eslint output: %w", err)
+	}
+
+	var findings []LintFinding
+	for _, file := range report {
+		path := file.FilePath
+		if rel, err := filepath.Rel(workingDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+		for _, msg := range file.Messages {
+			severity := "warning"
+			if msg.Severity >= 2 {
+				severity = "error"
+			}
+			findings = append(findings, LintFinding{
+				File:     path,
+				Line:     msg.Line,
+				Column:   msg.Column,
+				Severity: severity,
+				Message:  msg.Message,
+				Rule:     msg.RuleID,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// parsePylintOutput parses `pylint --output-format=json` output
+func parsePylintOutput(output []byte, _ string) ([]LintFinding, error) {
+	var report []struct {
+		Type    string `json:"type"`
+		Path    string `json:"path"`
+		Line    int    `json:"line"`
+		Column  int    `json:"column"`
+		Symbol  string `json:"symbol"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(output, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse pylint output: %w", err)
+	}
+
+	findings := make([]LintFinding, 0, len(report))
+	for _, msg := range report {
+		findings = append(findings, LintFinding{
+			File:     msg.Path,
+			Line:     msg.Line,
+			Column:   msg.Column,
+			Severity: msg.Type,
+			Message:  msg.Message,
+			Rule:     msg.Symbol,
+		})
+	}
+	return findings, nil
+}
+
+func (t *lintFormatTool) runFormatter(ctx context.Context, command string, files []string) (map[string]interface{}, error) {
 	parts := strings.Fields(command)
 	if len(parts) == 0 {
 		return nil, fmt.Errorf("empty format command")
 	}
 
-	cmd := exec.Command(parts[0], parts[1:]...)
+	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
 	if len(files) > 0 {
 		cmd.Args = append(cmd.Args, files...)
 	}
 	cmd.Dir = t.workingDir
 
-	output, err := cmd.CombinedOutput()
-	
+	output, err := runStreaming(command, cmd, t.onOutput)
+
 	result := map[string]interface{}{
 		"command": command,
-		"output":  string(output),
+		"output":  string(output.combined),
 		"success": err == nil,
 	}
 
@@ -208,18 +540,109 @@ func (t *lintFormatTool) runFormatter(command string, files []string) (map[strin
 	return result, nil
 }
 
+// dryRunFormatCommands maps in-place format commands to variants that report
+// the changes they would make without modifying files. prettier --check only
+// names the files that differ, so prettier uses the temporary copy instead.
+var dryRunFormatCommands = map[string]string{
+	"gofmt -w":  "gofmt -d",
+	"black":     "black --diff",
+	"cargo fmt": "cargo fmt -- --check",
+}
+
+// runFormatterDryRun reports the changes a formatter would make. Formatters
+// with a check mode run it directly; others format a temporary copy of the
+// files which is then diffed against the originals.
+func (t *lintFormatTool) runFormatterDryRun(ctx context.Context, command string, files []string) (map[string]interface{}, error) {
+	if checkCommand, ok := dryRunFormatCommands[command]; ok {
+		result, err := t.runFormatter(ctx, checkCommand, files)
+		if result != nil {
+			result["dry_run"] = true
+		}
+		return result, err
+	}
+
+	parts := strings.Fields(command)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("empty format command")
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("dry run for %q requires explicit files", command)
+	}
+
+	tempDir, err := os.MkdirTemp("", "crush-format-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	originals := make(map[string]string, len(files))
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(t.workingDir, file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		originals[file] = string(content)
+
+		tempFile := filepath.Join(tempDir, file)
+		if err := os.MkdirAll(filepath.Dir(tempFile), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create temp directory for %s: %w", file, err)
+		}
+		if err := os.WriteFile(tempFile, content, 0o644); err != nil {
+			return nil, fmt.Errorf("failed to copy %s: %w", file, err)
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
+	cmd.Args = append(cmd.Args, files...)
+	cmd.Dir = tempDir
+
+	output, err := runStreaming(command, cmd, t.onOutput)
+
+	result := map[string]interface{}{
+		"command": command,
+		"output":  string(output.combined),
+		"success": err == nil,
+		"dry_run": true,
+	}
+	if err != nil {
+		result["error"] = err.Error()
+		return result, nil
+	}
+
+	var diffs strings.Builder
+	for _, file := range files {
+		formatted, err := os.ReadFile(filepath.Join(tempDir, file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read formatted %s: %w", file, err)
+		}
+		if string(formatted) == originals[file] {
+			continue
+		}
+		fileDiff, _, _ := diff.GenerateDiff(originals[file], string(formatted), file)
+		diffs.WriteString(fileDiff)
+	}
+	result["diff"] = diffs.String()
+
+	return result, nil
+}
+
 // Enhanced language detection for specific files
 func (t *lintFormatTool) detectLanguageForFiles(files []string) (string, *language.SupportedLanguage, error) {
 	if len(files) == 0 {
 		return language.DetectLanguage(t.workingDir)
 	}
 
+	config, err := language.LoadProjectLanguageConfig(t.workingDir)
+	if err != nil {
+		return "", nil, err
+	}
+
 	// Use the first file's extension to determine language
 	ext := filepath.Ext(files[0])
-	langName, langConfig := language.GetLanguageByExtension(ext)
+	langName, langConfig := config.LanguageByExtension(ext)
 	if langName == "" {
 		return "", nil, fmt.Errorf("could not detect language for file extension: %s", ext)
 	}
 
 	return langName, langConfig, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,359 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/stretchr/testify/require"
+)
+
+func runLintFormat(t *testing.T, workingDir string, params LintFormatParams) ToolResponse {
+	t.Helper()
+
+	input, err := json.Marshal(params)
+	require.NoError(t, err)
+
+	tool := NewLintFormatTool(permission.NewPermissionService(workingDir, true, nil), workingDir)
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, "session")
+	resp, err := tool.Run(ctx, ToolCall{ID: "call-1", Name: LintFormatToolName, Input: string(input)})
+	require.NoError(t, err)
+	return resp
+}
+
+func TestLintFormatRejectsPathsOutsideWorkingDir(t *testing.T) {
+	t.Parallel()
+
+	paths := []string{
+		"../../secret",
+		"/etc/hosts",
+		"src/../../outside.go",
+	}
+
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			t.Parallel()
+
+			resp := runLintFormat(t, t.TempDir(), LintFormatParams{
+				Action:   "format",
+				Files:    []string{"main.go", path},
+				Language: "go",
+			})
+			require.True(t, resp.IsError)
+			require.Contains(t, resp.Content, "Invalid file path")
+		})
+	}
+}
+
+func TestLintFormatAcceptsPathsInsideWorkingDir(t *testing.T) {
+	t.Parallel()
+
+	workingDir := t.TempDir()
+	resp := runLintFormat(t, workingDir, LintFormatParams{
+		Action:   "lint",
+		Files:    []string{"main.go", filepath.Join(workingDir, "pkg", "util.go")},
+		Language: "go",
+	})
+	require.False(t, resp.IsError, resp.Content)
+}
+
+const unformattedGo = "package main\nfunc main() {\nprintln(\"hi\")\n}\n"
+
+func TestLintFormatDryRunLeavesFilesUntouched(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("gofmt"); err != nil {
+		t.Skip("gofmt not available")
+	}
+
+	workingDir := t.TempDir()
+	file := filepath.Join(workingDir, "main.go")
+	require.NoError(t, os.WriteFile(file, []byte(unformattedGo), 0o644))
+
+	resp := runLintFormat(t, workingDir, LintFormatParams{
+		Action:   "format",
+		Files:    []string{"main.go"},
+		Language: "go",
+		DryRun:   true,
+	})
+	require.False(t, resp.IsError, resp.Content)
+	require.Contains(t, resp.Content, `"dry_run":true`)
+	require.Contains(t, resp.Content, "gofmt -d")
+
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.Equal(t, unformattedGo, string(content))
+}
+
+func TestLintFormatDryRunFallsBackToTempCopy(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("gofmt"); err != nil {
+		t.Skip("gofmt not available")
+	}
+
+	workingDir := t.TempDir()
+	file := filepath.Join(workingDir, "cmd", "main.go")
+	require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o755))
+	require.NoError(t, os.WriteFile(file, []byte(unformattedGo), 0o644))
+
+	tool := &lintFormatTool{workingDir: workingDir}
+	result, err := tool.runFormatterDryRun(context.Background(), "gofmt -l -w", []string{filepath.Join("cmd", "main.go")})
+	require.NoError(t, err)
+	require.Equal(t, true, result["success"])
+	require.Contains(t, result["diff"], "+\tprintln(\"hi\")")
+
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.Equal(t, unformattedGo, string(content))
+
+	_, err = tool.runFormatterDryRun(context.Background(), "gofmt -l -w", nil)
+	require.Error(t, err)
+}
+
+func TestLintFormatStreamsOutputLines(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	var mu sync.Mutex
+	var streamed []string
+	tool := &lintFormatTool{
+		workingDir: t.TempDir(),
+		onOutput: func(command, line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			streamed = append(streamed, command+": "+line)
+		},
+	}
+
+	// Sleeps order the lines across the two streams
+	script := "echo first; sleep 0.05; echo second >&2; sleep 0.05; echo third; sleep 0.05; printf 'last without newline' >&2; exit 3"
+	cmd := exec.Command("sh", "-c", script)
+	cmd.Dir = tool.workingDir
+	output, err := runStreaming("lint", cmd, tool.onOutput)
+
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, 3, exitErr.ExitCode())
+	require.Equal(t, "first\nsecond\nthird\nlast without newline", string(output.combined))
+	require.Equal(t, "first\nthird\n", string(output.stdout))
+	require.Equal(t, []string{"lint: first", "lint: second", "lint: third", "lint: last without newline"}, streamed)
+}
+
+func TestLintFormatReportsFailingLinter(t *testing.T) {
+	t.Parallel()
+
+	workingDir := t.TempDir()
+	tool := &lintFormatTool{workingDir: workingDir}
+	result, findings, err := tool.runLinter(context.Background(), "false", nil)
+	require.NoError(t, err)
+	require.Empty(t, findings)
+	require.Equal(t, false, result["success"])
+	require.Contains(t, result["error"], "exit status 1")
+
+	result, _, err = tool.runLinter(context.Background(), "crush-missing-linter", nil)
+	require.NoError(t, err)
+	require.Equal(t, false, result["success"])
+	require.Contains(t, result["error"], "not found")
+}
+
+func TestLintFormatUsesProjectLanguageOverrides(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("gofmt"); err != nil {
+		t.Skip("gofmt not available")
+	}
+
+	workingDir := t.TempDir()
+	file := filepath.Join(workingDir, "main.go")
+	require.NoError(t, os.WriteFile(file, []byte(unformattedGo), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(workingDir, ".crush"), 0o755))
+	overrides := `{"languages": {"go": {"format_command": "gofmt -l -w"}}}`
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, ".crush", "language.json"), []byte(overrides), 0o644))
+
+	resp := runLintFormat(t, workingDir, LintFormatParams{
+		Action:   "format",
+		Files:    []string{"main.go"},
+		Language: "go",
+	})
+	require.False(t, resp.IsError, resp.Content)
+
+	var result LintFormatResult
+	require.NoError(t, json.Unmarshal([]byte(resp.Content), &result))
+	format := result.Results["format"].(map[string]any)
+	require.Equal(t, "gofmt -l -w", format["command"])
+	require.Contains(t, format["output"], "main.go", "-l lists the files it rewrote")
+
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "\tprintln(\"hi\")")
+}
+
+func TestLintFormatAsksPermissionForProjectCommands(t *testing.T) {
+	t.Parallel()
+
+	workingDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, "main.go"), []byte(unformattedGo), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(workingDir, ".crush"), 0o755))
+	overrides := `{"languages": {"go": {"lint_command": "sh -c 'touch pwned'", "format_command": "sh -c 'touch pwned'"}}}`
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, ".crush", "language.json"), []byte(overrides), 0o644))
+
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, "session")
+	for _, params := range []LintFormatParams{
+		{Action: "lint", Language: "go"},
+		{Action: "format", Language: "go", DryRun: true},
+	} {
+		perms := &recordingPermissions{}
+		input, err := json.Marshal(params)
+		require.NoError(t, err)
+
+		resp, err := NewLintFormatTool(perms, workingDir).Run(ctx, ToolCall{Name: LintFormatToolName, Input: string(input)})
+		require.NoError(t, err)
+		require.True(t, resp.IsError, "%+v", params)
+		require.Contains(t, resp.Content, "Permission denied")
+		require.Len(t, perms.requests, 1)
+		require.Contains(t, perms.requests[0].Description, "sh -c 'touch pwned'")
+
+		_, err = os.Stat(filepath.Join(workingDir, "pwned"))
+		require.True(t, os.IsNotExist(err), "the override must not run without permission")
+	}
+
+	// Without a session the command can't be approved, so it must not run
+	input, err := json.Marshal(LintFormatParams{Action: "lint", Language: "go"})
+	require.NoError(t, err)
+	_, err = NewLintFormatTool(&recordingPermissions{grant: true}, workingDir).Run(context.Background(), ToolCall{Name: LintFormatToolName, Input: string(input)})
+	require.ErrorContains(t, err, "session ID is required")
+}
+
+func TestLintFormatDefaultLintNeedsNoPermission(t *testing.T) {
+	t.Parallel()
+
+	perms := &recordingPermissions{}
+	input, err := json.Marshal(LintFormatParams{Action: "lint", Language: "go"})
+	require.NoError(t, err)
+
+	_, err = NewLintFormatTool(perms, t.TempDir()).Run(context.Background(), ToolCall{Name: LintFormatToolName, Input: string(input)})
+	require.NoError(t, err)
+	require.Empty(t, perms.requests)
+}
+
+func TestLintFormatRejectsInvalidLanguageOverrides(t *testing.T) {
+	t.Parallel()
+
+	workingDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(workingDir, ".crush"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, ".crush", "language.json"), []byte("{"), 0o644))
+
+	resp := runLintFormat(t, workingDir, LintFormatParams{Action: "lint", Language: "go"})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "Invalid language configuration")
+}
+
+// stubGolangciLint puts a fake golangci-lint first on PATH that reports
+// version. Like v2, it rejects --out-format; given --output.json.path it
+// prints one JSON issue, and otherwise it prints a plain text issue.
+func stubGolangciLint(t *testing.T, version string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("stub golangci-lint binary requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	script := `#!/bin/sh
+if [ "$1" = "version" ]; then
+  echo "golangci-lint has version ` + version + ` built with go1.24.0"
+  exit 0
+fi
+for arg in "$@"; do
+  case "$arg" in
+    --out-format) echo "Error: unknown flag: --out-format" >&2; exit 3 ;;
+    --output.json.path) echo '{"Issues":[{"FromLinter":"errcheck","Text":"unchecked","Pos":{"Filename":"main.go","Line":4,"Column":2}}]}'; exit 1 ;;
+  esac
+done
+echo "main.go:4:2: unchecked (errcheck)"
+exit 1
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "golangci-lint"), []byte(script), 0o755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestLintFormatUsesGolangciLintV2JSONFlags(t *testing.T) {
+	stubGolangciLint(t, "2.1.6")
+
+	tool := &lintFormatTool{workingDir: t.TempDir()}
+	result, findings, err := tool.runLinter(context.Background(), "golangci-lint run", nil)
+	require.NoError(t, err)
+	require.Equal(t, "golangci-lint run --output.json.path stdout", result["command"])
+	require.Equal(t, []LintFinding{
+		{File: "main.go", Line: 4, Column: 2, Severity: "error", Message: "unchecked", Rule: "errcheck"},
+	}, findings)
+}
+
+func TestLintFormatFallsBackToPlainLintCommand(t *testing.T) {
+	// A v1 version string makes the tool pass --out-format, which the stub
+	// rejects like a linter that does not support the JSON flags
+	stubGolangciLint(t, "1.64.8")
+
+	tool := &lintFormatTool{workingDir: t.TempDir()}
+	result, findings, err := tool.runLinter(context.Background(), "golangci-lint run", nil)
+	require.NoError(t, err)
+	require.Empty(t, findings)
+	require.Equal(t, "golangci-lint run", result["command"])
+	require.Contains(t, result["output"], "main.go:4:2: unchecked (errcheck)")
+	require.Contains(t, result["structured_error"], "golangci-lint run --out-format json")
+}
+
+func TestParseGolangciLintOutput(t *testing.T) {
+	t.Parallel()
+
+	output := `{"Issues":[{"FromLinter":"errcheck","Text":"Error return value is not checked","Severity":"","SourceLines":["\tf.Close()"],"Pos":{"Filename":"internal/app/app.go","Offset":120,"Line":42,"Column":9}},{"FromLinter":"gofumpt","Text":"File is not properly formatted","Severity":"warning","Pos":{"Filename":"main.go","Offset":0,"Line":3,"Column":1}}],"Report":{"Linters":[]}}`
+
+	findings, err := parseGolangciLintOutput([]byte(output), "/project")
+	require.NoError(t, err)
+	require.Equal(t, []LintFinding{
+		{File: "internal/app/app.go", Line: 42, Column: 9, Severity: "error", Message: "Error return value is not checked", Rule: "errcheck"},
+		{File: "main.go", Line: 3, Column: 1, Severity: "warning", Message: "File is not properly formatted", Rule: "gofumpt"},
+	}, findings)
+
+	findings, err = parseGolangciLintOutput([]byte(`{"Issues":null}`), "/project")
+	require.NoError(t, err)
+	require.Empty(t, findings)
+
+	_, err = parseGolangciLintOutput([]byte("level=error msg=\"timeout\""), "/project")
+	require.Error(t, err)
+}
+
+func TestParseESLintOutput(t *testing.T) {
+	t.Parallel()
+
+	output := `[{"filePath":"/project/src/index.js","messages":[{"ruleId":"no-unused-vars","severity":2,"message":"'x' is assigned a value but never used.","line":1,"column":7,"nodeType":"Identifier"},{"ruleId":"semi","severity":1,"message":"Missing semicolon.","line":2,"column":12}],"errorCount":1,"warningCount":1},{"filePath":"/project/src/clean.js","messages":[],"errorCount":0,"warningCount":0}]`
+
+	findings, err := parseESLintOutput([]byte(output), "/project")
+	require.NoError(t, err)
+	require.Equal(t, []LintFinding{
+		{File: filepath.Join("src", "index.js"), Line: 1, Column: 7, Severity: "error", Message: "'x' is assigned a value but never used.", Rule: "no-unused-vars"},
+		{File: filepath.Join("src", "index.js"), Line: 2, Column: 12, Severity: "warning", Message: "Missing semicolon.", Rule: "semi"},
+	}, findings)
+}
+
+func TestParsePylintOutput(t *testing.T) {
+	t.Parallel()
+
+	output := `[{"type":"convention","module":"app","obj":"","line":1,"column":0,"endLine":null,"endColumn":null,"path":"app.py","symbol":"missing-module-docstring","message":"Missing module docstring","message-id":"C0114"},{"type":"error","module":"app","obj":"main","line":5,"column":4,"path":"app.py","symbol":"undefined-variable","message":"Undefined variable 'foo'","message-id":"E0602"}]`
+
+	findings, err := parsePylintOutput([]byte(output), "/project")
+	require.NoError(t, err)
+	require.Equal(t, []LintFinding{
+		{File: "app.py", Line: 1, Column: 0, Severity: "convention", Message: "Missing module docstring", Rule: "missing-module-docstring"},
+		{File: "app.py", Line: 5, Column: 4, Severity: "error", Message: "Undefined variable 'foo'", Rule: "undefined-variable"},
+	}, findings)
+}
@@ -3,7 +3,9 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/crush/internal/notifications"
@@ -11,7 +13,7 @@ import (
 )
 
 type NotificationParams struct {
-	Service  string            `json:"service"`  // "discord", "telegram", "both"
+	Service  string            `json:"service"` // "discord", "telegram", "email", "both", "all"
 	Title    string            `json:"title"`
 	Message  string            `json:"message"`
 	Level    string            `json:"level,omitempty"` // "info", "warning", "error", "success"
@@ -19,44 +21,38 @@ type NotificationParams struct {
 }
 
 type notificationTool struct {
-	permissions     permission.Service
-	discordService  *notifications.DiscordService
-	telegramService *notifications.TelegramService
+	permissions permission.Service
+	registry    *notifications.Registry
 }
 
 const NotificationToolName = "notify"
 
+// NewNotificationTool creates a notification tool for the built-in services
+// in config
 func NewNotificationTool(permissions permission.Service, config *notifications.NotificationConfig) BaseTool {
-	var discordService *notifications.DiscordService
-	var telegramService *notifications.TelegramService
-
-	if config != nil {
-		if config.Discord.Enabled {
-			discordService = notifications.NewDiscordService(config.Discord)
-		}
-		if config.Telegram.Enabled {
-			telegramService = notifications.NewTelegramService(config.Telegram)
-		}
-	}
+	return NewNotificationToolWithRegistry(permissions, notifications.NewRegistryFromConfig(config))
+}
 
+// NewNotificationToolWithRegistry creates a notification tool that sends
+// through the services in registry, including any custom ones
+func NewNotificationToolWithRegistry(permissions permission.Service, registry *notifications.Registry) BaseTool {
 	return &notificationTool{
-		permissions:     permissions,
-		discordService:  discordService,
-		telegramService: telegramService,
+		permissions: permissions,
+		registry:    registry,
 	}
 }
 
 func (t *notificationTool) Info() ToolInfo {
 	return ToolInfo{
 		Name:        NotificationToolName,
-		Description: "Send notifications via Discord webhooks or Telegram bot. Useful for alerting about task completion, errors, or important events.",
+		Description: fmt.Sprintf("Send notifications via %s. Useful for alerting about task completion, errors, or important events.", strings.Join(t.serviceLabels(), ", ")),
 		Parameters: map[string]any{
 			"type": "object",
 			"properties": map[string]any{
 				"service": map[string]any{
 					"type":        "string",
-					"enum":        []string{"discord", "telegram", "both"},
-					"description": "Notification service to use",
+					"enum":        t.registry.Selections(),
+					"description": "Notification service to use ('both' sends to Discord and Telegram, 'all' sends to every configured service)",
 				},
 				"title": map[string]any{
 					"type":        "string",
@@ -81,6 +77,15 @@ func (t *notificationTool) Info() ToolInfo {
 	}
 }
 
+// serviceLabels returns the labels of the registered services
+func (t *notificationTool) serviceLabels() []string {
+	var labels []string
+	for _, svc := range t.registry.Services() {
+		labels = append(labels, svc.Label)
+	}
+	return labels
+}
+
 func (t *notificationTool) Name() string {
 	return NotificationToolName
 }
@@ -125,57 +130,79 @@ func (t *notificationTool) Run(ctx context.Context, params ToolCall) (ToolRespon
 		Metadata:  notifyParams.Metadata,
 	}
 
-	// Check which services are available and requested
+	requested, err := t.registry.Select(notifyParams.Service)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
 	var results []map[string]interface{}
-	var errors []string
-
-	if notifyParams.Service == "discord" || notifyParams.Service == "both" {
-		if t.discordService != nil && t.discordService.IsEnabled() {
-			if err := t.discordService.SendNotification(ctx, notification); err != nil {
-				errors = append(errors, fmt.Sprintf("Discord: %v", err))
-			} else {
-				results = append(results, map[string]interface{}{
-					"service": "discord",
-					"success": true,
-					"message": "Notification sent successfully",
-				})
-			}
-		} else {
-			errors = append(errors, "Discord service is not enabled or configured")
+	var succeeded, suppressed, failed, failures []string
+
+	for _, svc := range requested {
+		if svc.Service == nil || !svc.Service.IsEnabled() {
+			failed = append(failed, svc.Name)
+			failures = append(failures, fmt.Sprintf("%s service is not enabled or configured", svc.Label))
+			results = append(results, map[string]interface{}{
+				"service": svc.Name,
+				"success": false,
+				"error":   "service is not enabled or configured",
+			})
+			continue
 		}
-	}
 
-	if notifyParams.Service == "telegram" || notifyParams.Service == "both" {
-		if t.telegramService != nil && t.telegramService.IsEnabled() {
-			if err := t.telegramService.SendNotification(ctx, notification); err != nil {
-				errors = append(errors, fmt.Sprintf("Telegram: %v", err))
-			} else {
-				results = append(results, map[string]interface{}{
-					"service": "telegram",
-					"success": true,
-					"message": "Notification sent successfully",
-				})
-			}
-		} else {
-			errors = append(errors, "Telegram service is not enabled or configured")
+		err := svc.Service.SendNotification(ctx, notification)
+		var suppressedErr *notifications.SuppressedError
+		if errors.As(err, &suppressedErr) {
+			suppressed = append(suppressed, svc.Name)
+			results = append(results, map[string]interface{}{
+				"service":    svc.Name,
+				"success":    true,
+				"suppressed": true,
+				"message":    suppressedErr.Error(),
+			})
+			continue
 		}
+		if err != nil {
+			failed = append(failed, svc.Name)
+			failures = append(failures, fmt.Sprintf("%s: %v", svc.Label, err))
+			results = append(results, map[string]interface{}{
+				"service": svc.Name,
+				"success": false,
+				"error":   err.Error(),
+			})
+			continue
+		}
+
+		succeeded = append(succeeded, svc.Name)
+		results = append(results, map[string]interface{}{
+			"service": svc.Name,
+			"success": true,
+			"message": "Notification sent successfully",
+		})
+	}
+
+	if len(succeeded) == 0 && len(suppressed) == 0 {
+		return NewTextErrorResponse(fmt.Sprintf("No notifications were sent: %s", strings.Join(failures, "; "))), nil
 	}
 
 	// Prepare response
 	response := map[string]interface{}{
-		"success":      len(errors) == 0,
+		"success":      len(failed) == 0,
+		"partial":      len(failed) > 0,
+		"succeeded":    succeeded,
 		"results":      results,
 		"notification": notification,
 	}
 
-	if len(errors) > 0 {
-		response["errors"] = errors
+	if len(suppressed) > 0 {
+		// Rate limited, which is not an error the agent should retry
+		response["suppressed"] = suppressed
 	}
-
-	if len(results) == 0 {
-		return NewTextErrorResponse("No notifications were sent. Check service configuration."), nil
+	if len(failed) > 0 {
+		response["failed"] = failed
+		response["errors"] = failures
 	}
 
 	output, _ := json.Marshal(response)
 	return NewTextResponse(string(output)), nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,212 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/notifications"
+	"github.com/stretchr/testify/require"
+)
+
+// discordStub starts a webhook server that answers with status
+func discordStub(t *testing.T, status int) notifications.DiscordConfig {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return notifications.DiscordConfig{WebhookURL: server.URL, Enabled: true}
+}
+
+// unreachableEmail returns an email configuration pointing at a closed port
+func unreachableEmail(t *testing.T) notifications.EmailConfig {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	require.NoError(t, ln.Close())
+	return notifications.EmailConfig{
+		Host:    "127.0.0.1",
+		Port:    port,
+		From:    "crush@example.com",
+		To:      []string{"dev@example.com"},
+		Enabled: true,
+	}
+}
+
+func runNotify(t *testing.T, config *notifications.NotificationConfig, service string) (ToolResponse, map[string]any) {
+	t.Helper()
+
+	input, err := json.Marshal(NotificationParams{Service: service, Title: "Build", Message: "Build finished"})
+	require.NoError(t, err)
+
+	tool := NewNotificationTool(nil, config)
+	resp, err := tool.Run(context.Background(), ToolCall{ID: "call", Name: NotificationToolName, Input: string(input)})
+	require.NoError(t, err)
+
+	var body map[string]any
+	if !resp.IsError {
+		require.NoError(t, json.Unmarshal([]byte(resp.Content), &body))
+	}
+	return resp, body
+}
+
+func TestNotifyPartialSuccess(t *testing.T) {
+	t.Parallel()
+
+	config := &notifications.NotificationConfig{
+		Discord: discordStub(t, http.StatusNoContent),
+		Email:   unreachableEmail(t),
+	}
+
+	resp, body := runNotify(t, config, "all")
+	require.False(t, resp.IsError, resp.Content)
+	require.Equal(t, false, body["success"])
+	require.Equal(t, true, body["partial"])
+	require.Equal(t, []any{"discord"}, body["succeeded"])
+	require.Equal(t, []any{"email"}, body["failed"])
+
+	results := body["results"].([]any)
+	require.Len(t, results, 2)
+	for _, r := range results {
+		result := r.(map[string]any)
+		switch result["service"] {
+		case "discord":
+			require.Equal(t, true, result["success"])
+		case "email":
+			require.Equal(t, false, result["success"])
+			require.Contains(t, result["error"], "failed to connect to SMTP server")
+		default:
+			t.Fatalf("unexpected service %v", result["service"])
+		}
+	}
+}
+
+func TestNotifyAllOnlyUsesConfiguredServices(t *testing.T) {
+	t.Parallel()
+
+	config := &notifications.NotificationConfig{Discord: discordStub(t, http.StatusNoContent)}
+
+	resp, body := runNotify(t, config, "all")
+	require.False(t, resp.IsError, resp.Content)
+	require.Equal(t, true, body["success"])
+	require.Equal(t, false, body["partial"])
+	require.Equal(t, []any{"discord"}, body["succeeded"])
+	require.NotContains(t, body, "failed")
+}
+
+func TestNotifyBothReportsUnconfiguredService(t *testing.T) {
+	t.Parallel()
+
+	config := &notifications.NotificationConfig{Discord: discordStub(t, http.StatusNoContent)}
+
+	resp, body := runNotify(t, config, "both")
+	require.False(t, resp.IsError, resp.Content)
+	require.Equal(t, true, body["partial"])
+	require.Equal(t, []any{"telegram"}, body["failed"])
+}
+
+func TestNotifyTotalFailure(t *testing.T) {
+	t.Parallel()
+
+	config := &notifications.NotificationConfig{
+		Discord: discordStub(t, http.StatusBadRequest),
+		Email:   unreachableEmail(t),
+	}
+
+	resp, _ := runNotify(t, config, "all")
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "No notifications were sent")
+	require.Contains(t, resp.Content, "Discord: ")
+	require.Contains(t, resp.Content, "Email: ")
+}
+
+func TestNotifyInvalidSelection(t *testing.T) {
+	t.Parallel()
+
+	for service, want := range map[string]string{
+		"all":   "no notification services are configured",
+		"slack": "invalid service",
+		"":      "invalid service",
+	} {
+		resp, _ := runNotify(t, &notifications.NotificationConfig{}, service)
+		require.True(t, resp.IsError, "service %q", service)
+		require.Contains(t, resp.Content, want, "service %q", service)
+	}
+}
+
+// fakeService records the notifications sent through it
+type fakeService struct {
+	sent []*notifications.Notification
+}
+
+func (f *fakeService) SendNotification(ctx context.Context, notification *notifications.Notification) error {
+	f.sent = append(f.sent, notification)
+	return nil
+}
+
+func (f *fakeService) IsEnabled() bool { return true }
+
+func TestNotifyCustomService(t *testing.T) {
+	t.Parallel()
+
+	registry := notifications.NewRegistryFromConfig(&notifications.NotificationConfig{})
+	slack := &fakeService{}
+	registry.Register("slack", "Slack", slack)
+	tool := NewNotificationToolWithRegistry(nil, registry)
+
+	require.Contains(t, tool.Info().Parameters["properties"].(map[string]any)["service"].(map[string]any)["enum"], "slack")
+
+	for _, service := range []string{"slack", "all"} {
+		input, err := json.Marshal(NotificationParams{Service: service, Title: "Build", Message: "Build finished"})
+		require.NoError(t, err)
+		resp, err := tool.Run(context.Background(), ToolCall{ID: "call", Name: NotificationToolName, Input: string(input)})
+		require.NoError(t, err)
+		require.False(t, resp.IsError, resp.Content)
+
+		var body map[string]any
+		require.NoError(t, json.Unmarshal([]byte(resp.Content), &body))
+		require.Equal(t, []any{"slack"}, body["succeeded"])
+	}
+	require.Len(t, slack.sent, 2)
+	require.Equal(t, "Build finished", slack.sent[0].Message)
+}
+
+func TestNotifyReportsSuppressedDuplicates(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	config := &notifications.NotificationConfig{
+		Discord: notifications.DiscordConfig{WebhookURL: server.URL, Enabled: true},
+	}
+
+	tool := NewNotificationTool(nil, config)
+	input, err := json.Marshal(NotificationParams{Service: "discord", Title: "Build", Message: "Build failed", Level: "error"})
+	require.NoError(t, err)
+	for i := range 5 {
+		resp, err := tool.Run(context.Background(), ToolCall{ID: "call", Name: NotificationToolName, Input: string(input)})
+		require.NoError(t, err)
+		require.False(t, resp.IsError, resp.Content)
+
+		var body map[string]any
+		require.NoError(t, json.Unmarshal([]byte(resp.Content), &body))
+		if i == 0 {
+			require.Equal(t, []any{"discord"}, body["succeeded"])
+			require.NotContains(t, body, "suppressed")
+		} else {
+			require.Nil(t, body["succeeded"])
+			require.Equal(t, []any{"discord"}, body["suppressed"])
+		}
+	}
+	require.Equal(t, int32(1), calls.Load())
+}
@@ -3,17 +3,29 @@ package tools
 import (
 	"fmt"
 	"log/slog"
+	"os"
 	"path/filepath"
 	"strings"
 )
 
+// maxSymlinkHops bounds how many dangling symlinks are followed while resolving a path
+const maxSymlinkHops = 40
+
 // ValidatePathSecurity validates and sanitizes file paths to prevent directory traversal attacks
 func ValidatePathSecurity(requestedPath, workingDir string) (string, error) {
+	return ValidatePathSecurityWithRoots(requestedPath, workingDir, nil)
+}
+
+// ValidatePathSecurityWithRoots is like ValidatePathSecurity but also accepts
+// paths that resolve within one of the explicitly allowed extra roots, such as
+// a shared cache or a sibling module. Relative paths are still resolved against
+// workingDir, so extra roots are normally reached through absolute paths.
+func ValidatePathSecurityWithRoots(requestedPath, workingDir string, extraRoots []string) (string, error) {
 	// Sanitize the path
 	sanitizedPath := filepath.Clean(requestedPath)
-	
+
 	// Check for obvious path traversal attempts
-	if strings.Contains(sanitizedPath, "..") {
+	if hasParentSegment(sanitizedPath) {
 		slog.Warn("🚨 SECURITY: Path traversal attempt blocked",
 			"requested_path", requestedPath,
 			"sanitized_path", sanitizedPath,
@@ -27,6 +39,18 @@ func ValidatePathSecurity(requestedPath, workingDir string) (string, error) {
 		return "", fmt.Errorf("failed to resolve working directory: %w", err)
 	}
 
+	roots := []string{workingDirAbs}
+	for _, root := range extraRoots {
+		if root == "" {
+			continue
+		}
+		rootAbs, err := filepath.Abs(root)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve allowed root %s: %w", root, err)
+		}
+		roots = append(roots, rootAbs)
+	}
+
 	var finalPath string
 	if filepath.IsAbs(sanitizedPath) {
 		// For absolute paths, ensure they are within the working directory or a safe location
@@ -42,32 +66,126 @@ func ValidatePathSecurity(requestedPath, workingDir string) (string, error) {
 		return "", fmt.Errorf("failed to resolve final path: %w", err)
 	}
 
-	// Ensure the final path is within the working directory
-	rel, err := filepath.Rel(workingDirAbs, finalPathAbs)
-	if err != nil {
-		return "", fmt.Errorf("failed to compute relative path: %w", err)
-	}
-
-	// Check if the path escapes the working directory
-	if strings.HasPrefix(rel, "..") || strings.HasPrefix(rel, "/") {
+	// Ensure the final path is within the working directory or an allowed root
+	root, rel, ok := withinRoots(finalPathAbs, roots)
+	if !ok {
 		slog.Warn("🚨 SECURITY: Path outside working directory blocked",
 			"requested_path", requestedPath,
 			"working_dir", workingDirAbs,
+			"allowed_roots", extraRoots,
 			"resolved_path", finalPathAbs,
-			"relative_path", rel,
 		)
 		return "", fmt.Errorf("path resolves outside working directory: %s", requestedPath)
 	}
 
+	// Resolve symlinks so a link inside an allowed root cannot point outside of all of them
+	if err := validateSymlinkTarget(requestedPath, roots, finalPathAbs); err != nil {
+		return "", err
+	}
+
 	slog.Debug("Path validation successful",
 		"requested_path", requestedPath,
 		"final_path", finalPathAbs,
+		"root", root,
 		"relative_path", rel,
 	)
 
 	return finalPathAbs, nil
 }
 
+// withinRoots returns the first root containing path along with the path
+// relative to that root
+func withinRoots(path string, roots []string) (string, string, bool) {
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			continue
+		}
+		if !hasParentSegment(rel) && !filepath.IsAbs(rel) {
+			return root, rel, true
+		}
+	}
+	return "", "", false
+}
+
+// validateSymlinkTarget verifies that finalPathAbs still lies within one of
+// the roots once symlinks in all paths are resolved
+func validateSymlinkTarget(requestedPath string, roots []string, finalPathAbs string) error {
+	realRoots := make([]string, 0, len(roots))
+	for _, root := range roots {
+		realRoot, err := resolveSymlinks(root)
+		if err != nil {
+			return fmt.Errorf("failed to resolve allowed root %s: %w", root, err)
+		}
+		realRoots = append(realRoots, realRoot)
+	}
+	finalPathReal, err := resolveSymlinks(finalPathAbs)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	if _, _, ok := withinRoots(finalPathReal, realRoots); !ok {
+		slog.Warn("🚨 SECURITY: Symlink escape outside working directory blocked",
+			"requested_path", requestedPath,
+			"allowed_roots", realRoots,
+			"resolved_path", finalPathReal,
+		)
+		return fmt.Errorf("path resolves outside working directory via symlink: %s", requestedPath)
+	}
+	return nil
+}
+
+// resolveSymlinks evaluates symlinks in path. Files that do not exist yet are
+// resolved through their nearest existing parent, and dangling symlinks are
+// followed to the location they would create.
+func resolveSymlinks(path string) (string, error) {
+	var missing []string
+	current := path
+	for hops := 0; ; {
+		resolved, err := filepath.EvalSymlinks(current)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		if info, lerr := os.Lstat(current); lerr == nil && info.Mode()&os.ModeSymlink != 0 {
+			hops++
+			if hops > maxSymlinkHops {
+				return "", fmt.Errorf("too many levels of symbolic links: %s", path)
+			}
+			target, err := os.Readlink(current)
+			if err != nil {
+				return "", err
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(current), target)
+			}
+			current = target
+			continue
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return filepath.Join(append([]string{current}, missing...)...), nil
+		}
+		missing = append([]string{filepath.Base(current)}, missing...)
+		current = parent
+	}
+}
+
+// hasParentSegment reports whether path contains a ".." segment. Names that
+// merely contain two dots, such as "my..notes.txt" or "..config", are allowed.
+func hasParentSegment(path string) bool {
+	for segment := range strings.SplitSeq(filepath.ToSlash(path), "/") {
+		if segment == ".." {
+			return true
+		}
+	}
+	return false
+}
+
 // ValidatePathSecurityRelative is like ValidatePathSecurity but returns a path relative to workingDir
 func ValidatePathSecurityRelative(requestedPath, workingDir string) (string, error) {
 	finalPath, err := ValidatePathSecurity(requestedPath, workingDir)
@@ -86,4 +204,4 @@ func ValidatePathSecurityRelative(requestedPath, workingDir string) (string, err
 	}
 
 	return rel, nil
-}
\ No newline at end of file
+}
@@ -310,7 +310,7 @@ func readTextFile(filePath string, offset, limit int) (string, int, error) {
 	lines := make([]string, 0, limit)
 	lineCount = offset
 
-	for scanner.Scan() && len(lines) < limit {
+	for len(lines) < limit && scanner.Scan() {
 		lineCount++
 		lineText := scanner.Text()
 		if len(lineText) > MaxLineLength {
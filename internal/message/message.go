@@ -280,3 +280,14 @@ func unmarshallParts(data []byte) ([]ContentPart, error) {
 
 	return parts, nil
 }
+
+// MarshalParts encodes content parts with their type information so they can
+// be decoded again with [UnmarshalParts].
+func MarshalParts(parts []ContentPart) ([]byte, error) {
+	return marshallParts(parts)
+}
+
+// UnmarshalParts decodes content parts encoded with [MarshalParts].
+func UnmarshalParts(data []byte) ([]ContentPart, error) {
+	return unmarshallParts(data)
+}
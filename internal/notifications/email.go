@@ -0,0 +1,210 @@
+package notifications
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"html"
+	"log/slog"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmailConfig holds SMTP email configuration
+type EmailConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	// LevelTo routes notifications of a level to their own recipients; levels
+	// without any go to To
+	LevelTo map[NotificationLevel][]string `json:"level_to,omitempty"`
+	// UseTLS connects with implicit TLS (SMTPS, usually port 465). When
+	// false, STARTTLS is used if the server advertises it.
+	UseTLS  bool `json:"use_tls,omitempty"`
+	Enabled bool `json:"enabled"`
+}
+
+// recipientsFor returns the recipients of notifications of level
+func (c EmailConfig) recipientsFor(level NotificationLevel) []string {
+	if to := c.LevelTo[level]; len(to) > 0 {
+		return to
+	}
+	return c.To
+}
+
+// EmailService implements SMTP email notifications
+type EmailService struct {
+	config    EmailConfig
+	timeout   time.Duration
+	tlsConfig *tls.Config
+}
+
+// NewEmailService creates a new email notification service
+func NewEmailService(config EmailConfig) *EmailService {
+	return &EmailService{
+		config:  config,
+		timeout: 10 * time.Second,
+	}
+}
+
+// IsEnabled returns whether email notifications are enabled
+func (e *EmailService) IsEnabled() bool {
+	return e.config.Enabled && e.config.Host != "" && e.config.From != "" && len(e.config.To) > 0
+}
+
+// SendNotification sends a notification as an HTML email over SMTP
+func (e *EmailService) SendNotification(ctx context.Context, notification *Notification) error {
+	if !e.IsEnabled() {
+		return fmt.Errorf("email notifications are not enabled")
+	}
+
+	port := e.config.Port
+	if port == 0 {
+		port = 587
+		if e.config.UseTLS {
+			port = 465
+		}
+	}
+	addr := net.JoinHostPort(e.config.Host, strconv.Itoa(port))
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	conn, err := e.dial(ctx, addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, e.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to create SMTP client: %w", err)
+	}
+	defer client.Close()
+
+	if !e.config.UseTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(e.tlsClientConfig()); err != nil {
+				return fmt.Errorf("failed to start TLS: %w", err)
+			}
+		}
+	}
+
+	if e.config.Username != "" {
+		auth := smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(e.config.From); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM failed: %w", err)
+	}
+	recipients := e.config.recipientsFor(notification.Level)
+	for _, to := range recipients {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP RCPT TO %s failed: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if _, err := w.Write(e.buildMessage(notification, recipients)); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to write email body: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	if err := client.Quit(); err != nil {
+		slog.Debug("SMTP QUIT failed", "error", err)
+	}
+
+	slog.Debug("Email notification sent successfully",
+		"title", notification.Title,
+		"level", notification.Level)
+	return nil
+}
+
+// dial opens a plaintext or implicit TLS connection to the SMTP server
+func (e *EmailService) dial(ctx context.Context, addr string) (net.Conn, error) {
+	if e.config.UseTLS {
+		dialer := &tls.Dialer{Config: e.tlsClientConfig()}
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+func (e *EmailService) tlsClientConfig() *tls.Config {
+	if e.tlsConfig != nil {
+		return e.tlsConfig
+	}
+	return &tls.Config{ServerName: e.config.Host}
+}
+
+// buildMessage renders the notification as a MIME HTML message
+func (e *EmailService) buildMessage(notification *Notification, recipients []string) []byte {
+	var msg strings.Builder
+	subject := fmt.Sprintf("%s %s", levelEmoji(notification.Level), notification.Title)
+
+	fmt.Fprintf(&msg, "From: %s\r\n", e.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mimeEncodeHeader(subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", notification.Timestamp.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(e.renderHTML(notification))
+	return []byte(msg.String())
+}
+
+// renderHTML renders a simple HTML body colored by notification level
+func (e *EmailService) renderHTML(notification *Notification) string {
+	var body strings.Builder
+	color := fmt.Sprintf("#%06x", levelColor(notification.Level))
+
+	body.WriteString("<html><body style=\"font-family: sans-serif;\">\r\n")
+	fmt.Fprintf(&body, "<div style=\"border-left: 4px solid %s; padding: 8px 16px;\">\r\n", color)
+	fmt.Fprintf(&body, "<h2 style=\"color: %s;\">%s %s</h2>\r\n", color,
+		levelEmoji(notification.Level), html.EscapeString(notification.Title))
+	fmt.Fprintf(&body, "<p>%s</p>\r\n",
+		strings.ReplaceAll(html.EscapeString(notification.Message), "\n", "<br>"))
+
+	if len(notification.Metadata) > 0 {
+		body.WriteString("<table>\r\n")
+		for key, value := range notification.Metadata {
+			fmt.Fprintf(&body, "<tr><td><b>%s</b></td><td>%s</td></tr>\r\n",
+				html.EscapeString(key), html.EscapeString(value))
+		}
+		body.WriteString("</table>\r\n")
+	}
+
+	fmt.Fprintf(&body, "<p style=\"color: #888888; font-size: small;\">%s</p>\r\n",
+		notification.Timestamp.Format(time.RFC3339))
+	body.WriteString("</div>\r\n</body></html>\r\n")
+	return body.String()
+}
+
+// mimeEncodeHeader encodes non-ASCII header values per RFC 2047
+func mimeEncodeHeader(value string) string {
+	for _, r := range value {
+		if r > 127 {
+			return mime.QEncoding.Encode("UTF-8", value)
+		}
+	}
+	return value
+}
@@ -0,0 +1,155 @@
+package notifications
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Message size limits imposed by the notification APIs
+const (
+	discordTitleLimit       = 256
+	discordDescriptionLimit = 4096
+	discordFieldNameLimit   = 256
+	discordFieldValueLimit  = 1024
+	discordFieldCountLimit  = 25
+	discordEmbedTotalLimit  = 6000
+
+	telegramMessageLimit = 4096
+	telegramTitleLimit   = 256
+	telegramDetailLimit  = 256
+	// Metadata is dropped when it would leave less room than this for the message
+	telegramMinBodyLimit = 512
+)
+
+// ellipsis marks truncated text
+const ellipsis = "…"
+
+// truncateText shortens s to at most limit runes, ending it with an ellipsis
+// when anything was cut
+func truncateText(s string, limit int) string {
+	if limit <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:limit-1]) + ellipsis
+}
+
+// telegramMarkdownV2Special lists the characters that must be escaped in
+// Telegram MarkdownV2 text
+const telegramMarkdownV2Special = "_*[]()~`>#+-=|{}.!\\"
+
+// escapeMarkdownV2 escapes s for use as plain text in a MarkdownV2 message
+func escapeMarkdownV2(s string) string {
+	return escapeMarkdownV2Limit(s, -1)
+}
+
+// escapeMarkdownV2Limit escapes s for MarkdownV2 and truncates the escaped
+// result to at most limit runes, never splitting an escape sequence.
+// A negative limit disables truncation.
+func escapeMarkdownV2Limit(s string, limit int) string {
+	var b strings.Builder
+	size := 0
+	runes := []rune(s)
+	for i, r := range runes {
+		width := 1
+		if strings.ContainsRune(telegramMarkdownV2Special, r) {
+			width = 2
+		}
+		// Reserve room for the ellipsis unless this is the final rune
+		reserve := 0
+		if i < len(runes)-1 {
+			reserve = 1
+		}
+		if limit >= 0 && size+width+reserve > limit {
+			if limit > 0 {
+				b.WriteString(ellipsis)
+			}
+			return b.String()
+		}
+		if width == 2 {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+		size += width
+	}
+	return b.String()
+}
+
+// sortedMetadataKeys returns the metadata keys in a stable order
+func sortedMetadataKeys(metadata map[string]string) []string {
+	return slices.Sorted(maps.Keys(metadata))
+}
+
+// formatTelegramMessage renders a notification as a MarkdownV2 message within
+// Telegram's size limit. Metadata is dropped before the message body when
+// there is not enough room for both.
+func formatTelegramMessage(emoji string, notification *Notification) string {
+	header := emoji + " *" + escapeMarkdownV2(truncateText(notification.Title, telegramTitleLimit)) + "*"
+
+	var details strings.Builder
+	if len(notification.Metadata) > 0 {
+		details.WriteString("\n\n*Details:*")
+		for _, key := range sortedMetadataKeys(notification.Metadata) {
+			details.WriteString("\n• ")
+			details.WriteString(escapeMarkdownV2(truncateText(key, telegramDetailLimit)))
+			details.WriteString(": ")
+			details.WriteString(escapeMarkdownV2(truncateText(notification.Metadata[key], telegramDetailLimit)))
+		}
+	}
+	detailsText := details.String()
+
+	const separator = "\n\n"
+	budget := telegramMessageLimit - utf8.RuneCountInString(header) - len(separator)
+	if budget-utf8.RuneCountInString(detailsText) < telegramMinBodyLimit {
+		detailsText = ""
+	}
+	budget -= utf8.RuneCountInString(detailsText)
+
+	return header + separator + escapeMarkdownV2Limit(notification.Message, budget) + detailsText
+}
+
+// discordEmbed renders a notification as a Discord embed within Discord's
+// per-field and total size limits
+func discordEmbed(notification *Notification, timestamp string, color int) map[string]interface{} {
+	title := truncateText(notification.Title, discordTitleLimit)
+	remaining := discordEmbedTotalLimit - utf8.RuneCountInString(title)
+
+	// The message takes priority over metadata for the shared embed budget
+	description := truncateText(notification.Message, min(discordDescriptionLimit, remaining))
+	remaining -= utf8.RuneCountInString(description)
+
+	var fields []map[string]interface{}
+	for _, key := range sortedMetadataKeys(notification.Metadata) {
+		if len(fields) == discordFieldCountLimit {
+			break
+		}
+		name := truncateText(key, discordFieldNameLimit)
+		valueLimit := min(discordFieldValueLimit, remaining-utf8.RuneCountInString(name))
+		if valueLimit <= 0 {
+			break
+		}
+		value := truncateText(notification.Metadata[key], valueLimit)
+		remaining -= utf8.RuneCountInString(name) + utf8.RuneCountInString(value)
+		fields = append(fields, map[string]interface{}{
+			"name":   name,
+			"value":  value,
+			"inline": true,
+		})
+	}
+
+	embed := map[string]interface{}{
+		"title":       title,
+		"description": description,
+		"timestamp":   timestamp,
+		"color":       color,
+	}
+	if len(fields) > 0 {
+		embed["fields"] = fields
+	}
+	return embed
+}
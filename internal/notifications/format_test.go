@@ -0,0 +1,176 @@
+package notifications
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateText(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "short", truncateText("short", 10))
+	require.Equal(t, "exact", truncateText("exact", 5))
+	require.Equal(t, "trun…", truncateText("truncated", 5))
+	require.Equal(t, "héé…", truncateText("hééééé", 4))
+	require.Equal(t, "", truncateText("anything", 0))
+}
+
+func TestEscapeMarkdownV2(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "snake\\_case \\*bold\\* \\[link\\]\\(url\\) \\`code\\`", escapeMarkdownV2("snake_case *bold* [link](url) `code`"))
+	require.Equal(t, `v1\.2\.3 \- 100%\! a\\b`, escapeMarkdownV2(`v1.2.3 - 100%! a\b`))
+	require.Equal(t, "plain text", escapeMarkdownV2("plain text"))
+
+	// Truncation never splits an escape sequence
+	require.Equal(t, `ab…`, escapeMarkdownV2Limit("ab_cd", 4))
+	require.Equal(t, `ab\_…`, escapeMarkdownV2Limit("ab_cd", 5))
+	require.Equal(t, `ab\_c`, escapeMarkdownV2Limit("ab_c", 5))
+	require.Equal(t, "", escapeMarkdownV2Limit("abc", 0))
+}
+
+// captureTelegram starts a Telegram API stub and returns the service along with
+// a function reporting the last sent payload
+func captureTelegram(t *testing.T) (*TelegramService, func() map[string]any) {
+	t.Helper()
+
+	var payload map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(body, &payload))
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	service := NewTelegramService(TelegramConfig{BotToken: "token", ChatID: "42", Enabled: true})
+	service.baseURL = server.URL
+	service.SetRetryPolicy(testRetryPolicy())
+	return service, func() map[string]any { return payload }
+}
+
+func TestTelegramServiceEscapesAndTruncates(t *testing.T) {
+	t.Parallel()
+
+	service, payload := captureTelegram(t)
+
+	require.NoError(t, service.SendNotification(t.Context(), &Notification{
+		Title:     "Build *failed* on feature_branch",
+		Message:   "See [logs](http://ci) for `make test`.",
+		Level:     LevelError,
+		Timestamp: time.Now(),
+		Metadata:  map[string]string{"exit_code": "2", "job": "lint-1.2"},
+	}))
+
+	sent := payload()
+	require.Equal(t, "MarkdownV2", sent["parse_mode"])
+	require.Equal(t, "❌ *Build \\*failed\\* on feature\\_branch*\n\n"+
+		"See \\[logs\\]\\(http://ci\\) for \\`make test\\`\\.\n\n"+
+		"*Details:*\n• exit\\_code: 2\n• job: lint\\-1\\.2", sent["text"])
+
+	require.NoError(t, service.SendNotification(t.Context(), &Notification{
+		Title:     strings.Repeat("T", 1000),
+		Message:   strings.Repeat("a_", 5000),
+		Level:     LevelInfo,
+		Timestamp: time.Now(),
+		Metadata:  map[string]string{"key": "value"},
+	}))
+
+	text := payload()["text"].(string)
+	require.LessOrEqual(t, utf8.RuneCountInString(text), telegramMessageLimit)
+	require.Contains(t, text, "…")
+	require.Contains(t, text, "*Details:*\n• key: value")
+	require.NotContains(t, text, `\…`, "escape sequences must not be split")
+}
+
+func TestTelegramServiceDropsDetailsForHugeMetadata(t *testing.T) {
+	t.Parallel()
+
+	service, payload := captureTelegram(t)
+
+	metadata := make(map[string]string)
+	for i := range 40 {
+		metadata[strings.Repeat("k", i+1)] = strings.Repeat("v", 200)
+	}
+	require.NoError(t, service.SendNotification(t.Context(), &Notification{
+		Title:     "Report",
+		Message:   "Body",
+		Level:     LevelInfo,
+		Timestamp: time.Now(),
+		Metadata:  metadata,
+	}))
+
+	text := payload()["text"].(string)
+	require.LessOrEqual(t, utf8.RuneCountInString(text), telegramMessageLimit)
+	require.Equal(t, "ℹ️ *Report*\n\nBody", text)
+}
+
+func TestDiscordServiceTruncates(t *testing.T) {
+	t.Parallel()
+
+	var payload struct {
+		Embeds []struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			Fields      []struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"fields"`
+		} `json:"embeds"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	metadata := make(map[string]string)
+	for i := range 30 {
+		metadata[strings.Repeat("k", i+1)] = strings.Repeat("v", 2000)
+	}
+
+	service := NewDiscordService(DiscordConfig{WebhookURL: server.URL, Enabled: true})
+	service.SetRetryPolicy(testRetryPolicy())
+	require.NoError(t, service.SendNotification(t.Context(), &Notification{
+		Title:     strings.Repeat("T", 500),
+		Message:   strings.Repeat("m", 10000),
+		Level:     LevelWarning,
+		Timestamp: time.Now(),
+		Metadata:  metadata,
+	}))
+
+	require.Len(t, payload.Embeds, 1)
+	embed := payload.Embeds[0]
+	require.Equal(t, discordTitleLimit, utf8.RuneCountInString(embed.Title))
+	require.True(t, strings.HasSuffix(embed.Title, "…"))
+	require.Equal(t, discordDescriptionLimit, utf8.RuneCountInString(embed.Description))
+	require.NotEmpty(t, embed.Fields)
+
+	total := utf8.RuneCountInString(embed.Title) + utf8.RuneCountInString(embed.Description)
+	for _, field := range embed.Fields {
+		require.LessOrEqual(t, utf8.RuneCountInString(field.Value), discordFieldValueLimit)
+		total += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+	}
+	require.LessOrEqual(t, utf8.RuneCountInString(embed.Description), discordDescriptionLimit)
+	require.LessOrEqual(t, total, discordEmbedTotalLimit)
+}
+
+func TestDiscordEmbedFieldCountLimit(t *testing.T) {
+	t.Parallel()
+
+	metadata := make(map[string]string)
+	for i := range 30 {
+		metadata[strings.Repeat("k", i+1)] = "v"
+	}
+	embed := discordEmbed(&Notification{Title: "Title", Message: "Body", Metadata: metadata}, "", 0)
+	require.Len(t, embed["fields"], discordFieldCountLimit)
+	require.Equal(t, "Body", embed["description"])
+}
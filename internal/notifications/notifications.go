@@ -1,7 +1,6 @@
 package notifications
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -38,34 +37,69 @@ type NotificationService interface {
 // DiscordConfig holds Discord webhook configuration
 type DiscordConfig struct {
 	WebhookURL string `json:"webhook_url"`
-	Username   string `json:"username,omitempty"`
-	AvatarURL  string `json:"avatar_url,omitempty"`
-	Enabled    bool   `json:"enabled"`
+	// LevelWebhooks routes notifications of a level to their own webhook;
+	// levels without one go to WebhookURL
+	LevelWebhooks map[NotificationLevel]string `json:"level_webhooks,omitempty"`
+	Username      string                       `json:"username,omitempty"`
+	AvatarURL     string                       `json:"avatar_url,omitempty"`
+	Enabled       bool                         `json:"enabled"`
+}
+
+// webhookFor returns the webhook that receives notifications of level
+func (c DiscordConfig) webhookFor(level NotificationLevel) string {
+	if url := c.LevelWebhooks[level]; url != "" {
+		return url
+	}
+	return c.WebhookURL
 }
 
 // TelegramConfig holds Telegram bot configuration
 type TelegramConfig struct {
-	BotToken  string `json:"bot_token"`
-	ChatID    string `json:"chat_id"`
-	Enabled   bool   `json:"enabled"`
+	BotToken string `json:"bot_token"`
+	ChatID   string `json:"chat_id"`
+	// LevelChatIDs routes notifications of a level to their own chat; levels
+	// without one go to ChatID
+	LevelChatIDs map[NotificationLevel]string `json:"level_chat_ids,omitempty"`
+	Enabled      bool                         `json:"enabled"`
+}
+
+// chatIDFor returns the chat that receives notifications of level
+func (c TelegramConfig) chatIDFor(level NotificationLevel) string {
+	if chatID := c.LevelChatIDs[level]; chatID != "" {
+		return chatID
+	}
+	return c.ChatID
+}
+
+// CompletionConfig controls automatic notifications when an agent run finishes
+type CompletionConfig struct {
+	Enabled     bool `json:"enabled"`
+	MinDuration int  `json:"min_duration,omitempty"` // Seconds; shorter runs are not notified
+	ErrorsOnly  bool `json:"errors_only,omitempty"`
 }
 
 // NotificationConfig holds all notification configurations
 type NotificationConfig struct {
-	Discord  DiscordConfig  `json:"discord,omitempty"`
-	Telegram TelegramConfig `json:"telegram,omitempty"`
+	Discord      DiscordConfig    `json:"discord,omitempty"`
+	Telegram     TelegramConfig   `json:"telegram,omitempty"`
+	Email        EmailConfig      `json:"email,omitempty"`
+	OnCompletion CompletionConfig `json:"on_completion,omitempty"`
+	RateLimit    RateLimitConfig  `json:"rate_limit,omitempty"`
 }
 
 // DiscordService implements Discord notifications
 type DiscordService struct {
 	config DiscordConfig
 	client *http.Client
+	retry  RetryPolicy
 }
 
 // TelegramService implements Telegram notifications
 type TelegramService struct {
-	config TelegramConfig
-	client *http.Client
+	config  TelegramConfig
+	client  *http.Client
+	retry   RetryPolicy
+	baseURL string
 }
 
 // NewDiscordService creates a new Discord notification service
@@ -73,17 +107,30 @@ func NewDiscordService(config DiscordConfig) *DiscordService {
 	return &DiscordService{
 		config: config,
 		client: &http.Client{Timeout: 10 * time.Second},
+		retry:  DefaultRetryPolicy(),
 	}
 }
 
 // NewTelegramService creates a new Telegram notification service
 func NewTelegramService(config TelegramConfig) *TelegramService {
 	return &TelegramService{
-		config: config,
-		client: &http.Client{Timeout: 10 * time.Second},
+		config:  config,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		retry:   DefaultRetryPolicy(),
+		baseURL: "https://api.telegram.org",
 	}
 }
 
+// SetRetryPolicy configures how failed Discord deliveries are retried
+func (d *DiscordService) SetRetryPolicy(policy RetryPolicy) {
+	d.retry = policy
+}
+
+// SetRetryPolicy configures how failed Telegram deliveries are retried
+func (t *TelegramService) SetRetryPolicy(policy RetryPolicy) {
+	t.retry = policy
+}
+
 // IsEnabled returns whether Discord notifications are enabled
 func (d *DiscordService) IsEnabled() bool {
 	return d.config.Enabled && d.config.WebhookURL != ""
@@ -100,24 +147,7 @@ func (d *DiscordService) SendNotification(ctx context.Context, notification *Not
 		return fmt.Errorf("Discord notifications are not enabled")
 	}
 
-	embed := map[string]interface{}{
-		"title":       notification.Title,
-		"description": notification.Message,
-		"timestamp":   notification.Timestamp.Format(time.RFC3339),
-		"color":       d.getColorForLevel(notification.Level),
-	}
-
-	if len(notification.Metadata) > 0 {
-		fields := make([]map[string]interface{}, 0, len(notification.Metadata))
-		for key, value := range notification.Metadata {
-			fields = append(fields, map[string]interface{}{
-				"name":   key,
-				"value":  value,
-				"inline": true,
-			})
-		}
-		embed["fields"] = fields
-	}
+	embed := discordEmbed(notification, notification.Timestamp.Format(time.RFC3339), d.getColorForLevel(notification.Level))
 
 	payload := map[string]interface{}{
 		"embeds": []map[string]interface{}{embed},
@@ -135,24 +165,12 @@ func (d *DiscordService) SendNotification(ctx context.Context, notification *Not
 		return fmt.Errorf("failed to marshal Discord payload: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", d.config.WebhookURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("failed to create Discord request: %w", err)
+	if err := postJSON(ctx, d.client, d.retry, "Discord", d.config.webhookFor(notification.Level), jsonData); err != nil {
+		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := d.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send Discord notification: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("Discord API returned status %d", resp.StatusCode)
-	}
-
-	slog.Debug("Discord notification sent successfully", 
-		"title", notification.Title, 
+	slog.Debug("Discord notification sent successfully",
+		"title", notification.Title,
 		"level", notification.Level)
 	return nil
 }
@@ -164,20 +182,12 @@ func (t *TelegramService) SendNotification(ctx context.Context, notification *No
 	}
 
 	// Format message with emoji based on level
-	emoji := t.getEmojiForLevel(notification.Level)
-	message := fmt.Sprintf("%s *%s*\n\n%s", emoji, notification.Title, notification.Message)
-
-	if len(notification.Metadata) > 0 {
-		message += "\n\n*Details:*"
-		for key, value := range notification.Metadata {
-			message += fmt.Sprintf("\n• %s: %s", key, value)
-		}
-	}
+	message := formatTelegramMessage(t.getEmojiForLevel(notification.Level), notification)
 
 	payload := map[string]interface{}{
-		"chat_id":    t.config.ChatID,
+		"chat_id":    t.config.chatIDFor(notification.Level),
 		"text":       message,
-		"parse_mode": "Markdown",
+		"parse_mode": "MarkdownV2",
 	}
 
 	jsonData, err := json.Marshal(payload)
@@ -185,31 +195,29 @@ func (t *TelegramService) SendNotification(ctx context.Context, notification *No
 		return fmt.Errorf("failed to marshal Telegram payload: %w", err)
 	}
 
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.config.BotToken)
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("failed to create Telegram request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := t.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send Telegram notification: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("Telegram API returned status %d", resp.StatusCode)
+	url := fmt.Sprintf("%s/bot%s/sendMessage", t.baseURL, t.config.BotToken)
+	if err := postJSON(ctx, t.client, t.retry, "Telegram", url, jsonData); err != nil {
+		return err
 	}
 
-	slog.Debug("Telegram notification sent successfully", 
-		"title", notification.Title, 
+	slog.Debug("Telegram notification sent successfully",
+		"title", notification.Title,
 		"level", notification.Level)
 	return nil
 }
 
 // getColorForLevel returns Discord embed color for notification level
 func (d *DiscordService) getColorForLevel(level NotificationLevel) int {
+	return levelColor(level)
+}
+
+// getEmojiForLevel returns emoji for notification level
+func (t *TelegramService) getEmojiForLevel(level NotificationLevel) string {
+	return levelEmoji(level)
+}
+
+// levelColor returns the RGB color used to render a notification level
+func levelColor(level NotificationLevel) int {
 	switch level {
 	case LevelSuccess:
 		return 0x00ff00 // Green
@@ -222,8 +230,8 @@ func (d *DiscordService) getColorForLevel(level NotificationLevel) int {
 	}
 }
 
-// getEmojiForLevel returns emoji for notification level
-func (t *TelegramService) getEmojiForLevel(level NotificationLevel) string {
+// levelEmoji returns the emoji used to render a notification level
+func levelEmoji(level NotificationLevel) string {
 	switch level {
 	case LevelSuccess:
 		return "✅"
@@ -234,4 +242,4 @@ func (t *TelegramService) getEmojiForLevel(level NotificationLevel) string {
 	default:
 		return "ℹ️"
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,404 @@
+package notifications
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+	}
+}
+
+func testNotification() *Notification {
+	return &Notification{
+		Title:     "Task completed",
+		Message:   "All tests passed",
+		Level:     LevelSuccess,
+		Timestamp: time.Now(),
+	}
+}
+
+func TestDiscordServiceRetriesTransientFailures(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusInternalServerError)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	service := NewDiscordService(DiscordConfig{WebhookURL: server.URL, Enabled: true})
+	service.SetRetryPolicy(testRetryPolicy())
+
+	require.NoError(t, service.SendNotification(t.Context(), testNotification()))
+	require.Equal(t, int32(3), calls.Load())
+}
+
+func TestTelegramServiceRetriesTransientFailures(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/bottoken/sendMessage", r.URL.Path)
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	service := NewTelegramService(TelegramConfig{BotToken: "token", ChatID: "42", Enabled: true})
+	service.baseURL = server.URL
+	service.SetRetryPolicy(testRetryPolicy())
+
+	require.NoError(t, service.SendNotification(t.Context(), testNotification()))
+	require.Equal(t, int32(3), calls.Load())
+}
+
+func TestDiscordServiceRoutesByLevel(t *testing.T) {
+	t.Parallel()
+
+	var paths []string
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	service := NewDiscordService(DiscordConfig{
+		WebhookURL:    server.URL + "/general",
+		LevelWebhooks: map[NotificationLevel]string{LevelError: server.URL + "/oncall"},
+		Enabled:       true,
+	})
+
+	failure := testNotification()
+	failure.Level = LevelError
+	require.NoError(t, service.SendNotification(t.Context(), failure))
+	require.NoError(t, service.SendNotification(t.Context(), testNotification()))
+	require.Equal(t, []string{"/oncall", "/general"}, paths)
+}
+
+func TestTelegramServiceRoutesByLevel(t *testing.T) {
+	t.Parallel()
+
+	var chats []string
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			ChatID string `json:"chat_id"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		mu.Lock()
+		chats = append(chats, payload.ChatID)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	service := NewTelegramService(TelegramConfig{
+		BotToken:     "token",
+		ChatID:       "42",
+		LevelChatIDs: map[NotificationLevel]string{LevelError: "-100"},
+		Enabled:      true,
+	})
+	service.baseURL = server.URL
+
+	failure := testNotification()
+	failure.Level = LevelError
+	require.NoError(t, service.SendNotification(t.Context(), failure))
+	require.NoError(t, service.SendNotification(t.Context(), testNotification()))
+	require.Equal(t, []string{"-100", "42"}, chats)
+}
+
+func TestPostJSONGivesUp(t *testing.T) {
+	t.Parallel()
+
+	t.Run("after max attempts", func(t *testing.T) {
+		t.Parallel()
+
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		err := postJSON(t.Context(), server.Client(), testRetryPolicy(), "Test", server.URL, []byte("{}"))
+		require.ErrorContains(t, err, "status 503")
+		require.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("on permanent client errors", func(t *testing.T) {
+		t.Parallel()
+
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		err := postJSON(t.Context(), server.Client(), testRetryPolicy(), "Test", server.URL, []byte("{}"))
+		require.ErrorContains(t, err, "status 400")
+		require.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("when the context is cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+		defer cancel()
+
+		policy := testRetryPolicy()
+		policy.MaxBackoff = time.Minute
+		err := postJSON(ctx, server.Client(), policy, "Test", server.URL, []byte("{}"))
+		require.ErrorContains(t, err, "retry aborted")
+	})
+}
+
+func TestPostJSONCapsRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "86400")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	err := postJSON(t.Context(), server.Client(), testRetryPolicy(), "Test", server.URL, []byte("{}"))
+	require.ErrorContains(t, err, "status 429")
+	require.Equal(t, int32(3), calls.Load())
+	require.Less(t, time.Since(start), 5*time.Second, "retries wait at most MaxBackoff")
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	delay, ok := parseRetryAfter("2")
+	require.True(t, ok)
+	require.Equal(t, 2*time.Second, delay)
+
+	_, ok = parseRetryAfter("")
+	require.False(t, ok)
+
+	_, ok = parseRetryAfter("soon")
+	require.False(t, ok)
+
+	delay, ok = parseRetryAfter(time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+	require.True(t, ok)
+	require.Zero(t, delay)
+}
+
+// mockSMTPServer accepts a single SMTP session and records the commands and
+// message data it receives.
+type mockSMTPServer struct {
+	listener net.Listener
+	commands []string
+	data     string
+	done     chan struct{}
+}
+
+func newMockSMTPServer(t *testing.T) *mockSMTPServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	server := &mockSMTPServer{listener: listener, done: make(chan struct{})}
+	go server.serve()
+	return server
+}
+
+func (s *mockSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *mockSMTPServer) serve() {
+	defer close(s.done)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+
+	reply("220 localhost ESMTP mock")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		s.commands = append(s.commands, line)
+
+		verb := strings.ToUpper(strings.Fields(line + " ")[0])
+		switch verb {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL", "RCPT":
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			s.data = data.String()
+			reply("250 OK queued")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestEmailServiceSendsNotification(t *testing.T) {
+	t.Parallel()
+
+	server := newMockSMTPServer(t)
+	service := NewEmailService(EmailConfig{
+		Host:     "127.0.0.1",
+		Port:     server.port(),
+		Username: "crush",
+		Password: "secret",
+		From:     "crush@example.com",
+		To:       []string{"dev@example.com", "ops@example.com"},
+		Enabled:  true,
+	})
+
+	notification := testNotification()
+	notification.Level = LevelError
+	notification.Message = "Build <failed>"
+	notification.Metadata = map[string]string{"branch": "main"}
+
+	require.NoError(t, service.SendNotification(t.Context(), notification))
+	<-server.done
+
+	require.Contains(t, server.commands, "MAIL FROM:<crush@example.com>")
+	require.Contains(t, server.commands, "RCPT TO:<dev@example.com>")
+	require.Contains(t, server.commands, "RCPT TO:<ops@example.com>")
+	require.True(t, slices.ContainsFunc(server.commands, func(c string) bool {
+		return strings.HasPrefix(c, "AUTH PLAIN")
+	}))
+
+	require.Contains(t, server.data, "Content-Type: text/html; charset=UTF-8")
+	require.Contains(t, server.data, "Subject: =?UTF-8?q?")
+	require.Contains(t, server.data, "#ff0000")
+	require.Contains(t, server.data, "Build &lt;failed&gt;")
+	require.Contains(t, server.data, "<td><b>branch</b></td><td>main</td>")
+}
+
+func TestEmailServiceRoutesByLevel(t *testing.T) {
+	t.Parallel()
+
+	server := newMockSMTPServer(t)
+	service := NewEmailService(EmailConfig{
+		Host:    "127.0.0.1",
+		Port:    server.port(),
+		From:    "crush@example.com",
+		To:      []string{"dev@example.com"},
+		LevelTo: map[NotificationLevel][]string{LevelError: {"oncall@example.com"}},
+		Enabled: true,
+	})
+
+	notification := testNotification()
+	notification.Level = LevelError
+	require.NoError(t, service.SendNotification(t.Context(), notification))
+	<-server.done
+
+	require.Contains(t, server.commands, "RCPT TO:<oncall@example.com>")
+	require.NotContains(t, server.commands, "RCPT TO:<dev@example.com>")
+	require.Contains(t, server.data, "To: oncall@example.com\r\n")
+}
+
+func TestEmailServiceIsEnabled(t *testing.T) {
+	t.Parallel()
+
+	require.False(t, NewEmailService(EmailConfig{Host: "smtp.example.com", From: "a@example.com", Enabled: true}).IsEnabled())
+	require.False(t, NewEmailService(EmailConfig{Host: "smtp.example.com", From: "a@example.com", To: []string{"b@example.com"}}).IsEnabled())
+	require.True(t, NewEmailService(EmailConfig{Host: "smtp.example.com", From: "a@example.com", To: []string{"b@example.com"}, Enabled: true}).IsEnabled())
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistryFromConfig(&NotificationConfig{
+		Discord: DiscordConfig{WebhookURL: "https://discord.example/webhook", Enabled: true},
+	})
+	require.Equal(t, []string{"discord", "telegram", "email"}, registry.Names())
+	require.Equal(t, []string{"discord", "telegram", "email", "both", "all"}, registry.Selections())
+
+	enabled := registry.Enabled()
+	require.Len(t, enabled, 1)
+	require.Equal(t, "Discord", enabled[0].Label)
+
+	both, err := registry.Select("both")
+	require.NoError(t, err)
+	require.Len(t, both, 2)
+
+	// Registering under an existing name replaces the service in place
+	registry.Register("telegram", "", NewTelegramService(TelegramConfig{BotToken: "token", ChatID: "1", Enabled: true}))
+	require.Equal(t, []string{"discord", "telegram", "email"}, registry.Names())
+	telegram, ok := registry.Get("telegram")
+	require.True(t, ok)
+	require.Equal(t, "telegram", telegram.Label)
+	require.Len(t, registry.Enabled(), 2)
+
+	_, err = registry.Select("pager")
+	require.ErrorContains(t, err, "must be one of discord, telegram, email, both, all")
+
+	_, err = NewRegistry().Select("all")
+	require.ErrorContains(t, err, "no notification services are configured")
+}
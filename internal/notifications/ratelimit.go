@@ -0,0 +1,152 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultDedupWindow is how long identical notifications are suppressed
+	defaultDedupWindow = 5 * time.Minute
+	// defaultMaxPerMinute caps how many notifications a service sends per minute
+	defaultMaxPerMinute = 10
+)
+
+// ErrSuppressed is returned, wrapped in a *SuppressedError, for notifications
+// the rate limiter did not send
+var ErrSuppressed = errors.New("notification suppressed")
+
+// SuppressedError reports why a notification was not sent
+type SuppressedError struct {
+	Reason string
+}
+
+func (e *SuppressedError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrSuppressed, e.Reason)
+}
+
+func (e *SuppressedError) Unwrap() error {
+	return ErrSuppressed
+}
+
+// RateLimitConfig protects against alert storms, such as a failing agent loop
+// notifying on every attempt
+type RateLimitConfig struct {
+	// DedupWindow is how many seconds an identical notification (same title,
+	// message and level) is suppressed after being sent. Defaults to 300;
+	// negative disables deduplication.
+	DedupWindow int `json:"dedup_window,omitempty"`
+	// MaxPerMinute caps the notifications each service sends per minute.
+	// Defaults to 10; negative disables the cap.
+	MaxPerMinute int `json:"max_per_minute,omitempty"`
+}
+
+// RateLimitedService wraps a notification service, suppressing duplicate
+// notifications and those beyond a per-minute cap
+type RateLimitedService struct {
+	service      NotificationService
+	dedupWindow  time.Duration
+	maxPerMinute int
+	now          func() time.Time
+
+	mu sync.Mutex
+	// Last time each notification was sent, keyed by notificationKey
+	sent map[string]time.Time
+	// Send times within the last minute, oldest first
+	recent []time.Time
+}
+
+// NewRateLimitedService wraps service with the limits in config
+func NewRateLimitedService(service NotificationService, config RateLimitConfig) *RateLimitedService {
+	r := &RateLimitedService{
+		service:      service,
+		dedupWindow:  defaultDedupWindow,
+		maxPerMinute: defaultMaxPerMinute,
+		now:          time.Now,
+		sent:         make(map[string]time.Time),
+	}
+	if config.DedupWindow != 0 {
+		r.dedupWindow = time.Duration(max(config.DedupWindow, 0)) * time.Second
+	}
+	if config.MaxPerMinute != 0 {
+		r.maxPerMinute = max(config.MaxPerMinute, 0)
+	}
+	return r
+}
+
+// IsEnabled returns whether the wrapped service is enabled
+func (r *RateLimitedService) IsEnabled() bool {
+	return r.service.IsEnabled()
+}
+
+// SendNotification sends the notification through the wrapped service unless
+// it is a recent duplicate or the per-minute cap is reached, in which case a
+// *SuppressedError is returned. Failed deliveries don't count against either
+// limit.
+func (r *RateLimitedService) SendNotification(ctx context.Context, notification *Notification) error {
+	key := notificationKey(notification)
+	if err := r.reserve(key); err != nil {
+		slog.Debug("Notification suppressed", "title", notification.Title, "level", notification.Level, "reason", err.Reason)
+		return err
+	}
+
+	if err := r.service.SendNotification(ctx, notification); err != nil {
+		r.release(key)
+		return err
+	}
+	return nil
+}
+
+// reserve records a send of key, or reports why it must be suppressed
+func (r *RateLimitedService) reserve(key string) *SuppressedError {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if last, ok := r.sent[key]; ok && r.dedupWindow > 0 && now.Sub(last) < r.dedupWindow {
+		return &SuppressedError{Reason: fmt.Sprintf("identical notification sent %s ago", now.Sub(last).Round(time.Second))}
+	}
+
+	for len(r.recent) > 0 && now.Sub(r.recent[0]) >= time.Minute {
+		r.recent = r.recent[1:]
+	}
+	if r.maxPerMinute > 0 && len(r.recent) >= r.maxPerMinute {
+		return &SuppressedError{Reason: fmt.Sprintf("limit of %d notifications per minute reached", r.maxPerMinute)}
+	}
+
+	for k, sentAt := range r.sent {
+		if now.Sub(sentAt) >= r.dedupWindow {
+			delete(r.sent, k)
+		}
+	}
+	r.sent[key] = now
+	r.recent = append(r.recent, now)
+	return nil
+}
+
+// release forgets the most recent reservation of key after a failed send
+func (r *RateLimitedService) release(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sentAt, ok := r.sent[key]
+	if !ok {
+		return
+	}
+	delete(r.sent, key)
+	for i := len(r.recent) - 1; i >= 0; i-- {
+		if r.recent[i].Equal(sentAt) {
+			r.recent = append(r.recent[:i], r.recent[i+1:]...)
+			break
+		}
+	}
+}
+
+// notificationKey identifies notifications considered duplicates
+func notificationKey(notification *Notification) string {
+	return fmt.Sprintf("%s\x00%s\x00%s", notification.Level, notification.Title, notification.Message)
+}
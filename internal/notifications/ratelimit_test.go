@@ -0,0 +1,140 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countingService counts deliveries, failing while err is set
+type countingService struct {
+	mu   sync.Mutex
+	sent []*Notification
+	err  error
+}
+
+func (c *countingService) SendNotification(ctx context.Context, notification *Notification) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.sent = append(c.sent, notification)
+	return nil
+}
+
+func (c *countingService) IsEnabled() bool { return true }
+
+// fakeClock is a settable time source
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func TestRateLimitedServiceSuppressesDuplicates(t *testing.T) {
+	t.Parallel()
+
+	inner := &countingService{}
+	service := NewRateLimitedService(inner, RateLimitConfig{})
+
+	var wg sync.WaitGroup
+	errs := make([]error, 20)
+	for i := range errs {
+		wg.Go(func() {
+			errs[i] = service.SendNotification(t.Context(), testNotification())
+		})
+	}
+	wg.Wait()
+
+	require.Len(t, inner.sent, 1)
+	suppressed := 0
+	for _, err := range errs {
+		if err != nil {
+			require.ErrorIs(t, err, ErrSuppressed)
+			suppressed++
+		}
+	}
+	require.Equal(t, 19, suppressed)
+
+	// Notifications differing in level are not duplicates
+	other := testNotification()
+	other.Level = LevelError
+	require.NoError(t, service.SendNotification(t.Context(), other))
+	require.Len(t, inner.sent, 2)
+}
+
+func TestRateLimitedServiceDedupWindow(t *testing.T) {
+	t.Parallel()
+
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	inner := &countingService{}
+	service := NewRateLimitedService(inner, RateLimitConfig{DedupWindow: 30})
+	service.now = clock.Now
+
+	require.NoError(t, service.SendNotification(t.Context(), testNotification()))
+
+	clock.now = clock.now.Add(29 * time.Second)
+	var suppressed *SuppressedError
+	require.ErrorAs(t, service.SendNotification(t.Context(), testNotification()), &suppressed)
+	require.Contains(t, suppressed.Reason, "identical notification sent 29s ago")
+
+	clock.now = clock.now.Add(time.Second)
+	require.NoError(t, service.SendNotification(t.Context(), testNotification()))
+	require.Len(t, inner.sent, 2)
+}
+
+func TestRateLimitedServicePerMinuteCap(t *testing.T) {
+	t.Parallel()
+
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	inner := &countingService{}
+	service := NewRateLimitedService(inner, RateLimitConfig{MaxPerMinute: 3})
+	service.now = clock.Now
+
+	notification := func(i int) *Notification {
+		n := testNotification()
+		n.Message = fmt.Sprintf("attempt %d", i)
+		return n
+	}
+	for i := range 3 {
+		require.NoError(t, service.SendNotification(t.Context(), notification(i)))
+		clock.now = clock.now.Add(10 * time.Second)
+	}
+	err := service.SendNotification(t.Context(), notification(3))
+	require.ErrorIs(t, err, ErrSuppressed)
+	require.ErrorContains(t, err, "limit of 3 notifications per minute reached")
+
+	// The first send falls out of the window a minute after it was made
+	clock.now = clock.now.Add(30 * time.Second)
+	require.NoError(t, service.SendNotification(t.Context(), notification(4)))
+	require.Len(t, inner.sent, 4)
+}
+
+func TestRateLimitedServiceFailuresDontCount(t *testing.T) {
+	t.Parallel()
+
+	inner := &countingService{err: errors.New("webhook unreachable")}
+	service := NewRateLimitedService(inner, RateLimitConfig{MaxPerMinute: 1})
+
+	require.EqualError(t, service.SendNotification(t.Context(), testNotification()), "webhook unreachable")
+	require.EqualError(t, service.SendNotification(t.Context(), testNotification()), "webhook unreachable")
+
+	inner.err = nil
+	require.NoError(t, service.SendNotification(t.Context(), testNotification()))
+	require.Len(t, inner.sent, 1)
+}
+
+func TestRateLimitedServiceDisabled(t *testing.T) {
+	t.Parallel()
+
+	inner := &countingService{}
+	service := NewRateLimitedService(inner, RateLimitConfig{DedupWindow: -1, MaxPerMinute: -1})
+	for range 20 {
+		require.NoError(t, service.SendNotification(t.Context(), testNotification()))
+	}
+	require.Len(t, inner.sent, 20)
+}
@@ -0,0 +1,134 @@
+package notifications
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// NamedService is a notification service registered under the name used to
+// select it, along with a label used when reporting on it
+type NamedService struct {
+	Name    string
+	Label   string
+	Service NotificationService
+}
+
+// Registry holds notification services by name, in registration order. It
+// lets custom backends be added next to the built-in ones without changing
+// the code that sends notifications.
+type Registry struct {
+	services []NamedService
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// NewRegistryFromConfig creates a registry holding the built-in Discord,
+// Telegram and email services, each rate limited according to the config.
+// They are registered even when not configured, so selecting one by name
+// reports that it is disabled rather than unknown.
+func NewRegistryFromConfig(config *NotificationConfig) *Registry {
+	if config == nil {
+		config = &NotificationConfig{}
+	}
+
+	r := NewRegistry()
+	r.Register("discord", "Discord", NewRateLimitedService(NewDiscordService(config.Discord), config.RateLimit))
+	r.Register("telegram", "Telegram", NewRateLimitedService(NewTelegramService(config.Telegram), config.RateLimit))
+	r.Register("email", "Email", NewRateLimitedService(NewEmailService(config.Email), config.RateLimit))
+	return r
+}
+
+// Register adds service under name, replacing any service already registered
+// under it. An empty label defaults to the name.
+func (r *Registry) Register(name, label string, service NotificationService) {
+	if label == "" {
+		label = name
+	}
+	named := NamedService{Name: name, Label: label, Service: service}
+	for i, existing := range r.services {
+		if existing.Name == name {
+			r.services[i] = named
+			return
+		}
+	}
+	r.services = append(r.services, named)
+}
+
+// Get returns the service registered under name
+func (r *Registry) Get(name string) (NamedService, bool) {
+	for _, svc := range r.services {
+		if svc.Name == name {
+			return svc, true
+		}
+	}
+	return NamedService{}, false
+}
+
+// Services returns all registered services in registration order
+func (r *Registry) Services() []NamedService {
+	return slices.Clone(r.services)
+}
+
+// Names returns the names of all registered services in registration order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.services))
+	for _, svc := range r.services {
+		names = append(names, svc.Name)
+	}
+	return names
+}
+
+// Enabled returns the registered services that are enabled
+func (r *Registry) Enabled() []NamedService {
+	var enabled []NamedService
+	for _, svc := range r.services {
+		if svc.Service != nil && svc.Service.IsEnabled() {
+			enabled = append(enabled, svc)
+		}
+	}
+	return enabled
+}
+
+// Select resolves a service selection to the services to notify. "all"
+// selects every enabled service and "both" selects Discord and Telegram;
+// any other selection must be a registered name.
+func (r *Registry) Select(selection string) ([]NamedService, error) {
+	switch selection {
+	case "all":
+		enabled := r.Enabled()
+		if len(enabled) == 0 {
+			return nil, fmt.Errorf("no notification services are configured")
+		}
+		return enabled, nil
+	case "both":
+		var selected []NamedService
+		for _, name := range []string{"discord", "telegram"} {
+			if svc, ok := r.Get(name); ok {
+				selected = append(selected, svc)
+			}
+		}
+		if len(selected) > 0 {
+			return selected, nil
+		}
+	}
+
+	if svc, ok := r.Get(selection); ok {
+		return []NamedService{svc}, nil
+	}
+	return nil, fmt.Errorf("invalid service %q: must be one of %s", selection, strings.Join(r.Selections(), ", "))
+}
+
+// Selections returns every value accepted by Select
+func (r *Registry) Selections() []string {
+	selections := r.Names()
+	_, hasDiscord := r.Get("discord")
+	_, hasTelegram := r.Get("telegram")
+	if hasDiscord || hasTelegram {
+		selections = append(selections, "both")
+	}
+	return append(selections, "all")
+}
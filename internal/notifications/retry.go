@@ -0,0 +1,109 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed notification deliveries are retried
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy used by new services
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+	}
+}
+
+// backoff returns the delay before the given retry attempt (1-based)
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// postJSON posts the payload to url, retrying transient failures
+// (network errors, 429 and 5xx responses) according to the policy
+func postJSON(ctx context.Context, client *http.Client, policy RetryPolicy, service, url string, payload []byte) error {
+	attempts := max(policy.MaxAttempts, 1)
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+		if err != nil {
+			return fmt.Errorf("failed to create %s request: %w", service, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		delay := policy.backoff(attempt)
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("failed to send %s notification: %w", service, err)
+		} else {
+			resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				return nil
+			}
+			lastErr = fmt.Errorf("%s API returned status %d", service, resp.StatusCode)
+			if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+				return lastErr
+			}
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				// Don't let the server stall delivery for longer than the
+				// policy would wait on its own
+				delay = retryAfter
+				if policy.MaxBackoff > 0 {
+					delay = min(retryAfter, policy.MaxBackoff)
+				}
+			}
+		}
+
+		if attempt == attempts || ctx.Err() != nil {
+			break
+		}
+
+		slog.Debug("Retrying notification delivery",
+			"service", service,
+			"attempt", attempt,
+			"delay", delay,
+			"error", lastErr)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (retry aborted: %v)", lastErr, ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+
+	return lastErr
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
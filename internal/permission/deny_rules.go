@@ -0,0 +1,143 @@
+package permission
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// denyRuleRegexPrefix marks a deny rule as a regular expression rather than a glob
+const denyRuleRegexPrefix = "re:"
+
+// denyRule is a compiled deny rule along with the text it was written as
+type denyRule struct {
+	source  string
+	pattern *regexp.Regexp
+}
+
+// compileDenyRule compiles a deny rule. Rules are matched against
+// "tool:action:path" for each request, and "tool:action:command" for requests
+// that run a command. A rule prefixed with "re:" is a
+// regular expression that may match anywhere in that string, anything else
+// is a glob that must match all of it, where "*" matches within one path
+// segment, "**" matches across segments and "?" matches a single character.
+func compileDenyRule(rule string) (denyRule, error) {
+	expr, isRegex := strings.CutPrefix(rule, denyRuleRegexPrefix)
+	if strings.TrimSpace(expr) == "" {
+		return denyRule{}, fmt.Errorf("invalid deny rule %q: rule is empty", rule)
+	}
+	if !isRegex {
+		expr = globToRegexp(expr)
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return denyRule{}, fmt.Errorf("invalid deny rule %q: %w", rule, err)
+	}
+	return denyRule{source: rule, pattern: pattern}, nil
+}
+
+// globToRegexp converts a glob to an anchored regular expression
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// denyRuleTargets are the strings deny rules are matched against for a
+// request: "tool:action:path", and "tool:action:command" when the request's
+// params carry a command, such as the command line bash is asked to run
+func denyRuleTargets(opts CreatePermissionRequest) []string {
+	targets := []string{fmt.Sprintf("%s:%s:%s", opts.ToolName, opts.Action, filepath.ToSlash(opts.Path))}
+	if command := requestCommand(opts.Params); command != "" {
+		targets = append(targets, fmt.Sprintf("%s:%s:%s", opts.ToolName, opts.Action, command))
+	}
+	return targets
+}
+
+// requestCommand returns the "command" field of a request's params, or ""
+// when they have none
+func requestCommand(params any) string {
+	if params == nil {
+		return ""
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return ""
+	}
+	var fields struct {
+		Command string `json:"command"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return ""
+	}
+	return fields.Command
+}
+
+// SetDenyRules replaces the rules that deny matching requests without
+// prompting. Unlike learned patterns they are authoritative: a match denies
+// the request even when learning would auto-approve it. Nothing is changed if
+// any rule fails to compile.
+func (s *SmartPermissionService) SetDenyRules(rules []string) error {
+	compiled := make([]denyRule, 0, len(rules))
+	for _, rule := range rules {
+		r, err := compileDenyRule(rule)
+		if err != nil {
+			return err
+		}
+		compiled = append(compiled, r)
+	}
+
+	s.patternsMu.Lock()
+	s.denyRules = compiled
+	s.patternsMu.Unlock()
+	return nil
+}
+
+// DenyRules returns the configured deny rules as written
+func (s *SmartPermissionService) DenyRules() []string {
+	s.patternsMu.RLock()
+	defer s.patternsMu.RUnlock()
+
+	rules := make([]string, 0, len(s.denyRules))
+	for _, rule := range s.denyRules {
+		rules = append(rules, rule.source)
+	}
+	return rules
+}
+
+// matchDenyRule returns the first deny rule matching the request
+func (s *SmartPermissionService) matchDenyRule(opts CreatePermissionRequest) (string, bool) {
+	s.patternsMu.RLock()
+	defer s.patternsMu.RUnlock()
+
+	if len(s.denyRules) == 0 {
+		return "", false
+	}
+	targets := denyRuleTargets(opts)
+	for _, rule := range s.denyRules {
+		for _, target := range targets {
+			if rule.pattern.MatchString(target) {
+				return rule.source, true
+			}
+		}
+	}
+	return "", false
+}
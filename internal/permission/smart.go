@@ -1,11 +1,13 @@
 package permission
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -21,6 +23,59 @@ type SmartPermissionPattern struct {
 	LastUsed      time.Time `json:"last_used"`
 	Confidence    float64   `json:"confidence"`
 	AutoApprove   bool      `json:"auto_approve"`
+	// RecentDecisions holds the latest decisions, oldest first, with true for
+	// an approval. Confidence is computed from these, while the counts above
+	// cover the pattern's whole lifetime.
+	RecentDecisions []bool `json:"recent_decisions,omitempty"`
+}
+
+const (
+	// recentDecisionLimit is how many of a pattern's latest decisions are kept
+	recentDecisionLimit = 10
+	// recencyDecay is the weight of each decision relative to the one after it
+	recencyDecay = 0.8
+)
+
+// clone returns a copy of the pattern that shares no memory with it
+func (p *SmartPermissionPattern) clone() SmartPermissionPattern {
+	patternCopy := *p
+	patternCopy.RecentDecisions = slices.Clone(p.RecentDecisions)
+	return patternCopy
+}
+
+// recordDecision adds a decision to the lifetime counts and the recent ones
+func (p *SmartPermissionPattern) recordDecision(approved bool) {
+	if approved {
+		p.ApprovalCount++
+	} else {
+		p.DenialCount++
+	}
+
+	p.RecentDecisions = append(p.RecentDecisions, approved)
+	if extra := len(p.RecentDecisions) - recentDecisionLimit; extra > 0 {
+		p.RecentDecisions = slices.Delete(p.RecentDecisions, 0, extra)
+	}
+}
+
+// approvalRate returns the share of decisions that were approvals. Recent
+// decisions are weighted so each counts recencyDecay times as much as the one
+// after it, letting a reversal outweigh a long history. Patterns saved before
+// recent decisions were kept fall back to the lifetime counts.
+func (p *SmartPermissionPattern) approvalRate() float64 {
+	if len(p.RecentDecisions) == 0 {
+		return float64(p.ApprovalCount) / float64(p.ApprovalCount+p.DenialCount)
+	}
+
+	var approved, total float64
+	weight := 1.0
+	for _, decision := range slices.Backward(p.RecentDecisions) {
+		if decision {
+			approved += weight
+		}
+		total += weight
+		weight *= recencyDecay
+	}
+	return approved / total
 }
 
 // SmartPermissionService extends the basic permission service with learning capabilities
@@ -31,6 +86,12 @@ type SmartPermissionService struct {
 	learningFile        string
 	enabled             bool
 	confidenceThreshold float64
+	// Rules that deny matching requests outright, checked before anything else
+	denyRules []denyRule
+	// Serializes writes to learningFile so saves land in order
+	saveMu sync.Mutex
+	// Tracks saves started in the background
+	pendingSaves sync.WaitGroup
 }
 
 // NewSmartPermissionService creates an enhanced permission service with learning
@@ -52,6 +113,18 @@ func NewSmartPermissionService(baseService Service, workingDir string, enabled b
 
 // Request overrides the base Request method to add smart learning
 func (s *SmartPermissionService) Request(opts CreatePermissionRequest) bool {
+	// Deny rules are authoritative, so they apply even when learning is
+	// disabled and take precedence over any learned approval
+	if rule, denied := s.matchDenyRule(opts); denied {
+		slog.Info("Denied by permission rule",
+			"rule", rule,
+			"tool", opts.ToolName,
+			"action", opts.Action,
+			"path", opts.Path,
+		)
+		return false
+	}
+
 	if !s.enabled {
 		return s.Service.Request(opts)
 	}
@@ -77,8 +150,8 @@ func (s *SmartPermissionService) Request(opts CreatePermissionRequest) bool {
 
 // shouldAutoApprove checks if the request matches a high-confidence pattern
 func (s *SmartPermissionService) shouldAutoApprove(opts CreatePermissionRequest) bool {
-	s.patternsMu.RLock()
-	defer s.patternsMu.RUnlock()
+	s.patternsMu.Lock()
+	defer s.patternsMu.Unlock()
 
 	key := s.getPatternKey(opts.ToolName, opts.Action, opts.Path)
 	pattern, exists := s.patterns[key]
@@ -111,13 +184,7 @@ func (s *SmartPermissionService) learnFromDecision(opts CreatePermissionRequest,
 		s.patterns[key] = pattern
 	}
 
-	// Update counts
-	if approved {
-		pattern.ApprovalCount++
-	} else {
-		pattern.DenialCount++
-	}
-
+	pattern.recordDecision(approved)
 	pattern.LastUsed = time.Now()
 
 	// Calculate confidence and auto-approval eligibility
@@ -132,7 +199,7 @@ func (s *SmartPermissionService) learnFromDecision(opts CreatePermissionRequest,
 	)
 
 	// Save patterns asynchronously
-	go s.savePatterns()
+	s.pendingSaves.Go(s.savePatterns)
 }
 
 // updatePatternConfidence calculates confidence and auto-approval eligibility
@@ -144,8 +211,8 @@ func (s *SmartPermissionService) updatePatternConfidence(pattern *SmartPermissio
 		return
 	}
 
-	// Base confidence is approval rate
-	approvalRate := float64(pattern.ApprovalCount) / float64(total)
+	// Base confidence is the recency-weighted approval rate
+	approvalRate := pattern.approvalRate()
 
 	// Adjust confidence based on sample size (more samples = higher confidence)
 	sampleSizeBonus := 1.0
@@ -168,12 +235,94 @@ func (s *SmartPermissionService) updatePatternConfidence(pattern *SmartPermissio
 
 	pattern.Confidence = approvalRate * sampleSizeBonus * timeDecay
 
-	// Enable auto-approval for consistently approved actions
+	// Enable auto-approval for consistently approved actions, never for
+	// patterns that were ever denied
 	pattern.AutoApprove = pattern.Confidence >= s.confidenceThreshold &&
 		pattern.ApprovalCount >= 3 &&
 		pattern.DenialCount == 0
 }
 
+// ConfidenceThreshold returns the confidence required for auto-approval
+func (s *SmartPermissionService) ConfidenceThreshold() float64 {
+	s.patternsMu.RLock()
+	defer s.patternsMu.RUnlock()
+
+	return s.confidenceThreshold
+}
+
+// SetConfidenceThreshold changes the confidence required for auto-approval and
+// re-evaluates every learned pattern against it. Negative values are treated as 0.
+func (s *SmartPermissionService) SetConfidenceThreshold(threshold float64) {
+	threshold = max(threshold, 0)
+
+	s.patternsMu.Lock()
+	s.confidenceThreshold = threshold
+	for _, pattern := range s.patterns {
+		s.updatePatternConfidence(pattern)
+	}
+	s.patternsMu.Unlock()
+
+	slog.Debug("Updated permission confidence threshold", "threshold", threshold)
+
+	if s.enabled {
+		s.pendingSaves.Go(s.savePatterns)
+	}
+}
+
+// GetPattern returns a copy of the learned pattern that applies to a request
+// for the given tool, action and path
+func (s *SmartPermissionService) GetPattern(toolName, action, path string) (*SmartPermissionPattern, bool) {
+	s.patternsMu.RLock()
+	defer s.patternsMu.RUnlock()
+
+	pattern, exists := s.patterns[s.getPatternKey(toolName, action, path)]
+	if !exists {
+		return nil, false
+	}
+	patternCopy := pattern.clone()
+	return &patternCopy, true
+}
+
+// Patterns returns copies of all learned patterns, ordered by tool, action and path
+func (s *SmartPermissionService) Patterns() []SmartPermissionPattern {
+	s.patternsMu.RLock()
+	defer s.patternsMu.RUnlock()
+
+	patterns := make([]SmartPermissionPattern, 0, len(s.patterns))
+	for _, pattern := range s.patterns {
+		patterns = append(patterns, pattern.clone())
+	}
+	slices.SortFunc(patterns, func(a, b SmartPermissionPattern) int {
+		return cmp.Or(
+			strings.Compare(a.ToolName, b.ToolName),
+			strings.Compare(a.Action, b.Action),
+			strings.Compare(a.PathPattern, b.PathPattern),
+		)
+	})
+	return patterns
+}
+
+// RevokePattern forgets a learned pattern, identified by the tool name, action
+// and generalized path pattern reported in SmartPermissionPattern. It reports
+// whether the pattern existed.
+func (s *SmartPermissionService) RevokePattern(toolName, action, pathPattern string) bool {
+	s.patternsMu.Lock()
+	key := fmt.Sprintf("%s:%s:%s", toolName, action, pathPattern)
+	_, exists := s.patterns[key]
+	delete(s.patterns, key)
+	s.patternsMu.Unlock()
+
+	if !exists {
+		return false
+	}
+
+	slog.Info("Revoked learned permission pattern", "tool", toolName, "action", action, "path_pattern", pathPattern)
+	if s.enabled {
+		s.pendingSaves.Go(s.savePatterns)
+	}
+	return true
+}
+
 // getPatternKey creates a unique key for permission patterns
 func (s *SmartPermissionService) getPatternKey(toolName, action, path string) string {
 	generalizedPath := s.generalizePattern(path)
@@ -234,12 +383,17 @@ func (s *SmartPermissionService) loadPatterns() {
 	slog.Debug("Loaded permission patterns", "count", len(patterns))
 }
 
-// savePatterns saves learned patterns to disk
+// savePatterns saves learned patterns to disk. Saves are serialized and each
+// one snapshots the patterns after acquiring the save lock, so the file always
+// ends up with the latest state.
 func (s *SmartPermissionService) savePatterns() {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
 	s.patternsMu.RLock()
-	patterns := make(map[string]*SmartPermissionPattern)
+	patterns := make(map[string]SmartPermissionPattern, len(s.patterns))
 	for k, v := range s.patterns {
-		patterns[k] = v
+		patterns[k] = v.clone()
 	}
 	s.patternsMu.RUnlock()
 
@@ -255,7 +409,15 @@ func (s *SmartPermissionService) savePatterns() {
 		return
 	}
 
-	if err := os.WriteFile(s.learningFile, data, 0644); err != nil {
+	// Write to a temporary file and rename it into place so a crash or a
+	// concurrent reader never sees a truncated file
+	tmpPath := s.learningFile + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		slog.Warn("Failed to save permission patterns", "error", err)
+		return
+	}
+	if err := os.Rename(tmpPath, s.learningFile); err != nil {
+		os.Remove(tmpPath)
 		slog.Warn("Failed to save permission patterns", "error", err)
 		return
 	}
@@ -289,6 +451,9 @@ func (s *SmartPermissionService) GetLearningStats() map[string]interface{} {
 
 // ClearLearning removes all learned patterns
 func (s *SmartPermissionService) ClearLearning() error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
 	s.patternsMu.Lock()
 	s.patterns = make(map[string]*SmartPermissionPattern)
 	s.patternsMu.Unlock()
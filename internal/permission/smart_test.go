@@ -0,0 +1,320 @@
+package permission
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSmartPermissionService_ConcurrentSavesKeepFileValid(t *testing.T) {
+	dir := t.TempDir()
+	service := NewSmartPermissionService(NewPermissionService(dir, true, nil), dir, true)
+
+	const workers = 8
+	const decisions = 50
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Go(func() {
+			for i := range decisions {
+				service.learnFromDecision(CreatePermissionRequest{
+					ToolName: fmt.Sprintf("tool-%d", w),
+					Action:   "write",
+					Path:     filepath.Join(dir, fmt.Sprintf("file-%d.txt", i%5)),
+				}, i%7 != 0)
+			}
+		})
+	}
+
+	// Readers must never observe a partially written file
+	done := make(chan struct{})
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			data, err := os.ReadFile(service.learningFile)
+			if err != nil {
+				continue
+			}
+			var patterns map[string]*SmartPermissionPattern
+			assert.NoError(t, json.Unmarshal(data, &patterns), "permission patterns file is not valid JSON")
+		}
+	}()
+
+	wg.Wait()
+	service.pendingSaves.Wait()
+	close(done)
+	<-readerDone
+
+	data, err := os.ReadFile(service.learningFile)
+	require.NoError(t, err)
+	var patterns map[string]*SmartPermissionPattern
+	require.NoError(t, json.Unmarshal(data, &patterns))
+	require.Len(t, patterns, workers*5)
+
+	total := 0
+	for _, pattern := range patterns {
+		total += pattern.ApprovalCount + pattern.DenialCount
+	}
+	require.Equal(t, workers*decisions, total, "the last save must contain every decision")
+
+	_, err = os.Stat(service.learningFile + ".tmp")
+	require.True(t, os.IsNotExist(err), "temporary file should be renamed into place")
+
+	reloaded := NewSmartPermissionService(NewPermissionService(dir, true, nil), dir, true)
+	require.Len(t, reloaded.patterns, workers*5)
+}
+
+func TestSmartPermissionService_SetConfidenceThreshold(t *testing.T) {
+	dir := t.TempDir()
+	service := NewSmartPermissionService(NewPermissionService(dir, true, nil), dir, true)
+	t.Cleanup(service.pendingSaves.Wait)
+
+	request := CreatePermissionRequest{ToolName: "edit", Action: "write", Path: filepath.Join(dir, "main.go")}
+	for range 3 {
+		service.learnFromDecision(request, true)
+	}
+
+	pattern, ok := service.GetPattern(request.ToolName, request.Action, request.Path)
+	require.True(t, ok)
+	require.Equal(t, 3, pattern.ApprovalCount)
+	require.InDelta(t, 1.0, pattern.Confidence, 1e-9)
+	require.True(t, pattern.AutoApprove)
+	require.True(t, service.shouldAutoApprove(request))
+
+	// Three approvals are not enough once more certainty than the raw approval rate is required
+	service.SetConfidenceThreshold(1.05)
+	require.Equal(t, 1.05, service.ConfidenceThreshold())
+	pattern, ok = service.GetPattern(request.ToolName, request.Action, request.Path)
+	require.True(t, ok)
+	require.False(t, pattern.AutoApprove)
+	require.False(t, service.shouldAutoApprove(request))
+
+	// Two more approvals earn the sample size bonus
+	for range 2 {
+		service.learnFromDecision(request, true)
+	}
+	require.True(t, service.shouldAutoApprove(request))
+
+	service.SetConfidenceThreshold(1.5)
+	require.False(t, service.shouldAutoApprove(request))
+
+	service.SetConfidenceThreshold(0.8)
+	require.True(t, service.shouldAutoApprove(request))
+
+	service.SetConfidenceThreshold(-1)
+	require.Equal(t, 0.0, service.ConfidenceThreshold())
+}
+
+func TestSmartPermissionService_GetPattern(t *testing.T) {
+	dir := t.TempDir()
+	service := NewSmartPermissionService(NewPermissionService(dir, true, nil), dir, true)
+	t.Cleanup(service.pendingSaves.Wait)
+
+	_, ok := service.GetPattern("edit", "write", filepath.Join(dir, "main.go"))
+	require.False(t, ok)
+
+	request := CreatePermissionRequest{ToolName: "edit", Action: "write", Path: filepath.Join(dir, "main.go")}
+	service.learnFromDecision(request, true)
+	service.learnFromDecision(request, false)
+
+	pattern, ok := service.GetPattern("edit", "write", filepath.Join(dir, "main.go"))
+	require.True(t, ok)
+	require.Equal(t, 1, pattern.ApprovalCount)
+	require.Equal(t, 1, pattern.DenialCount)
+	// The later denial outweighs the earlier approval
+	require.InDelta(t, recencyDecay/(1+recencyDecay), pattern.Confidence, 1e-9)
+	require.False(t, pattern.AutoApprove)
+
+	// The returned pattern is a copy
+	pattern.AutoApprove = true
+	require.False(t, service.shouldAutoApprove(request))
+
+	_, ok = service.GetPattern("edit", "read", filepath.Join(dir, "main.go"))
+	require.False(t, ok)
+}
+
+func TestSmartPermissionService_RecentDenialsDisableAutoApprove(t *testing.T) {
+	dir := t.TempDir()
+	service := NewSmartPermissionService(NewPermissionService(dir, true, nil), dir, true)
+	t.Cleanup(service.pendingSaves.Wait)
+
+	request := CreatePermissionRequest{ToolName: "bash", Action: "execute", Path: dir}
+	for range 10 {
+		service.learnFromDecision(request, true)
+	}
+	pattern, ok := service.GetPattern(request.ToolName, request.Action, request.Path)
+	require.True(t, ok)
+	require.GreaterOrEqual(t, pattern.Confidence, service.ConfidenceThreshold())
+	require.True(t, service.shouldAutoApprove(request))
+
+	// Two denials are a small share of the lifetime decisions but weigh the
+	// most, so they bring confidence below the threshold
+	for range 2 {
+		service.learnFromDecision(request, false)
+	}
+	pattern, ok = service.GetPattern(request.ToolName, request.Action, request.Path)
+	require.True(t, ok)
+	require.Equal(t, 10, pattern.ApprovalCount)
+	require.Equal(t, 2, pattern.DenialCount)
+	require.Len(t, pattern.RecentDecisions, recentDecisionLimit)
+	require.Less(t, pattern.Confidence, service.ConfidenceThreshold())
+	require.False(t, pattern.AutoApprove)
+	require.False(t, service.shouldAutoApprove(request))
+
+	// Once the denials leave the recent decisions, confidence recovers but a
+	// pattern that was ever denied is not auto-approved again
+	for range recentDecisionLimit {
+		service.learnFromDecision(request, true)
+	}
+	pattern, ok = service.GetPattern(request.ToolName, request.Action, request.Path)
+	require.True(t, ok)
+	require.Equal(t, 2, pattern.DenialCount)
+	require.GreaterOrEqual(t, pattern.Confidence, service.ConfidenceThreshold())
+	require.False(t, pattern.AutoApprove)
+	require.False(t, service.shouldAutoApprove(request))
+}
+
+func TestSmartPermissionPattern_ApprovalRateWithoutRecentDecisions(t *testing.T) {
+	// Patterns saved before recent decisions were recorded use lifetime counts
+	pattern := &SmartPermissionPattern{ApprovalCount: 3, DenialCount: 1}
+	require.InDelta(t, 0.75, pattern.approvalRate(), 1e-9)
+}
+
+func TestSmartPermissionService_RevokePattern(t *testing.T) {
+	dir := t.TempDir()
+	service := NewSmartPermissionService(NewPermissionService(dir, true, nil), dir, true)
+	t.Cleanup(service.pendingSaves.Wait)
+
+	edit := CreatePermissionRequest{ToolName: "edit", Action: "write", Path: filepath.Join(dir, "main.go")}
+	bash := CreatePermissionRequest{ToolName: "bash", Action: "execute", Path: dir}
+	for range 3 {
+		service.learnFromDecision(edit, true)
+		service.learnFromDecision(bash, true)
+	}
+
+	patterns := service.Patterns()
+	require.Len(t, patterns, 2)
+	require.Equal(t, "bash", patterns[0].ToolName)
+	require.Equal(t, "edit", patterns[1].ToolName)
+	require.True(t, service.shouldAutoApprove(edit))
+
+	require.True(t, service.RevokePattern(patterns[1].ToolName, patterns[1].Action, patterns[1].PathPattern))
+	require.False(t, service.shouldAutoApprove(edit))
+	require.True(t, service.shouldAutoApprove(bash))
+	require.Len(t, service.Patterns(), 1)
+
+	require.False(t, service.RevokePattern("edit", "write", patterns[1].PathPattern), "already revoked")
+}
+
+func TestSmartPermissionService_DenyRulesOverrideLearnedApproval(t *testing.T) {
+	dir := t.TempDir()
+	// The base service approves everything, so only a deny rule can refuse
+	service := NewSmartPermissionService(NewPermissionService(dir, true, nil), dir, true)
+	t.Cleanup(service.pendingSaves.Wait)
+
+	secret := CreatePermissionRequest{ToolName: "edit", Action: "write", Path: filepath.Join(dir, "secrets", "prod.env")}
+	other := CreatePermissionRequest{ToolName: "edit", Action: "write", Path: filepath.Join(dir, "main.go")}
+	for range 10 {
+		service.learnFromDecision(secret, true)
+		service.learnFromDecision(other, true)
+	}
+	require.True(t, service.shouldAutoApprove(secret))
+	require.True(t, service.Request(secret))
+
+	require.NoError(t, service.SetDenyRules([]string{"*:write:" + filepath.ToSlash(dir) + "/secrets/**"}))
+	require.False(t, service.Request(secret), "a matching rule must win over a learned approval")
+	require.True(t, service.Request(other))
+
+	// Denials by rule are not learned from
+	pattern, ok := service.GetPattern(secret.ToolName, secret.Action, secret.Path)
+	require.True(t, ok)
+	require.Zero(t, pattern.DenialCount)
+	require.True(t, pattern.AutoApprove)
+
+	require.NoError(t, service.SetDenyRules(nil))
+	require.True(t, service.Request(secret))
+}
+
+func TestSmartPermissionService_DenyRulesApplyWithoutLearning(t *testing.T) {
+	dir := t.TempDir()
+	service := NewSmartPermissionService(NewPermissionService(dir, true, nil), dir, false)
+
+	require.NoError(t, service.SetDenyRules([]string{"re:^bash:", "*:write:/etc/**"}))
+	require.Equal(t, []string{"re:^bash:", "*:write:/etc/**"}, service.DenyRules())
+
+	require.False(t, service.Request(CreatePermissionRequest{ToolName: "bash", Action: "execute", Path: dir}))
+	require.False(t, service.Request(CreatePermissionRequest{ToolName: "edit", Action: "write", Path: "/etc/nginx/nginx.conf"}))
+	require.True(t, service.Request(CreatePermissionRequest{ToolName: "edit", Action: "read", Path: "/etc/hosts"}))
+	require.True(t, service.Request(CreatePermissionRequest{ToolName: "edit", Action: "write", Path: "/etcetera/file"}))
+}
+
+func TestSmartPermissionService_DenyRulesMatchCommands(t *testing.T) {
+	dir := t.TempDir()
+	service := NewSmartPermissionService(NewPermissionService(dir, true, nil), dir, true)
+	require.NoError(t, service.SetDenyRules([]string{`re:^bash:execute:rm\b`}))
+
+	bash := func(command string) CreatePermissionRequest {
+		return CreatePermissionRequest{
+			ToolName:    "bash",
+			Action:      "execute",
+			Path:        dir,
+			Description: "Execute command: " + command,
+			Params: struct {
+				Command string `json:"command"`
+			}{Command: command},
+		}
+	}
+	require.False(t, service.Request(bash("rm -rf build")))
+	require.True(t, service.Request(bash("ls -la")))
+	require.Equal(t, []string{"bash:execute:" + filepath.ToSlash(dir), "bash:execute:rm -rf build"}, denyRuleTargets(bash("rm -rf build")))
+	require.Len(t, denyRuleTargets(CreatePermissionRequest{ToolName: "edit", Action: "write", Path: dir}), 1)
+}
+
+func TestCompileDenyRule(t *testing.T) {
+	tests := []struct {
+		rule   string
+		target string
+		match  bool
+	}{
+		{"edit:write:/tmp/*.go", "edit:write:/tmp/main.go", true},
+		{"edit:write:/tmp/*.go", "edit:write:/tmp/pkg/main.go", false},
+		{"edit:write:/tmp/**.go", "edit:write:/tmp/pkg/main.go", true},
+		{"*:*:/tmp/?.txt", "view:read:/tmp/a.txt", true},
+		{"*:*:/tmp/?.txt", "view:read:/tmp/ab.txt", false},
+		{"edit:write:/tmp/a+b", "edit:write:/tmp/a+b", true},
+		{"edit:write:/tmp/a+b", "edit:write:/tmp/aab", false},
+		{"re:rm -rf", "bash:execute:/home/me", false},
+		{"re:rm -rf", "bash:execute:rm -rf build", true},
+		{"bash:execute:rm **", "bash:execute:rm -rf /tmp/out", true},
+		{"re:\\.env$", "edit:write:/srv/app/.env", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule+"/"+tt.target, func(t *testing.T) {
+			rule, err := compileDenyRule(tt.rule)
+			require.NoError(t, err)
+			require.Equal(t, tt.match, rule.pattern.MatchString(tt.target))
+		})
+	}
+
+	_, err := compileDenyRule("re:[")
+	require.ErrorContains(t, err, `invalid deny rule "re:["`)
+	_, err = compileDenyRule("")
+	require.Error(t, err)
+
+	service := NewSmartPermissionService(NewPermissionService(t.TempDir(), true, nil), t.TempDir(), false)
+	require.NoError(t, service.SetDenyRules([]string{"re:^bash:"}))
+	require.Error(t, service.SetDenyRules([]string{"view:*:*", "re:("}))
+	require.Equal(t, []string{"re:^bash:"}, service.DenyRules(), "a failed update keeps the previous rules")
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const (
+	// defaultMaxChatBodyBytes bounds chat, session and permission request bodies
+	defaultMaxChatBodyBytes = 1 << 20
+	// defaultMaxDockerBodyBytes bounds docker request bodies, which may carry whole file maps
+	defaultMaxDockerBodyBytes = 32 << 20
+)
+
+// decodeJSONBody decodes the JSON request body into v, reading at most limit
+// bytes. It writes a 413 response when the body is too large and a 400
+// response when it is not valid JSON, and reports whether decoding succeeded.
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, limit int64, v any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit))
+			return false
+		}
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return false
+	}
+	return true
+}
+
+// ErrorResponse is the body of every API error response
+type ErrorResponse struct {
+	Error  string `json:"error"`
+	Status int    `json:"status"`
+}
+
+// writeJSONError replies with status and an ErrorResponse carrying message,
+// so API clients can always parse responses as JSON
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: message, Status: status})
+}
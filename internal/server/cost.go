@@ -0,0 +1,79 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/charmbracelet/crush/internal/llm/agent"
+)
+
+// costReporter is implemented by agents that track the token usage of each
+// session and the session budget
+type costReporter interface {
+	SessionUsage(sessionID string) agent.SessionUsage
+	SessionBudget() float64
+}
+
+// Cost API endpoint. GET reports how much a session has cost, for a live cost
+// meter in the UI.
+func (s *WebServer) handleCost(w http.ResponseWriter, r *http.Request) {
+	s.setCORSHeaders(w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if r.Method != "GET" {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	sessionID := r.URL.Query().Get("session_id")
+	if sessionID == "" {
+		writeJSONError(w, http.StatusBadRequest, "session_id is required")
+		return
+	}
+
+	sess, err := s.sessions.Get(r.Context(), sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("Session not found: %s", sessionID))
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Error loading session: %v", err))
+		return
+	}
+
+	// The stored cost covers every run of the session, while token counts are
+	// only tracked by the running agent
+	resp := CostResponse{SessionID: sess.ID, Cost: sess.Cost}
+	if reporter, ok := s.agent.(costReporter); ok {
+		usage := reporter.SessionUsage(sess.ID)
+		resp.InputTokens = usage.InputTokens
+		resp.OutputTokens = usage.OutputTokens
+		resp.CacheCreationTokens = usage.CacheCreationTokens
+		resp.CacheReadTokens = usage.CacheReadTokens
+		resp.Budget = reporter.SessionBudget()
+	}
+	if resp.Budget > 0 {
+		remaining := max(resp.Budget-resp.Cost, 0)
+		resp.Remaining = &remaining
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+type CostResponse struct {
+	SessionID           string  `json:"session_id"`
+	InputTokens         int64   `json:"input_tokens"`
+	OutputTokens        int64   `json:"output_tokens"`
+	CacheCreationTokens int64   `json:"cache_creation_tokens"`
+	CacheReadTokens     int64   `json:"cache_read_tokens"`
+	Cost                float64 `json:"cost"`
+	// Budget is the maximum cost of a session, or 0 when there is none
+	Budget float64 `json:"budget"`
+	// Remaining is how much of the budget is left, omitted without a budget
+	Remaining *float64 `json:"remaining,omitempty"`
+}
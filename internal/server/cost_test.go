@@ -0,0 +1,87 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/stretchr/testify/require"
+)
+
+// costAgent reports fixed usage for every session
+type costAgent struct {
+	agent.Service
+	usage  agent.SessionUsage
+	budget float64
+}
+
+func (a *costAgent) SessionUsage(string) agent.SessionUsage {
+	return a.usage
+}
+
+func (a *costAgent) SessionBudget() float64 {
+	return a.budget
+}
+
+func TestHandleCost(t *testing.T) {
+	t.Parallel()
+
+	sessions := newTestSessions(t)
+	sess, err := sessions.Create(t.Context(), "Costly")
+	require.NoError(t, err)
+	sess.Cost = 1.25
+	_, err = sessions.Save(t.Context(), sess)
+	require.NoError(t, err)
+
+	fake := &costAgent{
+		usage:  agent.SessionUsage{InputTokens: 120_000, OutputTokens: 8_000, CacheReadTokens: 40_000, Cost: 1.25},
+		budget: 2,
+	}
+	handler, err := NewWebServer("", 0, fake, sessions, nil).Handler()
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/cost?session_id="+sess.ID, nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &payload))
+	require.Equal(t, map[string]any{
+		"session_id":            sess.ID,
+		"input_tokens":          120_000.0,
+		"output_tokens":         8_000.0,
+		"cache_creation_tokens": 0.0,
+		"cache_read_tokens":     40_000.0,
+		"cost":                  1.25,
+		"budget":                2.0,
+		"remaining":             0.75,
+	}, payload)
+
+	// Without a budget there is no headroom to report
+	fake.budget = 0
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/cost?session_id="+sess.ID, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp CostResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Zero(t, resp.Budget)
+	require.Nil(t, resp.Remaining)
+}
+
+func TestHandleCostUnknownSession(t *testing.T) {
+	t.Parallel()
+
+	handler, err := NewWebServer("", 0, &costAgent{}, newTestSessions(t), nil).Handler()
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/cost?session_id=missing", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Contains(t, rec.Body.String(), "Session not found: missing")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/cost", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
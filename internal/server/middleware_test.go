@@ -0,0 +1,67 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLoggingMiddleware(t *testing.T) {
+	t.Parallel()
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	var contextID string
+	handler := withRequestLogging(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contextID = RequestIDFromContext(r.Context())
+		http.Error(w, "missing", http.StatusNotFound)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/docker", nil))
+
+	requestID := rec.Header().Get(RequestIDHeader)
+	require.NotEmpty(t, requestID)
+	require.Equal(t, requestID, contextID)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+	require.Equal(t, "HTTP request", entry["msg"])
+	require.Equal(t, requestID, entry["request_id"])
+	require.Equal(t, http.MethodPost, entry["method"])
+	require.Equal(t, "/api/docker", entry["path"])
+	require.EqualValues(t, http.StatusNotFound, entry["status"])
+	require.Contains(t, entry, "duration")
+}
+
+func TestRequestLoggingDefaultsToOK(t *testing.T) {
+	t.Parallel()
+
+	var logs bytes.Buffer
+	server := NewWebServer("", 0, nil, nil, nil)
+	server.logger = slog.New(slog.NewJSONHandler(&logs, nil))
+
+	handler, err := server.Handler()
+	require.NoError(t, err)
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+	second := httptest.NewRecorder()
+	handler.ServeHTTP(second, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+
+	require.Equal(t, http.StatusOK, first.Code)
+	require.NotEqual(t, first.Header().Get(RequestIDHeader), second.Header().Get(RequestIDHeader))
+
+	var entry map[string]any
+	line, _, _ := bytes.Cut(logs.Bytes(), []byte("\n"))
+	require.NoError(t, json.Unmarshal(line, &entry))
+	require.EqualValues(t, http.StatusOK, entry["status"])
+	require.Equal(t, "/api/health", entry["path"])
+}
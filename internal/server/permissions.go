@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/charmbracelet/crush/internal/permission"
+)
+
+// permissionLearner is implemented by permission services that learn which
+// requests to auto-approve, such as permission.SmartPermissionService
+type permissionLearner interface {
+	GetLearningStats() map[string]interface{}
+	SuggestAutoApproval() []string
+	Patterns() []permission.SmartPermissionPattern
+	ClearLearning() error
+	RevokePattern(toolName, action, pathPattern string) bool
+}
+
+// Permissions API endpoint. GET reports what has been learned; POST clears
+// all learning or revokes a single pattern.
+func (s *WebServer) handlePermissions(w http.ResponseWriter, r *http.Request) {
+	s.setCORSHeaders(w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	learner, ok := s.permissions.(permissionLearner)
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "Permission learning is not enabled")
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		suggestions := learner.SuggestAutoApproval()
+		if suggestions == nil {
+			suggestions = []string{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(PermissionsResponse{
+			Stats:       learner.GetLearningStats(),
+			Suggestions: suggestions,
+			Patterns:    learner.Patterns(),
+		})
+
+	case "POST":
+		var req PermissionsRequest
+		if !decodeJSONBody(w, r, s.maxChatBodyBytes, &req) {
+			return
+		}
+
+		switch req.Operation {
+		case "clear":
+			if err := learner.ClearLearning(); err != nil {
+				writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Error clearing learned permissions: %v", err))
+				return
+			}
+		case "revoke":
+			if req.ToolName == "" || req.Action == "" {
+				writeJSONError(w, http.StatusBadRequest, "tool_name and action are required to revoke a pattern")
+				return
+			}
+			if !learner.RevokePattern(req.ToolName, req.Action, req.PathPattern) {
+				writeJSONError(w, http.StatusNotFound, "Pattern not found")
+				return
+			}
+		default:
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Unknown operation %q: use clear or revoke", req.Operation))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":   true,
+			"operation": req.Operation,
+		})
+
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+type PermissionsResponse struct {
+	Stats       map[string]interface{}              `json:"stats"`
+	Suggestions []string                            `json:"suggestions"`
+	Patterns    []permission.SmartPermissionPattern `json:"patterns"`
+}
+
+type PermissionsRequest struct {
+	Operation   string `json:"operation"` // "clear" or "revoke"
+	ToolName    string `json:"tool_name,omitempty"`
+	Action      string `json:"action,omitempty"`
+	PathPattern string `json:"path_pattern,omitempty"`
+}
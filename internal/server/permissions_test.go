@@ -0,0 +1,154 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/stretchr/testify/require"
+)
+
+const testAuthToken = "secret-token"
+
+// newPermissionsTestServer returns a handler backed by a smart permission
+// service that has learned to auto-approve edits
+func newPermissionsTestServer(t *testing.T) (http.Handler, *permission.SmartPermissionService) {
+	t.Helper()
+
+	dir := t.TempDir()
+	smart := permission.NewSmartPermissionService(permission.NewPermissionService(dir, true, nil), dir, false)
+	for range 6 {
+		smart.Request(permission.CreatePermissionRequest{ToolName: "edit", Action: "write", Path: filepath.Join(dir, "main.go")})
+	}
+
+	server := NewWebServer("", 0, nil, nil, smart)
+	server.SetAuthToken(testAuthToken)
+	handler, err := server.Handler()
+	require.NoError(t, err)
+	return handler, smart
+}
+
+func permissionsRequest(method, body, token string) *http.Request {
+	req := httptest.NewRequest(method, "/api/permissions", strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func TestPermissionsEndpointRequiresAuth(t *testing.T) {
+	t.Parallel()
+
+	handler, _ := newPermissionsTestServer(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, permissionsRequest(http.MethodGet, "", ""))
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, permissionsRequest(http.MethodGet, "", "wrong"))
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Without a configured token the endpoint is disabled
+	server := NewWebServer("", 0, nil, nil, permission.NewPermissionService(t.TempDir(), true, nil))
+	unauthenticated, err := server.Handler()
+	require.NoError(t, err)
+	rec = httptest.NewRecorder()
+	unauthenticated.ServeHTTP(rec, permissionsRequest(http.MethodGet, "", ""))
+	require.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestPermissionsEndpointGet(t *testing.T) {
+	t.Parallel()
+
+	handler, _ := newPermissionsTestServer(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, permissionsRequest(http.MethodGet, "", testAuthToken))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var payload map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &payload))
+	require.ElementsMatch(t, []string{"stats", "suggestions", "patterns"}, mapKeys(payload))
+
+	var resp PermissionsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, false, resp.Stats["enabled"])
+	require.EqualValues(t, 0, resp.Stats["total_patterns"], "learning is disabled, so nothing is recorded")
+	require.Empty(t, resp.Suggestions)
+	require.NotNil(t, resp.Suggestions, "suggestions encode as an empty list")
+	require.Empty(t, resp.Patterns)
+}
+
+func TestPermissionsEndpointRevokeAndClear(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	smart := permission.NewSmartPermissionService(permission.NewPermissionService(dir, true, nil), dir, true)
+	// Keep every request going to the base service so each one is learned from
+	smart.SetConfidenceThreshold(2)
+	for range 6 {
+		smart.Request(permission.CreatePermissionRequest{ToolName: "edit", Action: "write", Path: filepath.Join(dir, "main.go")})
+		smart.Request(permission.CreatePermissionRequest{ToolName: "bash", Action: "execute", Path: dir})
+	}
+	server := NewWebServer("", 0, nil, nil, smart)
+	server.SetAuthToken(testAuthToken)
+	handler, err := server.Handler()
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, permissionsRequest(http.MethodGet, "", testAuthToken))
+	var resp PermissionsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.EqualValues(t, 2, resp.Stats["total_patterns"])
+	require.Len(t, resp.Suggestions, 2)
+	require.Len(t, resp.Patterns, 2)
+
+	edit := resp.Patterns[1]
+	require.Equal(t, "edit", edit.ToolName)
+	body, err := json.Marshal(PermissionsRequest{Operation: "revoke", ToolName: edit.ToolName, Action: edit.Action, PathPattern: edit.PathPattern})
+	require.NoError(t, err)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, permissionsRequest(http.MethodPost, string(body), testAuthToken))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Len(t, smart.Patterns(), 1)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, permissionsRequest(http.MethodPost, string(body), testAuthToken))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, permissionsRequest(http.MethodPost, `{"operation":"forget"}`, testAuthToken))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, permissionsRequest(http.MethodPost, `{"operation":"clear"}`, testAuthToken))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Empty(t, smart.Patterns())
+}
+
+func TestPermissionsEndpointWithoutLearning(t *testing.T) {
+	t.Parallel()
+
+	server := NewWebServer("", 0, nil, nil, permission.NewPermissionService(t.TempDir(), true, nil))
+	server.SetAuthToken(testAuthToken)
+	handler, err := server.Handler()
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, permissionsRequest(http.MethodGet, "", testAuthToken))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func mapKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
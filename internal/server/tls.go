@@ -0,0 +1,82 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+)
+
+// TLSOptions configures HTTPS for the web server. Either both CertFile and
+// KeyFile are set, or SelfSigned generates a throwaway certificate for local
+// development.
+type TLSOptions struct {
+	CertFile   string
+	KeyFile    string
+	SelfSigned bool
+}
+
+// Enabled reports whether the options turn on TLS
+func (o TLSOptions) Enabled() bool {
+	return o.SelfSigned || o.CertFile != "" || o.KeyFile != ""
+}
+
+// Validate checks that the options are consistent and the certificate files load
+func (o TLSOptions) Validate() error {
+	if o.SelfSigned {
+		if o.CertFile != "" || o.KeyFile != "" {
+			return errors.New("--tls-self-signed cannot be combined with --tls-cert or --tls-key")
+		}
+		return nil
+	}
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return errors.New("both --tls-cert and --tls-key must be provided to enable TLS")
+	}
+	if o.CertFile == "" {
+		return nil
+	}
+	if _, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile); err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	return nil
+}
+
+// selfSignedCertificate generates a certificate for localhost that is valid
+// for one year
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"Crush"}, CommonName: "localhost"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
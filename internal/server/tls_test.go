@@ -0,0 +1,125 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeCertificate writes a self-signed certificate and key as PEM files
+func writeCertificate(t *testing.T) (string, string) {
+	t.Helper()
+
+	cert, err := selfSignedCertificate()
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestTLSOptionsValidate(t *testing.T) {
+	t.Parallel()
+
+	certFile, keyFile := writeCertificate(t)
+
+	require.NoError(t, TLSOptions{}.Validate())
+	require.NoError(t, TLSOptions{CertFile: certFile, KeyFile: keyFile}.Validate())
+	require.NoError(t, TLSOptions{SelfSigned: true}.Validate())
+
+	err := TLSOptions{CertFile: certFile}.Validate()
+	require.ErrorContains(t, err, "both --tls-cert and --tls-key")
+	err = TLSOptions{KeyFile: keyFile}.Validate()
+	require.ErrorContains(t, err, "both --tls-cert and --tls-key")
+	err = TLSOptions{SelfSigned: true, CertFile: certFile, KeyFile: keyFile}.Validate()
+	require.ErrorContains(t, err, "cannot be combined")
+	err = TLSOptions{CertFile: keyFile, KeyFile: keyFile}.Validate()
+	require.ErrorContains(t, err, "failed to load TLS certificate")
+
+	server := NewWebServer("", 0, nil, nil, nil)
+	require.Error(t, server.SetTLS(TLSOptions{CertFile: certFile}))
+	require.False(t, server.tls.Enabled())
+}
+
+// serveTestServer serves the web server on a loopback port and returns its address
+func serveTestServer(t *testing.T, server *WebServer) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	handler, err := server.Handler()
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() { done <- server.serve(listener, handler) }()
+	t.Cleanup(func() {
+		listener.Close()
+		if err := <-done; err != nil && !errors.Is(err, net.ErrClosed) {
+			t.Errorf("serve returned: %v", err)
+		}
+	})
+	return listener.Addr().String()
+}
+
+func TestWebServerServesTLSWhenConfigured(t *testing.T) {
+	t.Parallel()
+
+	certFile, keyFile := writeCertificate(t)
+
+	tests := []struct {
+		name string
+		opts TLSOptions
+	}{
+		{"certificate files", TLSOptions{CertFile: certFile, KeyFile: keyFile}},
+		{"self-signed", TLSOptions{SelfSigned: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := NewWebServer("", 0, nil, nil, nil)
+			require.NoError(t, server.SetTLS(tt.opts))
+			addr := serveTestServer(t, server)
+
+			client := &http.Client{Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			}}
+			resp, err := client.Get("https://" + addr + "/api/health")
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			require.NotNil(t, resp.TLS)
+
+			// Plain HTTP is rejected
+			resp, err = http.Get("http://" + addr + "/api/health")
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		})
+	}
+}
+
+func TestWebServerServesPlainHTTPByDefault(t *testing.T) {
+	t.Parallel()
+
+	addr := serveTestServer(t, NewWebServer("", 0, nil, nil, nil))
+
+	resp, err := http.Get("http://" + addr + "/api/health")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Nil(t, resp.TLS)
+}
@@ -2,19 +2,26 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"database/sql"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/crush/internal/llm/agent"
 	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/session"
 )
@@ -22,34 +29,148 @@ import (
 //go:embed web/build/*
 var webFS embed.FS
 
+// DefaultHost is the address the web server binds to unless configured otherwise.
+// Loopback keeps the UI, which can build and run arbitrary containers, off the network.
+const DefaultHost = "127.0.0.1"
+
 type WebServer struct {
+	host        string
 	port        int
 	agent       agent.Service
 	sessions    session.Service
+	messages    message.Service
 	permissions permission.Service
+	logger      *slog.Logger
+	tls         TLSOptions
+	authToken   string
+
+	maxChatBodyBytes   int64
+	maxDockerBodyBytes int64
 }
 
-func NewWebServer(port int, agentService agent.Service, sessions session.Service, permissions permission.Service) *WebServer {
+func NewWebServer(host string, port int, agentService agent.Service, sessions session.Service, permissions permission.Service) *WebServer {
+	if host == "" {
+		host = DefaultHost
+	}
 	return &WebServer{
+		host:        host,
 		port:        port,
 		agent:       agentService,
 		sessions:    sessions,
 		permissions: permissions,
+		logger:      slog.Default(),
+
+		maxChatBodyBytes:   defaultMaxChatBodyBytes,
+		maxDockerBodyBytes: defaultMaxDockerBodyBytes,
+	}
+}
+
+// SetTLS configures the server to serve HTTPS
+func (s *WebServer) SetTLS(opts TLSOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	s.tls = opts
+	return nil
+}
+
+// SetAuthToken sets the bearer token required by authenticated API routes.
+// Those routes are disabled while no token is set.
+func (s *WebServer) SetAuthToken(token string) {
+	s.authToken = token
+}
+
+// SetBodyLimits sets the maximum request body sizes in bytes for docker
+// requests and for all other API requests. Zero keeps the current limit.
+func (s *WebServer) SetBodyLimits(chat, docker int64) {
+	if chat > 0 {
+		s.maxChatBodyBytes = chat
+	}
+	if docker > 0 {
+		s.maxDockerBodyBytes = docker
 	}
 }
 
 func (s *WebServer) Start() error {
+	handler, err := s.Handler()
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", s.listenAddr())
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.listenAddr(), err)
+	}
+
+	slog.Info("Starting web server", "address", listener.Addr().String(), "url", s.url(listener.Addr()), "tls", s.tls.Enabled())
+	return s.serve(listener, handler)
+}
+
+// listenAddr returns the host:port address the server binds to
+func (s *WebServer) listenAddr() string {
+	return net.JoinHostPort(s.host, strconv.Itoa(s.port))
+}
+
+// url returns the address users can open in a browser for the bound address
+func (s *WebServer) url(addr net.Addr) string {
+	scheme := "http"
+	if s.tls.Enabled() {
+		scheme = "https"
+	}
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return fmt.Sprintf("%s://%s", scheme, addr)
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	}
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, port))
+}
+
+// serve serves handler on listener, over TLS when it is configured
+func (s *WebServer) serve(listener net.Listener, handler http.Handler) error {
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	switch {
+	case s.tls.SelfSigned:
+		cert, err := selfSignedCertificate()
+		if err != nil {
+			listener.Close()
+			return fmt.Errorf("failed to generate self-signed certificate: %w", err)
+		}
+		slog.Warn("Serving with a self-signed certificate; browsers will show a warning. Use --tls-cert and --tls-key for real deployments")
+		srv.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+		return srv.ServeTLS(listener, "", "")
+	case s.tls.CertFile != "":
+		srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		return srv.ServeTLS(listener, s.tls.CertFile, s.tls.KeyFile)
+	default:
+		return srv.Serve(listener)
+	}
+}
+
+// Handler returns the HTTP handler serving the web UI and API routes, with
+// request logging applied to every route
+func (s *WebServer) Handler() (http.Handler, error) {
 	// Serve static files from embedded web build
 	webBuildFS, err := fs.Sub(webFS, "web/build")
 	if err != nil {
-		return fmt.Errorf("failed to create web filesystem: %w", err)
+		return nil, fmt.Errorf("failed to create web filesystem: %w", err)
 	}
 
 	// Create file server for static assets
 	fileServer := http.FileServer(http.FS(webBuildFS))
 
+	mux := http.NewServeMux()
+
 	// Handle routes
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		// Security headers
 		w.Header().Set("X-Frame-Options", "DENY")
 		w.Header().Set("X-Content-Type-Options", "nosniff")
@@ -88,13 +209,15 @@ func (s *WebServer) Start() error {
 	})
 
 	// API routes
-	http.HandleFunc("/api/chat", s.handleChat)
-	http.HandleFunc("/api/docker", s.handleDocker)
-	http.HandleFunc("/api/sessions", s.handleSessions)
-	http.HandleFunc("/api/health", s.handleHealth)
-
-	slog.Info("Starting web server", "port", s.port, "url", fmt.Sprintf("http://localhost:%d", s.port))
-	return http.ListenAndServe(fmt.Sprintf(":%d", s.port), nil)
+	mux.HandleFunc("/api/chat", s.handleChat)
+	mux.HandleFunc("/api/ws", s.handleWebSocket)
+	mux.HandleFunc("/api/docker", s.handleDocker)
+	mux.HandleFunc("/api/sessions", s.handleSessions)
+	mux.HandleFunc("/api/cost", s.handleCost)
+	mux.HandleFunc("/api/health", s.handleHealth)
+	mux.Handle("/api/permissions", requireAuth(s.authToken, http.HandlerFunc(s.handlePermissions)))
+
+	return withRequestLogging(s.logger, mux), nil
 }
 
 // Chat API endpoint
@@ -105,30 +228,41 @@ func (s *WebServer) handleChat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	var chatReq ChatRequest
-	if err := json.NewDecoder(r.Body).Decode(&chatReq); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSONBody(w, r, s.maxChatBodyBytes, &chatReq) {
+		return
+	}
+	if strings.TrimSpace(chatReq.Message) == "" {
+		writeJSONError(w, http.StatusBadRequest, "Message is required")
 		return
 	}
 
 	// Create or get session
-	sessionID := chatReq.SessionID
-	if sessionID == "" {
-		sessionID = "web-session-" + fmt.Sprintf("%d", time.Now().Unix())
+	ctx := context.Background()
+	sessionID, err := s.chatSession(ctx, chatReq.SessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("Session not found: %s", chatReq.SessionID))
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Error creating session: %v", err))
+		return
 	}
 
 	// Create context
-	ctx := context.Background()
 	ctx = context.WithValue(ctx, tools.SessionIDContextKey, sessionID)
+	if chatReq.NoCache {
+		ctx = agent.WithCacheBypass(ctx)
+	}
 
 	// Send message to agent
 	eventChan, err := s.agent.Run(ctx, sessionID, chatReq.Message)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Agent error: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Agent error: %v", err))
 		return
 	}
 
@@ -136,7 +270,7 @@ func (s *WebServer) handleChat(w http.ResponseWriter, r *http.Request) {
 	var responseContent string
 	for event := range eventChan {
 		if event.Error != nil {
-			http.Error(w, fmt.Sprintf("Agent error: %v", event.Error), http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Agent error: %v", event.Error))
 			return
 		}
 		if event.Type == agent.AgentEventTypeResponse {
@@ -155,6 +289,30 @@ func (s *WebServer) handleChat(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(chatResp)
 }
 
+// defaultSessionTitle is the title of sessions created without a name. The
+// agent replaces it with one generated from the first message.
+const defaultSessionTitle = "New Session"
+
+// chatSession returns the ID of the session a chat message is sent to. An
+// empty ID creates a new session, so web conversations are persisted and
+// listed like any other, while an ID that doesn't exist fails with
+// sql.ErrNoRows.
+func (s *WebServer) chatSession(ctx context.Context, sessionID string) (string, error) {
+	if sessionID == "" {
+		created, err := s.sessions.Create(ctx, defaultSessionTitle)
+		if err != nil {
+			return "", err
+		}
+		return created.ID, nil
+	}
+
+	existing, err := s.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return "", err
+	}
+	return existing.ID, nil
+}
+
 // Docker API endpoint
 func (s *WebServer) handleDocker(w http.ResponseWriter, r *http.Request) {
 	s.setCORSHeaders(w)
@@ -162,19 +320,40 @@ func (s *WebServer) handleDocker(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+	switch r.Method {
+	case "GET":
+		// List the app containers, for dashboards
+		s.runDocker(w, r.URL.Query().Get("session_id"), json.RawMessage(`{"action":"list"}`))
+	case "POST":
+		var dockerReq DockerRequest
+		if !decodeJSONBody(w, r, s.maxDockerBodyBytes, &dockerReq) {
+			return
+		}
 
-	var dockerReq DockerRequest
-	if err := json.NewDecoder(r.Body).Decode(&dockerReq); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
+		var params struct {
+			Action string `json:"action"`
+		}
+		if err := json.Unmarshal(dockerReq.Params, &params); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid params: %v", err))
+			return
+		}
+		if params.Action == "" {
+			writeJSONError(w, http.StatusBadRequest, "params.action is required")
+			return
+		}
+		if !slices.Contains(tools.DockerActions, params.Action) {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Unknown Docker action: %s (valid actions: %s)", params.Action, strings.Join(tools.DockerActions, ", ")))
+			return
+		}
+
+		s.runDocker(w, dockerReq.SessionID, dockerReq.Params)
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 	}
+}
 
-	// Create Docker tool call
-	sessionID := dockerReq.SessionID
+// runDocker runs the Docker tool with params and writes its result
+func (s *WebServer) runDocker(w http.ResponseWriter, sessionID string, params json.RawMessage) {
 	if sessionID == "" {
 		sessionID = "web-session-" + fmt.Sprintf("%d", time.Now().Unix())
 	}
@@ -189,12 +368,12 @@ func (s *WebServer) handleDocker(w http.ResponseWriter, r *http.Request) {
 	toolCall := tools.ToolCall{
 		ID:    fmt.Sprintf("docker-%d", time.Now().UnixNano()),
 		Name:  tools.DockerToolName,
-		Input: string(dockerReq.Params),
+		Input: string(params),
 	}
 
 	toolResponse, err := dockerTool.Run(ctx, toolCall)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Docker tool error: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Docker tool error: %v", err))
 		return
 	}
 
@@ -205,6 +384,10 @@ func (s *WebServer) handleDocker(w http.ResponseWriter, r *http.Request) {
 		Metadata:  toolResponse.Metadata,
 		Timestamp: time.Now(),
 	}
+	var metadata tools.DockerResponseMetadata
+	if toolResponse.Metadata != "" && json.Unmarshal([]byte(toolResponse.Metadata), &metadata) == nil && metadata.Action == "list" {
+		dockerResp.Containers = metadata.Containers
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(dockerResp)
@@ -222,7 +405,7 @@ func (s *WebServer) handleSessions(w http.ResponseWriter, r *http.Request) {
 		// List sessions
 		sessions, err := s.sessions.List(context.Background())
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Error listing sessions: %v", err), http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Error listing sessions: %v", err))
 			return
 		}
 
@@ -234,19 +417,18 @@ func (s *WebServer) handleSessions(w http.ResponseWriter, r *http.Request) {
 	case "POST":
 		// Create new session
 		var req CreateSessionRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+		if !decodeJSONBody(w, r, s.maxChatBodyBytes, &req) {
 			return
 		}
 
-		sessionData := session.Session{
-			ID:    fmt.Sprintf("web-session-%d", time.Now().Unix()),
-			Title: req.Name,
+		title := strings.TrimSpace(req.Name)
+		if title == "" {
+			title = defaultSessionTitle
 		}
 
-		_, err := s.sessions.Save(context.Background(), sessionData)
+		sessionData, err := s.sessions.Create(context.Background(), title)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Error creating session: %v", err), http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Error creating session: %v", err))
 			return
 		}
 
@@ -254,7 +436,7 @@ func (s *WebServer) handleSessions(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(sessionData)
 
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 	}
 }
 
@@ -298,6 +480,8 @@ func (s *WebServer) setCORSHeaders(w http.ResponseWriter) {
 type ChatRequest struct {
 	SessionID string `json:"session_id,omitempty"`
 	Message   string `json:"message"`
+	// NoCache asks for a fresh response even if an identical prompt is cached
+	NoCache bool `json:"no_cache,omitempty"`
 }
 
 type ChatResponse struct {
@@ -312,11 +496,13 @@ type DockerRequest struct {
 }
 
 type DockerResponse struct {
-	SessionID string    `json:"session_id"`
-	Success   bool      `json:"success"`
-	Message   string    `json:"message"`
-	Metadata  string    `json:"metadata,omitempty"`
-	Timestamp time.Time `json:"timestamp"`
+	SessionID string `json:"session_id"`
+	Success   bool   `json:"success"`
+	Message   string `json:"message"`
+	Metadata  string `json:"metadata,omitempty"`
+	// Containers is set for the list action
+	Containers []tools.DockerContainer `json:"containers,omitempty"`
+	Timestamp  time.Time               `json:"timestamp"`
 }
 
 type CreateSessionRequest struct {
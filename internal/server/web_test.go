@@ -0,0 +1,278 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebServerListenAddr(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		host string
+		port int
+		want string
+	}{
+		{"", 8080, "127.0.0.1:8080"},
+		{"127.0.0.1", 3000, "127.0.0.1:3000"},
+		{"0.0.0.0", 8080, "0.0.0.0:8080"},
+		{"localhost", 9000, "localhost:9000"},
+		{"::1", 8080, "[::1]:8080"},
+		{"::", 8080, "[::]:8080"},
+	}
+	for _, tt := range tests {
+		server := NewWebServer(tt.host, tt.port, nil, nil, nil)
+		require.Equal(t, tt.want, server.listenAddr(), "host %q", tt.host)
+	}
+}
+
+func TestWebServerURL(t *testing.T) {
+	t.Parallel()
+
+	server := NewWebServer("0.0.0.0", 8080, nil, nil, nil)
+	require.Equal(t, "http://localhost:8080", server.url(&net.TCPAddr{IP: net.IPv4zero, Port: 8080}))
+	require.Equal(t, "http://localhost:8080", server.url(&net.TCPAddr{IP: net.IPv6unspecified, Port: 8080}))
+	require.Equal(t, "http://192.168.1.5:8080", server.url(&net.TCPAddr{IP: net.IPv4(192, 168, 1, 5), Port: 8080}))
+
+	require.NoError(t, server.SetTLS(TLSOptions{SelfSigned: true}))
+	require.Equal(t, "https://[::1]:8443", server.url(&net.TCPAddr{IP: net.IPv6loopback, Port: 8443}))
+}
+
+func TestHandleChatRejectsOversizedBody(t *testing.T) {
+	t.Parallel()
+
+	server := NewWebServer("", 0, nil, nil, nil)
+	server.SetBodyLimits(64, 0)
+
+	body := `{"message": "` + strings.Repeat("a", 100) + `"}`
+	rec := httptest.NewRecorder()
+	server.handleChat(rec, httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(body)))
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	require.Contains(t, rec.Body.String(), "exceeds 64 bytes")
+}
+
+func TestHandleChatRejectsEmptyMessage(t *testing.T) {
+	t.Parallel()
+
+	server := NewWebServer("", 0, nil, nil, nil)
+	for _, body := range []string{`{"message": ""}`, `{"message": "  \n"}`, `{}`} {
+		rec := httptest.NewRecorder()
+		server.handleChat(rec, httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(body)))
+		require.Equal(t, http.StatusBadRequest, rec.Code, body)
+		require.Contains(t, rec.Body.String(), "Message is required", body)
+	}
+
+	rec := httptest.NewRecorder()
+	server.handleChat(rec, httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(`{"message":`)))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "Invalid request body")
+}
+
+func TestHandleDockerRejectsOversizedBody(t *testing.T) {
+	t.Parallel()
+
+	server := NewWebServer("", 0, nil, nil, nil)
+	server.SetBodyLimits(0, 128)
+
+	body := `{"params": {"files": {"main.go": "` + strings.Repeat("x", 200) + `"}}}`
+	rec := httptest.NewRecorder()
+	server.handleDocker(rec, httptest.NewRequest(http.MethodPost, "/api/docker", strings.NewReader(body)))
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
+
+func TestAPIErrorsAreJSON(t *testing.T) {
+	t.Parallel()
+
+	server := NewWebServer("", 0, nil, nil, nil)
+	server.SetBodyLimits(64, 0)
+	handler, err := server.Handler()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		status  int
+		message string
+	}{
+		{"chat method", http.MethodGet, "/api/chat", "", http.StatusMethodNotAllowed, "Method not allowed"},
+		{"chat empty message", http.MethodPost, "/api/chat", `{"message": ""}`, http.StatusBadRequest, "Message is required"},
+		{"chat invalid body", http.MethodPost, "/api/chat", `{"message":`, http.StatusBadRequest, "Invalid request body"},
+		{"chat oversized body", http.MethodPost, "/api/chat", `{"message": "` + strings.Repeat("a", 100) + `"}`, http.StatusRequestEntityTooLarge, "Request body exceeds 64 bytes"},
+		{"docker method", http.MethodDelete, "/api/docker", "", http.StatusMethodNotAllowed, "Method not allowed"},
+		{"sessions method", http.MethodDelete, "/api/sessions", "", http.StatusMethodNotAllowed, "Method not allowed"},
+		{"permissions without token", http.MethodGet, "/api/permissions", "", http.StatusForbidden, "Endpoint disabled: start the web server with --auth-token to enable it"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+
+			require.Equal(t, tt.status, rec.Code)
+			require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			var resp ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp), rec.Body.String())
+			require.Equal(t, ErrorResponse{Error: tt.message, Status: tt.status}, resp)
+		})
+	}
+}
+
+// stubDocker puts a fake docker binary first on PATH that prints output for
+// every command
+func stubDocker(t *testing.T, output string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("stub docker binary requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	script := "#!/bin/sh\nif [ \"$1\" = \"--version\" ]; then exit 0; fi\nprintf '%s' '" + output + "'\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker"), []byte(script), 0o755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestHandleDockerListsContainers(t *testing.T) {
+	stubDocker(t, "crush-app-web-instance\trunning\tUp 5 minutes\t0.0.0.0:3000->3000/tcp\ncrush-app-api-instance\texited\tExited (1) 2 minutes ago\t\n")
+
+	server := NewWebServer("", 0, nil, nil, permission.NewPermissionService(t.TempDir(), true, nil))
+	rec := httptest.NewRecorder()
+	server.handleDocker(rec, httptest.NewRequest(http.MethodGet, "/api/docker?session_id=dash", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp DockerResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.True(t, resp.Success, resp.Message)
+	require.Equal(t, "dash", resp.SessionID)
+	require.Equal(t, []tools.DockerContainer{
+		{Name: "crush-app-web-instance", Project: "web", State: "running", Status: "Up 5 minutes", Ports: "0.0.0.0:3000->3000/tcp"},
+		{Name: "crush-app-api-instance", Project: "api", State: "exited", Status: "Exited (1) 2 minutes ago"},
+	}, resp.Containers)
+}
+
+func TestHandleDockerRejectsUnknownAction(t *testing.T) {
+	t.Parallel()
+
+	server := NewWebServer("", 0, nil, nil, nil)
+	tests := map[string]string{
+		`{"params": {"action": "destroy_everything"}}`: "Unknown Docker action: destroy_everything",
+		`{"params": {"project_name": "web"}}`:          "params.action is required",
+		`{"params": "build"}`:                          "Invalid params",
+	}
+	for body, message := range tests {
+		rec := httptest.NewRecorder()
+		server.handleDocker(rec, httptest.NewRequest(http.MethodPost, "/api/docker", strings.NewReader(body)))
+		require.Equal(t, http.StatusBadRequest, rec.Code, body)
+		require.Contains(t, rec.Body.String(), message, body)
+	}
+}
+
+// newTestSessions returns a session service backed by a fresh SQLite database
+func newTestSessions(t *testing.T) session.Service {
+	t.Helper()
+
+	conn, err := db.ConnectSQLite(context.Background(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return session.NewService(db.New(conn))
+}
+
+func TestHandleSessionsCreatesUniqueSessions(t *testing.T) {
+	t.Parallel()
+
+	sessions := newTestSessions(t)
+	server := NewWebServer("", 0, nil, sessions, nil)
+
+	var created []session.Session
+	for _, body := range []string{`{"name": "Docker app"}`, `{"name": "Docker app"}`, `{}`} {
+		rec := httptest.NewRecorder()
+		server.handleSessions(rec, httptest.NewRequest(http.MethodPost, "/api/sessions", strings.NewReader(body)))
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var sess session.Session
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &sess))
+		_, err := uuid.Parse(sess.ID)
+		require.NoError(t, err, sess.ID)
+		created = append(created, sess)
+	}
+	require.NotEqual(t, created[0].ID, created[1].ID, "sessions created in the same second must not collide")
+	require.Equal(t, "Docker app", created[1].Title)
+	require.Equal(t, defaultSessionTitle, created[2].Title)
+
+	listed, err := sessions.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, listed, 3)
+}
+
+func TestHandleChatCreatesSession(t *testing.T) {
+	t.Parallel()
+
+	broker := pubsub.NewBroker[message.Message]()
+	t.Cleanup(broker.Shutdown)
+	sessions := newTestSessions(t)
+	server := NewWebServer("", 0, &fakeAgent{messages: broker}, sessions, nil)
+
+	rec := httptest.NewRecorder()
+	server.handleChat(rec, httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(`{"message": "hello"}`)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var resp ChatResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, "answer to hello", resp.Response)
+
+	sess, err := sessions.Get(context.Background(), resp.SessionID)
+	require.NoError(t, err)
+	require.Equal(t, defaultSessionTitle, sess.Title)
+
+	// Later messages continue the session instead of creating another
+	rec = httptest.NewRecorder()
+	server.handleChat(rec, httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(`{"message": "again", "session_id": "`+resp.SessionID+`"}`)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	listed, err := sessions.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+
+	rec = httptest.NewRecorder()
+	server.handleChat(rec, httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(`{"message": "hi", "session_id": "web-session-1"}`)))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Contains(t, rec.Body.String(), "Session not found: web-session-1")
+}
+
+func TestHandleChatBypassesCache(t *testing.T) {
+	t.Parallel()
+
+	broker := pubsub.NewBroker[message.Message]()
+	t.Cleanup(broker.Shutdown)
+	fake := &fakeAgent{messages: broker}
+	server := NewWebServer("", 0, fake, newTestSessions(t), nil)
+
+	for _, body := range []string{`{"message": "what time is it"}`, `{"message": "what time is it", "no_cache": true}`} {
+		rec := httptest.NewRecorder()
+		server.handleChat(rec, httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(body)))
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	ctxs := fake.contexts()
+	require.Len(t, ctxs, 2)
+	require.False(t, agent.CacheBypassed(ctxs[0]))
+	require.True(t, agent.CacheBypassed(ctxs[1]))
+}
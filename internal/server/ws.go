@@ -0,0 +1,306 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsPongWait is how long the connection may stay silent before it is
+	// considered dead; any message or pong from the client resets it
+	wsPongWait = 60 * time.Second
+	// wsPingInterval is how often the server pings the client, comfortably
+	// within wsPongWait
+	wsPingInterval = wsPongWait * 9 / 10
+	// wsWriteWait bounds how long a single frame may take to write
+	wsWriteWait = 10 * time.Second
+)
+
+// WebSocket frame types sent to the client. Agent events keep the name of
+// their agent.AgentEventType.
+const (
+	wsEventStarted    = "started"
+	wsEventQueued     = "queued"
+	wsEventToolCall   = "tool_call"
+	wsEventToolResult = "tool_result"
+)
+
+// WSClientMessage is a frame sent by a WebSocket client. Type is "message"
+// (the default) to send Message to the agent, or "cancel" to stop the
+// session's running request.
+type WSClientMessage struct {
+	Type      string `json:"type,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+	Message   string `json:"message,omitempty"`
+	// NoCache asks for a fresh response even if an identical prompt is cached
+	NoCache bool `json:"no_cache,omitempty"`
+}
+
+// WSEvent is a frame sent to a WebSocket client
+type WSEvent struct {
+	Type       string              `json:"type"`
+	SessionID  string              `json:"session_id,omitempty"`
+	Content    string              `json:"content,omitempty"`
+	ToolCall   *message.ToolCall   `json:"tool_call,omitempty"`
+	ToolResult *message.ToolResult `json:"tool_result,omitempty"`
+	Progress   string              `json:"progress,omitempty"`
+	Done       bool                `json:"done,omitempty"`
+	Error      string              `json:"error,omitempty"`
+	Timestamp  time.Time           `json:"timestamp"`
+}
+
+// SetMessages sets the message service the WebSocket endpoint watches to
+// report tool calls and results while the agent works. Without it only agent
+// events are streamed.
+func (s *WebServer) SetMessages(messages message.Service) {
+	s.messages = messages
+}
+
+// wsUpgrader upgrades /api/ws requests. Its default origin check only accepts
+// same-origin browsers, so other sites can't drive the agent.
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+}
+
+// wsSession is one WebSocket connection along with the chat sessions it
+// has sent messages to
+type wsSession struct {
+	server *WebServer
+	conn   *websocket.Conn
+	ctx    context.Context
+
+	// Serializes writes, which the connection allows from one goroutine at a time
+	writeMu sync.Mutex
+
+	sessionsMu sync.Mutex
+	sessions   map[string]bool
+
+	// Tool calls and results already reported, keyed by tool call ID
+	reportedMu sync.Mutex
+	calls      map[string]bool
+	results    map[string]bool
+
+	runs sync.WaitGroup
+}
+
+// WebSocket chat endpoint. Clients send WSClientMessage frames and receive
+// WSEvent frames for every agent event and tool call of their sessions.
+func (s *WebServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if s.agent == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "Agent unavailable")
+		return
+	}
+
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied with an error
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ws := &wsSession{
+		server:   s,
+		conn:     conn,
+		ctx:      ctx,
+		sessions: make(map[string]bool),
+		calls:    make(map[string]bool),
+		results:  make(map[string]bool),
+	}
+	defer func() {
+		// Stop requests for a client that is gone, then wait for their
+		// events to drain before closing the connection
+		cancel()
+		ws.runs.Wait()
+		conn.Close()
+	}()
+
+	if s.messages != nil {
+		messages := s.messages.Subscribe(ctx)
+		ws.runs.Go(func() { ws.watchMessages(messages) })
+	}
+	ws.runs.Go(ws.ping)
+	ws.read()
+}
+
+// read handles client frames until the connection closes or goes silent
+func (ws *wsSession) read() {
+	ws.conn.SetReadLimit(ws.server.maxChatBodyBytes)
+	ws.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	ws.conn.SetPongHandler(func(string) error {
+		return ws.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var msg WSClientMessage
+		if err := ws.conn.ReadJSON(&msg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				ws.server.logger.Debug("WebSocket connection closed", "error", err)
+			}
+			return
+		}
+		ws.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+
+		switch msg.Type {
+		case "", "message":
+			ws.startRun(msg)
+		case "cancel":
+			if msg.SessionID != "" && ws.owns(msg.SessionID) {
+				ws.server.agent.Cancel(msg.SessionID)
+			}
+		default:
+			ws.send(WSEvent{Type: string(agent.AgentEventTypeError), SessionID: msg.SessionID, Error: fmt.Sprintf("Unknown message type: %s", msg.Type)})
+		}
+	}
+}
+
+// startRun sends a client message to the agent and streams its events
+func (ws *wsSession) startRun(msg WSClientMessage) {
+	if strings.TrimSpace(msg.Message) == "" {
+		ws.send(WSEvent{Type: string(agent.AgentEventTypeError), SessionID: msg.SessionID, Error: "Message is required"})
+		return
+	}
+
+	sessionID, err := ws.server.chatSession(ws.ctx, msg.SessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		ws.send(WSEvent{Type: string(agent.AgentEventTypeError), SessionID: msg.SessionID, Error: fmt.Sprintf("Session not found: %s", msg.SessionID)})
+		return
+	}
+	if err != nil {
+		ws.send(WSEvent{Type: string(agent.AgentEventTypeError), SessionID: msg.SessionID, Error: fmt.Sprintf("Error creating session: %v", err)})
+		return
+	}
+	ws.sessionsMu.Lock()
+	ws.sessions[sessionID] = true
+	ws.sessionsMu.Unlock()
+
+	ctx := context.WithValue(ws.ctx, tools.SessionIDContextKey, sessionID)
+	if msg.NoCache {
+		ctx = agent.WithCacheBypass(ctx)
+	}
+	events, err := ws.server.agent.Run(ctx, sessionID, msg.Message)
+	if err != nil {
+		ws.send(WSEvent{Type: string(agent.AgentEventTypeError), SessionID: sessionID, Error: fmt.Sprintf("Agent error: %v", err)})
+		return
+	}
+	if events == nil {
+		// The session is busy, so the agent queued the message for later
+		ws.send(WSEvent{Type: wsEventQueued, SessionID: sessionID})
+		return
+	}
+
+	ws.send(WSEvent{Type: wsEventStarted, SessionID: sessionID})
+	ws.runs.Go(func() {
+		for event := range events {
+			ws.send(agentEventFrame(sessionID, event))
+		}
+	})
+}
+
+// agentEventFrame converts an agent event to a frame
+func agentEventFrame(sessionID string, event agent.AgentEvent) WSEvent {
+	frame := WSEvent{
+		Type:      string(event.Type),
+		SessionID: cmp.Or(event.SessionID, sessionID),
+		Progress:  event.Progress,
+		Done:      event.Done,
+	}
+	if event.Error != nil {
+		frame.Type = string(agent.AgentEventTypeError)
+		frame.Error = event.Error.Error()
+	}
+	if event.Type == agent.AgentEventTypeResponse {
+		frame.Content = event.Message.Content().String()
+	}
+	return frame
+}
+
+// watchMessages reports tool calls as they start and finish and tool results
+// as they arrive, for the sessions of this connection
+func (ws *wsSession) watchMessages(events <-chan pubsub.Event[message.Message]) {
+	for event := range events {
+		msg := event.Payload
+		if event.Type == pubsub.DeletedEvent || !ws.owns(msg.SessionID) {
+			continue
+		}
+
+		for _, call := range msg.ToolCalls() {
+			if ws.report(ws.calls, call.ID, call.Finished) {
+				ws.send(WSEvent{Type: wsEventToolCall, SessionID: msg.SessionID, ToolCall: &call})
+			}
+		}
+		for _, result := range msg.ToolResults() {
+			if ws.report(ws.results, result.ToolCallID, true) {
+				ws.send(WSEvent{Type: wsEventToolResult, SessionID: msg.SessionID, ToolResult: &result})
+			}
+		}
+	}
+}
+
+// report records that id reached the given completion and reports whether
+// that is news: the first sighting, and the first sighting once complete
+func (ws *wsSession) report(seen map[string]bool, id string, complete bool) bool {
+	ws.reportedMu.Lock()
+	defer ws.reportedMu.Unlock()
+
+	wasComplete, exists := seen[id]
+	if exists && (wasComplete || !complete) {
+		return false
+	}
+	seen[id] = complete
+	return true
+}
+
+// owns reports whether this connection has sent messages to sessionID
+func (ws *wsSession) owns(sessionID string) bool {
+	ws.sessionsMu.Lock()
+	defer ws.sessionsMu.Unlock()
+
+	return ws.sessions[sessionID]
+}
+
+// ping keeps the connection alive until it closes
+func (ws *wsSession) ping() {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ws.ctx.Done():
+			return
+		case <-ticker.C:
+			ws.writeMu.Lock()
+			err := ws.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+			ws.writeMu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// send writes a frame, dropping it when the client has gone away
+func (ws *wsSession) send(frame WSEvent) {
+	frame.Timestamp = time.Now()
+
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+
+	ws.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	if err := ws.conn.WriteJSON(frame); err != nil {
+		ws.server.logger.Debug("Failed to write WebSocket frame", "type", frame.Type, "error", err)
+	}
+}
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAgent answers every message after reporting one tool call through the
+// message broker, the way the real agent updates messages while it works
+type fakeAgent struct {
+	agent.Service
+	messages *pubsub.Broker[message.Message]
+
+	mu    sync.Mutex
+	runs  []string
+	ctxs  []context.Context
+	block bool
+}
+
+func (a *fakeAgent) Run(ctx context.Context, sessionID string, content string, _ ...message.Attachment) (<-chan agent.AgentEvent, error) {
+	a.mu.Lock()
+	a.runs = append(a.runs, content)
+	a.ctxs = append(a.ctxs, ctx)
+	block := a.block
+	a.mu.Unlock()
+
+	events := make(chan agent.AgentEvent)
+	go func() {
+		defer close(events)
+		if block {
+			<-ctx.Done()
+			return
+		}
+
+		call := message.ToolCall{ID: "call-" + content, Name: "view", Input: `{"file_path":"main.go"}`}
+		a.messages.Publish(pubsub.CreatedEvent, message.Message{SessionID: sessionID, Role: message.Assistant, Parts: []message.ContentPart{call}})
+		call.Finished = true
+		a.messages.Publish(pubsub.UpdatedEvent, message.Message{SessionID: sessionID, Role: message.Assistant, Parts: []message.ContentPart{call}})
+		a.messages.Publish(pubsub.CreatedEvent, message.Message{SessionID: sessionID, Role: message.Tool, Parts: []message.ContentPart{
+			message.ToolResult{ToolCallID: call.ID, Name: "view", Content: "package main"},
+		}})
+		// Another session's tool calls must not reach this client
+		a.messages.Publish(pubsub.CreatedEvent, message.Message{SessionID: "other", Role: message.Assistant, Parts: []message.ContentPart{
+			message.ToolCall{ID: "foreign", Name: "bash", Finished: true},
+		}})
+
+		// Give the message events a head start, as the real agent's response
+		// comes after its tool calls
+		time.Sleep(50 * time.Millisecond)
+		events <- agent.AgentEvent{
+			Type:    agent.AgentEventTypeResponse,
+			Message: message.Message{Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: "answer to " + content}}},
+			Done:    true,
+		}
+	}()
+	return events, nil
+}
+
+func (a *fakeAgent) contexts() []context.Context {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]context.Context(nil), a.ctxs...)
+}
+
+type fakeMessages struct {
+	message.Service
+	broker *pubsub.Broker[message.Message]
+}
+
+func (m fakeMessages) Subscribe(ctx context.Context) <-chan pubsub.Event[message.Message] {
+	return m.broker.Subscribe(ctx)
+}
+
+// fakeSessions knows every session it is asked for
+type fakeSessions struct {
+	session.Service
+}
+
+func (fakeSessions) Get(_ context.Context, id string) (session.Session, error) {
+	return session.Session{ID: id}, nil
+}
+
+func newWebSocketTestServer(t *testing.T) (*fakeAgent, *websocket.Conn) {
+	t.Helper()
+
+	broker := pubsub.NewBroker[message.Message]()
+	t.Cleanup(broker.Shutdown)
+	fake := &fakeAgent{messages: broker}
+
+	server := NewWebServer("", 0, fake, fakeSessions{}, nil)
+	server.SetMessages(fakeMessages{broker: broker})
+	handler, err := server.Handler()
+	require.NoError(t, err)
+	httpServer := httptest.NewServer(handler)
+	t.Cleanup(httpServer.Close)
+
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http")+"/api/ws", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	t.Cleanup(func() { conn.Close() })
+	return fake, conn
+}
+
+// readUntil reads frames until one of the given type arrives
+func readUntil(t *testing.T, conn *websocket.Conn, frameType string) []WSEvent {
+	t.Helper()
+
+	var frames []WSEvent
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	for {
+		var frame WSEvent
+		require.NoError(t, conn.ReadJSON(&frame))
+		frames = append(frames, frame)
+		if frame.Type == frameType {
+			return frames
+		}
+	}
+}
+
+func framesOfType(frames []WSEvent, frameType string) []WSEvent {
+	var matching []WSEvent
+	for _, frame := range frames {
+		if frame.Type == frameType {
+			matching = append(matching, frame)
+		}
+	}
+	return matching
+}
+
+func TestWebSocketStreamsToolCallsAndResponses(t *testing.T) {
+	t.Parallel()
+
+	fake, conn := newWebSocketTestServer(t)
+
+	require.NoError(t, conn.WriteJSON(WSClientMessage{SessionID: "s1", Message: "first"}))
+	frames := readUntil(t, conn, string(agent.AgentEventTypeResponse))
+	require.Equal(t, wsEventStarted, frames[0].Type)
+	require.Equal(t, "s1", frames[0].SessionID)
+
+	calls := framesOfType(frames, wsEventToolCall)
+	require.Len(t, calls, 2, "a tool call is reported when it starts and when it finishes")
+	require.Equal(t, "call-first", calls[0].ToolCall.ID)
+	require.False(t, calls[0].ToolCall.Finished)
+	require.True(t, calls[1].ToolCall.Finished)
+	require.Equal(t, `{"file_path":"main.go"}`, calls[1].ToolCall.Input)
+
+	results := framesOfType(frames, wsEventToolResult)
+	require.Len(t, results, 1)
+	require.Equal(t, "package main", results[0].ToolResult.Content)
+
+	response := frames[len(frames)-1]
+	require.Equal(t, "answer to first", response.Content)
+	require.True(t, response.Done)
+	require.Equal(t, "s1", response.SessionID)
+
+	// Follow-ups go over the same connection
+	require.NoError(t, conn.WriteJSON(WSClientMessage{Type: "message", SessionID: "s1", Message: "second"}))
+	frames = readUntil(t, conn, string(agent.AgentEventTypeResponse))
+	require.Equal(t, "answer to second", frames[len(frames)-1].Content)
+	require.Len(t, framesOfType(frames, wsEventToolCall), 2)
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	require.Equal(t, []string{"first", "second"}, fake.runs)
+}
+
+func TestWebSocketRejectsInvalidFrames(t *testing.T) {
+	t.Parallel()
+
+	_, conn := newWebSocketTestServer(t)
+
+	require.NoError(t, conn.WriteJSON(WSClientMessage{Message: "  "}))
+	frames := readUntil(t, conn, string(agent.AgentEventTypeError))
+	require.Equal(t, "Message is required", frames[0].Error)
+
+	require.NoError(t, conn.WriteJSON(WSClientMessage{Type: "shout", Message: "hi"}))
+	frames = readUntil(t, conn, string(agent.AgentEventTypeError))
+	require.Contains(t, frames[0].Error, "Unknown message type: shout")
+}
+
+func TestWebSocketAnswersPings(t *testing.T) {
+	t.Parallel()
+
+	_, conn := newWebSocketTestServer(t)
+
+	pong := make(chan string, 1)
+	conn.SetPongHandler(func(data string) error {
+		pong <- data
+		return nil
+	})
+	require.NoError(t, conn.WriteControl(websocket.PingMessage, []byte("hello"), time.Now().Add(time.Second)))
+
+	// Control frames are handled while reading, so read until the pong arrives
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	select {
+	case data := <-pong:
+		require.Equal(t, "hello", data)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no pong received")
+	}
+}
+
+func TestWebSocketDisconnectCancelsRuns(t *testing.T) {
+	t.Parallel()
+
+	fake, conn := newWebSocketTestServer(t)
+	fake.mu.Lock()
+	fake.block = true
+	fake.mu.Unlock()
+
+	require.NoError(t, conn.WriteJSON(WSClientMessage{SessionID: "s1", Message: "long task"}))
+	readUntil(t, conn, wsEventStarted)
+
+	require.NoError(t, conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")))
+	_, _, err := conn.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), "the server should answer the close: %v", err)
+
+	ctxs := fake.contexts()
+	require.Len(t, ctxs, 1)
+	select {
+	case <-ctxs[0].Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the run was not cancelled after the client disconnected")
+	}
+}
+
+func TestWebSocketRequiresAgent(t *testing.T) {
+	t.Parallel()
+
+	server := NewWebServer("", 0, nil, nil, nil)
+	rec := httptest.NewRecorder()
+	server.handleWebSocket(rec, httptest.NewRequest(http.MethodGet, "/api/ws", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}